@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+
+	"github.com/tunogya/etna/pkg/config"
+	"github.com/tunogya/etna/pkg/store/duckdb"
+)
+
+// Config holds analyze configuration
+type Config struct {
+	Symbol    string
+	Timeframe string
+
+	DuckDBPath string
+
+	// Reports
+	Correlation  bool
+	FeatureStats bool
+	ExplainQuery string // SQL to profile with EXPLAIN ANALYZE
+}
+
+func main() {
+	cfg := parseFlags()
+
+	ctx := context.Background()
+
+	// Initialize DuckDB
+	log.Println("Connecting to DuckDB...")
+	duckClient, err := duckdb.NewClient(cfg.DuckDBPath)
+	if err != nil {
+		log.Fatalf("Failed to connect to DuckDB: %v", err)
+	}
+	defer duckClient.Close()
+
+	featureRepo := duckdb.NewFeatureRepo(duckClient)
+
+	if cfg.Correlation {
+		matrix, err := featureRepo.ComputeFeatureCorrelationMatrix(ctx, cfg.Symbol, cfg.Timeframe)
+		if err != nil {
+			log.Fatalf("Failed to compute correlation matrix: %v", err)
+		}
+		printCorrelationMatrix(matrix)
+	}
+
+	if cfg.FeatureStats {
+		distributions, err := featureRepo.GetAllFeatureDistributions(ctx, cfg.Symbol, cfg.Timeframe)
+		if err != nil {
+			log.Fatalf("Failed to compute feature distributions: %v", err)
+		}
+		printFeatureDistributions(distributions)
+		printBucketSuggestions(distributions)
+	}
+
+	if cfg.ExplainQuery != "" {
+		profile, err := duckClient.ProfileQuery(cfg.ExplainQuery)
+		if err != nil {
+			log.Fatalf("Failed to profile query: %v", err)
+		}
+		printQueryProfile(profile)
+	}
+}
+
+// printQueryProfile prints the analyzed plan followed by its timing summary
+func printQueryProfile(p *duckdb.QueryProfile) {
+	fmt.Println("\n=== Query Plan ===")
+	fmt.Println(p.Plan)
+	fmt.Printf("Total time: %.3f ms, rows returned: %d\n", p.TotalTimeMs, p.RowsReturned)
+}
+
+// printFeatureDistributions prints each feature's quantiles and moments
+func printFeatureDistributions(distributions map[string]*duckdb.Distribution) {
+	fmt.Println("\n=== Feature Distributions ===")
+	fmt.Printf("%-20s %8s %10s %10s %10s %10s %10s %10s %10s %10s %10s\n",
+		"Feature", "Count", "Min", "P10", "P25", "P50", "P75", "P90", "Max", "Mean", "StdDev")
+
+	for _, f := range duckdb.FeatureColumns() {
+		d := distributions[f]
+		if d == nil {
+			continue
+		}
+		fmt.Printf("%-20s %8d %10.4f %10.4f %10.4f %10.4f %10.4f %10.4f %10.4f %10.4f %10.4f\n",
+			truncate(f, 20), d.Count, d.Min, d.P10, d.P25, d.P50, d.P75, d.P90, d.Max, d.Mean, d.StdDev)
+	}
+}
+
+// Current bucket boundaries of model.ClassifyTrendBucket and model.ClassifyVolBucket
+var (
+	currentTrendThresholds = [4]float64{-0.02, -0.005, 0.005, 0.02}
+	currentVolRange        = [2]float64{-2, 2}
+)
+
+// printBucketSuggestions suggests bucket boundaries fitted to the empirical distributions
+// Trend thresholds put 10% of windows in each strong bucket, 15% in each moderate bucket, and 50% in neutral;
+// the volume z-score range spans P10 to P90 so the ten volume buckets cover the bulk of the data
+func printBucketSuggestions(distributions map[string]*duckdb.Distribution) {
+	fmt.Println("\n=== Suggested Bucket Thresholds ===")
+
+	if d := distributions["trend_slope"]; d != nil && d.Count > 0 {
+		fmt.Println("ClassifyTrendBucket (strong down | down | neutral | up | strong up):")
+		fmt.Printf("  current:   %v\n", formatThresholds(currentTrendThresholds[:]))
+		fmt.Printf("  suggested: %v\n", formatThresholds([]float64{d.P10, d.P25, d.P75, d.P90}))
+	}
+
+	if d := distributions["vol_z_score"]; d != nil && d.Count > 0 {
+		fmt.Println("ClassifyVolBucket (z-score range mapped onto buckets 0-9):")
+		fmt.Printf("  current:   %v\n", formatThresholds(currentVolRange[:]))
+		fmt.Printf("  suggested: %v\n", formatThresholds([]float64{d.P10, d.P90}))
+	}
+}
+
+// formatThresholds formats threshold values as a bracketed list
+func formatThresholds(values []float64) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprintf("%.4f", v)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// printCorrelationMatrix prints the feature correlation matrix as a table
+func printCorrelationMatrix(m *duckdb.CorrelationMatrix) {
+	fmt.Println("\n=== Feature Correlation Matrix ===")
+
+	fmt.Printf("%-20s", "")
+	for _, f := range m.Features {
+		fmt.Printf(" %12s", truncate(f, 12))
+	}
+	fmt.Println()
+
+	for _, a := range m.Features {
+		fmt.Printf("%-20s", a)
+		for _, b := range m.Features {
+			v := m.Values[a][b]
+			if math.IsNaN(v) {
+				fmt.Printf(" %12s", "n/a")
+				continue
+			}
+			fmt.Printf(" %12.4f", v)
+		}
+		fmt.Println()
+	}
+}
+
+// truncate shortens s to at most n characters
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+func parseFlags() Config {
+	cfg := Config{}
+
+	flag.StringVar(&cfg.Symbol, "symbol", "BTCUSDT", "Trading symbol")
+	flag.StringVar(&cfg.Timeframe, "timeframe", "1d", "Timeframe")
+	flag.StringVar(&cfg.DuckDBPath, "duckdb", "etna.duckdb", "DuckDB path")
+	flag.BoolVar(&cfg.Correlation, "correlation", false, "Print the feature correlation matrix")
+	flag.BoolVar(&cfg.FeatureStats, "feature-stats", false, "Print feature distributions and suggested bucket thresholds")
+	flag.StringVar(&cfg.ExplainQuery, "explain-query", "", "Profile this SQL query with EXPLAIN ANALYZE and print its plan")
+
+	configPath := flag.String("config", "", "YAML or JSON config file; ETNA_* environment variables override it and explicit flags override both")
+
+	flag.Parse()
+	if err := config.ApplyFlags(flag.CommandLine, *configPath); err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
+	return cfg
+}
@@ -2,15 +2,29 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/tunogya/etna/pkg/config"
 	"github.com/tunogya/etna/pkg/data"
+	"github.com/tunogya/etna/pkg/data/binance"
 	"github.com/tunogya/etna/pkg/feature"
 	"github.com/tunogya/etna/pkg/model"
 	"github.com/tunogya/etna/pkg/outcome"
+	"github.com/tunogya/etna/pkg/queue/nats"
 	"github.com/tunogya/etna/pkg/rerank"
 	"github.com/tunogya/etna/pkg/store/duckdb"
 	"github.com/tunogya/etna/pkg/store/milvus"
@@ -20,22 +34,58 @@ import (
 // Config holds backfill configuration
 type Config struct {
 	// Data source
-	CSVPath   string
-	Symbol    string
-	Timeframe string
+	CSVPath    string
+	Symbol     string
+	Timeframe  string
+	Start      time.Time // First candle open time to backfill; zero for the start of the data
+	End        time.Time // Candles opening at or after End are left out; zero for now
+	MaxCandles int       // Backfill at most this many candles from Start (0 for no limit)
 
 	// Window configuration
 	WindowLength   int
 	StepSize       int
+	Overlap        float64 // Window overlap ratio in [0, 1); overrides StepSize when > 0
 	FeatureVersion int
 
 	// Storage
-	DuckDBPath string
-	MilvusAddr string
-	VectorDim  int
+	DuckDBPath      string
+	MilvusAddr      string
+	MilvusPartition string // Time partition granularity: year, quarter, or empty for none
+	VectorDim       int
 
 	// Processing
-	BatchSize int
+	BatchSize      int
+	Symbols        string        // Comma-separated symbols for concurrent candle ingestion
+	Workers        int           // Concurrent symbols when -symbols is set
+	StatusInterval time.Duration // How often to print the ingestion status table (0 disables)
+
+	// Optional data
+	IncludeFunding   bool
+	IncludeOrderBook bool  // Snapshot the current order book and join its spread onto recent windows
+	ComputeVWAP      bool  // Fill missing candle VWAP with the typical price
+	CacheOutcomes    bool  // Compute and store window outcomes once windows are stored
+	AnnealIndex      bool  // Rebuild the Milvus index with nlist sized to the final row count
+	Horizons         []int // Outcome horizons in bars
+
+	// Output
+	Output      string // direct writes DuckDB and Milvus; nats publishes write batches for the writer
+	NATSUrl     string
+	MaxInFlight int // Unacknowledged NATS publishes with -output nats
+
+	// Checkpointing
+	Resume bool // Continue the run with the same config from its last checkpoint
+
+	// Checks
+	DryRun bool // Report what the backfill would write, without writing anything
+	Verify bool // Compare stored DuckDB windows/features with Milvus vectors per symbol/timeframe
+
+	// Analysis
+	ClusterAnalysis bool // Report stored outcomes per volatility/trend cluster instead of backfilling
+
+	// Progress reporting
+	ProgressInterval time.Duration // How often to report progress
+	Quiet            bool          // Suppress progress reports
+	ProgressJSON     bool          // Report progress as JSON lines on stdout
 }
 
 func main() {
@@ -47,6 +97,21 @@ func main() {
 
 	ctx := context.Background()
 
+	// Dry run before connecting, since opening DuckDB creates the file and schema
+	if cfg.DryRun {
+		if problems := runDryRun(ctx, cfg); problems > 0 {
+			log.Fatalf("Dry run found %d problems", problems)
+		}
+		log.Println("Dry run found no problems")
+		return
+	}
+
+	// The writer owns DuckDB and Milvus in this mode, so neither is opened here
+	if cfg.Output == outputNATS {
+		publishBackfill(ctx, cfg)
+		return
+	}
+
 	// Initialize DuckDB
 	log.Println("Connecting to DuckDB...")
 	duckClient, err := duckdb.NewClient(cfg.DuckDBPath)
@@ -54,6 +119,9 @@ func main() {
 		log.Fatalf("Failed to connect to DuckDB: %v", err)
 	}
 	defer duckClient.Close()
+	if err := duckdb.ConfigureForBulkWrite(duckClient); err != nil {
+		log.Printf("Warning: failed to configure DuckDB: %v", err)
+	}
 
 	// Initialize schema
 	if err := duckdb.InitializeSchema(duckClient); err != nil {
@@ -66,9 +134,38 @@ func main() {
 	windowRepo := duckdb.NewWindowRepo(duckClient)
 	featureRepo := duckdb.NewFeatureRepo(duckClient)
 
+	if cfg.Symbols != "" {
+		runMultiSymbol(ctx, cfg, candleRepo)
+		return
+	}
+
+	if cfg.Verify {
+		if problems := runVerify(ctx, cfg, windowRepo); problems > 0 {
+			log.Fatalf("Verify found %d discrepancies", problems)
+		}
+		log.Println("Verify found no discrepancies")
+		return
+	}
+
+	if cfg.ClusterAnalysis {
+		runClusterAnalysis(ctx, cfg, candleRepo, featureRepo, duckdb.NewOutcomeRepo(duckClient))
+		return
+	}
+
+	// Load the run checkpoint, or start a new run
+	runRepo := duckdb.NewBackfillRunRepo(duckClient)
+	run := loadRun(ctx, cfg, runRepo)
+	if run.Phase == duckdb.BackfillPhaseDone {
+		log.Printf("Run %s already completed at %s, nothing to resume", run.RunID, run.UpdatedAt.Format(time.RFC3339))
+		return
+	}
+
 	// Initialize Milvus
 	log.Println("Connecting to Milvus...")
-	milvusClient, err := milvus.NewClient(ctx, milvus.Config{Address: cfg.MilvusAddr})
+	milvusClient, err := milvus.NewClient(ctx, milvus.Config{
+		Address:              cfg.MilvusAddr,
+		PartitionGranularity: cfg.MilvusPartition,
+	})
 	if err != nil {
 		log.Fatalf("Failed to connect to Milvus: %v", err)
 	}
@@ -85,113 +182,710 @@ func main() {
 	}
 	log.Println("Milvus collection ready")
 
+	progress := newProgress(cfg)
+	progress.Start()
+
 	// Load data
+	progress.StartStage("load", 0)
 	log.Printf("Loading data from %s...", cfg.CSVPath)
 	provider := data.NewCSVProvider(cfg.CSVPath)
-	candles, err := provider.FetchCandles(ctx, cfg.Symbol, cfg.Timeframe, time.Time{}, time.Now())
+	provider.ComputeMissingVWAP = cfg.ComputeVWAP
+	candles, err := fetchCandles(ctx, cfg, provider)
 	if err != nil {
 		log.Fatalf("Failed to load candles: %v", err)
 	}
 	log.Printf("Loaded %d candles", len(candles))
 
-	// Store candles in DuckDB
-	log.Println("Storing candles in DuckDB...")
-	if err := candleRepo.InsertBatch(ctx, candles); err != nil {
-		log.Fatalf("Failed to insert candles: %v", err)
+	if run.Phase == duckdb.BackfillPhaseCandles {
+		// Store candles in DuckDB
+		log.Println("Storing candles in DuckDB...")
+		storeCandles(ctx, cfg, run, runRepo, candleRepo, progress, candles)
+
+		// Fetch funding rates covering the candle range
+		if cfg.IncludeFunding && len(candles) > 0 {
+			log.Println("Fetching funding rates from Binance...")
+			fundingProvider := binance.NewFundingRateProvider()
+			rates, err := fundingProvider.FetchFundingRates(ctx, cfg.Symbol, candles[0].OpenTime, candles[len(candles)-1].CloseTime)
+			if err != nil {
+				log.Fatalf("Failed to fetch funding rates: %v", err)
+			}
+			if err := duckdb.NewFundingRateRepo(duckClient).InsertBatch(ctx, rates); err != nil {
+				log.Fatalf("Failed to insert funding rates: %v", err)
+			}
+			log.Printf("Stored %d funding rates", len(rates))
+		}
+
+		run.Phase = duckdb.BackfillPhaseWindows
+		saveRun(ctx, runRepo, run)
+	} else {
+		log.Printf("Skipping candles, already stored through %s", run.LastCandleOpenTime.Format(time.RFC3339))
 	}
 
 	// Build windows
+	progress.StartStage("build", 0)
+	log.Println("Building windows...")
+	windows := buildWindows(cfg, candles)
+	log.Printf("Built %d windows", len(windows))
+
+	extractor := feature.NewExtractor(cfg.FeatureVersion, cfg.VectorDim)
+	vectors := 0
+
+	if run.Phase == duckdb.BackfillPhaseWindows {
+		// Extract features, then store windows, features, and vectors one batch at a time
+		pending := windowsAfter(windows, run.LastWindowTEnd)
+		if len(pending) < len(windows) {
+			log.Printf("Skipping %d windows already embedded through %s", len(windows)-len(pending), run.LastWindowTEnd.Format(time.RFC3339))
+		}
+		log.Println("Extracting features and storing windows...")
+		progress.StartStage("windows", int64(len(pending)))
+		vectors = storeWindows(ctx, cfg, run, runRepo, windowRepo, featureRepo, milvusClient, extractor, progress, pending)
+
+		// Join a current order book snapshot onto windows ending within one bar of it
+		if cfg.IncludeOrderBook {
+			storeOrderBookSpread(ctx, cfg, duckClient)
+		}
+
+		// Flush Milvus
+		if err := milvusClient.Flush(ctx, milvus.DefaultCollectionName); err != nil {
+			log.Printf("Warning: failed to flush Milvus: %v", err)
+		} else {
+			run.FlushedBatches = run.MilvusBatches
+		}
+
+		run.Phase = duckdb.BackfillPhaseIndex
+		saveRun(ctx, runRepo, run)
+	} else {
+		log.Printf("Skipping windows, %d vector batches already written and flushed", run.FlushedBatches)
+	}
+
+	// Create index
+	progress.StartStage("index", 0)
+	log.Println("Creating Milvus index...")
+	if err := milvusClient.CreateIndex(ctx, milvus.DefaultCollectionName, "embedding"); err != nil {
+		log.Printf("Warning: failed to create index: %v", err)
+	}
+
+	// Load collection
+	if err := milvusClient.LoadCollection(ctx, milvus.DefaultCollectionName); err != nil {
+		log.Printf("Warning: failed to load collection: %v", err)
+	}
+
+	// Rebuild the index sized for the final row count
+	if cfg.AnnealIndex {
+		progress.StartStage("anneal", 0)
+		log.Println("Rebuilding Milvus index for the final row count...")
+		if err := milvusClient.AnnealIndex(ctx, milvus.DefaultCollectionName, "embedding", collectionCfg); err != nil {
+			log.Fatalf("Failed to anneal index: %v", err)
+		}
+	}
+
+	// Cache outcomes; already cached window-horizon pairs are skipped, so this is safe to repeat on resume
+	if cfg.CacheOutcomes {
+		progress.StartStage("outcomes", 0)
+		cacheOutcomes(ctx, cfg, candleRepo, windowRepo, duckdb.NewOutcomeRepo(duckClient))
+	}
+
+	run.Phase = duckdb.BackfillPhaseDone
+	saveRun(ctx, runRepo, run)
+	stages := progress.Stop()
+
+	log.Println("Backfill completed successfully!")
+	log.Printf("Summary: %d candles → %d windows → %d vectors written this run (run %s)", len(candles), len(windows), vectors, run.RunID)
+	logSummary(cfg, progress.Snapshot(), stages)
+
+	// Demo: query with the last window
+	if len(windows) > 0 {
+		demoQuery(ctx, cfg, windows[len(windows)-1], extractor, milvusClient, duckdb.NewOutcomeRepo(duckClient))
+	}
+}
+
+// Output modes
+const (
+	outputDirect = "direct"
+	outputNATS   = "nats"
+)
+
+// publishBackfill loads candles, builds windows, and publishes candle, window, and vector batches for the writer
+// Message IDs derive from each batch's symbol, timeframe, and time span, so rerunning within the stream's
+// duplicate window does not enqueue the same batches twice
+func publishBackfill(ctx context.Context, cfg Config) {
+	log.Println("Connecting to NATS...")
+	natsCfg := nats.DefaultConfig()
+	natsCfg.URL = cfg.NATSUrl
+	natsClient, err := nats.NewClient(natsCfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to NATS: %v", err)
+	}
+	defer natsClient.Close()
+	if err := natsClient.CreateStream(ctx, nats.StreamSubjects); err != nil {
+		log.Fatalf("Failed to create stream: %v", err)
+	}
+
+	progress := newProgress(cfg)
+	progress.Start()
+
+	progress.StartStage("load", 0)
+	log.Printf("Loading data from %s...", cfg.CSVPath)
+	provider := data.NewCSVProvider(cfg.CSVPath)
+	provider.ComputeMissingVWAP = cfg.ComputeVWAP
+	candles, err := fetchCandles(ctx, cfg, provider)
+	if err != nil {
+		log.Fatalf("Failed to load candles: %v", err)
+	}
+	log.Printf("Loaded %d candles", len(candles))
+
+	publisher := natsClient.NewAsyncPublisher(cfg.MaxInFlight)
+
+	progress.StartStage("candles", int64(len(candles)))
+	for i := 0; i < len(candles); i += cfg.BatchSize {
+		batch := candles[i:min(i+cfg.BatchSize, len(candles))]
+		id := batchID(cfg, "candles", batch[0].OpenTime, batch[len(batch)-1].OpenTime)
+		if err := publisher.PublishCandleBatch(ctx, id, &nats.CandleBatchMsg{Candles: batch}); err != nil {
+			log.Fatalf("Failed to publish candles: %v", err)
+		}
+		progress.AddCandles(int64(len(batch)))
+		progress.Advance(int64(len(batch)))
+	}
+
+	progress.StartStage("build", 0)
 	log.Println("Building windows...")
-	builder := window.NewBuilder(window.Config{
+	windows := buildWindows(cfg, candles)
+	log.Printf("Built %d windows", len(windows))
+
+	extractor := feature.NewExtractor(cfg.FeatureVersion, cfg.VectorDim)
+	progress.StartStage("windows", int64(len(windows)))
+	vectors := 0
+	for i := 0; i < len(windows); i += cfg.BatchSize {
+		batch := windows[i:min(i+cfg.BatchSize, len(windows))]
+
+		windowMsg := &nats.WindowBatchMsg{Windows: batch}
+		vectorMsg := &nats.MilvusBatchMsg{}
+		for _, w := range batch {
+			featureRow, shapeVector, err := extractor.Extract(w)
+			if err != nil {
+				log.Printf("Warning: failed to extract features for window %s: %v", w.WindowID, err)
+				continue
+			}
+			windowMsg.Features = append(windowMsg.Features, featureRow)
+			vectorMsg.Vectors = append(vectorMsg.Vectors, nats.NewMilvusWriteMsg(w, featureRow, shapeVector))
+		}
+
+		first, last := batch[0].TEnd, batch[len(batch)-1].TEnd
+		if err := publisher.PublishWindowBatch(ctx, batchID(cfg, "windows", first, last), windowMsg); err != nil {
+			log.Fatalf("Failed to publish windows: %v", err)
+		}
+		if len(vectorMsg.Vectors) > 0 {
+			if err := publisher.PublishMilvusBatch(ctx, batchID(cfg, "vectors", first, last), vectorMsg); err != nil {
+				log.Fatalf("Failed to publish vectors: %v", err)
+			}
+		}
+		vectors += len(vectorMsg.Vectors)
+		progress.AddWindows(int64(len(batch)))
+		progress.AddMilvusRows(int64(len(vectorMsg.Vectors)))
+		progress.Advance(int64(len(batch)))
+	}
+
+	progress.StartStage("acks", 0)
+	published, err := publisher.Wait()
+	if err != nil {
+		log.Fatalf("Failed to publish batches: %v", err)
+	}
+	stages := progress.Stop()
+
+	log.Println("Backfill published successfully!")
+	log.Printf("Summary: %d candles → %d windows → %d vectors in %d messages for the writer", len(candles), len(windows), vectors, published)
+	logSummary(cfg, progress.Snapshot(), stages)
+}
+
+// batchID returns the message ID base of a published batch spanning [first, last]
+// It depends only on the data, so republishing the same batch yields the same IDs
+func batchID(cfg Config, kind string, first, last time.Time) string {
+	return fmt.Sprintf("backfill.%s.%s.%s.v%d.%d-%d", cfg.Symbol, cfg.Timeframe, kind, cfg.FeatureVersion, first.UnixMilli(), last.UnixMilli())
+}
+
+// runID derives the checkpoint key of a single-symbol backfill from the config fields that shape its output
+func runID(cfg Config) string {
+	key := fmt.Sprintf("%s|%s|%s|%d|%d|%g|%d|%d|%s",
+		cfg.Symbol, cfg.Timeframe, cfg.CSVPath, cfg.WindowLength, cfg.StepSize, cfg.Overlap,
+		cfg.FeatureVersion, cfg.VectorDim, cfg.MilvusPartition)
+	// Only limited runs extend the key, so unlimited runs keep their earlier IDs
+	if !cfg.Start.IsZero() || !cfg.End.IsZero() || cfg.MaxCandles > 0 {
+		key += fmt.Sprintf("|%d|%d|%d", cfg.Start.Unix(), cfg.End.Unix(), cfg.MaxCandles)
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// loadRun returns the saved checkpoint of this config's run when resuming, otherwise a new run from the first phase
+func loadRun(ctx context.Context, cfg Config, runRepo *duckdb.BackfillRunRepo) *duckdb.BackfillRun {
+	id := runID(cfg)
+	if cfg.Resume {
+		run, err := runRepo.Get(ctx, id)
+		if err == nil {
+			log.Printf("Resuming run %s from phase %s (last checkpoint %s)", id, run.Phase, run.UpdatedAt.Format(time.RFC3339))
+			return run
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Fatalf("Failed to load checkpoint: %v", err)
+		}
+		log.Printf("No checkpoint for run %s, starting from scratch", id)
+	}
+
+	run := &duckdb.BackfillRun{
+		RunID:     id,
+		Symbol:    cfg.Symbol,
+		Timeframe: cfg.Timeframe,
+		Phase:     duckdb.BackfillPhaseCandles,
+	}
+	saveRun(ctx, runRepo, run)
+	log.Printf("Started run %s", id)
+	return run
+}
+
+// saveRun persists run's checkpoint, exiting on failure so progress is never silently lost
+func saveRun(ctx context.Context, runRepo *duckdb.BackfillRunRepo, run *duckdb.BackfillRun) {
+	if err := runRepo.Save(ctx, run); err != nil {
+		log.Fatalf("Failed to save checkpoint: %v", err)
+	}
+}
+
+// storeCandles inserts candles opened after the checkpoint in batches, checkpointing after each one
+func storeCandles(ctx context.Context, cfg Config, run *duckdb.BackfillRun, runRepo *duckdb.BackfillRunRepo, candleRepo *duckdb.CandleRepo,
+	progress *data.ProgressTracker, candles []model.Candle) {
+	start := 0
+	if !run.LastCandleOpenTime.IsZero() {
+		start = sort.Search(len(candles), func(i int) bool {
+			return candles[i].OpenTime.After(run.LastCandleOpenTime)
+		})
+		log.Printf("Skipping %d candles already stored through %s", start, run.LastCandleOpenTime.Format(time.RFC3339))
+	}
+
+	progress.StartStage("candles", int64(len(candles)-start))
+	for i := start; i < len(candles); i += cfg.BatchSize {
+		end := min(i+cfg.BatchSize, len(candles))
+		if err := candleRepo.InsertBatch(ctx, candles[i:end]); err != nil {
+			log.Fatalf("Failed to insert candles: %v", err)
+		}
+		run.LastCandleOpenTime = candles[end-1].OpenTime
+		saveRun(ctx, runRepo, run)
+		progress.AddCandles(int64(end - i))
+		progress.Advance(int64(end - i))
+	}
+}
+
+// windowsAfter returns the windows ending after t; windows are in TEnd order
+func windowsAfter(windows []*model.Window, t time.Time) []*model.Window {
+	if t.IsZero() {
+		return windows
+	}
+	i := sort.Search(len(windows), func(i int) bool {
+		return windows[i].TEnd.After(t)
+	})
+	return windows[i:]
+}
+
+// storeWindows extracts, stores, and embeds windows in batches, checkpointing the last TEnd after each one
+// Resumed runs upsert vectors, so a batch written to Milvus just before a crash is not duplicated
+// It returns the number of vectors written
+func storeWindows(ctx context.Context, cfg Config, run *duckdb.BackfillRun, runRepo *duckdb.BackfillRunRepo,
+	windowRepo *duckdb.WindowRepo, featureRepo *duckdb.FeatureRepo, milvusClient *milvus.Client,
+	extractor *feature.Extractor, progress *data.ProgressTracker, windows []*model.Window) int {
+	writeVectors := milvusClient.InsertBatch
+	if cfg.Resume {
+		writeVectors = milvusClient.UpsertBatch
+	}
+
+	vectors := 0
+	for i := 0; i < len(windows); i += cfg.BatchSize {
+		batch := windows[i:min(i+cfg.BatchSize, len(windows))]
+
+		var milvusData []*milvus.WindowData
+		var features []*model.FeatureRow
+		for _, w := range batch {
+			featureRow, shapeVector, err := extractor.Extract(w)
+			if err != nil {
+				log.Printf("Warning: failed to extract features for window %s: %v", w.WindowID, err)
+				continue
+			}
+
+			features = append(features, featureRow)
+			milvusData = append(milvusData, &milvus.WindowData{
+				WindowID:    w.WindowID,
+				Embedding:   shapeVector,
+				Symbol:      w.Symbol,
+				Timeframe:   w.Timeframe,
+				TEnd:        w.TEnd,
+				VolBucket:   int32(featureRow.VolBucket),
+				TrendBucket: int32(featureRow.TrendBucket),
+				DataVersion: int32(featureRow.DataVersion),
+			})
+		}
+
+		if err := windowRepo.InsertBatch(ctx, batch); err != nil {
+			log.Fatalf("Failed to insert windows: %v", err)
+		}
+		if err := featureRepo.InsertBatch(ctx, features); err != nil {
+			log.Fatalf("Failed to insert features: %v", err)
+		}
+		if len(milvusData) > 0 {
+			if err := writeVectors(ctx, milvus.DefaultCollectionName, milvusData); err != nil {
+				log.Fatalf("Failed to insert vectors: %v", err)
+			}
+			run.MilvusBatches++
+		}
+		vectors += len(milvusData)
+
+		run.LastWindowTEnd = batch[len(batch)-1].TEnd
+		saveRun(ctx, runRepo, run)
+		progress.AddWindows(int64(len(batch)))
+		progress.AddMilvusRows(int64(len(milvusData)))
+		progress.Advance(int64(len(batch)))
+	}
+	return vectors
+}
+
+// fetchCandles loads the candles in cfg's range, preceded by WindowLength warm-up candles when the range
+// starts mid-history so that the first window ending in range is complete
+func fetchCandles(ctx context.Context, cfg Config, provider data.CandleProvider) ([]model.Candle, error) {
+	start := cfg.Start
+	if !start.IsZero() {
+		barDuration, err := model.TimeframeDuration(cfg.Timeframe)
+		if err != nil {
+			return nil, err
+		}
+		start = start.Add(-time.Duration(cfg.WindowLength) * barDuration)
+	}
+	end := cfg.End
+	if end.IsZero() {
+		end = time.Now()
+	}
+
+	// FetchCandles includes candles opening at end
+	candles, err := provider.FetchCandles(ctx, cfg.Symbol, cfg.Timeframe, start, end.Add(-time.Nanosecond))
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.MaxCandles > 0 {
+		warmup := 0
+		for warmup < len(candles) && candles[warmup].OpenTime.Before(cfg.Start) {
+			warmup++
+		}
+		if len(candles)-warmup > cfg.MaxCandles {
+			candles = candles[:warmup+cfg.MaxCandles]
+		}
+	}
+	return candles, nil
+}
+
+// buildWindows builds the windows of candles, dropping those ending before cfg.Start
+func buildWindows(cfg Config, candles []model.Candle) []*model.Window {
+	windows := newBuilder(cfg).ProcessCandles(candles)
+	if cfg.Start.IsZero() {
+		return windows
+	}
+
+	inRange := windows[:0]
+	for _, w := range windows {
+		if !w.LastCandle().OpenTime.Before(cfg.Start) {
+			inRange = append(inRange, w)
+		}
+	}
+	return inRange
+}
+
+// newBuilder creates the window builder for cfg
+func newBuilder(cfg Config) *window.Builder {
+	builder, err := window.NewBuilder(window.Config{
 		W:              cfg.WindowLength,
 		S:              cfg.StepSize,
+		OverlapRatio:   cfg.Overlap,
 		FeatureVersion: cfg.FeatureVersion,
 		Symbol:         cfg.Symbol,
 		Timeframe:      cfg.Timeframe,
 	})
+	if err != nil {
+		log.Fatalf("Invalid window config: %v", err)
+	}
+	return builder
+}
 
-	windows := builder.ProcessCandles(candles)
-	log.Printf("Built %d windows", len(windows))
+// candleGap is a run of missing bars between two consecutive candles
+type candleGap struct {
+	After   time.Time // Open time of the candle before the gap
+	Before  time.Time // Open time of the candle after the gap
+	Missing int
+}
 
-	// Extract features and store
-	log.Println("Extracting features...")
-	extractor := feature.NewExtractor(cfg.FeatureVersion, cfg.VectorDim)
+// findGaps returns the gaps between consecutive candles and the number of duplicate or out-of-order candles
+func findGaps(candles []model.Candle, bar time.Duration) ([]candleGap, int) {
+	var gaps []candleGap
+	disordered := 0
+	for i := 1; i < len(candles); i++ {
+		step := candles[i].OpenTime.Sub(candles[i-1].OpenTime)
+		switch {
+		case step <= 0:
+			disordered++
+		case step > bar:
+			gaps = append(gaps, candleGap{After: candles[i-1].OpenTime, Before: candles[i].OpenTime, Missing: int(step/bar) - 1})
+		}
+	}
+	return gaps, disordered
+}
+
+// maxReportedGaps caps the gaps listed by a dry run
+const maxReportedGaps = 10
 
-	var milvusData []*milvus.WindowData
-	var features []*model.FeatureRow
+// runDryRun reports the candles, windows, and Milvus volume a backfill of cfg would write, without writing anything
+// Gaps, duplicate or out-of-order candles, and an empty CSV are problems; it returns how many were found
+func runDryRun(ctx context.Context, cfg Config) int {
+	barDuration, err := model.TimeframeDuration(cfg.Timeframe)
+	if err != nil {
+		log.Fatalf("Invalid timeframe: %v", err)
+	}
 
-	for i, w := range windows {
-		featureRow, shapeVector, err := extractor.Extract(w)
+	log.Printf("Dry run: loading data from %s...", cfg.CSVPath)
+	provider := data.NewCSVProvider(cfg.CSVPath)
+	provider.ComputeMissingVWAP = cfg.ComputeVWAP
+	candles, err := fetchCandles(ctx, cfg, provider)
+	if err != nil {
+		log.Fatalf("Failed to load candles: %v", err)
+	}
+	if len(candles) == 0 {
+		log.Printf("Problem: no candles in %s", cfg.CSVPath)
+		return 1
+	}
+
+	// Coverage and gaps
+	first, last := candles[0], candles[len(candles)-1]
+	expected := int(last.OpenTime.Sub(first.OpenTime)/barDuration) + 1
+	gaps, disordered := findGaps(candles, barDuration)
+	missing := 0
+	for _, g := range gaps {
+		missing += g.Missing
+	}
+	log.Printf("Candles: %d from %s to %s", len(candles), first.OpenTime.Format(time.RFC3339), last.CloseTime.Format(time.RFC3339))
+	log.Printf("Coverage: %d/%d bars (%.2f%%), %d gaps, %d missing bars", expected-missing, expected, 100*float64(expected-missing)/float64(expected), len(gaps), missing)
+	for i, g := range gaps {
+		if i == maxReportedGaps {
+			log.Printf("  ... %d more gaps", len(gaps)-maxReportedGaps)
+			break
+		}
+		log.Printf("  Gap after %s: %d bars missing before %s", g.After.Format(time.RFC3339), g.Missing, g.Before.Format(time.RFC3339))
+	}
+	if disordered > 0 {
+		log.Printf("Problem: %d duplicate or out-of-order candles", disordered)
+	}
+
+	// Windows, and how many are already stored
+	windows := buildWindows(cfg, candles)
+	existing := 0
+	if _, err := os.Stat(cfg.DuckDBPath); err == nil && len(windows) > 0 {
+		duckClient, err := duckdb.NewClient(cfg.DuckDBPath)
 		if err != nil {
-			log.Printf("Warning: failed to extract features for window %s: %v", w.WindowID, err)
-			continue
+			log.Fatalf("Failed to connect to DuckDB: %v", err)
 		}
+		defer duckClient.Close()
 
-		features = append(features, featureRow)
-		milvusData = append(milvusData, &milvus.WindowData{
-			WindowID:    w.WindowID,
-			Embedding:   shapeVector,
-			Symbol:      w.Symbol,
-			Timeframe:   w.Timeframe,
-			TEnd:        w.TEnd,
-			VolBucket:   int32(featureRow.VolBucket),
-			TrendBucket: int32(featureRow.TrendBucket),
-			DataVersion: int32(featureRow.DataVersion),
-		})
+		ids := make([]string, len(windows))
+		for i, w := range windows {
+			ids[i] = w.WindowID
+		}
+		stored, err := duckdb.NewWindowRepo(duckClient).ExistsBatch(ctx, ids)
+		if err != nil {
+			log.Fatalf("Failed to check existing windows: %v", err)
+		}
+		existing = len(stored)
+	}
+	log.Printf("Windows: %d would be built (W=%d, S=%d), %d already stored, %d new", len(windows), cfg.WindowLength, cfg.StepSize, existing, len(windows)-existing)
+
+	// Milvus volume: every built window is written, unless -resume skips checkpointed ones
+	if len(windows) > 0 {
+		vectorBytes := cfg.VectorDim*4 + len(windows[0].WindowID) + len(cfg.Symbol) + len(cfg.Timeframe) + 8 + 3*4
+		batches := (len(windows) + cfg.BatchSize - 1) / cfg.BatchSize
+		log.Printf("Milvus: up to %d vectors in %d batches of %d, ~%.1f MiB", len(windows), batches, cfg.BatchSize, float64(len(windows)*vectorBytes)/(1<<20))
+	}
+
+	return len(gaps) + disordered
+}
 
-		if (i+1)%1000 == 0 {
-			log.Printf("Processed %d/%d windows", i+1, len(windows))
+// runVerify compares DuckDB window and feature counts with Milvus vector counts for every stored symbol/timeframe
+// It prints one line per series and returns the number of discrepant series
+func runVerify(ctx context.Context, cfg Config, windowRepo *duckdb.WindowRepo) int {
+	counts, err := windowRepo.CountBySeries(ctx)
+	if err != nil {
+		log.Fatalf("Failed to count windows: %v", err)
+	}
+
+	milvusClient, err := milvus.NewClient(ctx, milvus.Config{Address: cfg.MilvusAddr})
+	if err != nil {
+		log.Fatalf("Failed to connect to Milvus: %v", err)
+	}
+	defer milvusClient.Close()
+	if err := milvusClient.LoadCollection(ctx, milvus.DefaultCollectionName); err != nil {
+		log.Fatalf("Failed to load collection: %v", err)
+	}
+
+	fmt.Printf("\n%-12s %-6s %-10s %-10s %-10s %s\n", "Symbol", "TF", "Windows", "Features", "Vectors", "Status")
+	fmt.Println("--------------------------------------------------------------")
+	problems := 0
+	for _, c := range counts {
+		filter := milvus.NewFilter().In("symbol", c.Symbol).In("timeframe", c.Timeframe)
+		vectors, err := milvusClient.Count(ctx, milvus.DefaultCollectionName, filter.String())
+		if err != nil {
+			log.Fatalf("Failed to count vectors: %v", err)
+		}
+
+		var issues []string
+		if missing := c.Windows - c.Features; missing > 0 {
+			issues = append(issues, fmt.Sprintf("%d windows without features", missing))
+		}
+		if diff := vectors - c.Windows; diff != 0 {
+			issues = append(issues, fmt.Sprintf("%+d vectors vs windows", diff))
 		}
+
+		status := "OK"
+		if len(issues) > 0 {
+			status = strings.Join(issues, ", ")
+			problems++
+		}
+		fmt.Printf("%-12s %-6s %-10d %-10d %-10d %s\n", c.Symbol, c.Timeframe, c.Windows, c.Features, vectors, status)
 	}
+	return problems
+}
+
+// progressLine is the -progress-json form of a progress report or the final summary
+type progressLine struct {
+	Event            string      `json:"event"` // progress or summary
+	Stage            string      `json:"stage,omitempty"`
+	StageTotal       int64       `json:"stage_total,omitempty"`
+	StageDone        int64       `json:"stage_done,omitempty"`
+	Percent          float64     `json:"percent,omitempty"`
+	ETASeconds       float64     `json:"eta_seconds,omitempty"`
+	ElapsedSeconds   float64     `json:"elapsed_seconds"`
+	Candles          int64       `json:"candles"`
+	Windows          int64       `json:"windows"`
+	MilvusRows       int64       `json:"milvus_rows"`
+	CandlesPerSec    float64     `json:"candles_per_sec,omitempty"`
+	WindowsPerSec    float64     `json:"windows_per_sec,omitempty"`
+	MilvusRowsPerSec float64     `json:"milvus_rows_per_sec,omitempty"`
+	Stages           []stageLine `json:"stages,omitempty"`
+}
 
-	// Store windows in DuckDB
-	log.Println("Storing windows in DuckDB...")
-	if err := windowRepo.InsertBatch(ctx, windows); err != nil {
-		log.Fatalf("Failed to insert windows: %v", err)
+// stageLine is the timing of one stage in the JSON summary
+type stageLine struct {
+	Stage   string  `json:"stage"`
+	Seconds float64 `json:"seconds"`
+}
+
+func newProgressLine(event string, p data.BackfillProgress) progressLine {
+	return progressLine{
+		Event:            event,
+		Stage:            p.Stage,
+		StageTotal:       p.StageTotal,
+		StageDone:        p.StageDone,
+		Percent:          p.Percent,
+		ETASeconds:       p.ETA.Seconds(),
+		ElapsedSeconds:   p.Elapsed.Seconds(),
+		Candles:          p.ProcessedCandles,
+		Windows:          p.ProcessedWindows,
+		MilvusRows:       p.MilvusRows,
+		CandlesPerSec:    p.CandlesPerSec,
+		WindowsPerSec:    p.WindowsPerSec,
+		MilvusRowsPerSec: p.MilvusRowsPerSec,
 	}
+}
 
-	// Store features in DuckDB
-	log.Println("Storing features in DuckDB...")
-	if err := featureRepo.InsertBatch(ctx, features); err != nil {
-		log.Fatalf("Failed to insert features: %v", err)
+// newProgress creates the progress tracker of a backfill, reporting as log lines or JSON lines unless -quiet
+func newProgress(cfg Config) *data.ProgressTracker {
+	var report data.ProgressCallback
+	switch {
+	case cfg.Quiet:
+	case cfg.ProgressJSON:
+		enc := json.NewEncoder(os.Stdout)
+		report = func(p data.BackfillProgress) { enc.Encode(newProgressLine("progress", p)) }
+	default:
+		report = logProgress
 	}
+	return data.NewProgressTracker(cfg.ProgressInterval, report)
+}
 
-	// Store vectors in Milvus
-	log.Println("Storing vectors in Milvus...")
-	batchSize := cfg.BatchSize
-	for i := 0; i < len(milvusData); i += batchSize {
-		end := i + batchSize
-		if end > len(milvusData) {
-			end = len(milvusData)
+// logProgress logs one progress report
+func logProgress(p data.BackfillProgress) {
+	done := fmt.Sprintf("%d done", p.StageDone)
+	if p.StageTotal > 0 {
+		done = fmt.Sprintf("%.1f%% (%d/%d)", p.Percent, p.StageDone, p.StageTotal)
+		if p.ETA > 0 {
+			done += fmt.Sprintf(", ETA %s", p.ETA.Round(time.Second))
 		}
-		if err := milvusClient.InsertBatch(ctx, milvus.DefaultCollectionName, milvusData[i:end]); err != nil {
-			log.Fatalf("Failed to insert vectors: %v", err)
+	}
+	log.Printf("Progress [%s] %s | %.0f candles/s, %.0f windows/s, %.0f Milvus rows/s | elapsed %s",
+		p.Stage, done, p.CandlesPerSec, p.WindowsPerSec, p.MilvusRowsPerSec, p.Elapsed.Round(time.Second))
+}
+
+// logSummary logs the per-stage timings of a completed backfill, and writes them as a JSON line with -progress-json
+func logSummary(cfg Config, p data.BackfillProgress, stages []data.StageTiming) {
+	parts := make([]string, len(stages))
+	lines := make([]stageLine, len(stages))
+	for i, st := range stages {
+		parts[i] = fmt.Sprintf("%s %s", st.Stage, st.Duration.Round(time.Millisecond))
+		lines[i] = stageLine{Stage: st.Stage, Seconds: st.Duration.Seconds()}
+	}
+	log.Printf("Stage timings: %s (total %s)", strings.Join(parts, ", "), p.Elapsed.Round(time.Millisecond))
+
+	if cfg.ProgressJSON {
+		line := newProgressLine("summary", p)
+		line.Stages = lines
+		json.NewEncoder(os.Stdout).Encode(line)
+	}
+}
+
+// cacheOutcomes computes and stores the outcomes of every stored window at cfg.Horizons
+func cacheOutcomes(ctx context.Context, cfg Config, candleRepo *duckdb.CandleRepo, windowRepo *duckdb.WindowRepo, outcomeRepo *duckdb.OutcomeRepo) {
+	var last outcome.CacheProgress
+	outcomeCfg := outcome.DefaultConfig()
+	outcomeCfg.Horizons = cfg.Horizons
+	outcomeCfg.Progress = func(p outcome.CacheProgress) {
+		last = p
+		if !cfg.Quiet {
+			log.Printf("Outcomes: %d/%d windows (%d already cached), %d outcomes stored", p.Windows, p.Total, p.Skipped, p.Cached)
 		}
 	}
 
-	// Flush Milvus
-	if err := milvusClient.Flush(ctx, milvus.DefaultCollectionName); err != nil {
-		log.Printf("Warning: failed to flush Milvus: %v", err)
+	log.Printf("Caching outcomes for horizons %v...", outcomeCfg.Horizons)
+	engine := outcome.NewEngineWithConfig(candleRepo, outcomeCfg)
+	cached, err := engine.ComputeAndCacheAll(ctx, cfg.Symbol, cfg.Timeframe, outcomeCfg.Horizons, windowRepo, outcomeRepo, cfg.BatchSize)
+	if err != nil {
+		log.Fatalf("Failed to cache outcomes: %v", err)
 	}
+	log.Printf("Cached %d outcomes: %d windows with complete forward data, %d partial (cached where the horizon fits), %d already cached",
+		cached, last.Complete, last.Partial, last.Skipped)
+}
 
-	// Create index
-	log.Println("Creating Milvus index...")
-	if err := milvusClient.CreateIndex(ctx, milvus.DefaultCollectionName, "embedding"); err != nil {
-		log.Printf("Warning: failed to create index: %v", err)
+// storeOrderBookSpread stores a current order book snapshot and applies its spread to matching window features
+// The depth API has no history, so only windows ending within one bar before now receive a spread
+func storeOrderBookSpread(ctx context.Context, cfg Config, duckClient *duckdb.Client) {
+	barDuration, err := model.TimeframeDuration(cfg.Timeframe)
+	if err != nil {
+		log.Fatalf("Invalid timeframe: %v", err)
 	}
 
-	// Load collection
-	if err := milvusClient.LoadCollection(ctx, milvus.DefaultCollectionName); err != nil {
-		log.Printf("Warning: failed to load collection: %v", err)
+	log.Println("Fetching order book snapshot from Binance...")
+	snapshot, err := binance.NewOrderBookSnapshotProvider().FetchSnapshot(ctx, cfg.Symbol)
+	if err != nil {
+		log.Fatalf("Failed to fetch order book: %v", err)
 	}
 
-	log.Println("Backfill completed successfully!")
-	log.Printf("Summary: %d candles → %d windows → %d vectors", len(candles), len(windows), len(milvusData))
+	orderBookRepo := duckdb.NewOrderBookRepo(duckClient)
+	if err := orderBookRepo.InsertBatch(ctx, []model.OrderBookSnapshot{*snapshot}); err != nil {
+		log.Fatalf("Failed to insert order book snapshot: %v", err)
+	}
 
-	// Demo: query with the last window
-	if len(windows) > 0 {
-		demoQuery(ctx, windows[len(windows)-1], extractor, milvusClient, candleRepo)
+	updated, err := orderBookRepo.ApplySpreadFeatures(ctx, cfg.Symbol, cfg.Timeframe, barDuration)
+	if err != nil {
+		log.Fatalf("Failed to apply spread features: %v", err)
 	}
+	log.Printf("Stored order book snapshot (spread %.4f%%), applied to %d windows", snapshot.RelativeSpread()*100, updated)
 }
 
 func parseFlags() Config {
@@ -200,24 +894,103 @@ func parseFlags() Config {
 	flag.StringVar(&cfg.CSVPath, "csv", "", "Path to CSV file with candle data (default: data/{symbol}_{timeframe}.csv)")
 	flag.StringVar(&cfg.Symbol, "symbol", "BTCUSDT", "Trading symbol")
 	flag.StringVar(&cfg.Timeframe, "timeframe", "1d", "Timeframe")
+	start := flag.String("start", "", "Backfill candles opening at or after this time, RFC3339 or YYYY-MM-DD (default: start of the data)")
+	end := flag.String("end", "", "Backfill candles opening before this time, RFC3339 or YYYY-MM-DD (default: now)")
+	flag.IntVar(&cfg.MaxCandles, "max-candles", 0, "Backfill at most this many candles from -start, e.g. for a smoke run (0 for no limit)")
 	flag.IntVar(&cfg.WindowLength, "window", 7, "Window length (number of candles)")
 	flag.IntVar(&cfg.StepSize, "step", 1, "Step size between windows")
+	flag.Float64Var(&cfg.Overlap, "overlap", 0, "Fraction of candles shared between consecutive windows, e.g. 0.5 (overrides -step when > 0)")
 	flag.IntVar(&cfg.FeatureVersion, "version", 1, "Feature version")
 	flag.StringVar(&cfg.DuckDBPath, "duckdb", "etna.duckdb", "DuckDB file path")
 	flag.StringVar(&cfg.MilvusAddr, "milvus", "localhost:19530", "Milvus server address")
+	flag.StringVar(&cfg.Output, "output", outputDirect, "Where to write: direct (DuckDB and Milvus) or nats (publish write batches for the writer)")
+	flag.StringVar(&cfg.NATSUrl, "nats", "nats://localhost:4222", "NATS server URL with -output nats")
+	flag.IntVar(&cfg.MaxInFlight, "max-inflight", 64, "Max unacknowledged NATS publishes with -output nats")
+	flag.StringVar(&cfg.MilvusPartition, "milvus-partition", "", "Milvus time partition granularity: year or quarter (empty disables)")
 	flag.IntVar(&cfg.VectorDim, "dim", 96, "Vector dimension")
 	flag.IntVar(&cfg.BatchSize, "batch", 1000, "Batch size for inserts")
+	flag.StringVar(&cfg.Symbols, "symbols", "", "Comma-separated symbols to ingest concurrently from data/{symbol}_{timeframe}.csv (candles only)")
+	flag.IntVar(&cfg.Workers, "workers", 4, "Concurrent symbols when -symbols is set")
+	flag.DurationVar(&cfg.StatusInterval, "status-interval", 0, "Print a live ingestion status table at this interval when -symbols is set (e.g. 5s)")
+	flag.BoolVar(&cfg.IncludeOrderBook, "include-orderbook", false, "Also snapshot the Binance order book and set the spread feature of windows ending within one bar of it")
+	flag.BoolVar(&cfg.IncludeFunding, "include-funding", false, "Also fetch and store perpetual funding rates from Binance")
+	flag.BoolVar(&cfg.ComputeVWAP, "compute-vwap", false, "Fill missing candle VWAP with the typical price (high+low+close)/3")
+	flag.BoolVar(&cfg.Resume, "resume", false, "Continue the previous run with the same config from its checkpoint in backfill_runs, skipping completed phases")
+	flag.BoolVar(&cfg.DryRun, "dry-run", false, "Report candle coverage, windows to build, windows already stored, and Milvus volume without writing; exits non-zero on gaps")
+	flag.BoolVar(&cfg.Verify, "verify", false, "Compare DuckDB window/feature counts with Milvus vector counts per symbol/timeframe; exits non-zero on discrepancies")
+	flag.BoolVar(&cfg.CacheOutcomes, "cache-outcomes", false, "Compute and store outcomes of all stored windows at the default horizons, skipping cached ones")
+	horizons := flag.String("horizons", "", "Comma-separated outcome horizons in bars, e.g. 5,20,60; computes and stores outcomes like -cache-outcomes at these horizons")
+	flag.BoolVar(&cfg.AnnealIndex, "anneal-index", false, "After inserting and flushing, rebuild the Milvus index with nlist = sqrt(rows); search is unavailable meanwhile")
+	flag.BoolVar(&cfg.ClusterAnalysis, "cluster-analysis", false, "Print stored outcome statistics per volatility/trend cluster and exit")
+	flag.DurationVar(&cfg.ProgressInterval, "progress-interval", 10*time.Second, "How often to report progress with rates and ETA")
+	flag.BoolVar(&cfg.Quiet, "quiet", false, "Suppress progress reports")
+	flag.BoolVar(&cfg.ProgressJSON, "progress-json", false, "Write progress and the final summary as JSON lines on stdout")
+
+	configPath := flag.String("config", "", "YAML or JSON config file; ETNA_* environment variables override it and explicit flags override both")
 
 	flag.Parse()
+	if err := config.ApplyFlags(flag.CommandLine, *configPath); err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
 
 	if cfg.CSVPath == "" {
 		cfg.CSVPath = fmt.Sprintf("data/%s_%s.csv", cfg.Symbol, cfg.Timeframe)
 	}
 
+	var err error
+	if cfg.Start, err = config.ParseTimeFlag(*start); err != nil {
+		log.Fatalf("Invalid -start: %v", err)
+	}
+	if cfg.End, err = config.ParseTimeFlag(*end); err != nil {
+		log.Fatalf("Invalid -end: %v", err)
+	}
+	if !cfg.End.IsZero() && !cfg.Start.Before(cfg.End) {
+		log.Fatalf("-start %s must be before -end %s", cfg.Start.Format(time.RFC3339), cfg.End.Format(time.RFC3339))
+	}
+	if cfg.End.IsZero() && cfg.Start.After(time.Now()) {
+		log.Fatalf("-start %s is in the future", cfg.Start.Format(time.RFC3339))
+	}
+	if cfg.MaxCandles < 0 {
+		log.Fatalf("-max-candles must not be negative, got %d", cfg.MaxCandles)
+	}
+	if cfg.MaxCandles > 0 && cfg.Symbols != "" {
+		log.Fatal("-max-candles cannot be combined with -symbols")
+	}
+
+	cfg.Horizons = outcome.DefaultConfig().Horizons
+	if *horizons != "" {
+		cfg.CacheOutcomes = true
+		cfg.Horizons = nil
+		for _, part := range strings.Split(*horizons, ",") {
+			h, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || h <= 0 {
+				log.Fatalf("Invalid horizon %q", part)
+			}
+			cfg.Horizons = append(cfg.Horizons, h)
+		}
+	}
+
+	switch cfg.Output {
+	case outputDirect:
+	case outputNATS:
+		// These modes read or write DuckDB and Milvus directly
+		if cfg.Resume || cfg.Verify || cfg.CacheOutcomes || cfg.AnnealIndex || cfg.IncludeFunding || cfg.IncludeOrderBook || cfg.ClusterAnalysis || cfg.Symbols != "" {
+			log.Fatal("-output nats cannot be combined with -resume, -verify, -cache-outcomes, -horizons, -anneal-index, -include-funding, -include-orderbook, -cluster-analysis, or -symbols")
+		}
+	default:
+		log.Fatalf("Unknown -output %q (want direct or nats)", cfg.Output)
+	}
+
 	return cfg
 }
 
-func demoQuery(ctx context.Context, w *model.Window, extractor *feature.Extractor, milvusClient *milvus.Client, candleRepo *duckdb.CandleRepo) {
+// Candidates the demo query recalls from Milvus and the neighbors it keeps after reranking
+const (
+	demoRecallK = 200
+	demoTopK    = 10
+)
+
+func demoQuery(ctx context.Context, cfg Config, w *model.Window, extractor *feature.Extractor, milvusClient *milvus.Client, outcomeRepo *duckdb.OutcomeRepo) {
 	log.Println("\n=== Demo Query ===")
 	log.Printf("Query window: %s (TEnd: %s)", w.WindowID, w.TEnd.Format(time.RFC3339))
 
@@ -225,36 +998,190 @@ func demoQuery(ctx context.Context, w *model.Window, extractor *feature.Extracto
 	_, embedding, _ := extractor.Extract(w)
 
 	// Search
-	filter := fmt.Sprintf("symbol == \"%s\" && timeframe == \"%s\"", w.Symbol, w.Timeframe)
-	results, err := milvusClient.Search(ctx, milvus.DefaultCollectionName, embedding, filter, 10)
+	filter := milvus.NewFilter().In("symbol", w.Symbol).In("timeframe", w.Timeframe)
+	results, err := milvusClient.Search(ctx, milvus.DefaultCollectionName, embedding, filter.String(), demoRecallK)
 	if err != nil {
 		log.Printf("Search failed: %v", err)
 		return
 	}
 
-	log.Printf("Found %d similar windows:", len(results))
+	// Rerank the recalled candidates by time and keep the best
+	reranker := rerank.NewReranker(rerank.DefaultForTimeframe(w.Timeframe))
+	ranked := rerank.NewPipeline(reranker.Stage(time.Now()), rerank.NewTopKStage(demoTopK)).Run(results)
 
-	// Rerank by time
-	reranker := rerank.NewReranker(rerank.DefaultTimeDecayConfig())
-	ranked := reranker.Rerank(results, time.Now())
+	log.Printf("Found %d similar windows, reranked to %d:", len(results), len(ranked))
 
 	for i, r := range ranked[:min(5, len(ranked))] {
 		log.Printf("  %d. %s (Score: %.4f, TimeWeight: %.4f, Final: %.4f, TEnd: %s)",
 			i+1, r.WindowID, r.OriginalScore, r.TimeWeight, r.FinalScore, r.TEnd.Format("2006-01-02 15:04"))
 	}
 
-	// Calculate outcomes
-	log.Println("\nOutcome statistics (placeholder - requires forward candle data):")
-	engine := outcome.NewEngine(candleRepo)
-	outcomes, err := engine.Calculate(ctx, []*model.Window{w}, []int{5, 20, 60})
+	// Aggregate the cached outcomes of the similar windows
+	ids := make([]string, len(ranked))
+	for i, r := range ranked {
+		ids[i] = r.WindowID
+	}
+	cached, err := outcomeRepo.GetByWindowIDs(ctx, ids, cfg.Horizons)
 	if err != nil {
-		log.Printf("Outcome calculation failed: %v", err)
+		log.Printf("Failed to load cached outcomes: %v", err)
+		return
+	}
+	if len(cached) == 0 {
+		log.Println("\nNo cached outcomes for these windows (run with -horizons or -cache-outcomes to compute them)")
 		return
 	}
 
-	for _, o := range outcomes {
-		log.Printf("  Horizon %d: Mean=%.4f%%, P50=%.4f%%, MDD=%.4f%%",
-			o.Horizon, o.FwdRetMean*100, o.FwdRetP50*100, o.MDDP95*100)
+	outcomes := make([]outcome.Result, len(cached))
+	for i, o := range cached {
+		outcomes[i] = outcome.ResultFromOutcome(o)
+	}
+	aggregated := outcome.AggregateResults(outcomes)
+
+	log.Println("\nCached outcomes of similar windows:")
+	for _, h := range cfg.Horizons {
+		a, ok := aggregated[h]
+		if !ok {
+			log.Printf("  Horizon %d: no cached outcomes", h)
+			continue
+		}
+		log.Printf("  Horizon %d: Samples=%d, Mean=%.4f%%, P50=%.4f%%, MDD95=%.4f%%, HitRate=%.2f",
+			h, a.SampleCount, a.MeanReturn*100, a.MedianP50*100, a.MDDP95*100, a.HitRate)
+	}
+}
+
+// runMultiSymbol ingests candles for every -symbols entry concurrently, reporting status as it goes
+func runMultiSymbol(ctx context.Context, cfg Config, candleRepo *duckdb.CandleRepo) {
+	var configs []data.BackfillConfig
+	for _, symbol := range strings.Split(cfg.Symbols, ",") {
+		symbol = strings.TrimSpace(symbol)
+		if symbol == "" {
+			continue
+		}
+		bc := data.DefaultBackfillConfig(symbol, cfg.Timeframe)
+		bc.StartTime = cfg.Start
+		bc.EndTime = cfg.End
+		if bc.EndTime.IsZero() {
+			bc.EndTime = time.Now()
+		}
+		bc.BatchSize = cfg.BatchSize
+		configs = append(configs, bc)
+	}
+
+	backfiller := data.NewMultiSymbolBackfiller(configs, newCSVDirProvider("data", cfg.ComputeVWAP), candleRepo, cfg.Workers)
+
+	done := make(chan error, 1)
+	go func() { done <- backfiller.Run(ctx) }()
+
+	var tick <-chan time.Time
+	if cfg.StatusInterval > 0 {
+		ticker := time.NewTicker(cfg.StatusInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-tick:
+			printStatusTable(backfiller.Statuses())
+		case err := <-done:
+			printStatusTable(backfiller.Statuses())
+			if err != nil {
+				log.Fatalf("Multi-symbol backfill failed: %v", err)
+			}
+			log.Println("Multi-symbol backfill completed successfully!")
+			return
+		}
+	}
+}
+
+// printStatusTable prints one line per symbol with its ingestion state
+func printStatusTable(statuses []data.BackfillStatus) {
+	fmt.Printf("\n%-12s %-6s %-8s %-10s %-10s\n", "Symbol", "TF", "State", "Candles", "Elapsed")
+	fmt.Println("------------------------------------------------")
+	for _, s := range statuses {
+		elapsed := ""
+		switch {
+		case !s.FinishedAt.IsZero():
+			elapsed = s.FinishedAt.Sub(s.StartedAt).Round(time.Millisecond).String()
+		case !s.StartedAt.IsZero():
+			elapsed = time.Since(s.StartedAt).Round(time.Millisecond).String()
+		}
+		fmt.Printf("%-12s %-6s %-8s %-10d %-10s\n", s.Symbol, s.Timeframe, s.State, s.Candles, elapsed)
+		if s.Err != nil {
+			fmt.Printf("  error: %v\n", s.Err)
+		}
+	}
+}
+
+// csvDirProvider serves each symbol/timeframe from its own {dir}/{symbol}_{timeframe}.csv file
+type csvDirProvider struct {
+	dir string
+
+	computeVWAP bool // Fill missing VWAP with the typical price
+
+	mu        sync.Mutex
+	providers map[string]*data.CSVProvider
+}
+
+// newCSVDirProvider creates a provider reading per-symbol CSV files from dir
+func newCSVDirProvider(dir string, computeVWAP bool) *csvDirProvider {
+	return &csvDirProvider{dir: dir, computeVWAP: computeVWAP, providers: make(map[string]*data.CSVProvider)}
+}
+
+// provider returns the CSV provider for a symbol/timeframe, creating it on first use
+func (p *csvDirProvider) provider(symbol, timeframe string) *data.CSVProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	path := filepath.Join(p.dir, fmt.Sprintf("%s_%s.csv", symbol, timeframe))
+	if _, ok := p.providers[path]; !ok {
+		provider := data.NewCSVProvider(path)
+		provider.ComputeMissingVWAP = p.computeVWAP
+		p.providers[path] = provider
+	}
+	return p.providers[path]
+}
+
+func (p *csvDirProvider) FetchCandles(ctx context.Context, symbol, timeframe string, start, end time.Time) ([]model.Candle, error) {
+	return p.provider(symbol, timeframe).FetchCandles(ctx, symbol, timeframe, start, end)
+}
+
+func (p *csvDirProvider) FetchLatestCandles(ctx context.Context, symbol, timeframe string, limit int) ([]model.Candle, error) {
+	return p.provider(symbol, timeframe).FetchLatestCandles(ctx, symbol, timeframe, limit)
+}
+
+// runClusterAnalysis prints aggregated stored outcomes for every populated volatility/trend cluster
+func runClusterAnalysis(ctx context.Context, cfg Config, candleRepo *duckdb.CandleRepo, featureRepo *duckdb.FeatureRepo, outcomeRepo *duckdb.OutcomeRepo) {
+	engine := outcome.NewEngine(candleRepo)
+	horizons := cfg.Horizons
+
+	clusters, err := engine.CalculateAllClusters(ctx, cfg.Symbol, cfg.Timeframe, horizons, featureRepo, outcomeRepo)
+	if err != nil {
+		log.Fatalf("Cluster analysis failed: %v", err)
+	}
+
+	keys := make([]outcome.ClusterKey, 0, len(clusters))
+	for k := range clusters {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].VolBucket != keys[j].VolBucket {
+			return keys[i].VolBucket < keys[j].VolBucket
+		}
+		return keys[i].TrendBucket < keys[j].TrendBucket
+	})
+
+	fmt.Printf("\n=== Outcomes by Cluster (%s %s) ===\n", cfg.Symbol, cfg.Timeframe)
+	fmt.Printf("%-6s %-6s %-8s %-8s %-10s %-10s %-10s %-10s\n", "Vol", "Trend", "Horizon", "Samples", "Mean", "P50", "MDD95", "HitRate")
+	fmt.Println("--------------------------------------------------------------------------------")
+	for _, k := range keys {
+		for _, h := range horizons {
+			agg, ok := clusters[k][h]
+			if !ok {
+				continue
+			}
+			fmt.Printf("%-6d %-6d %-8d %-8d %-10.4f %-10.4f %-10.4f %-10.2f\n",
+				k.VolBucket, k.TrendBucket, h, agg.SampleCount, agg.MeanReturn, agg.MedianP50, agg.MDDP95, agg.HitRate)
+		}
 	}
 }
 
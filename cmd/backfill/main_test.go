@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/tunogya/etna/pkg/model"
+	"github.com/tunogya/etna/pkg/queue/nats"
+	"github.com/tunogya/etna/pkg/store/duckdb"
+)
+
+// newTestServer starts an embedded JetStream-enabled NATS server, shut down when the test ends
+func newTestServer(t *testing.T) *server.Server {
+	t.Helper()
+	srv, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: t.TempDir(), NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	go srv.Start()
+	if !srv.ReadyForConnections(5 * time.Second) {
+		t.Fatal("embedded NATS server did not start")
+	}
+	t.Cleanup(srv.Shutdown)
+	return srv
+}
+
+// writeCandleCSV writes n wavy 1m candles for symbol to a CSV file in a temp dir and returns its path
+func writeCandleCSV(t *testing.T, symbol string, start time.Time, n int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "candles.csv")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	defer f.Close()
+
+	fmt.Fprintln(f, "symbol,timeframe,open_time,close_time,open,high,low,close,volume,trades,vwap")
+	for i := 0; i < n; i++ {
+		open := start.Add(time.Duration(i) * time.Minute)
+		price := 100 + 5*math.Sin(float64(i)/3) + 0.1*float64(i)
+		fmt.Fprintf(f, "%s,1m,%d,%d,%g,%g,%g,%g,%d,%d,%g\n",
+			symbol, open.UnixMilli(), open.Add(time.Minute-time.Millisecond).UnixMilli(),
+			price-0.2, price+0.5, price-0.5, price, 10+i%7, 50+i, price)
+	}
+	return path
+}
+
+// streamMsgs returns the number of messages held by the work stream
+func streamMsgs(t *testing.T, url string) uint64 {
+	t.Helper()
+	nc, err := natsgo.Connect(url)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("jetstream.New: %v", err)
+	}
+	stream, err := js.Stream(context.Background(), nats.DefaultConfig().StreamName)
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	info, err := stream.Info(context.Background())
+	if err != nil {
+		t.Fatalf("Stream info: %v", err)
+	}
+	return info.State.Msgs
+}
+
+// startTestWriter consumes the write subjects the way cmd/writer does, storing candles, windows and features
+// in client and counting the vectors it would upsert into Milvus
+func startTestWriter(t *testing.T, url string, client *duckdb.Client, vectors *int64, mu *sync.Mutex) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	natsCfg := nats.DefaultConfig()
+	natsCfg.URL = url
+	natsClient, err := nats.NewClient(natsCfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(natsClient.Close)
+
+	candleRepo := duckdb.NewCandleRepo(client)
+	windowRepo := duckdb.NewWindowRepo(client)
+	featureRepo := duckdb.NewFeatureRepo(client)
+
+	_, err = natsClient.SubscribeBatch(ctx, nats.ConsumerSubjects(nats.SubjectCandleWrite, nil, ""), "candle-writer", 10, 50*time.Millisecond, func(msgs []jetstream.Msg) error {
+		var candles []model.Candle
+		for _, msg := range msgs {
+			batch, err := nats.DecodeCandleBatchMsg(msg)
+			if err != nil {
+				return nats.Permanent(err)
+			}
+			candles = append(candles, batch.Candles...)
+		}
+		if len(candles) == 0 {
+			return nil
+		}
+		return candleRepo.InsertBatch(ctx, candles)
+	})
+	if err != nil {
+		t.Fatalf("SubscribeBatch candles: %v", err)
+	}
+
+	_, err = natsClient.SubscribeBatch(ctx, nats.ConsumerSubjects(nats.SubjectWindowWrite, nil, ""), "window-writer", 10, 50*time.Millisecond, func(msgs []jetstream.Msg) error {
+		var windows []*model.Window
+		var features []*model.FeatureRow
+		for _, msg := range msgs {
+			batch, err := nats.DecodeWindowBatchMsg(msg)
+			if err != nil {
+				return nats.Permanent(err)
+			}
+			windows = append(windows, batch.Windows...)
+			features = append(features, batch.Features...)
+		}
+		if len(windows) == 0 {
+			return nil
+		}
+		if err := windowRepo.InsertBatch(ctx, windows); err != nil {
+			return err
+		}
+		if len(features) == 0 {
+			return nil
+		}
+		return featureRepo.InsertBatch(ctx, features)
+	})
+	if err != nil {
+		t.Fatalf("SubscribeBatch windows: %v", err)
+	}
+
+	_, err = natsClient.Subscribe(ctx, nats.ConsumerSubjects(nats.SubjectMilvusWrite, nil, ""), "milvus-writer", func(msg jetstream.Msg) error {
+		batch, err := nats.DecodeMilvusBatchMsg(msg)
+		if err != nil {
+			return nats.Permanent(err)
+		}
+		mu.Lock()
+		*vectors += int64(len(batch.Vectors))
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Subscribe vectors: %v", err)
+	}
+}
+
+func TestPublishBackfillFeedsWriter(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	const n, w = 60, 7
+	cfg := Config{
+		CSVPath:          writeCandleCSV(t, "BTCUSDT", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), n),
+		Symbol:           "BTCUSDT",
+		Timeframe:        "1m",
+		WindowLength:     w,
+		StepSize:         1,
+		FeatureVersion:   1,
+		VectorDim:        96,
+		BatchSize:        16,
+		Output:           outputNATS,
+		NATSUrl:          srv.ClientURL(),
+		MaxInFlight:      4,
+		ProgressInterval: time.Hour,
+		Quiet:            true,
+	}
+
+	publishBackfill(ctx, cfg)
+	published := streamMsgs(t, srv.ClientURL())
+	if published == 0 {
+		t.Fatal("backfill published no messages")
+	}
+
+	// Rerunning publishes the same message IDs, which JetStream drops as duplicates
+	publishBackfill(ctx, cfg)
+	if got := streamMsgs(t, srv.ClientURL()); got != published {
+		t.Errorf("stream holds %d messages after rerun, want %d", got, published)
+	}
+
+	client, err := duckdb.NewClient("")
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer client.Close()
+	if err := duckdb.InitializeSchema(client); err != nil {
+		t.Fatalf("InitializeSchema: %v", err)
+	}
+
+	var mu sync.Mutex
+	var vectors int64
+	startTestWriter(t, srv.ClientURL(), client, &vectors, &mu)
+
+	const windows = n - w + 1
+	candleRepo := duckdb.NewCandleRepo(client)
+	windowRepo := duckdb.NewWindowRepo(client)
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		candles, err := candleRepo.Count(ctx, cfg.Symbol, cfg.Timeframe)
+		if err != nil {
+			t.Fatalf("candle Count: %v", err)
+		}
+		stored, err := windowRepo.Count(ctx, cfg.Symbol, cfg.Timeframe)
+		if err != nil {
+			t.Fatalf("window Count: %v", err)
+		}
+		var features int64
+		if err := client.QueryRow("SELECT COUNT(*) FROM window_features").Scan(&features); err != nil {
+			t.Fatalf("count features: %v", err)
+		}
+		mu.Lock()
+		upserted := vectors
+		mu.Unlock()
+
+		if candles == n && stored == windows && features == windows && upserted == windows {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("writer stored %d candles, %d windows, %d feature rows and %d vectors, want %d, %d, %d and %d",
+				candles, stored, features, upserted, n, windows, windows, windows)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
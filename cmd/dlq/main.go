@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/tunogya/etna/pkg/config"
+	"github.com/tunogya/etna/pkg/queue/nats"
+)
+
+// Config holds dead-letter tool configuration
+type Config struct {
+	NATSUrl string
+	Subject string // Original subject filter (empty for all)
+	Limit   int    // Max dead letters listed or replayed (0 for all)
+	Seq     uint64 // DLQ sequence to replay (0 replays every listed dead letter)
+
+	Command string // ls or replay
+}
+
+func main() {
+	cfg := parseFlags()
+
+	ctx := context.Background()
+
+	natsClient, err := nats.NewClient(nats.Config{
+		URL:        cfg.NATSUrl,
+		StreamName: "etna",
+	})
+	if err != nil {
+		log.Fatalf("Failed to connect to NATS: %v", err)
+	}
+	defer natsClient.Close()
+
+	switch cfg.Command {
+	case "ls":
+		letters, err := natsClient.ListDeadLetters(ctx, cfg.Subject, cfg.Limit)
+		if err != nil {
+			log.Fatalf("Failed to list dead letters: %v", err)
+		}
+		printDeadLetters(letters)
+
+	case "replay":
+		seqs := []uint64{cfg.Seq}
+		if cfg.Seq == 0 {
+			letters, err := natsClient.ListDeadLetters(ctx, cfg.Subject, cfg.Limit)
+			if err != nil {
+				log.Fatalf("Failed to list dead letters: %v", err)
+			}
+			seqs = seqs[:0]
+			for _, l := range letters {
+				seqs = append(seqs, l.Sequence)
+			}
+		}
+
+		replayed := 0
+		for _, seq := range seqs {
+			letter, err := natsClient.ReplayDeadLetter(ctx, seq)
+			if err != nil {
+				log.Fatalf("Failed to replay dead letter %d: %v", seq, err)
+			}
+			log.Printf("Replayed dead letter %d to %s (%d bytes)", seq, letter.Subject, len(letter.Data))
+			replayed++
+		}
+		log.Printf("Replayed %d dead letters", replayed)
+	}
+}
+
+// printDeadLetters prints dead letters as a table
+func printDeadLetters(letters []nats.StoredDeadLetter) {
+	fmt.Printf("%-8s %-22s %-16s %-20s %-5s %-9s %-8s %s\n",
+		"Seq", "Subject", "Consumer", "Failed At", "Tries", "Permanent", "Bytes", "Error")
+	for _, l := range letters {
+		fmt.Printf("%-8d %-22s %-16s %-20s %-5d %-9t %-8d %s\n",
+			l.Sequence, l.Subject, l.Consumer, l.FailedAt.Format("2006-01-02 15:04:05"),
+			l.Deliveries, l.Permanent, len(l.Data), l.Error)
+	}
+	fmt.Printf("%d dead letters\n", len(letters))
+}
+
+func parseFlags() Config {
+	cfg := Config{}
+
+	flag.StringVar(&cfg.NATSUrl, "nats", "nats://localhost:4222", "NATS server URL")
+	flag.StringVar(&cfg.Subject, "subject", "", "Only dead letters from this original subject (empty for all)")
+	flag.IntVar(&cfg.Limit, "limit", 0, "Max dead letters to list or replay (0 for all)")
+	flag.Uint64Var(&cfg.Seq, "seq", 0, "DLQ sequence to replay (0 replays all matching dead letters)")
+
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: dlq [options] ls|replay")
+		flag.PrintDefaults()
+	}
+	configPath := flag.String("config", "", "YAML or JSON config file; ETNA_* environment variables override it and explicit flags override both")
+
+	flag.Parse()
+	if err := config.ApplyFlags(flag.CommandLine, *configPath); err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
+
+	cfg.Command = flag.Arg(0)
+	if cfg.Command != "ls" && cfg.Command != "replay" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	return cfg
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/tunogya/etna/pkg/config"
+)
+
+// Config holds etna config init configuration
+type Config struct {
+	Command string // Only "config init" for now
+	Output  string // Path of the generated config file, or "-" for stdout
+	Format  string // yaml or json
+	Force   bool   // Overwrite an existing file
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "config":
+		runConfigInit(os.Args[2:])
+	case "reindex":
+		runReindex(os.Args[2:])
+	case "outcomes":
+		runOutcomes(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: etna config init [options]")
+	fmt.Fprintln(os.Stderr, "       etna reindex [options]")
+	fmt.Fprintln(os.Stderr, "       etna outcomes <refresh|enqueue> [options]")
+}
+
+// runConfigInit writes an example config file
+func runConfigInit(args []string) {
+	cfg := parseFlags(args)
+
+	data, err := config.Example().Encode(cfg.Format)
+	if err != nil {
+		log.Fatalf("Failed to encode config: %v", err)
+	}
+
+	if cfg.Output == "-" {
+		os.Stdout.Write(data)
+		return
+	}
+
+	if !cfg.Force {
+		if _, err := os.Stat(cfg.Output); err == nil {
+			log.Fatalf("%s already exists (use -force to overwrite)", cfg.Output)
+		}
+	}
+	if err := os.WriteFile(cfg.Output, data, 0o644); err != nil {
+		log.Fatalf("Failed to write config: %v", err)
+	}
+	log.Printf("Wrote example config to %s", cfg.Output)
+}
+
+func parseFlags(args []string) Config {
+	if len(args) < 1 || args[0] != "init" {
+		usage()
+		os.Exit(2)
+	}
+
+	cfg := Config{Command: "config init"}
+	fs := flag.NewFlagSet("config init", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: etna config init [options]")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&cfg.Output, "o", "", "Output path, or - for stdout (default: etna.yaml or etna.json)")
+	fs.StringVar(&cfg.Format, "format", "yaml", "Config format: yaml or json")
+	fs.BoolVar(&cfg.Force, "force", false, "Overwrite an existing output file")
+	fs.Parse(args[1:])
+
+	if cfg.Output == "" {
+		cfg.Output = "etna." + cfg.Format
+	}
+	return cfg
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tunogya/etna/pkg/config"
+	"github.com/tunogya/etna/pkg/outcome"
+	"github.com/tunogya/etna/pkg/queue/nats"
+	"github.com/tunogya/etna/pkg/store/duckdb"
+)
+
+// OutcomesConfig holds etna outcomes configuration
+type OutcomesConfig struct {
+	Symbol    string
+	Timeframe string
+
+	DuckDBPath string
+	NATSUrl    string // Queue that enqueue publishes jobs to
+
+	Horizons []int
+	AsOf     time.Time
+}
+
+// runOutcomes dispatches the etna outcomes subcommands
+func runOutcomes(args []string) {
+	if len(args) < 1 {
+		outcomesUsage()
+	}
+
+	switch args[0] {
+	case "refresh":
+		refreshOutcomes(parseOutcomesFlags("refresh", args[1:]))
+	case "enqueue":
+		enqueueOutcomes(parseOutcomesFlags("enqueue", args[1:]))
+	default:
+		outcomesUsage()
+	}
+}
+
+// outcomesUsage prints the outcomes subcommands and exits
+func outcomesUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: etna outcomes <command> [flags]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Commands:")
+	fmt.Fprintln(os.Stderr, "  refresh   Compute and store outcomes for windows whose horizons have completed")
+	fmt.Fprintln(os.Stderr, "  enqueue   Queue outcome jobs for those windows, to be computed by writer workers")
+	os.Exit(2)
+}
+
+// refreshOutcomes computes outcomes that have become available since the windows were backfilled
+func refreshOutcomes(cfg OutcomesConfig) {
+	ctx := context.Background()
+
+	// Initialize DuckDB
+	log.Println("Connecting to DuckDB...")
+	duckClient, err := duckdb.NewClient(cfg.DuckDBPath)
+	if err != nil {
+		log.Fatalf("Failed to connect to DuckDB: %v", err)
+	}
+	defer duckClient.Close()
+
+	if err := duckdb.InitializeSchema(duckClient); err != nil {
+		log.Fatalf("Failed to initialize schema: %v", err)
+	}
+
+	engine := outcome.NewEngine(duckdb.NewCandleRepo(duckClient))
+
+	log.Printf("Refreshing outcomes for %s %s as of %s...", cfg.Symbol, cfg.Timeframe, cfg.AsOf.Format(time.RFC3339))
+	stats, err := engine.RefreshPending(ctx, cfg.Symbol, cfg.Timeframe, cfg.Horizons, cfg.AsOf,
+		duckdb.NewWindowRepo(duckClient), duckdb.NewOutcomeRepo(duckClient))
+	for _, s := range stats {
+		log.Printf("Horizon %d: %d pending, %d computed, %d incomplete", s.Horizon, s.Pending, s.Computed, s.Incomplete)
+	}
+	if err != nil {
+		log.Fatalf("Refresh failed: %v", err)
+	}
+
+	log.Println("Refresh completed successfully!")
+}
+
+// enqueueOutcomes publishes an outcome job for every window with pending outcomes
+func enqueueOutcomes(cfg OutcomesConfig) {
+	ctx := context.Background()
+
+	log.Println("Connecting to DuckDB...")
+	duckClient, err := duckdb.NewClient(cfg.DuckDBPath)
+	if err != nil {
+		log.Fatalf("Failed to connect to DuckDB: %v", err)
+	}
+	defer duckClient.Close()
+
+	if err := duckdb.InitializeSchema(duckClient); err != nil {
+		log.Fatalf("Failed to initialize schema: %v", err)
+	}
+
+	pending, err := outcome.ListPending(ctx, cfg.Symbol, cfg.Timeframe, cfg.Horizons, cfg.AsOf, duckdb.NewOutcomeRepo(duckClient))
+	if err != nil {
+		log.Fatalf("Failed to list pending outcomes: %v", err)
+	}
+	log.Printf("Found %d windows of %s %s with pending outcomes as of %s", len(pending), cfg.Symbol, cfg.Timeframe, cfg.AsOf.Format(time.RFC3339))
+	if len(pending) == 0 {
+		return
+	}
+
+	log.Println("Connecting to NATS...")
+	natsClient, err := nats.NewClient(nats.Config{
+		URL:        cfg.NATSUrl,
+		StreamName: "etna",
+	})
+	if err != nil {
+		log.Fatalf("Failed to connect to NATS: %v", err)
+	}
+	defer natsClient.Close()
+
+	if err := natsClient.CreateStream(ctx, nats.StreamSubjects); err != nil {
+		log.Fatalf("Failed to create stream: %v", err)
+	}
+
+	jobs := make([]nats.OutcomeJobMsg, len(pending))
+	for i, p := range pending {
+		jobs[i] = nats.OutcomeJobMsg{
+			WindowID:  p.WindowID,
+			Symbol:    cfg.Symbol,
+			Timeframe: cfg.Timeframe,
+			Horizons:  p.Horizons,
+		}
+	}
+
+	published, err := natsClient.PublishOutcomeJobs(ctx, jobs)
+	if err != nil {
+		log.Fatalf("Failed to enqueue outcome jobs (%d/%d published): %v", published, len(jobs), err)
+	}
+
+	log.Printf("Enqueued %d outcome jobs", published)
+}
+
+// parseOutcomesFlags parses the flags of outcomes subcommand name
+func parseOutcomesFlags(name string, args []string) OutcomesConfig {
+	cfg := OutcomesConfig{}
+	fs := flag.NewFlagSet("outcomes "+name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: etna outcomes %s [options]\n", name)
+		fs.PrintDefaults()
+	}
+
+	var horizons, asOf string
+	fs.StringVar(&cfg.Symbol, "symbol", "BTCUSDT", "Trading symbol")
+	fs.StringVar(&cfg.Timeframe, "timeframe", "1d", "Timeframe")
+	fs.StringVar(&cfg.DuckDBPath, "duckdb", "etna.duckdb", "DuckDB file path")
+	fs.StringVar(&horizons, "horizons", "", "Comma-separated horizons in bars (defaults to the engine defaults)")
+	fs.StringVar(&asOf, "as-of", "", "Reference time (RFC3339, defaults to now)")
+	if name == "enqueue" {
+		fs.StringVar(&cfg.NATSUrl, "nats", "nats://localhost:4222", "NATS server URL")
+	}
+
+	configPath := fs.String("config", "", "YAML or JSON config file; ETNA_* environment variables override it and explicit flags override both")
+
+	fs.Parse(args)
+	if err := config.ApplyFlags(fs, *configPath); err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
+
+	cfg.Horizons = outcome.DefaultConfig().Horizons
+	if horizons != "" {
+		cfg.Horizons = nil
+		for _, part := range strings.Split(horizons, ",") {
+			h, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || h <= 0 {
+				log.Fatalf("Invalid horizon %q", part)
+			}
+			cfg.Horizons = append(cfg.Horizons, h)
+		}
+	}
+
+	cfg.AsOf = time.Now()
+	if asOf != "" {
+		t, err := time.Parse(time.RFC3339, asOf)
+		if err != nil {
+			log.Fatalf("Invalid -as-of time: %v", err)
+		}
+		cfg.AsOf = t
+	}
+
+	return cfg
+}
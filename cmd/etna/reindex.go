@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tunogya/etna/pkg/config"
+	"github.com/tunogya/etna/pkg/data"
+	"github.com/tunogya/etna/pkg/feature"
+	"github.com/tunogya/etna/pkg/store/duckdb"
+	"github.com/tunogya/etna/pkg/store/milvus"
+)
+
+// ReindexConfig holds etna reindex configuration
+type ReindexConfig struct {
+	DuckDBPath      string
+	MilvusAddr      string
+	MilvusPartition string // Time partition granularity: year, quarter, or empty for none
+	VectorDim       int
+	Collection      string // Target collection, created if missing
+	Alias           string // Alias pointed at Collection once it verifies; empty leaves aliases alone
+
+	// Windows to reindex; empty for every stored series
+	Symbol    string
+	Timeframe string
+
+	BatchSize        int
+	ProgressInterval time.Duration
+}
+
+// runReindex rebuilds Milvus vectors from the windows and candles stored in DuckDB
+// Vectors are re-extracted from each window's hydrated candles, as DuckDB does not store embeddings.
+// Windows already in the target are skipped, so an interrupted reindex resumes by running it again
+func runReindex(args []string) {
+	cfg := parseReindexFlags(args)
+	ctx := context.Background()
+
+	log.Println("Connecting to DuckDB...")
+	duckClient, err := duckdb.NewClient(cfg.DuckDBPath)
+	if err != nil {
+		log.Fatalf("Failed to connect to DuckDB: %v", err)
+	}
+	defer duckClient.Close()
+	if err := duckdb.ConfigureForRead(duckClient); err != nil {
+		log.Printf("Warning: failed to configure DuckDB: %v", err)
+	}
+	if err := duckdb.InitializeSchema(duckClient); err != nil {
+		log.Fatalf("Failed to initialize schema: %v", err)
+	}
+	windowRepo := duckdb.NewWindowRepo(duckClient)
+	candleRepo := duckdb.NewCandleRepo(duckClient)
+
+	series, err := reindexSeries(ctx, cfg, windowRepo)
+	if err != nil {
+		log.Fatalf("Failed to count windows: %v", err)
+	}
+	var total int64
+	for _, s := range series {
+		total += s.Windows
+	}
+	log.Printf("Reindexing %d windows in %d series into %s", total, len(series), cfg.Collection)
+
+	log.Println("Connecting to Milvus...")
+	milvusClient, err := milvus.NewClient(ctx, milvus.Config{
+		Address:              cfg.MilvusAddr,
+		PartitionGranularity: cfg.MilvusPartition,
+	})
+	if err != nil {
+		log.Fatalf("Failed to connect to Milvus: %v", err)
+	}
+	defer milvusClient.Close()
+
+	// The target must be indexed and loaded before Get can report the windows it already holds
+	collectionCfg := milvus.CollectionConfig{Name: cfg.Collection, Dimension: cfg.VectorDim, Shards: 2}
+	if err := milvusClient.CreateCollection(ctx, collectionCfg); err != nil {
+		log.Fatalf("Failed to create Milvus collection: %v", err)
+	}
+	if err := milvusClient.ValidateSchema(ctx, cfg.Collection, collectionCfg); err != nil {
+		log.Fatalf("Target collection does not match: %v", err)
+	}
+	if err := milvusClient.CreateIndex(ctx, cfg.Collection, "embedding"); err != nil {
+		log.Printf("Warning: failed to create index: %v", err)
+	}
+	if err := milvusClient.LoadCollection(ctx, cfg.Collection); err != nil {
+		log.Fatalf("Failed to load collection: %v", err)
+	}
+
+	progress := data.NewProgressTracker(cfg.ProgressInterval, func(p data.BackfillProgress) {
+		log.Printf("Progress %.1f%% (%d/%d windows), %.0f vectors/s, ETA %s",
+			p.Percent, p.StageDone, p.StageTotal, p.MilvusRowsPerSec, p.ETA.Round(time.Second))
+	})
+	progress.Start()
+	progress.StartStage("reindex", total)
+
+	extractors := make(map[int]*feature.Extractor)
+	var upserted, skipped, failed int
+	for _, s := range series {
+		for offset := 0; ; offset += cfg.BatchSize {
+			windows, err := windowRepo.GetWindowsPage(ctx, s.Symbol, s.Timeframe, offset, cfg.BatchSize)
+			if err != nil {
+				log.Fatalf("Failed to load windows: %v", err)
+			}
+			if len(windows) == 0 {
+				break
+			}
+
+			ids := make([]string, len(windows))
+			for i, w := range windows {
+				ids[i] = w.WindowID
+			}
+			existing, err := milvusClient.GetByIDs(ctx, cfg.Collection, ids, false)
+			if err != nil {
+				log.Fatalf("Failed to check existing vectors: %v", err)
+			}
+			present := make(map[string]bool, len(existing))
+			for _, r := range existing {
+				present[r.WindowID] = true
+			}
+
+			var batch []*milvus.WindowData
+			for _, w := range windows {
+				if present[w.WindowID] {
+					skipped++
+					continue
+				}
+
+				candles, err := candleRepo.GetWindowCandles(ctx, w.Symbol, w.Timeframe, w.TEnd, w.W)
+				if err != nil {
+					log.Fatalf("Failed to hydrate window %s: %v", w.WindowID, err)
+				}
+				w.Candles = candles
+
+				extractor, ok := extractors[w.FeatureVersion]
+				if !ok {
+					extractor = feature.NewExtractor(w.FeatureVersion, cfg.VectorDim)
+					extractors[w.FeatureVersion] = extractor
+				}
+				featureRow, shapeVector, err := extractor.Extract(w)
+				if err != nil || featureRow == nil {
+					log.Printf("Warning: cannot re-extract window %s (%d/%d candles stored): %v", w.WindowID, len(candles), w.W, err)
+					failed++
+					continue
+				}
+
+				batch = append(batch, &milvus.WindowData{
+					WindowID:    w.WindowID,
+					Embedding:   shapeVector,
+					Symbol:      w.Symbol,
+					Timeframe:   w.Timeframe,
+					TEnd:        w.TEnd,
+					VolBucket:   int32(featureRow.VolBucket),
+					TrendBucket: int32(featureRow.TrendBucket),
+					DataVersion: int32(featureRow.DataVersion),
+				})
+			}
+
+			if len(batch) > 0 {
+				if err := milvusClient.UpsertBatch(ctx, cfg.Collection, batch); err != nil {
+					log.Fatalf("Failed to upsert vectors: %v", err)
+				}
+			}
+			upserted += len(batch)
+			progress.AddWindows(int64(len(windows)))
+			progress.AddMilvusRows(int64(len(batch)))
+			progress.Advance(int64(len(windows)))
+		}
+	}
+	progress.Stop()
+
+	if err := milvusClient.Flush(ctx, cfg.Collection); err != nil {
+		log.Fatalf("Failed to flush Milvus: %v", err)
+	}
+	log.Printf("Upserted %d vectors, skipped %d already present, %d windows could not be re-extracted", upserted, skipped, failed)
+
+	if problems := verifyReindex(ctx, cfg, milvusClient, series); problems > 0 {
+		log.Fatalf("Reindex verification found %d mismatched series; rerun to fill missing vectors", problems)
+	}
+	log.Println("Reindex verified: every series has as many vectors as windows")
+
+	if cfg.Alias != "" {
+		if err := milvusClient.SetAlias(ctx, cfg.Collection, cfg.Alias); err != nil {
+			log.Fatalf("Failed to flip alias: %v", err)
+		}
+		log.Printf("Alias %s now points at %s", cfg.Alias, cfg.Collection)
+	}
+}
+
+// reindexSeries returns the stored series selected by cfg's symbol and timeframe filters
+func reindexSeries(ctx context.Context, cfg ReindexConfig, windowRepo *duckdb.WindowRepo) ([]duckdb.SeriesCount, error) {
+	counts, err := windowRepo.CountBySeries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var series []duckdb.SeriesCount
+	for _, c := range counts {
+		if (cfg.Symbol == "" || c.Symbol == cfg.Symbol) && (cfg.Timeframe == "" || c.Timeframe == cfg.Timeframe) {
+			series = append(series, c)
+		}
+	}
+	return series, nil
+}
+
+// verifyReindex compares the target's vector count with the stored window count of each series
+// It returns the number of series that differ
+func verifyReindex(ctx context.Context, cfg ReindexConfig, milvusClient *milvus.Client, series []duckdb.SeriesCount) int {
+	fmt.Printf("\n%-12s %-6s %-10s %-10s %s\n", "Symbol", "TF", "Windows", "Vectors", "Status")
+	fmt.Println("--------------------------------------------------")
+	problems := 0
+	for _, s := range series {
+		filter := milvus.NewFilter().In("symbol", s.Symbol).In("timeframe", s.Timeframe)
+		vectors, err := milvusClient.Count(ctx, cfg.Collection, filter.String())
+		if err != nil {
+			log.Fatalf("Failed to count vectors: %v", err)
+		}
+
+		status := "OK"
+		if diff := vectors - s.Windows; diff != 0 {
+			status = fmt.Sprintf("%+d vectors vs windows", diff)
+			problems++
+		}
+		fmt.Printf("%-12s %-6s %-10d %-10d %s\n", s.Symbol, s.Timeframe, s.Windows, vectors, status)
+	}
+	return problems
+}
+
+func parseReindexFlags(args []string) ReindexConfig {
+	cfg := ReindexConfig{}
+	fs := flag.NewFlagSet("reindex", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: etna reindex [options]")
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(&cfg.DuckDBPath, "duckdb", "etna.duckdb", "DuckDB file path")
+	fs.StringVar(&cfg.MilvusAddr, "milvus", "localhost:19530", "Milvus server address")
+	fs.StringVar(&cfg.MilvusPartition, "milvus-partition", "", "Milvus time partition granularity: year or quarter (empty disables)")
+	fs.IntVar(&cfg.VectorDim, "dim", 96, "Vector dimension of the target collection")
+	fs.StringVar(&cfg.Collection, "collection", milvus.DefaultCollectionName, "Target Milvus collection, created if missing")
+	fs.StringVar(&cfg.Alias, "alias", "", "Point this alias at the target collection once it verifies (must not name an existing collection)")
+	fs.StringVar(&cfg.Symbol, "symbol", "", "Only reindex this symbol (empty for all)")
+	fs.StringVar(&cfg.Timeframe, "timeframe", "", "Only reindex this timeframe (empty for all)")
+	fs.IntVar(&cfg.BatchSize, "batch", 1000, "Windows read and upserted per batch")
+	fs.DurationVar(&cfg.ProgressInterval, "progress-interval", 10*time.Second, "How often to report progress")
+
+	configPath := fs.String("config", "", "YAML or JSON config file; ETNA_* environment variables override it and explicit flags override both")
+
+	fs.Parse(args)
+	// The shared symbol/timeframe select what to backfill; here empty means every series
+	if err := config.ApplyFlags(fs, *configPath, "symbol", "timeframe"); err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
+
+	if cfg.BatchSize <= 0 {
+		log.Fatalf("-batch must be positive, got %d", cfg.BatchSize)
+	}
+	if cfg.Alias != "" && strings.EqualFold(cfg.Alias, cfg.Collection) {
+		log.Fatalf("-alias must differ from -collection")
+	}
+	return cfg
+}
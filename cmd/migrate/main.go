@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/tunogya/etna/pkg/config"
+	"github.com/tunogya/etna/pkg/store/duckdb"
+)
+
+// Config holds migrate configuration
+type Config struct {
+	DuckDBPath string
+	Dir        string
+}
+
+func main() {
+	cfg := parseFlags()
+
+	ctx := context.Background()
+
+	// Initialize DuckDB
+	log.Println("Connecting to DuckDB...")
+	duckClient, err := duckdb.NewClient(cfg.DuckDBPath)
+	if err != nil {
+		log.Fatalf("Failed to connect to DuckDB: %v", err)
+	}
+	defer duckClient.Close()
+
+	// Ensure base tables exist before applying migrations
+	if err := duckdb.InitializeSchema(duckClient); err != nil {
+		log.Fatalf("Failed to initialize schema: %v", err)
+	}
+
+	log.Println("Applying data migrations...")
+	if err := duckdb.RunDataMigrations(ctx, duckClient); err != nil {
+		log.Fatalf("Data migration failed: %v", err)
+	}
+
+	log.Printf("Applying migrations from %s...", cfg.Dir)
+	if err := duckClient.ExecuteDirectory(ctx, cfg.Dir); err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+
+	log.Println("Migrations applied successfully!")
+}
+
+func parseFlags() Config {
+	cfg := Config{}
+
+	flag.StringVar(&cfg.DuckDBPath, "duckdb", "etna.duckdb", "DuckDB file path")
+	flag.StringVar(&cfg.Dir, "dir", "migrations/", "Directory of .sql migration files")
+
+	configPath := flag.String("config", "", "YAML or JSON config file; ETNA_* environment variables override it and explicit flags override both")
+
+	flag.Parse()
+	if err := config.ApplyFlags(flag.CommandLine, *configPath); err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
+	return cfg
+}
@@ -2,13 +2,24 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"math"
+	"regexp"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/tunogya/etna/pkg/chart"
+	"github.com/tunogya/etna/pkg/config"
+	"github.com/tunogya/etna/pkg/data"
 	"github.com/tunogya/etna/pkg/feature"
+	"github.com/tunogya/etna/pkg/model"
+	"github.com/tunogya/etna/pkg/outcome"
 	"github.com/tunogya/etna/pkg/rerank"
 	"github.com/tunogya/etna/pkg/store/duckdb"
 	"github.com/tunogya/etna/pkg/store/milvus"
@@ -23,9 +34,67 @@ type Config struct {
 	StepSize       int
 	FeatureVersion int
 
-	DuckDBPath string
-	MilvusAddr string
-	TopK       int
+	DuckDBPath      string
+	MilvusAddr      string
+	MilvusPartition string // Time partition granularity: year, quarter, or empty for none
+	TopK            int    // Neighbors kept after reranking, for display and outcome aggregation
+	RecallK         int    // Candidates fetched from Milvus for reranking; at least TopK
+
+	// Reranking
+	Mode            string  // recency, analogy, or hybrid
+	RecencyWeight   float64 // Weight of recency decay in hybrid mode
+	AnalogyWeight   float64 // Weight of inverse decay in hybrid mode
+	BucketMatch     bool    // Scale scores by vol/trend bucket agreement with the query window
+	ScoreMode       string  // Score combination: multiplicative or additive
+	Alpha           float64 // Additive similarity coefficient
+	Beta            float64 // Additive time weight coefficient
+	Gamma           float64 // Additive bucket weight coefficient
+	Dedup           bool    // Drop neighbors overlapping a higher-ranked neighbor in time
+	DedupOverlap    float64 // Max time overlap fraction allowed between ranked neighbors
+	MMR             bool    // Reorder neighbors for embedding-space diversity
+	CrossSymbol     bool    // Search neighbors from every symbol of the timeframe, not just Symbol
+	Symbols         string  // Comma-separated symbols searched, ANY for all (default: Symbol, or ANY with CrossSymbol)
+	Timeframes      string  // Comma-separated timeframes searched, ANY for all (default: Timeframe)
+	AllowedSymbols  string  // Comma-separated symbols allowed in cross-symbol results (empty allows all)
+	SameSymbolBonus float64 // Fractional score boost for same-symbol neighbors in cross-symbol mode
+	PerSymbolCap    float64 // Max fraction of cross-symbol results from any one symbol (0 disables)
+	FeatureWeight   float64 // Blend weight of structured-feature similarity to the query (0 disables)
+	FeatureCols     string  // Comma-separated window_features columns compared when FeatureWeight > 0
+	QualityHorizon  int     // Horizon whose stored outcomes rescore neighbors by path quality (0 disables)
+	QualityStrict   bool    // Drop neighbors without a stored outcome for QualityHorizon
+	MMRLambda       float64 // MMR relevance/diversity trade-off (1 = pure relevance)
+	Explain         bool    // Print a per-neighbor breakdown of how each final score was reached
+	BatchSearch     string  // Comma-separated symbols whose latest windows are searched concurrently instead of Symbol
+
+	// Neighbor metadata filters (empty or zero disables each)
+	VolBuckets   []int     // Allowed vol_bucket values
+	TrendBuckets []int     // Allowed trend_bucket values
+	After        time.Time // Neighbors must end at or after this time
+	Before       time.Time // Neighbors must end before this time
+	DataVersion  int       // Required data_version
+
+	// Query window source (default: built from the latest candles)
+	WindowID string    // Search from this stored window
+	At       time.Time // Search from the latest stored window ending at or before this time
+	QueryCSV string    // Search from the last WindowLength candles of this CSV file
+
+	// Calibration
+	Recalibrate       bool // Refit the score calibration even if one is stored
+	CalibrationSample int  // Corpus windows sampled when fitting the score calibration (0 disables calibration)
+
+	// Outcomes
+	TargetPct  float64 // Time-to-target threshold in percent (0 disables)
+	MaxOverlap float64 // Max time overlap between aggregated neighbors (1 disables de-duplication)
+	FanChart   bool    // Render the P10/P50/P90 forward return path envelope
+	Partial    string  // Incomplete horizon policy: skip, partial, or error
+	Horizons   []int   // Outcome horizons in bars
+	Bootstrap  int     // Resamples for the weighted mean return's confidence interval (0 disables)
+
+	// Shape charts
+	Chart       bool // Print close sparklines of the query and top neighbors under their result rows
+	ChartTop    int  // Neighbors charted
+	ChartWidth  int  // Max chart width in characters
+	ChartHeight int  // Rows of the high/low block chart drawn under each sparkline (0 disables)
 }
 
 func main() {
@@ -40,9 +109,404 @@ func main() {
 		log.Fatalf("Failed to connect to DuckDB: %v", err)
 	}
 	defer duckClient.Close()
+	if err := duckdb.ConfigureForRead(duckClient); err != nil {
+		log.Printf("Warning: failed to configure DuckDB: %v", err)
+	}
 
 	candleRepo := duckdb.NewCandleRepo(duckClient)
+	windowRepo := duckdb.NewWindowRepo(duckClient)
+	featureRepo := duckdb.NewFeatureRepo(duckClient)
+	outcomeRepo := duckdb.NewOutcomeRepo(duckClient)
+	calibrationRepo := duckdb.NewCalibrationRepo(duckClient)
+
+	if cfg.BatchSearch != "" {
+		runBatchSearch(ctx, cfg, candleRepo)
+		return
+	}
+
+	currentWindow, err := loadQueryWindow(ctx, cfg, candleRepo, windowRepo)
+	if err != nil {
+		log.Fatalf("Failed to build query window: %v", err)
+	}
+	log.Printf("Built analysis window: %s (TEnd: %s)", currentWindow.WindowID, currentWindow.TEnd.Format(time.RFC3339))
+
+	// A stored query window searches its own series with its own shape
+	cfg.Symbol, cfg.Timeframe = currentWindow.Symbol, currentWindow.Timeframe
+	cfg.WindowLength, cfg.FeatureVersion = currentWindow.W, currentWindow.FeatureVersion
+
+	// Historical queries are ranked as of the query end, so recency decay does not favor neighbors after it
+	asOf := time.Now()
+	if cfg.WindowID != "" || !cfg.At.IsZero() {
+		asOf = currentWindow.TEnd
+	}
+
+	// Extract features
+	extractor := feature.NewExtractor(cfg.FeatureVersion, 96) // 96 dim is standard for now
+	queryFeatures, embedding, err := extractor.Extract(currentWindow)
+	if err != nil {
+		log.Fatalf("Failed to extract features: %v", err)
+	}
+
+	// Initialize Milvus
+	log.Println("Connecting to Milvus...")
+	milvusClient, err := milvus.NewClient(ctx, milvus.Config{
+		Address:              cfg.MilvusAddr,
+		PartitionGranularity: cfg.MilvusPartition,
+	})
+	if err != nil {
+		log.Fatalf("Failed to connect to Milvus: %v", err)
+	}
+	defer milvusClient.Close()
+
+	if err := milvusClient.LoadCollection(ctx, milvus.DefaultCollectionName); err != nil {
+		log.Fatalf("Failed to load collection: %v", err)
+	}
+
+	// Search
+	symbols, timeframes := searchedSeries(cfg)
+	cfg.CrossSymbol = len(symbols) != 1 || symbols[0] != cfg.Symbol
+	corpus := searchFilter(symbols, timeframes)
+	filter := metadataFilter(cfg, corpus).String()
+	for _, w := range emptyFilterWarnings(cfg, currentWindow.TEnd) {
+		log.Printf("Warning: %s; the search cannot return neighbors", w)
+	}
+	log.Printf("Searching for %d most similar windows of %s %s, reranked to the top %d...", cfg.RecallK, strings.Join(symbols, ","), strings.Join(timeframes, ","), cfg.TopK)
+	results, err := milvusClient.Search(ctx, milvus.DefaultCollectionName, embedding, filter, cfg.RecallK)
+	if err != nil {
+		log.Fatalf("Search failed: %v", err)
+	}
+
+	// MMR compares neighbors to each other, so it needs their stored embeddings
+	if cfg.MMR {
+		if err := attachEmbeddings(ctx, milvusClient, results); err != nil {
+			log.Fatalf("Failed to fetch neighbor embeddings: %v", err)
+		}
+	}
+
+	// Results
+	log.Println("\n=== Search Results ===")
+
+	// Rerank through the pipeline configured by flags
+	barDuration, err := model.TimeframeDuration(cfg.Timeframe)
+	if err != nil {
+		log.Fatalf("Invalid timeframe: %v", err)
+	}
+
+	// Neighbors whose longest outcome horizon extends past the query end would leak future returns
+	outcomeCfg := outcome.DefaultConfig()
+	outcomeCfg.TargetPct = cfg.TargetPct / 100
+	outcomeCfg.IncludePath = cfg.FanChart
+	outcomeCfg.Partial = outcome.PartialPolicy(cfg.Partial)
+	outcomeCfg.Horizons = cfg.Horizons
+	horizons := outcomeCfg.Horizons
+	lookahead := rerank.NewExcludeFutureStage(currentWindow.TEnd, maxInt(horizons), barDuration)
+
+	var quality *rerank.OutcomeQualityStage
+	if cfg.QualityHorizon > 0 {
+		quality = rerank.NewOutcomeQualityStage(ctx, outcomeRepo, rerank.OutcomeQualityConfig{
+			Horizon: cfg.QualityHorizon,
+			Strict:  cfg.QualityStrict,
+		})
+	}
+
+	var featureDist *rerank.FeatureDistanceStage
+	if cfg.FeatureWeight > 0 {
+		featureDist, err = newFeatureDistanceStage(ctx, cfg, featureRepo, queryFeatures)
+		if err != nil {
+			log.Fatalf("Failed to calibrate feature distance: %v", err)
+		}
+	}
+
+	var calibrator *rerank.Calibrator
+	if cfg.CalibrationSample > 0 {
+		calibrator, err = loadCalibrator(ctx, cfg, calibrationRepo, windowRepo, milvusClient, symbols, timeframes)
+		if err != nil {
+			log.Printf("Warning: score calibration unavailable: %v", err)
+		}
+	}
+
+	pipeline := buildPipeline(cfg, queryFeatures, lookahead, featureDist, quality, barDuration, asOf)
+	if calibrator != nil {
+		pipeline.Add(calibrator)
+	}
+	ranked := pipeline.Run(results)
+	if featureDist != nil && featureDist.Err() != nil {
+		log.Printf("Warning: feature distance rescoring skipped: %v", featureDist.Err())
+	}
+	if quality != nil && quality.Err() != nil {
+		log.Printf("Warning: outcome quality rescoring skipped: %v", quality.Err())
+	}
+	if n := lookahead.Excluded(); n > 0 {
+		log.Printf("Excluded %d look-ahead neighbors ending within %d bars of the query", n, maxInt(horizons))
+	}
+
+	if len(ranked) > 0 {
+		fmt.Printf("Score mode: %s\n", ranked[0].ScoreMode)
+	}
+	// Match is the calibrated percentile of the raw score; Sim% is shown when no calibration is available
+	strengthHeader := "Sim%"
+	if calibrator != nil {
+		strengthHeader = "Match"
+	}
+	if cfg.Chart {
+		printShapeChart(cfg, "Query", currentWindow.Candles)
+	}
+	fmt.Printf("%-5s %-32s %-10s %-4s %-20s %-10s %-10s %-10s\n", "Rank", "WindowID", "Symbol", "TF", "End Date", "Score", strengthHeader, "Final")
+	fmt.Println("------------------------------------------------------------------------------------------------")
+
+	var neighborIDs []string
+	var neighbors []outcome.Neighbor
+	charted := 0
+	for i, r := range ranked {
+		// Ignore the query window itself if it appears (which it might if it was backfilled)
+		if r.WindowID == currentWindow.WindowID {
+			continue
+		}
+		// Cross-timeframe neighbors span their own bars
+		neighborBar := barDuration
+		if d, err := model.TimeframeDuration(r.Timeframe); err == nil {
+			neighborBar = d
+		}
+		neighborIDs = append(neighborIDs, r.WindowID)
+		neighbors = append(neighbors, outcome.Neighbor{
+			WindowID:   r.WindowID,
+			Symbol:     r.Symbol,
+			Similarity: float64(r.OriginalScore),
+			TStart:     r.TEnd.Add(-time.Duration(cfg.WindowLength) * neighborBar),
+			TEnd:       r.TEnd,
+		})
+
+		strength := fmt.Sprintf("%.2f%%", r.OriginalScore*100)
+		if calibrator != nil {
+			strength = fmt.Sprintf("%.1f", r.MatchStrength)
+		}
+		fmt.Printf("%-5d %-32s %-10s %-4s %-20s %-10.4f %-10s %-.4f\n", i+1, r.WindowID, r.Symbol, r.Timeframe,
+			r.TEnd.Format("2006-01-02"), r.OriginalScore, strength, r.FinalScore)
+
+		if cfg.Chart && charted < cfg.ChartTop {
+			charted++
+			candles, err := candleRepo.GetWindowCandles(ctx, r.Symbol, r.Timeframe, r.TEnd, cfg.WindowLength)
+			if err != nil {
+				fmt.Printf("%-6s(candles unavailable: %v)\n", "", err)
+				continue
+			}
+			printShapeChart(cfg, "", candles)
+		}
+	}
+
+	if cfg.CrossSymbol {
+		printSymbolDistribution(rerank.SymbolDistribution(ranked))
+	}
+
+	if cfg.Explain {
+		printExplanations(newTimeReranker(cfg), ranked, currentWindow.WindowID)
+	}
+
+	// Neighbor outcome summary
+	engine := outcome.NewEngineWithConfig(candleRepo, outcomeCfg)
+	outcomes, err := engine.LoadOrCalculate(ctx, neighborIDs, horizons, windowRepo, outcomeRepo)
+	if err != nil {
+		log.Printf("Outcome calculation failed: %v", err)
+		return
+	}
+	printOutcomeSummary(outcome.AggregateResultsDedup(outcomes, neighbors, cfg.MaxOverlap), horizons)
+	printWeightedSummary(outcome.AggregateWeighted(outcomes, neighbors, cfg.MaxOverlap, cfg.Bootstrap), horizons)
+
+	if cfg.FanChart {
+		printFanChart(outcome.AggregatePathsDedup(outcomes, neighbors, cfg.MaxOverlap), fanChartHeight)
+	}
+}
+
+// printShapeChart prints a window's close sparkline after label, padded to start under the WindowID column,
+// followed by its high/low block chart when cfg.ChartHeight > 0
+// Windows with fewer stored candles than the window length are drawn from what is stored and say so
+func printShapeChart(cfg Config, label string, candles []model.Candle) {
+	if len(candles) == 0 {
+		fmt.Printf("%-6s(no candles stored)\n", label)
+		return
+	}
+
+	note := ""
+	if len(candles) < cfg.WindowLength {
+		note = fmt.Sprintf(" (%d/%d candles stored)", len(candles), cfg.WindowLength)
+	}
+	fmt.Printf("%-6s%s%s\n", label, chart.Sparkline(model.CandleSeries(candles).Closes(), cfg.ChartWidth), note)
+	for _, line := range chart.BlockChart(candles, cfg.ChartWidth, cfg.ChartHeight) {
+		fmt.Printf("%-6s%s\n", "", line)
+	}
+}
+
+// fanChartHeight is the number of text rows used to render the fan chart
+const fanChartHeight = 15
+
+// printFanChart renders the path envelope as an ASCII chart: '*' median, '|' P10-P90 band, '-' zero line
+func printFanChart(env outcome.PathEnvelope, height int) {
+	fmt.Println("\n=== Expected Path (P10/P50/P90) ===")
+	if env.Len() == 0 || height < 2 {
+		fmt.Println("(no forward data)")
+		return
+	}
+
+	lo, hi := 0.0, 0.0
+	for i := 0; i < env.Len(); i++ {
+		lo = math.Min(lo, env.P10[i])
+		hi = math.Max(hi, env.P90[i])
+	}
+	if hi == lo {
+		hi = lo + 1e-9
+	}
+
+	// row maps a return to a row index, with row 0 at the top
+	row := func(v float64) int {
+		return int(math.Round((hi - v) / (hi - lo) * float64(height-1)))
+	}
+
+	grid := make([][]byte, height)
+	for r := range grid {
+		grid[r] = []byte(strings.Repeat(" ", env.Len()))
+	}
+	zero := row(0)
+	for c := 0; c < env.Len(); c++ {
+		grid[zero][c] = '-'
+		for r := row(env.P90[c]); r <= row(env.P10[c]); r++ {
+			grid[r][c] = '|'
+		}
+		grid[row(env.P50[c])][c] = '*'
+	}
+
+	for r, line := range grid {
+		label := ""
+		switch r {
+		case 0:
+			label = fmt.Sprintf("%+.2f%%", hi*100)
+		case zero:
+			label = "0.00%"
+		case height - 1:
+			label = fmt.Sprintf("%+.2f%%", lo*100)
+		}
+		fmt.Printf("%9s %s\n", label, line)
+	}
+	fmt.Printf("%9s bar 1..%d (n=%d at bar 1, %d at bar %d)\n", "",
+		env.Len(), env.Counts[0], env.Counts[env.Len()-1], env.Len())
+}
+
+// buildPipeline assembles the rerank pipeline selected by flags
+// featureDist and quality may be nil when their rescoring is disabled
+func buildPipeline(cfg Config, queryFeatures *model.FeatureRow, lookahead *rerank.ExcludeFutureStage, featureDist *rerank.FeatureDistanceStage, quality *rerank.OutcomeQualityStage, barDuration time.Duration, now time.Time) *rerank.Pipeline {
+	pipeline := rerank.NewPipeline(lookahead)
+
+	pipeline.Add(newTimeReranker(cfg).Stage(now))
+
+	if cfg.BucketMatch {
+		pipeline.Add(rerank.NewBucketReranker(queryFeatures, rerank.DefaultBucketConfig()))
+	}
+
+	pipeline.Add(rerank.NewScoreCombiner(rerank.CombinerConfig{
+		Mode:  cfg.ScoreMode,
+		Alpha: cfg.Alpha,
+		Beta:  cfg.Beta,
+		Gamma: cfg.Gamma,
+	}))
+
+	// Additive mode rebuilds FinalScore from the similarity, time and bucket weights, so stages
+	// scaling FinalScore by other signals must run after the combiner
+	if featureDist != nil {
+		pipeline.Add(featureDist)
+	}
+
+	if quality != nil {
+		pipeline.Add(quality)
+	}
+
+	if cfg.CrossSymbol {
+		pipeline.Add(rerank.NewSymbolStage(rerank.SymbolConfig{
+			QuerySymbol:     cfg.Symbol,
+			SameSymbolBonus: cfg.SameSymbolBonus,
+			PerSymbolCap:    cfg.PerSymbolCap,
+			AllowedSymbols:  splitList(cfg.AllowedSymbols),
+			TopK:            cfg.TopK,
+		}))
+	}
+
+	if cfg.Dedup {
+		pipeline.Add(rerank.NewDedupStage(cfg.WindowLength, barDuration, cfg.DedupOverlap))
+	}
+
+	if cfg.MMR {
+		pipeline.Add(rerank.NewMMRStage(cfg.MMRLambda, cfg.TopK))
+	}
+
+	// Everything above ranks the wide recall set; only the best TopK are shown and aggregated
+	pipeline.Add(rerank.NewTopKStage(cfg.TopK))
+
+	return pipeline
+}
+
+// timeReranker is the time weighting of a rerank mode, able to explain the weights its stage applied
+type timeReranker interface {
+	rerank.Explainer
+	Stage(now time.Time) rerank.Stage
+}
+
+// newTimeReranker returns the time weighting of the selected rerank mode
+func newTimeReranker(cfg Config) timeReranker {
+	if cfg.Mode == "hybrid" {
+		return rerank.NewHybridRerankerWithConfig(rerank.DefaultForTimeframe(cfg.Timeframe), rerank.InverseDecayConfig(),
+			cfg.RecencyWeight, cfg.AnalogyWeight)
+	}
+	return rerank.NewReranker(decayConfig(cfg))
+}
+
+// decayConfig returns the time decay of the recency or analogy rerank mode
+func decayConfig(cfg Config) rerank.TimeDecayConfig {
+	if cfg.Mode == "analogy" {
+		return rerank.InverseDecayConfig()
+	}
+	return rerank.DefaultForTimeframe(cfg.Timeframe)
+}
+
+// loadQueryWindow returns the window to search: the stored -window-id window, the latest stored window ending at
+// or before -at, a window built from the -query-csv snippet, or by default one built from the latest candles
+func loadQueryWindow(ctx context.Context, cfg Config, candleRepo *duckdb.CandleRepo, windowRepo *duckdb.WindowRepo) (*model.Window, error) {
+	var w *model.Window
+	var err error
+	switch {
+	case cfg.WindowID != "":
+		log.Printf("Loading window %s...", cfg.WindowID)
+		w, err = windowRepo.GetByID(ctx, cfg.WindowID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load window %s: %w", cfg.WindowID, err)
+		}
+	case !cfg.At.IsZero():
+		log.Printf("Finding the latest %s %s window ending at or before %s...", cfg.Symbol, cfg.Timeframe, cfg.At.Format(time.RFC3339))
+		w, err = windowRepo.GetClosestByTEnd(ctx, cfg.Symbol, cfg.Timeframe, cfg.At)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("no %s %s window ends at or before %s", cfg.Symbol, cfg.Timeframe, cfg.At.Format(time.RFC3339))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to find window: %w", err)
+		}
+	case cfg.QueryCSV != "":
+		return csvWindow(ctx, cfg)
+	default:
+		return latestWindow(ctx, cfg, candleRepo)
+	}
+
+	// The windows table does not store candles
+	candles, err := candleRepo.GetWindowCandles(ctx, w.Symbol, w.Timeframe, w.TEnd, w.W)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hydrate window %s: %w", w.WindowID, err)
+	}
+	if len(candles) < w.W {
+		return nil, fmt.Errorf("window %s has %d of %d candles stored", w.WindowID, len(candles), w.W)
+	}
+	w.Candles = candles
 
+	return w, nil
+}
+
+// latestWindow builds a window from the latest cfg.WindowLength candles of the symbol/timeframe
+func latestWindow(ctx context.Context, cfg Config, candleRepo *duckdb.CandleRepo) (*model.Window, error) {
 	// Fetch latest candles for the window
 	// Need enough candles for one window
 	log.Printf("Fetching latest %d candles for %s %s...", cfg.WindowLength, cfg.Symbol, cfg.Timeframe)
@@ -54,11 +518,11 @@ func main() {
 
 	candles, err := candleRepo.GetLatest(ctx, cfg.Symbol, cfg.Timeframe, cfg.WindowLength)
 	if err != nil {
-		log.Fatalf("Failed to fetch latest candles: %v", err)
+		return nil, fmt.Errorf("failed to fetch latest candles: %w", err)
 	}
 
 	if len(candles) < cfg.WindowLength {
-		log.Fatalf("Not enough candles found. Need %d, got %d", cfg.WindowLength, len(candles))
+		return nil, fmt.Errorf("not enough candles found: need %d, got %d", cfg.WindowLength, len(candles))
 	}
 
 	// Ensure they are sorted by time (GetLatest usually returns DESC, we need ASC)
@@ -68,73 +532,488 @@ func main() {
 
 	log.Printf("Latest candle: %s", candles[len(candles)-1].CloseTime.Format(time.RFC3339))
 
-	// Build SINGLE current window
-	builder := window.NewBuilder(window.Config{
+	return buildWindow(cfg, candles)
+}
+
+// csvWindow builds a window from the last cfg.WindowLength candles of the -query-csv file, without the candles table
+// The snippet is taken to be cfg.Symbol/cfg.Timeframe, so its rows must open exactly one timeframe bar apart
+func csvWindow(ctx context.Context, cfg Config) (*model.Window, error) {
+	barDuration, err := model.TimeframeDuration(cfg.Timeframe)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timeframe: %w", err)
+	}
+
+	log.Printf("Loading query candles from %s...", cfg.QueryCSV)
+	candles, err := data.NewCSVProvider(cfg.QueryCSV).FetchLatestCandles(ctx, "", "", cfg.WindowLength)
+	if err != nil {
+		return nil, err
+	}
+	if len(candles) < cfg.WindowLength {
+		return nil, fmt.Errorf("%s has %d valid rows, need at least %d", cfg.QueryCSV, len(candles), cfg.WindowLength)
+	}
+
+	for i := range candles {
+		if i > 0 {
+			if gap := candles[i].OpenTime.Sub(candles[i-1].OpenTime); gap != barDuration {
+				return nil, fmt.Errorf("rows opening at %s and %s are %s apart, want %s for timeframe %s",
+					candles[i-1].OpenTime.Format(time.RFC3339), candles[i].OpenTime.Format(time.RFC3339), gap, barDuration, cfg.Timeframe)
+			}
+		}
+		// CSVProvider defaults a missing close_time to one minute after open
+		candles[i].Symbol, candles[i].Timeframe = cfg.Symbol, cfg.Timeframe
+		candles[i].CloseTime = candles[i].OpenTime.Add(barDuration - time.Millisecond)
+	}
+	log.Printf("Query snippet: %s to %s", candles[0].OpenTime.Format(time.RFC3339), candles[len(candles)-1].CloseTime.Format(time.RFC3339))
+
+	return buildWindow(cfg, candles)
+}
+
+// buildWindow builds the window ending at the last of candles, which are in chronological order
+func buildWindow(cfg Config, candles []model.Candle) (*model.Window, error) {
+	builder, err := window.NewBuilder(window.Config{
 		W:              cfg.WindowLength,
 		S:              cfg.StepSize,
 		FeatureVersion: cfg.FeatureVersion,
 		Symbol:         cfg.Symbol,
 		Timeframe:      cfg.Timeframe,
 	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid window config: %w", err)
+	}
 
 	// ProcessCandles usually handles sliding windows. Since we have exactly W candles (or slightly more),
 	// passing them might just generate 1 window if count == W.
 	windows := builder.ProcessCandles(candles)
 	if len(windows) == 0 {
-		log.Fatalf("Failed to build window from candles")
+		return nil, errors.New("failed to build window from candles")
 	}
 
-	currentWindow := windows[len(windows)-1] // Take the very last one
-	log.Printf("Built analysis window: %s (TEnd: %s)", currentWindow.WindowID, currentWindow.TEnd.Format(time.RFC3339))
+	return windows[len(windows)-1], nil // Take the very last one
+}
 
-	// Extract features
-	extractor := feature.NewExtractor(cfg.FeatureVersion, 96) // 96 dim is standard for now
-	_, embedding, err := extractor.Extract(currentWindow)
-	if err != nil {
-		log.Fatalf("Failed to extract features: %v", err)
+// runBatchSearch searches the latest window of every -batch-search symbol with one SearchBatch call
+// Each symbol's neighbors are time-decay reranked and printed; the full rerank pipeline and outcomes are skipped
+func runBatchSearch(ctx context.Context, cfg Config, candleRepo *duckdb.CandleRepo) {
+	extractor := feature.NewExtractor(cfg.FeatureVersion, 96)
+
+	var symbols []string
+	var queries []milvus.SearchQuery
+	for _, symbol := range strings.Split(cfg.BatchSearch, ",") {
+		if symbol = strings.TrimSpace(symbol); symbol == "" {
+			continue
+		}
+
+		candles, err := candleRepo.GetLatest(ctx, symbol, cfg.Timeframe, cfg.WindowLength)
+		if err != nil || len(candles) < cfg.WindowLength {
+			log.Printf("Warning: skipping %s, not enough candles (%v)", symbol, err)
+			continue
+		}
+		sort.Slice(candles, func(i, j int) bool {
+			return candles[i].OpenTime.Before(candles[j].OpenTime)
+		})
+
+		last := candles[len(candles)-1]
+		w := model.NewWindow(symbol, cfg.Timeframe, last.CloseTime, cfg.WindowLength, cfg.FeatureVersion, candles)
+		_, embedding, err := extractor.Extract(w)
+		if err != nil {
+			log.Printf("Warning: skipping %s, failed to extract features: %v", symbol, err)
+			continue
+		}
+
+		for _, warning := range emptyFilterWarnings(cfg, w.TEnd) {
+			log.Printf("Warning: %s: %s; the search cannot return neighbors", symbol, warning)
+		}
+		symbols = append(symbols, symbol)
+		queries = append(queries, milvus.SearchQuery{Embedding: embedding, Filter: batchQueryFilter(cfg, symbol)})
+	}
+	if len(queries) == 0 {
+		log.Fatalf("No symbols to search")
 	}
 
-	// Initialize Milvus
 	log.Println("Connecting to Milvus...")
-	milvusClient, err := milvus.NewClient(ctx, milvus.Config{Address: cfg.MilvusAddr})
+	milvusClient, err := milvus.NewClient(ctx, milvus.Config{
+		Address:              cfg.MilvusAddr,
+		PartitionGranularity: cfg.MilvusPartition,
+	})
 	if err != nil {
 		log.Fatalf("Failed to connect to Milvus: %v", err)
 	}
 	defer milvusClient.Close()
-
 	if err := milvusClient.LoadCollection(ctx, milvus.DefaultCollectionName); err != nil {
 		log.Fatalf("Failed to load collection: %v", err)
 	}
 
-	// Search
-	log.Printf("Searching for %d most similar windows...", cfg.TopK)
-	filter := fmt.Sprintf("symbol == \"%s\" && timeframe == \"%s\"", cfg.Symbol, cfg.Timeframe)
-	results, err := milvusClient.Search(ctx, milvus.DefaultCollectionName, embedding, filter, cfg.TopK)
+	log.Printf("Searching %d symbols for %d most similar windows each, reranked to the top %d...", len(queries), cfg.RecallK, cfg.TopK)
+	start := time.Now()
+	batch, err := milvusClient.SearchBatch(ctx, milvus.DefaultCollectionName, queries, cfg.RecallK)
 	if err != nil {
-		log.Fatalf("Search failed: %v", err)
+		log.Fatalf("Batch search failed: %v", err)
 	}
+	log.Printf("Batch search took %s", time.Since(start).Round(time.Millisecond))
 
-	// Results
-	log.Println("\n=== Search Results ===")
+	pipeline := rerank.NewPipeline(newTimeReranker(cfg).Stage(time.Now()), rerank.NewTopKStage(cfg.TopK))
+	for i, results := range batch {
+		fmt.Printf("\n=== %s %s ===\n", symbols[i], cfg.Timeframe)
+		fmt.Printf("%-5s %-32s %-10s %-20s %-10s %-10s\n", "Rank", "WindowID", "Symbol", "End Date", "Score", "Final")
+		for j, r := range pipeline.Run(results) {
+			fmt.Printf("%-5d %-32s %-10s %-20s %-10.4f %-.4f\n", j+1, r.WindowID, r.Symbol, r.TEnd.Format("2006-01-02"), r.OriginalScore, r.FinalScore)
+		}
+	}
+}
+
+// printExplanations prints the score breakdown of every ranked neighbor except the query window
+func printExplanations(explainer rerank.Explainer, ranked []rerank.RankedResult, queryWindowID string) {
+	fmt.Println("\nRanking explanations:")
+	for i, r := range ranked {
+		if r.WindowID == queryWindowID {
+			continue
+		}
+		fmt.Printf("%-5d %s\n", i+1, explainer.ExplainRanking(r))
+	}
+}
+
+// newFeatureDistanceStage calibrates per-column standard deviations from the corpus and builds the stage
+func newFeatureDistanceStage(ctx context.Context, cfg Config, featureRepo *duckdb.FeatureRepo, queryFeatures *model.FeatureRow) (*rerank.FeatureDistanceStage, error) {
+	columns := splitList(cfg.FeatureCols)
+
+	stats, err := featureRepo.GetColumnStats(ctx, cfg.Symbol, cfg.Timeframe, columns)
+	if err != nil {
+		return nil, err
+	}
+	std := make(map[string]float64, len(stats))
+	for c, st := range stats {
+		std[c] = st.Std
+	}
+
+	return rerank.NewFeatureDistanceStage(ctx, featureRepo, queryFeatures, rerank.FeatureDistanceConfig{
+		Columns: columns,
+		Std:     std,
+		Weight:  cfg.FeatureWeight,
+	}), nil
+}
+
+// loadCalibrator returns the stored score calibration for the searched corpus, fitting and saving one if missing
+// The fit samples windows at random from DuckDB, since a Milvus query returns rows in storage order, where
+// consecutive windows overlap and would skew the pair scores high
+func loadCalibrator(ctx context.Context, cfg Config, repo *duckdb.CalibrationRepo, windowRepo *duckdb.WindowRepo, client *milvus.Client, symbols, timeframes []string) (*rerank.Calibrator, error) {
+	key := corpusKey(symbols, timeframes)
+	if !cfg.Recalibrate {
+		stored, err := repo.Get(ctx, key)
+		if err == nil {
+			return rerank.NewCalibrator(stored.Quantiles)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	// ANY lists restrict nothing
+	if containsAny(symbols) {
+		symbols = nil
+	}
+	if containsAny(timeframes) {
+		timeframes = nil
+	}
+	ids, err := windowRepo.SampleWindowIDs(ctx, symbols, timeframes, cfg.CalibrationSample)
+	if err != nil {
+		return nil, err
+	}
+	sample, err := client.GetByIDs(ctx, milvus.DefaultCollectionName, ids, true)
+	if err != nil {
+		return nil, err
+	}
+
+	embeddings := make([][]float32, 0, len(sample))
+	for _, r := range sample {
+		if len(r.Embedding) > 0 {
+			embeddings = append(embeddings, r.Embedding)
+		}
+	}
+
+	scores := rerank.PairwiseScores(embeddings)
+	calibrator, err := rerank.FitCalibrator(scores)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repo.Save(ctx, key, calibrator.Quantiles, int64(len(scores))); err != nil {
+		return nil, err
+	}
+	log.Printf("Fitted score calibration %s from %d windows (%d pairs)", key, len(embeddings), len(scores))
+
+	return calibrator, nil
+}
+
+// anySeries in -symbols or -timeframes searches every symbol or timeframe
+const anySeries = "ANY"
+
+// searchedSeries returns the symbols and timeframes to search, defaulting to the query's own
+// -cross-symbol without -symbols searches every symbol
+func searchedSeries(cfg Config) (symbols, timeframes []string) {
+	symbols = splitList(cfg.Symbols)
+	if len(symbols) == 0 {
+		symbols = []string{cfg.Symbol}
+		if cfg.CrossSymbol {
+			symbols = []string{anySeries}
+		}
+	}
+	timeframes = splitList(cfg.Timeframes)
+	if len(timeframes) == 0 {
+		timeframes = []string{cfg.Timeframe}
+	}
+	return symbols, timeframes
+}
+
+// searchFilter builds the Milvus filter restricting results to symbols and timeframes
+// A list containing ANY adds no clause for its field, so searching ANY of both matches every row
+func searchFilter(symbols, timeframes []string) *milvus.Filter {
+	filter := milvus.NewFilter()
+	if !containsAny(symbols) {
+		filter.In("symbol", symbols...)
+	}
+	if !containsAny(timeframes) {
+		filter.In("timeframe", timeframes...)
+	}
+	return filter
+}
+
+// batchQueryFilter builds the Milvus filter of symbol's -batch-search query, with the metadata restrictions of cfg
+// With CrossSymbol the query searches every symbol of the timeframe
+func batchQueryFilter(cfg Config, symbol string) string {
+	symbols := []string{symbol}
+	if cfg.CrossSymbol {
+		symbols = nil
+	}
+	return metadataFilter(cfg, searchFilter(symbols, []string{cfg.Timeframe})).String()
+}
+
+// metadataFilter adds the bucket, t_end, and data version restrictions of cfg to filter
+func metadataFilter(cfg Config, filter *milvus.Filter) *milvus.Filter {
+	filter.IntIn("vol_bucket", cfg.VolBuckets...)
+	filter.IntIn("trend_bucket", cfg.TrendBuckets...)
+	filter.TEndRange(cfg.After, cfg.Before)
+	if cfg.DataVersion > 0 {
+		filter.IntIn("data_version", cfg.DataVersion)
+	}
+	return filter
+}
+
+// emptyFilterWarnings describes metadata restrictions of cfg that no neighbor of a query ending at queryTEnd can meet
+func emptyFilterWarnings(cfg Config, queryTEnd time.Time) []string {
+	var warnings []string
+	if len(cfg.VolBuckets) > 0 && !anyInRange(cfg.VolBuckets, 0, 9) {
+		warnings = append(warnings, "-vol-bucket has no value in 0..9")
+	}
+	if len(cfg.TrendBuckets) > 0 && !anyInRange(cfg.TrendBuckets, model.TrendStrongDown, model.TrendStrongUp) {
+		warnings = append(warnings, fmt.Sprintf("-trend-bucket has no value in %d..%d", model.TrendStrongDown, model.TrendStrongUp))
+	}
+	if !cfg.After.IsZero() && !cfg.Before.IsZero() && !cfg.After.Before(cfg.Before) {
+		warnings = append(warnings, "-after is not before -before")
+	}
+	// Look-ahead exclusion drops every neighbor ending after the query
+	if !cfg.After.IsZero() && !cfg.After.Before(queryTEnd) {
+		warnings = append(warnings, fmt.Sprintf("-after is not before the query end %s", queryTEnd.Format(time.RFC3339)))
+	}
+	return warnings
+}
+
+// anyInRange reports whether any of values lies within [lo, hi]
+func anyInRange(values []int, lo, hi int) bool {
+	for _, v := range values {
+		if v >= lo && v <= hi {
+			return true
+		}
+	}
+	return false
+}
+
+// bucketRangePattern matches a bucket range such as 7-9 or -2--1
+var bucketRangePattern = regexp.MustCompile(`^(-?\d+)-(-?\d+)$`)
+
+// parseBuckets parses a comma list of bucket values and inclusive ranges, e.g. "8", "7-9", or "-2,0,1-2"
+func parseBuckets(spec string) ([]int, error) {
+	var buckets []int
+	for _, item := range splitList(spec) {
+		if m := bucketRangePattern.FindStringSubmatch(item); m != nil {
+			lo, _ := strconv.Atoi(m[1])
+			hi, _ := strconv.Atoi(m[2])
+			if lo > hi {
+				return nil, fmt.Errorf("range %q is reversed", item)
+			}
+			for b := lo; b <= hi; b++ {
+				buckets = append(buckets, b)
+			}
+			continue
+		}
+		b, err := strconv.Atoi(item)
+		if err != nil {
+			return nil, fmt.Errorf("%q is neither a bucket nor a lo-hi range", item)
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, nil
+}
+
+// corpusKey names the searched corpus for score calibration, e.g. BTCUSDT/1d, or */1d when searching ANY symbol
+func corpusKey(symbols, timeframes []string) string {
+	key := func(values []string) string {
+		if containsAny(values) {
+			return "*"
+		}
+		return strings.Join(values, ",")
+	}
+	return key(symbols) + "/" + key(timeframes)
+}
+
+// containsAny reports whether values includes ANY, case-insensitively
+func containsAny(values []string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, anySeries) {
+			return true
+		}
+	}
+	return false
+}
+
+// printSymbolDistribution prints how many final results came from each symbol
+func printSymbolDistribution(dist []rerank.SymbolCount) {
+	total := 0
+	for _, d := range dist {
+		total += d.Count
+	}
+
+	fmt.Println("\n=== Symbol Distribution ===")
+	for _, d := range dist {
+		fmt.Printf("%-12s %4d  %5.1f%%\n", d.Symbol, d.Count, float64(d.Count)/float64(total)*100)
+	}
+}
 
-	// Rerank (optional, using time decay as in backfill demo)
-	reranker := rerank.NewReranker(rerank.DefaultTimeDecayConfig())
-	ranked := reranker.Rerank(results, time.Now())
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
 
-	fmt.Printf("%-5s %-32s %-20s %-10s %-10s\n", "Rank", "WindowID", "End Date", "Score", "Sim%")
+// maxInt returns the largest value in values, or 0 if empty
+func maxInt(values []int) int {
+	m := 0
+	for _, v := range values {
+		if v > m {
+			m = v
+		}
+	}
+	return m
+}
+
+// attachEmbeddings fills in each search result's stored embedding
+func attachEmbeddings(ctx context.Context, client *milvus.Client, results []milvus.SearchResult) error {
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = r.WindowID
+	}
+
+	stored, err := client.GetByIDs(ctx, milvus.DefaultCollectionName, ids, true)
+	if err != nil {
+		return err
+	}
+
+	embeddings := make(map[string][]float32, len(stored))
+	for _, r := range stored {
+		embeddings[r.WindowID] = r.Embedding
+	}
+	for i := range results {
+		results[i].Embedding = embeddings[results[i].WindowID]
+	}
+
+	return nil
+}
+
+// printOutcomeSummary prints aggregated neighbor outcomes for each horizon
+func printOutcomeSummary(aggregated map[int]outcome.AggregatedOutcome, horizons []int) {
+	fmt.Println("\n=== Neighbor Outcomes ===")
+	fmt.Printf("%-8s %-8s %-10s %-10s %-10s %-10s\n", "Horizon", "Samples", "Mean", "P50", "MDD95", "HitRate")
 	fmt.Println("--------------------------------------------------------------------------------")
 
-	for i, r := range ranked {
-		// Ignore the query window itself if it appears (which it might if it was backfilled)
-		if r.WindowID == currentWindow.WindowID {
+	for _, h := range horizons {
+		agg, ok := aggregated[h]
+		if !ok {
+			fmt.Printf("%-8d %-8d (no forward data)\n", h, 0)
 			continue
 		}
+		fmt.Printf("%-8d %-8s %-10s %-10s %-10s %-.1f%%\n",
+			h, fmt.Sprintf("%d/%d", agg.SampleCount, agg.RawSampleCount),
+			fmt.Sprintf("%.2f%%", agg.MeanReturn*100),
+			fmt.Sprintf("%.2f%%", agg.MedianP50*100),
+			fmt.Sprintf("%.2f%%", agg.MDDP95*100),
+			agg.HitRate*100,
+		)
+	}
 
-		simPct := r.OriginalScore * 100
-		fmt.Printf("%-5d %-32s %-20s %-.4f     %-.2f%%\n", i+1, r.WindowID, r.TEnd.Format("2006-01-02"), r.OriginalScore, simPct)
+	// Partial horizon summary
+	for _, h := range horizons {
+		agg, ok := aggregated[h]
+		if !ok || agg.PartialIncluded+agg.PartialExcluded == 0 {
+			continue
+		}
+		fmt.Printf("Horizon %d: %d partial results included, %d incomplete excluded\n",
+			h, agg.PartialIncluded, agg.PartialExcluded)
+	}
+
+	// Time-to-target summary
+	for _, h := range horizons {
+		agg, ok := aggregated[h]
+		if !ok || agg.TargetPct == 0 {
+			continue
+		}
+		fmt.Printf("Horizon %d: +%.2f%% reached %.1f%% (median bar %s), -%.2f%% reached %.1f%% (median bar %s)\n",
+			h,
+			agg.TargetPct*100, agg.PctReachedUp*100, formatBars(agg.MedianBarsToTargetUp),
+			agg.TargetPct*100, agg.PctReachedDown*100, formatBars(agg.MedianBarsToTargetDown),
+		)
 	}
 }
 
+// printWeightedSummary prints similarity-weighted neighbor outcomes for each horizon
+func printWeightedSummary(aggregated map[int]outcome.WeightedOutcome, horizons []int) {
+	fmt.Println("\n=== Similarity-Weighted Outcomes ===")
+	fmt.Printf("%-8s %-8s %-10s %-10s %-10s %-10s %s\n", "Horizon", "Samples", "Mean", "Median", "HitRate", "MDD95", "CI")
+	fmt.Println("--------------------------------------------------------------------------------")
+
+	for _, h := range horizons {
+		agg, ok := aggregated[h]
+		if !ok {
+			fmt.Printf("%-8d %-8d (no forward data)\n", h, 0)
+			continue
+		}
+		ci := "-"
+		if agg.CILevel > 0 {
+			ci = fmt.Sprintf("%.0f%% [%.2f%%, %.2f%%]", agg.CILevel*100, agg.CILow*100, agg.CIHigh*100)
+		}
+		fmt.Printf("%-8d %-8d %-10s %-10s %-10s %-10s %s\n",
+			h, agg.SampleCount,
+			fmt.Sprintf("%.2f%%", agg.MeanReturn*100),
+			fmt.Sprintf("%.2f%%", agg.MedianReturn*100),
+			fmt.Sprintf("%.1f%%", agg.HitRate*100),
+			fmt.Sprintf("%.2f%%", agg.MDDP95*100),
+			ci,
+		)
+	}
+	fmt.Println("Returns are at the horizon bar, weighted by neighbor similarity")
+}
+
+// formatBars formats a median bar index, where -1 means the target was never reached
+func formatBars(bars float64) string {
+	if bars < 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f", bars)
+}
+
 func parseFlags() Config {
 	cfg := Config{}
 
@@ -145,8 +1024,122 @@ func parseFlags() Config {
 	flag.IntVar(&cfg.FeatureVersion, "version", 1, "Feature version")
 	flag.StringVar(&cfg.DuckDBPath, "duckdb", "etna.duckdb", "DuckDB path")
 	flag.StringVar(&cfg.MilvusAddr, "milvus", "localhost:19530", "Milvus address")
-	flag.IntVar(&cfg.TopK, "topk", 10, "Top K results")
+	flag.StringVar(&cfg.MilvusPartition, "milvus-partition", "", "Milvus time partition granularity: year or quarter (empty disables)")
+	flag.IntVar(&cfg.TopK, "topk", 10, "Neighbors kept after reranking")
+	flag.IntVar(&cfg.RecallK, "recall-k", 200, "Candidates fetched from Milvus before reranking down to -topk (raised to -topk if smaller)")
+	flag.StringVar(&cfg.Mode, "mode", "recency", "Rerank mode: recency, analogy, or hybrid")
+	flag.Float64Var(&cfg.RecencyWeight, "recency-weight", 0.5, "Recency weight for hybrid mode")
+	flag.Float64Var(&cfg.AnalogyWeight, "analogy-weight", 0.5, "Analogy weight for hybrid mode")
+	flag.BoolVar(&cfg.BucketMatch, "bucket-match", false, "Boost neighbors whose vol/trend buckets match the query window")
+	flag.StringVar(&cfg.ScoreMode, "score-mode", rerank.CombineMultiplicative, "Score combination: multiplicative or additive")
+	flag.Float64Var(&cfg.Alpha, "alpha", 0.7, "Additive mode similarity coefficient")
+	flag.Float64Var(&cfg.Beta, "beta", 0.3, "Additive mode time weight coefficient")
+	flag.Float64Var(&cfg.Gamma, "gamma", 0, "Additive mode bucket match coefficient")
+	flag.BoolVar(&cfg.Dedup, "dedup", true, "Drop neighbors that overlap a higher-ranked neighbor in time")
+	flag.Float64Var(&cfg.DedupOverlap, "dedup-overlap", 0.5, "Max time overlap fraction between ranked neighbors when -dedup is set")
+	flag.BoolVar(&cfg.CrossSymbol, "cross-symbol", false, "Search neighbors across every symbol of the timeframe")
+	flag.StringVar(&cfg.Symbols, "symbols", "", "Comma-separated symbols to search, or ANY for all (default: the query symbol)")
+	volBuckets := flag.String("vol-bucket", "", "Only neighbors in these vol buckets (0-9): a value, lo-hi range, or comma list, e.g. 7-9")
+	trendBuckets := flag.String("trend-bucket", "", "Only neighbors in these trend buckets (-2 to 2): a value, lo-hi range, or comma list, e.g. 1,2")
+	after := flag.String("after", "", "Only neighbors ending at or after this time (RFC3339 or YYYY-MM-DD)")
+	before := flag.String("before", "", "Only neighbors ending before this time (RFC3339 or YYYY-MM-DD)")
+	flag.IntVar(&cfg.DataVersion, "data-version", 0, "Only neighbors with this data_version (0 allows all)")
+	flag.StringVar(&cfg.Timeframes, "timeframes", "", "Comma-separated timeframes to search, or ANY for all (default: the query timeframe)")
+	flag.StringVar(&cfg.AllowedSymbols, "allowed-symbols", "", "Comma-separated symbols allowed in -cross-symbol results (empty allows all)")
+	flag.Float64Var(&cfg.SameSymbolBonus, "same-symbol-bonus", 0, "Fractional score boost for same-symbol neighbors with -cross-symbol (e.g. 0.1)")
+	flag.Float64Var(&cfg.PerSymbolCap, "per-symbol-cap", 0, "Max fraction of -cross-symbol results from any one symbol (0 disables)")
+	flag.Float64Var(&cfg.FeatureWeight, "feature-weight", 0, "Blend weight of structured-feature similarity to the query window (0 disables)")
+	flag.StringVar(&cfg.FeatureCols, "feature-cols", strings.Join(rerank.DefaultFeatureDistanceColumns, ","), "Comma-separated feature columns compared when -feature-weight is set")
+	flag.IntVar(&cfg.QualityHorizon, "quality-horizon", 0, "Rescore neighbors by the drawdown-to-return quality of their stored outcome at this horizon (0 disables)")
+	flag.BoolVar(&cfg.QualityStrict, "quality-strict", false, "Drop neighbors lacking a stored outcome when -quality-horizon is set")
+	flag.StringVar(&cfg.BatchSearch, "batch-search", "", "Comma-separated symbols whose latest windows are searched in parallel, printing time-decay ranked neighbors per symbol")
+	flag.StringVar(&cfg.WindowID, "window-id", "", "Search from this stored window instead of the latest candles")
+	at := flag.String("at", "", "Search as of this time (RFC3339 or YYYY-MM-DD) from the latest stored window ending at or before it")
+	flag.StringVar(&cfg.QueryCSV, "query-csv", "", "Search from the last -window candles of this CSV file (CSVProvider columns, spaced one -timeframe bar apart)")
+	flag.BoolVar(&cfg.Explain, "explain", false, "Print how each neighbor's final score was reached (hybrid mode shows the recency decay formula)")
+	flag.BoolVar(&cfg.MMR, "mmr", false, "Reorder neighbors by maximal marginal relevance for embedding-space diversity")
+	flag.Float64Var(&cfg.MMRLambda, "mmr-lambda", 0.7, "MMR relevance/diversity trade-off when -mmr is set (1 = pure relevance)")
+	flag.BoolVar(&cfg.Recalibrate, "recalibrate", false, "Refit the score calibration from the corpus even if one is stored")
+	flag.IntVar(&cfg.CalibrationSample, "calibration-sample", 200, "Corpus windows sampled to fit the score calibration (0 shows raw Sim% instead of Match)")
+	flag.Float64Var(&cfg.MaxOverlap, "max-overlap", 1, "Max time overlap fraction between aggregated neighbors (1 disables de-duplication)")
+	flag.Float64Var(&cfg.TargetPct, "target-pct", 0, "Time-to-target threshold in percent, e.g. 2 for ±2% (0 disables)")
+	flag.StringVar(&cfg.Partial, "partial", "skip", "Incomplete horizon policy: skip, partial, or error")
+	flag.BoolVar(&cfg.FanChart, "fan-chart", false, "Render an ASCII fan chart of the expected forward return path")
+	flag.BoolVar(&cfg.Chart, "chart", false, "Print close-price sparklines of the query and top neighbors under their result rows")
+	flag.IntVar(&cfg.ChartTop, "chart-top", 5, "Neighbors charted with -chart")
+	flag.IntVar(&cfg.ChartWidth, "chart-width", 40, "Max width of -chart charts in characters; longer windows are compressed")
+	flag.IntVar(&cfg.ChartHeight, "chart-height", 0, "Rows of a high/low block chart drawn under each -chart sparkline (0 disables)")
+	horizons := flag.String("horizons", "", "Comma-separated outcome horizons in bars shown in the summaries (defaults to the engine defaults)")
+	flag.IntVar(&cfg.Bootstrap, "bootstrap", 0, "Bootstrap resamples for a 95% confidence interval of the weighted mean return (0 disables)")
+
+	configPath := flag.String("config", "", "YAML or JSON config file; ETNA_* environment variables override it and explicit flags override both")
 
 	flag.Parse()
+	if err := config.ApplyFlags(flag.CommandLine, *configPath); err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
+
+	switch cfg.Mode {
+	case "recency", "analogy", "hybrid":
+	default:
+		log.Fatalf("Invalid mode %q: must be recency, analogy, or hybrid", cfg.Mode)
+	}
+
+	if _, err := rerank.ParseCombineMode(cfg.ScoreMode); err != nil {
+		log.Fatalf("Invalid -score-mode: %v", err)
+	}
+
+	if _, err := outcome.ParsePartialPolicy(cfg.Partial); err != nil {
+		log.Fatalf("Invalid -partial: %v", err)
+	}
+
+	cfg.Horizons = outcome.DefaultConfig().Horizons
+	if *horizons != "" {
+		cfg.Horizons = nil
+		for _, part := range strings.Split(*horizons, ",") {
+			h, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || h <= 0 {
+				log.Fatalf("Invalid horizon %q", part)
+			}
+			cfg.Horizons = append(cfg.Horizons, h)
+		}
+	}
+	if cfg.Chart && cfg.ChartWidth <= 0 {
+		log.Fatalf("-chart-width must be positive, got %d", cfg.ChartWidth)
+	}
+	if cfg.TopK <= 0 {
+		log.Fatalf("-topk must be positive, got %d", cfg.TopK)
+	}
+	cfg.RecallK = max(cfg.RecallK, cfg.TopK)
+	if cfg.Bootstrap < 0 {
+		log.Fatalf("-bootstrap must not be negative, got %d", cfg.Bootstrap)
+	}
+
+	var err error
+	if cfg.At, err = config.ParseTimeFlag(*at); err != nil {
+		log.Fatalf("Invalid -at: %v", err)
+	}
+	if cfg.After, err = config.ParseTimeFlag(*after); err != nil {
+		log.Fatalf("Invalid -after: %v", err)
+	}
+	if cfg.Before, err = config.ParseTimeFlag(*before); err != nil {
+		log.Fatalf("Invalid -before: %v", err)
+	}
+	if cfg.VolBuckets, err = parseBuckets(*volBuckets); err != nil {
+		log.Fatalf("Invalid -vol-bucket: %v", err)
+	}
+	if cfg.TrendBuckets, err = parseBuckets(*trendBuckets); err != nil {
+		log.Fatalf("Invalid -trend-bucket: %v", err)
+	}
+	modes := 0
+	for _, set := range []bool{cfg.WindowID != "", !cfg.At.IsZero(), cfg.QueryCSV != "", cfg.BatchSearch != ""} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 {
+		log.Fatalf("-window-id, -at, -query-csv, and -batch-search are mutually exclusive")
+	}
+
 	return cfg
 }
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+	"github.com/tunogya/etna/pkg/rerank"
+	"github.com/tunogya/etna/pkg/store/milvus"
+)
+
+// fakeFeatureSource serves stored feature rows from a map
+type fakeFeatureSource map[string]*model.FeatureRow
+
+func (f fakeFeatureSource) GetByIDs(ctx context.Context, ids []string) ([]*model.FeatureRow, error) {
+	var rows []*model.FeatureRow
+	for _, id := range ids {
+		if row, ok := f[id]; ok {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func TestBuildPipelineAdditiveKeepsFeatureWeight(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	cfg := Config{
+		Mode:          "recency",
+		Timeframe:     "1d",
+		WindowLength:  7,
+		ScoreMode:     rerank.CombineAdditive,
+		Alpha:         0.7,
+		Beta:          0.3,
+		FeatureWeight: 1,
+		TopK:          10,
+	}
+
+	// "far" is slightly more similar, but its features are far from the query's
+	query := &model.FeatureRow{RealizedVolatility: 0.02}
+	source := fakeFeatureSource{
+		"near": {WindowID: "near", RealizedVolatility: 0.02},
+		"far":  {WindowID: "far", RealizedVolatility: 0.12},
+	}
+	featureDist := rerank.NewFeatureDistanceStage(context.Background(), source, query, rerank.FeatureDistanceConfig{
+		Columns: []string{"realized_volatility"},
+		Std:     map[string]float64{"realized_volatility": 0.01},
+		Weight:  cfg.FeatureWeight,
+	})
+	lookahead := rerank.NewExcludeFutureStage(now, 0, 24*time.Hour)
+
+	tEnd := now.AddDate(0, 0, -30)
+	results := []milvus.SearchResult{
+		{WindowID: "far", Score: 0.95, TEnd: tEnd, Timeframe: "1d"},
+		{WindowID: "near", Score: 0.94, TEnd: tEnd, Timeframe: "1d"},
+	}
+	ranked := buildPipeline(cfg, query, lookahead, featureDist, nil, 24*time.Hour, now).Run(results)
+
+	if len(ranked) != 2 {
+		t.Fatalf("got %d results, want 2", len(ranked))
+	}
+	if ranked[0].WindowID != "near" {
+		t.Errorf("top result = %s, want near: feature distance was discarded by the additive combiner", ranked[0].WindowID)
+	}
+	if ranked[1].FeatureDistance == 0 {
+		t.Errorf("far result has no feature distance recorded")
+	}
+}
+
+func TestHybridExplanationMatchesPipelineDecay(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	cfg := Config{
+		Mode:          "hybrid",
+		Timeframe:     "1d",
+		RecencyWeight: 0.5,
+		AnalogyWeight: 0.5,
+		ScoreMode:     rerank.CombineMultiplicative,
+		TopK:          10,
+	}
+	lookahead := rerank.NewExcludeFutureStage(now, 0, 24*time.Hour)
+	results := []milvus.SearchResult{{WindowID: "old", Score: 0.9, TEnd: now.AddDate(-1, 0, 0), Timeframe: "1d"}}
+	ranked := buildPipeline(cfg, nil, lookahead, nil, nil, 24*time.Hour, now).Run(results)
+
+	e := newTimeReranker(cfg).Explain(ranked[0])
+	unweighted := ranked[0]
+	unweighted.TimeWeight = 0
+	if recomputed := newTimeReranker(cfg).Explain(unweighted).TimeDecay; math.Abs(recomputed-e.TimeDecay) > 1e-12 {
+		t.Errorf("explained decay %v differs from the applied weight %v", recomputed, e.TimeDecay)
+	}
+	if !strings.Contains(e.DecayFormula, "0.5*") {
+		t.Errorf("DecayFormula %q does not describe the hybrid blend", e.DecayFormula)
+	}
+}
+
+func TestBatchQueryFilter(t *testing.T) {
+	cfg := Config{
+		Timeframe:    "1h",
+		VolBuckets:   []int{7, 8, 9},
+		TrendBuckets: []int{-1},
+		After:        time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		DataVersion:  3,
+	}
+	metadata := ` && vol_bucket >= 7 && vol_bucket <= 9 && trend_bucket == -1 && t_end >= 1704067200 && data_version == 3`
+
+	if got, want := batchQueryFilter(cfg, `BTC"USDT`), `symbol == "BTC\"USDT" && timeframe == "1h"`+metadata; got != want {
+		t.Errorf("batchQueryFilter = %s, want %s", got, want)
+	}
+
+	cfg.CrossSymbol = true
+	if got, want := batchQueryFilter(cfg, "BTCUSDT"), `timeframe == "1h"`+metadata; got != want {
+		t.Errorf("cross-symbol batchQueryFilter = %s, want %s", got, want)
+	}
+}
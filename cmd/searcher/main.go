@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/tunogya/etna/pkg/config"
+	"github.com/tunogya/etna/pkg/queue/nats"
+	"github.com/tunogya/etna/pkg/store/duckdb"
+	"github.com/tunogya/etna/pkg/store/milvus"
+)
+
+// Config holds searcher service configuration
+type Config struct {
+	NATSUrl         string
+	Queue           string // Queue group shared by searcher replicas
+	DuckDBPath      string
+	MilvusAddr      string
+	MilvusPartition string // Time partition granularity: year, quarter, or empty for none
+
+	WindowLength   int
+	FeatureVersion int
+	VectorDim      int
+
+	TopK         int     // Neighbors returned when a request does not set top_k
+	MaxTopK      int     // Largest top_k a request may ask for
+	RecallK      int     // Candidates fetched from Milvus before reranking down to top_k
+	DedupOverlap float64 // Max time overlap fraction between ranked neighbors
+	MaxOverlap   float64 // Max time overlap between aggregated neighbors (1 disables de-duplication)
+}
+
+func main() {
+	cfg := parseFlags()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Initialize DuckDB
+	log.Println("Connecting to DuckDB...")
+	duckClient, err := duckdb.NewClient(cfg.DuckDBPath)
+	if err != nil {
+		log.Fatalf("Failed to connect to DuckDB: %v", err)
+	}
+	defer duckClient.Close()
+	if err := duckdb.ConfigureForRead(duckClient); err != nil {
+		log.Printf("Warning: failed to configure DuckDB: %v", err)
+	}
+
+	// Initialize Milvus
+	log.Println("Connecting to Milvus...")
+	milvusClient, err := milvus.NewClient(ctx, milvus.Config{
+		Address:              cfg.MilvusAddr,
+		PartitionGranularity: cfg.MilvusPartition,
+	})
+	if err != nil {
+		log.Fatalf("Failed to connect to Milvus: %v", err)
+	}
+	defer milvusClient.Close()
+
+	if err := milvusClient.LoadCollection(ctx, milvus.DefaultCollectionName); err != nil {
+		log.Fatalf("Failed to load collection: %v", err)
+	}
+
+	// Initialize NATS
+	log.Println("Connecting to NATS...")
+	natsCfg := nats.DefaultConfig()
+	natsCfg.URL = cfg.NATSUrl
+	natsClient, err := nats.NewClient(natsCfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to NATS: %v", err)
+	}
+	defer natsClient.Close()
+
+	s := &searcher{
+		cfg:         cfg,
+		candleRepo:  duckdb.NewCandleRepo(duckClient),
+		windowRepo:  duckdb.NewWindowRepo(duckClient),
+		outcomeRepo: duckdb.NewOutcomeRepo(duckClient),
+		calibRepo:   duckdb.NewCalibrationRepo(duckClient),
+		milvus:      milvusClient,
+	}
+
+	sub, err := natsClient.ServeSearch(ctx, cfg.Queue, s.handle)
+	if err != nil {
+		log.Fatalf("Failed to serve search requests: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	log.Printf("Searcher started, answering requests on %s...", nats.SubjectSearchRequest)
+
+	// Wait for shutdown signal
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	<-sigCh
+
+	log.Println("Shutting down Searcher...")
+	if err := sub.Drain(); err != nil {
+		log.Printf("Warning: failed to drain search subscription: %v", err)
+	}
+}
+
+func parseFlags() Config {
+	cfg := Config{}
+
+	flag.StringVar(&cfg.NATSUrl, "nats", "nats://localhost:4222", "NATS server URL")
+	flag.StringVar(&cfg.Queue, "queue", "searchers", "Queue group shared by searcher replicas")
+	flag.StringVar(&cfg.DuckDBPath, "duckdb", "etna.duckdb", "DuckDB path")
+	flag.StringVar(&cfg.MilvusAddr, "milvus", "localhost:19530", "Milvus address")
+	flag.StringVar(&cfg.MilvusPartition, "milvus-partition", "", "Milvus time partition granularity: year or quarter (empty disables)")
+	flag.IntVar(&cfg.WindowLength, "window", 7, "Window length")
+	flag.IntVar(&cfg.FeatureVersion, "version", 1, "Feature version")
+	flag.IntVar(&cfg.VectorDim, "dim", 96, "Vector dimension")
+	flag.IntVar(&cfg.TopK, "topk", 10, "Top K results when a request does not set top_k")
+	flag.IntVar(&cfg.MaxTopK, "max-topk", 200, "Largest top_k a request may ask for")
+	flag.IntVar(&cfg.RecallK, "recall-k", 200, "Candidates fetched from Milvus before reranking down to top_k (raised to top_k if smaller)")
+	flag.Float64Var(&cfg.DedupOverlap, "dedup-overlap", 0.5, "Max time overlap fraction between ranked neighbors")
+	flag.Float64Var(&cfg.MaxOverlap, "max-overlap", 1, "Max time overlap fraction between aggregated neighbors (1 disables de-duplication)")
+
+	configPath := flag.String("config", "", "YAML or JSON config file; ETNA_* environment variables override it and explicit flags override both")
+
+	flag.Parse()
+	if err := config.ApplyFlags(flag.CommandLine, *configPath); err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
+
+	return cfg
+}
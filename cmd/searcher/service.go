@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tunogya/etna/pkg/feature"
+	"github.com/tunogya/etna/pkg/model"
+	"github.com/tunogya/etna/pkg/outcome"
+	"github.com/tunogya/etna/pkg/queue/nats"
+	"github.com/tunogya/etna/pkg/rerank"
+	"github.com/tunogya/etna/pkg/store/duckdb"
+	"github.com/tunogya/etna/pkg/store/milvus"
+	"github.com/tunogya/etna/pkg/window"
+)
+
+// vectorSearcher finds the stored windows nearest an embedding; *milvus.Client satisfies it
+type vectorSearcher interface {
+	Search(ctx context.Context, collectionName string, embedding []float32, filter string, topK int) ([]milvus.SearchResult, error)
+}
+
+// searcher runs extract → search → rerank → outcome aggregation for search requests
+type searcher struct {
+	cfg         Config
+	candleRepo  *duckdb.CandleRepo
+	windowRepo  *duckdb.WindowRepo
+	outcomeRepo *duckdb.OutcomeRepo
+	calibRepo   *duckdb.CalibrationRepo
+	milvus      vectorSearcher
+}
+
+// handle answers a single search request
+func (s *searcher) handle(ctx context.Context, req *nats.SearchRequestMsg) (*nats.SearchResponseMsg, error) {
+	if !validSymbol(req.Symbol) {
+		return nil, fmt.Errorf("invalid symbol %q", req.Symbol)
+	}
+	barDuration, err := model.TimeframeDuration(req.Timeframe)
+	if err != nil {
+		return nil, err
+	}
+
+	topK := req.TopK
+	if topK <= 0 {
+		topK = s.cfg.TopK
+	}
+	if topK > s.cfg.MaxTopK {
+		return nil, fmt.Errorf("top_k %d exceeds the limit of %d", topK, s.cfg.MaxTopK)
+	}
+
+	candles := req.Candles
+	if len(candles) == 0 {
+		candles, err = s.candleRepo.GetLatest(ctx, req.Symbol, req.Timeframe, s.cfg.WindowLength)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch latest candles: %w", err)
+		}
+		sort.Slice(candles, func(i, j int) bool {
+			return candles[i].OpenTime.Before(candles[j].OpenTime)
+		})
+	}
+	if len(candles) < s.cfg.WindowLength {
+		return nil, fmt.Errorf("not enough candles: need %d, got %d", s.cfg.WindowLength, len(candles))
+	}
+	candles = candles[len(candles)-s.cfg.WindowLength:]
+
+	// Build and extract the query window
+	builder, err := window.NewBuilder(window.Config{
+		W:              s.cfg.WindowLength,
+		S:              1,
+		FeatureVersion: s.cfg.FeatureVersion,
+		Symbol:         req.Symbol,
+		Timeframe:      req.Timeframe,
+	})
+	if err != nil {
+		return nil, err
+	}
+	windows := builder.ProcessCandles(candles)
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("failed to build window from candles")
+	}
+	query := windows[len(windows)-1]
+
+	extractor := feature.NewExtractor(s.cfg.FeatureVersion, s.cfg.VectorDim)
+	_, embedding, err := extractor.Extract(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract features: %w", err)
+	}
+
+	// Search
+	filter := milvus.NewFilter()
+	if req.Symbol != "" {
+		filter.In("symbol", req.Symbol)
+	}
+	filter.In("timeframe", req.Timeframe)
+	results, err := s.milvus.Search(ctx, milvus.DefaultCollectionName, embedding, filter.String(), max(s.cfg.RecallK, topK))
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+
+	// Rerank the recalled candidates down to topK, excluding neighbors whose outcomes would leak past the query end
+	outcomeCfg := outcome.DefaultConfig()
+	horizons := outcomeCfg.Horizons
+	pipeline := rerank.NewPipeline(
+		rerank.NewExcludeFutureStage(query.TEnd, maxInt(horizons), barDuration),
+		rerank.NewReranker(rerank.DefaultForTimeframe(req.Timeframe)).Stage(time.Now()),
+		rerank.NewDedupStage(s.cfg.WindowLength, barDuration, s.cfg.DedupOverlap),
+		rerank.NewTopKStage(topK),
+	)
+	// Neighbors carry a match strength only if cmd/search has already fitted the corpus calibration
+	if calibrator := s.storedCalibrator(ctx, req.Symbol, req.Timeframe); calibrator != nil {
+		pipeline.Add(calibrator)
+	}
+	ranked := pipeline.Run(results)
+
+	resp := &nats.SearchResponseMsg{QueryWindowID: query.WindowID, QueryTEnd: query.TEnd}
+	var neighborIDs []string
+	var neighbors []outcome.Neighbor
+	for _, r := range ranked {
+		if r.WindowID == query.WindowID {
+			continue
+		}
+		resp.Neighbors = append(resp.Neighbors, nats.SearchNeighbor{
+			WindowID:      r.WindowID,
+			Symbol:        r.Symbol,
+			TEnd:          r.TEnd,
+			Similarity:    float64(r.OriginalScore),
+			FinalScore:    r.FinalScore,
+			MatchStrength: r.MatchStrength,
+		})
+		neighborIDs = append(neighborIDs, r.WindowID)
+		neighbors = append(neighbors, outcome.Neighbor{
+			WindowID:   r.WindowID,
+			Symbol:     r.Symbol,
+			Similarity: float64(r.OriginalScore),
+			TStart:     r.TEnd.Add(-time.Duration(s.cfg.WindowLength) * barDuration),
+			TEnd:       r.TEnd,
+		})
+	}
+
+	// Aggregate neighbor outcomes
+	engine := outcome.NewEngineWithConfig(s.candleRepo, outcomeCfg)
+	outcomes, err := engine.LoadOrCalculate(ctx, neighborIDs, horizons, s.windowRepo, s.outcomeRepo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load outcomes: %w", err)
+	}
+	aggregated := outcome.AggregateResultsDedup(outcomes, neighbors, s.cfg.MaxOverlap)
+	for _, h := range horizons {
+		agg, ok := aggregated[h]
+		if !ok {
+			continue
+		}
+		resp.Outcomes = append(resp.Outcomes, nats.SearchOutcome{
+			Horizon:     h,
+			SampleCount: agg.SampleCount,
+			MeanReturn:  agg.MeanReturn,
+			P10:         agg.MedianP10,
+			P50:         agg.MedianP50,
+			P90:         agg.MedianP90,
+			MDDP95:      agg.MDDP95,
+			HitRate:     agg.HitRate,
+		})
+	}
+
+	return resp, nil
+}
+
+// storedCalibrator returns the score calibration cmd/search stored for the searched corpus, or nil if there is none
+// An empty symbol searches every symbol, which cmd/search keys as *
+func (s *searcher) storedCalibrator(ctx context.Context, symbol, timeframe string) *rerank.Calibrator {
+	if symbol == "" {
+		symbol = "*"
+	}
+	stored, err := s.calibRepo.Get(ctx, symbol+"/"+timeframe)
+	if err != nil {
+		return nil
+	}
+	calibrator, err := rerank.NewCalibrator(stored.Quantiles)
+	if err != nil {
+		return nil
+	}
+	return calibrator
+}
+
+// validSymbol reports whether symbol is safe to embed in a Milvus filter expression
+func validSymbol(symbol string) bool {
+	for _, r := range symbol {
+		if !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// maxInt returns the largest value in values, or 0 if empty
+func maxInt(values []int) int {
+	m := 0
+	for _, v := range values {
+		if v > m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/tunogya/etna/pkg/model"
+	"github.com/tunogya/etna/pkg/queue/nats"
+	"github.com/tunogya/etna/pkg/store/duckdb"
+	"github.com/tunogya/etna/pkg/store/milvus"
+)
+
+// fakeVectorSearcher returns fixed results and records the last search
+type fakeVectorSearcher struct {
+	results []milvus.SearchResult
+	filter  string
+	topK    int
+}
+
+func (f *fakeVectorSearcher) Search(ctx context.Context, collectionName string, embedding []float32, filter string, topK int) ([]milvus.SearchResult, error) {
+	f.filter = filter
+	f.topK = topK
+	return f.results, nil
+}
+
+// newTestSearcher returns a searcher over an in-memory DuckDB holding 200 daily BTCUSDT candles from start
+func newTestSearcher(t *testing.T, start time.Time, vectors vectorSearcher) *searcher {
+	t.Helper()
+	client, err := duckdb.NewClient("")
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	if err := duckdb.InitializeSchema(client); err != nil {
+		t.Fatalf("InitializeSchema: %v", err)
+	}
+
+	candles := make([]model.Candle, 200)
+	for i := range candles {
+		open := start.AddDate(0, 0, i)
+		price := 100 + 10*float64(i%7) + float64(i)
+		candles[i] = model.Candle{
+			Symbol: "BTCUSDT", Timeframe: "1d", OpenTime: open, CloseTime: open.Add(24*time.Hour - time.Millisecond),
+			Open: price, High: price + 2, Low: price - 2, Close: price + 1, Volume: 10,
+		}
+	}
+	candleRepo := duckdb.NewCandleRepo(client)
+	if err := candleRepo.InsertBatch(context.Background(), candles); err != nil {
+		t.Fatalf("InsertBatch: %v", err)
+	}
+
+	return &searcher{
+		cfg: Config{
+			WindowLength: 7, FeatureVersion: 1, VectorDim: 96,
+			TopK: 10, MaxTopK: 50, RecallK: 20, DedupOverlap: 0.5, MaxOverlap: 1,
+		},
+		candleRepo:  candleRepo,
+		windowRepo:  duckdb.NewWindowRepo(client),
+		outcomeRepo: duckdb.NewOutcomeRepo(client),
+		calibRepo:   duckdb.NewCalibrationRepo(client),
+		milvus:      vectors,
+	}
+}
+
+// serveTestSearcher answers search requests with s on an in-process NATS server and returns a requesting client
+func serveTestSearcher(t *testing.T, s *searcher) *nats.Client {
+	t.Helper()
+	srv, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	go srv.Start()
+	if !srv.ReadyForConnections(5 * time.Second) {
+		t.Fatal("in-process NATS server did not start")
+	}
+	t.Cleanup(srv.Shutdown)
+
+	cfg := nats.DefaultConfig()
+	cfg.URL = srv.ClientURL()
+	client, err := nats.NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(client.Close)
+
+	sub, err := client.ServeSearch(context.Background(), "searchers", s.handle)
+	if err != nil {
+		t.Fatalf("ServeSearch: %v", err)
+	}
+	t.Cleanup(func() { sub.Unsubscribe() })
+	return client
+}
+
+func TestSearcherAnswersOverNATS(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	neighbor := func(id string, day int, score float32) milvus.SearchResult {
+		return milvus.SearchResult{WindowID: id, Symbol: "BTCUSDT", Timeframe: "1d", Score: score, TEnd: start.AddDate(0, 0, day)}
+	}
+	vectors := &fakeVectorSearcher{results: []milvus.SearchResult{
+		neighbor("early", 20, 0.95),
+		neighbor("middle", 60, 0.9),
+		neighbor("leaking", 180, 0.99), // Its 60-bar outcome runs past the query
+	}}
+	s := newTestSearcher(t, start, vectors)
+
+	// Stored outcomes of the neighbors, so none are recomputed
+	var outcomes []*model.Outcome
+	for _, id := range []string{"early", "middle"} {
+		for _, h := range []int{5, 20, 60} {
+			outcomes = append(outcomes, &model.Outcome{WindowID: id, Horizon: h, FwdRetMean: 0.02, FwdRetP50: 0.02, HitRate: 1})
+		}
+	}
+	if err := s.outcomeRepo.InsertBatch(context.Background(), outcomes); err != nil {
+		t.Fatalf("InsertBatch outcomes: %v", err)
+	}
+
+	client := serveTestSearcher(t, s)
+	resp, err := client.SearchRequest(context.Background(), &nats.SearchRequestMsg{Symbol: "BTCUSDT", Timeframe: "1d", TopK: 5})
+	if err != nil {
+		t.Fatalf("SearchRequest: %v", err)
+	}
+
+	if want := start.AddDate(0, 0, 199); resp.QueryTEnd.Before(want) {
+		t.Errorf("query ends %s, want the latest candle %s", resp.QueryTEnd, want)
+	}
+	if vectors.filter != `symbol == "BTCUSDT" && timeframe == "1d"` || vectors.topK != 20 {
+		t.Errorf("searched %q for %d candidates", vectors.filter, vectors.topK)
+	}
+	var ids []string
+	for _, n := range resp.Neighbors {
+		ids = append(ids, n.WindowID)
+	}
+	// Time decay ranks the newer neighbor first; the leaking one is excluded
+	if strings.Join(ids, ",") != "middle,early" {
+		t.Errorf("neighbors = %v, want [middle early]", ids)
+	}
+	if len(resp.Outcomes) != 3 || resp.Outcomes[0].SampleCount != 2 || resp.Outcomes[0].HitRate != 1 {
+		t.Errorf("outcomes = %+v, want 3 horizons aggregated over both neighbors", resp.Outcomes)
+	}
+}
+
+func TestSearcherRepliesErrors(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := serveTestSearcher(t, newTestSearcher(t, start, &fakeVectorSearcher{}))
+
+	tests := []struct {
+		name string
+		req  nats.SearchRequestMsg
+		want string
+	}{
+		{"missing timeframe", nats.SearchRequestMsg{Symbol: "BTCUSDT"}, "needs a timeframe"},
+		{"filter injection", nats.SearchRequestMsg{Symbol: `BTC" || true`, Timeframe: "1d"}, "invalid symbol"},
+		{"top_k over the limit", nats.SearchRequestMsg{Symbol: "BTCUSDT", Timeframe: "1d", TopK: 51}, "exceeds the limit"},
+		{"unknown symbol", nats.SearchRequestMsg{Symbol: "ETHUSDT", Timeframe: "1d"}, "not enough candles"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.SearchRequest(context.Background(), &tt.req)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("SearchRequest error = %v, want one containing %q", err, tt.want)
+			}
+			if resp != nil && len(resp.Neighbors) > 0 {
+				t.Errorf("failed search returned neighbors %+v", resp.Neighbors)
+			}
+		})
+	}
+}
@@ -2,30 +2,53 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/nats-io/nats.go/jetstream"
+	"github.com/tunogya/etna/pkg/config"
+	"github.com/tunogya/etna/pkg/model"
+	"github.com/tunogya/etna/pkg/outcome"
 	"github.com/tunogya/etna/pkg/queue/nats"
 	"github.com/tunogya/etna/pkg/store/duckdb"
+	"github.com/tunogya/etna/pkg/store/milvus"
 )
 
 // Config holds writer worker configuration
 type Config struct {
-	NATSUrl    string
-	DuckDBPath string
-	MilvusAddr string
+	NATSUrl         string
+	DuckDBPath      string
+	MilvusAddr      string
+	MilvusPartition string        // Time partition granularity: year, quarter, or empty for none
+	VectorDim       int           // Embedding dimension used when creating the collection
+	FlushInterval   time.Duration // Interval between Milvus flushes of upserted vectors
+	DrainTimeout    time.Duration // Max wait for in-flight messages on shutdown
+	OutcomeJobs     bool          // Consume outcome computation jobs
+
+	// Batching
+	BatchSize int           // Max messages fetched and inserted per transaction
+	BatchWait time.Duration // Max wait for a fetched batch to fill
+
+	// Routing
+	Symbols   []string // Only consume messages for these symbols (empty for all)
+	Timeframe string   // Only consume messages for this timeframe (empty for all)
+
+	HealthAddr string // Address serving /health/nats; empty disables it
 }
 
 func main() {
 	cfg := parseFlags()
 
 	log.Println("Starting Writer Worker...")
-	log.Printf("NATS: %s, DuckDB: %s", cfg.NATSUrl, cfg.DuckDBPath)
+	log.Printf("NATS: %s, DuckDB: %s, Milvus: %s", cfg.NATSUrl, cfg.DuckDBPath, cfg.MilvusAddr)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -48,6 +71,27 @@ func main() {
 	candleRepo := duckdb.NewCandleRepo(duckClient)
 	windowRepo := duckdb.NewWindowRepo(duckClient)
 	featureRepo := duckdb.NewFeatureRepo(duckClient)
+	outcomeRepo := duckdb.NewOutcomeRepo(duckClient)
+
+	// Initialize Milvus
+	log.Println("Connecting to Milvus...")
+	milvusClient, err := milvus.NewClient(ctx, milvus.Config{
+		Address:              cfg.MilvusAddr,
+		PartitionGranularity: cfg.MilvusPartition,
+	})
+	if err != nil {
+		log.Fatalf("Failed to connect to Milvus: %v", err)
+	}
+	defer milvusClient.Close()
+
+	if err := milvusClient.CreateCollection(ctx, milvus.CollectionConfig{
+		Name:      milvus.DefaultCollectionName,
+		Dimension: cfg.VectorDim,
+		Shards:    2,
+	}); err != nil {
+		log.Fatalf("Failed to create Milvus collection: %v", err)
+	}
+	log.Println("Milvus collection ready")
 
 	// Initialize NATS
 	log.Println("Connecting to NATS...")
@@ -61,70 +105,128 @@ func main() {
 	defer natsClient.Close()
 
 	// Create stream
-	subjects := []string{nats.SubjectCandleWrite, nats.SubjectWindowWrite}
-	if err := natsClient.CreateStream(ctx, subjects); err != nil {
+	if err := natsClient.CreateStream(ctx, nats.StreamSubjects); err != nil {
 		log.Fatalf("Failed to create stream: %v", err)
 	}
+	if err := natsClient.CreateDLQStream(ctx); err != nil {
+		log.Fatalf("Failed to create DLQ stream: %v", err)
+	}
 	log.Println("NATS stream ready")
 
-	// Subscribe to candle writes
-	candleConsumer, err := natsClient.Subscribe(ctx, nats.SubjectCandleWrite, "candle-writer", func(msg jetstream.Msg) error {
-		batch, err := nats.DecodeCandleBatch(msg.Data())
-		if err != nil {
-			log.Printf("Failed to decode candle batch: %v", err)
-			return err
+	// Subscribe to candle writes, inserting each fetched batch of messages in one transaction
+	_, err = natsClient.SubscribeBatch(ctx, nats.ConsumerSubjects(nats.SubjectCandleWrite, cfg.Symbols, cfg.Timeframe), consumerName("candle-writer", cfg), cfg.BatchSize, cfg.BatchWait, func(msgs []jetstream.Msg) error {
+		var candles []model.Candle
+		for _, msg := range msgs {
+			batch, err := nats.DecodeCandleBatchMsg(msg)
+			if err != nil {
+				log.Printf("Failed to decode candle batch: %v", err)
+				return nats.Permanent(err)
+			}
+			candles = append(candles, batch.Candles...)
 		}
 
-		if len(batch.Candles) == 0 {
+		if len(candles) == 0 {
 			return nil
 		}
 
-		if err := candleRepo.InsertBatch(ctx, batch.Candles); err != nil {
+		if err := candleRepo.InsertBatch(ctx, candles); err != nil {
 			log.Printf("Failed to insert candles: %v", err)
 			return err
 		}
 
-		log.Printf("Inserted %d candles", len(batch.Candles))
+		log.Printf("Inserted %d candles from %d messages", len(candles), len(msgs))
 		return nil
 	})
 	if err != nil {
 		log.Fatalf("Failed to subscribe to candle writes: %v", err)
 	}
-	defer candleConsumer.Stop()
 
-	// Subscribe to window writes
-	windowConsumer, err := natsClient.Subscribe(ctx, nats.SubjectWindowWrite, "window-writer", func(msg jetstream.Msg) error {
-		batch, err := nats.DecodeWindowBatch(msg.Data())
-		if err != nil {
-			log.Printf("Failed to decode window batch: %v", err)
-			return err
+	// Subscribe to window writes, inserting each fetched batch of messages in one transaction per table
+	_, err = natsClient.SubscribeBatch(ctx, nats.ConsumerSubjects(nats.SubjectWindowWrite, cfg.Symbols, cfg.Timeframe), consumerName("window-writer", cfg), cfg.BatchSize, cfg.BatchWait, func(msgs []jetstream.Msg) error {
+		var windows []*model.Window
+		var features []*model.FeatureRow
+		for _, msg := range msgs {
+			batch, err := nats.DecodeWindowBatchMsg(msg)
+			if err != nil {
+				log.Printf("Failed to decode window batch: %v", err)
+				return nats.Permanent(err)
+			}
+			windows = append(windows, batch.Windows...)
+			features = append(features, batch.Features...)
 		}
 
-		if len(batch.Windows) == 0 {
+		if len(windows) == 0 {
 			return nil
 		}
 
 		// Insert windows
-		if err := windowRepo.InsertBatch(ctx, batch.Windows); err != nil {
+		if err := windowRepo.InsertBatch(ctx, windows); err != nil {
 			log.Printf("Failed to insert windows: %v", err)
 			return err
 		}
 
 		// Insert features
-		if len(batch.Features) > 0 {
-			if err := featureRepo.InsertBatch(ctx, batch.Features); err != nil {
+		if len(features) > 0 {
+			if err := featureRepo.InsertBatch(ctx, features); err != nil {
 				log.Printf("Failed to insert features: %v", err)
 				return err
 			}
 		}
 
-		log.Printf("Inserted %d windows with features", len(batch.Windows))
+		log.Printf("Inserted %d windows with features from %d messages", len(windows), len(msgs))
 		return nil
 	})
 	if err != nil {
 		log.Fatalf("Failed to subscribe to window writes: %v", err)
 	}
-	defer windowConsumer.Stop()
+
+	// Subscribe to vector writes
+	vectors := newVectorWriter(milvusClient, milvus.DefaultCollectionName)
+	go vectors.run(ctx, cfg.FlushInterval)
+
+	_, err = natsClient.Subscribe(ctx, nats.ConsumerSubjects(nats.SubjectMilvusWrite, cfg.Symbols, cfg.Timeframe), consumerName("milvus-writer", cfg), func(msg jetstream.Msg) error {
+		if err := vectors.handle(ctx, msg); err != nil {
+			log.Printf("Failed to write vectors: %v", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Failed to subscribe to vector writes: %v", err)
+	}
+
+	// Compute outcomes for queued windows; failed jobs are retried with backoff, then dead-lettered
+	if cfg.OutcomeJobs {
+		engine := outcome.NewEngine(candleRepo)
+		_, err = natsClient.Subscribe(ctx, nats.ConsumerSubjects(nats.SubjectOutcomeCompute, cfg.Symbols, cfg.Timeframe), consumerName("outcome-worker", cfg), func(msg jetstream.Msg) error {
+			job, err := nats.DecodeOutcomeJob(msg.Data())
+			if err != nil {
+				log.Printf("Failed to decode outcome job: %v", err)
+				return nats.Permanent(err)
+			}
+
+			// The window may not have been written yet, so a missing window is retried
+			if _, err := windowRepo.GetByID(ctx, job.WindowID); err != nil {
+				return fmt.Errorf("failed to load window %s: %w", job.WindowID, err)
+			}
+
+			computed, err := engine.RefreshWindow(ctx, job.WindowID, job.Symbol, job.Timeframe, job.Horizons, windowRepo, outcomeRepo)
+			if err != nil {
+				log.Printf("Failed to compute outcomes for window %s: %v", job.WindowID, err)
+				return err
+			}
+
+			log.Printf("Computed %d/%d outcomes for window %s", computed, len(job.Horizons), job.WindowID)
+			return nil
+		})
+		if err != nil {
+			log.Fatalf("Failed to subscribe to outcome jobs: %v", err)
+		}
+	}
+
+	if cfg.HealthAddr != "" {
+		go serveHealth(cfg.HealthAddr, natsClient)
+	}
 
 	log.Println("Writer Worker started, waiting for messages...")
 
@@ -133,7 +235,59 @@ func main() {
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	<-sigCh
 
+	// Stop delivery and let in-flight batches commit and ack before closing the connection
 	log.Println("Shutting down Writer Worker...")
+	drainCtx, drainCancel := context.WithTimeout(ctx, cfg.DrainTimeout)
+	defer drainCancel()
+	if err := natsClient.Drain(drainCtx); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	if err := vectors.flush(ctx); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// serveHealth serves /health/nats, reporting the health of every consumer the writer subscribed
+// The status is 503 when any consumer is lagging or its health cannot be read
+func serveHealth(addr string, natsClient *nats.Client) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health/nats", func(w http.ResponseWriter, r *http.Request) {
+		health, err := natsClient.ConsumersHealth(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		status := http.StatusOK
+		for _, stats := range health {
+			if stats.IsLagging {
+				status = http.StatusServiceUnavailable
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(health); err != nil {
+			log.Printf("Failed to write health response: %v", err)
+		}
+	})
+
+	log.Printf("Serving consumer health on %s/health/nats", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Printf("Warning: health endpoint stopped: %v", err)
+	}
+}
+
+// consumerName returns the durable consumer name for a writer, qualified by its symbol and timeframe filters
+// Writers owning disjoint symbol sets need distinct durables on the work-queue stream
+func consumerName(base string, cfg Config) string {
+	name := base
+	if len(cfg.Symbols) > 0 {
+		name += "-" + strings.Join(cfg.Symbols, "-")
+	}
+	if cfg.Timeframe != "" {
+		name += "-" + cfg.Timeframe
+	}
+	return name
 }
 
 func parseFlags() Config {
@@ -142,14 +296,44 @@ func parseFlags() Config {
 	flag.StringVar(&cfg.NATSUrl, "nats", "nats://localhost:4222", "NATS server URL")
 	flag.StringVar(&cfg.DuckDBPath, "duckdb", "etna.duckdb", "DuckDB file path")
 	flag.StringVar(&cfg.MilvusAddr, "milvus", "localhost:19530", "Milvus server address")
+	flag.StringVar(&cfg.MilvusPartition, "milvus-partition", "", "Milvus time partition granularity: year or quarter (empty disables)")
+	flag.IntVar(&cfg.VectorDim, "dim", 96, "Vector dimension used when creating the Milvus collection")
+	symbols := flag.String("symbols", "", "Comma-separated symbols whose messages this writer consumes (empty for all)")
+	flag.StringVar(&cfg.Timeframe, "timeframe", "", "Only consume messages for this timeframe (empty for all)")
+	flag.IntVar(&cfg.BatchSize, "batch-size", 100, "Max messages fetched and inserted per transaction")
+	flag.DurationVar(&cfg.BatchWait, "batch-wait", time.Second, "Max wait for a fetched batch to fill")
+	flag.DurationVar(&cfg.DrainTimeout, "drain-timeout", 30*time.Second, "Max wait for in-flight messages to finish on shutdown")
+	flag.BoolVar(&cfg.OutcomeJobs, "outcome-jobs", true, "Consume outcome computation jobs queued by 'etna outcomes enqueue'")
+	flag.DurationVar(&cfg.FlushInterval, "flush-interval", 10*time.Second, "Interval between Milvus flushes of upserted vectors")
+	flag.StringVar(&cfg.HealthAddr, "health-addr", "", "Serve consumer lag at /health/nats on this address, e.g. :8081 (empty disables)")
+
+	configPath := flag.String("config", "", "YAML or JSON config file; ETNA_* environment variables override it and explicit flags override both")
 
 	flag.Parse()
+	if err := config.ApplyFlags(flag.CommandLine, *configPath, "timeframe"); err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
+
+	for _, symbol := range strings.Split(*symbols, ",") {
+		if symbol = strings.TrimSpace(symbol); symbol != "" {
+			cfg.Symbols = append(cfg.Symbols, symbol)
+		}
+	}
 
 	if cfg.DuckDBPath == "" {
 		fmt.Println("Usage: writer [options]")
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
+	if cfg.BatchSize <= 0 {
+		log.Fatalf("-batch-size must be positive, got %d", cfg.BatchSize)
+	}
+	if cfg.BatchWait <= 0 {
+		log.Fatalf("-batch-wait must be positive, got %s", cfg.BatchWait)
+	}
+	if cfg.FlushInterval <= 0 {
+		log.Fatalf("-flush-interval must be positive, got %s", cfg.FlushInterval)
+	}
 
 	return cfg
 }
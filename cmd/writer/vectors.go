@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/tunogya/etna/pkg/queue/nats"
+	"github.com/tunogya/etna/pkg/store/milvus"
+)
+
+// vectorStore is the part of *milvus.Client used by vectorWriter
+type vectorStore interface {
+	UpsertBatch(ctx context.Context, collectionName string, dataList []*milvus.WindowData) error
+	Flush(ctx context.Context, collectionName string) error
+}
+
+// vectorWriter upserts vector batches from NATS into Milvus and flushes them periodically
+type vectorWriter struct {
+	store      vectorStore
+	collection string
+
+	mu      sync.Mutex
+	pending int // Vectors upserted since the last flush
+}
+
+// newVectorWriter creates a vector writer for collection
+func newVectorWriter(store vectorStore, collection string) *vectorWriter {
+	return &vectorWriter{store: store, collection: collection}
+}
+
+// handle decodes a MilvusBatchMsg and upserts its vectors
+// Undecodable payloads are reported as permanent errors; upsert failures are transient
+func (w *vectorWriter) handle(ctx context.Context, msg jetstream.Msg) error {
+	batch, err := nats.DecodeMilvusBatchMsg(msg)
+	if err != nil {
+		return nats.Permanent(fmt.Errorf("failed to decode milvus batch: %w", err))
+	}
+
+	if len(batch.Vectors) == 0 {
+		return nil
+	}
+
+	dataList := make([]*milvus.WindowData, len(batch.Vectors))
+	for i, v := range batch.Vectors {
+		dataList[i] = &milvus.WindowData{
+			WindowID:    v.WindowID,
+			Embedding:   v.Embedding,
+			Symbol:      v.Symbol,
+			Timeframe:   v.Timeframe,
+			TEnd:        v.TEnd,
+			VolBucket:   v.VolBucket,
+			TrendBucket: v.TrendBucket,
+			DataVersion: v.DataVersion,
+		}
+	}
+
+	if err := w.store.UpsertBatch(ctx, w.collection, dataList); err != nil {
+		return fmt.Errorf("failed to upsert vectors: %w", err)
+	}
+
+	w.mu.Lock()
+	w.pending += len(dataList)
+	w.mu.Unlock()
+	return nil
+}
+
+// flush flushes the collection if any vectors were upserted since the last flush
+func (w *vectorWriter) flush(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.pending == 0 {
+		return nil
+	}
+	if err := w.store.Flush(ctx, w.collection); err != nil {
+		return fmt.Errorf("failed to flush vectors: %w", err)
+	}
+	log.Printf("Flushed %d vectors", w.pending)
+	w.pending = 0
+	return nil
+}
+
+// run flushes every interval until ctx is done
+func (w *vectorWriter) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.flush(ctx); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	etnanats "github.com/tunogya/etna/pkg/queue/nats"
+	"github.com/tunogya/etna/pkg/store/milvus"
+)
+
+// fakeVectorStore records upserted vectors and flushes
+type fakeVectorStore struct {
+	mu       sync.Mutex
+	upserted []*milvus.WindowData
+	flushes  int
+}
+
+func (s *fakeVectorStore) UpsertBatch(ctx context.Context, collectionName string, dataList []*milvus.WindowData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.upserted = append(s.upserted, dataList...)
+	return nil
+}
+
+func (s *fakeVectorStore) Flush(ctx context.Context, collectionName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushes++
+	return nil
+}
+
+func (s *fakeVectorStore) counts() (upserted, flushes int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.upserted), s.flushes
+}
+
+// fakeMsg is a delivered JetStream message carrying only a payload and headers
+type fakeMsg struct {
+	jetstream.Msg
+	data   []byte
+	header nats.Header
+}
+
+func (m *fakeMsg) Data() []byte         { return m.data }
+func (m *fakeMsg) Headers() nats.Header { return m.header }
+
+// milvusBatchMsg encodes batch as a delivered message with codec
+func milvusBatchMsg(t *testing.T, codec etnanats.Codec, batch *etnanats.MilvusBatchMsg) jetstream.Msg {
+	t.Helper()
+	data, err := etnanats.EncodeWith(codec, batch)
+	if err != nil {
+		t.Fatalf("EncodeWith: %v", err)
+	}
+	header := nats.Header{}
+	header.Set(etnanats.HeaderSchemaVersion, "1")
+	return &fakeMsg{data: data, header: header}
+}
+
+func TestVectorWriterUpsertsAndFlushes(t *testing.T) {
+	store := &fakeVectorStore{}
+	w := newVectorWriter(store, milvus.DefaultCollectionName)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.run(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	tEnd := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, codec := range []etnanats.Codec{etnanats.CodecJSON, etnanats.CodecProto} {
+		batch := &etnanats.MilvusBatchMsg{Vectors: []etnanats.MilvusWriteMsg{
+			{WindowID: "a-" + string(codec), Embedding: []float32{0.1, 0.2}, Symbol: "BTCUSDT", Timeframe: "1m", TEnd: tEnd, DataVersion: 2},
+			{WindowID: "b-" + string(codec), Embedding: []float32{0.3, 0.4}, Symbol: "BTCUSDT", Timeframe: "1m", TEnd: tEnd, DataVersion: 2},
+		}}
+		if err := w.handle(ctx, milvusBatchMsg(t, codec, batch)); err != nil {
+			t.Fatalf("handle %s batch: %v", codec, err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		upserted, flushes := store.counts()
+		if upserted == 4 && flushes > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("upserted %d vectors and flushed %d times, want 4 vectors flushed", upserted, flushes)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Nothing new was upserted, so later ticks must not flush again
+	_, flushes := store.counts()
+	time.Sleep(50 * time.Millisecond)
+	if _, again := store.counts(); again != flushes {
+		t.Errorf("flushed %d more times with nothing pending", again-flushes)
+	}
+
+	cancel()
+	<-done
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if got := store.upserted[0]; got.WindowID != "a-json" || got.DataVersion != 2 || len(got.Embedding) != 2 {
+		t.Errorf("first upserted vector = %+v", got)
+	}
+}
+
+func TestVectorWriterRejectsUndecodablePayload(t *testing.T) {
+	w := newVectorWriter(&fakeVectorStore{}, milvus.DefaultCollectionName)
+	err := w.handle(context.Background(), &fakeMsg{data: []byte("not a batch"), header: nats.Header{}})
+	if !etnanats.IsPermanent(err) {
+		t.Errorf("handle error = %v, want a permanent error", err)
+	}
+}
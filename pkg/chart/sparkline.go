@@ -0,0 +1,100 @@
+package chart
+
+import (
+	"math"
+	"strings"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// sparkLevels are the eighth-block characters a sparkline scales values onto, lowest first
+var sparkLevels = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders values as a one-line unicode chart of at most width characters
+// Longer series are compressed by keeping the last value of each column's span; a flat series renders mid-height
+func Sparkline(values []float64, width int) string {
+	if len(values) == 0 || width <= 0 {
+		return ""
+	}
+
+	columns := columnSpans(len(values), width)
+	points := make([]float64, len(columns))
+	for c, span := range columns {
+		points[c] = values[span[1]-1]
+	}
+
+	lo, hi := points[0], points[0]
+	for _, v := range points {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+
+	var b strings.Builder
+	for _, v := range points {
+		level := len(sparkLevels) / 2
+		if hi > lo {
+			level = int(math.Round((v - lo) / (hi - lo) * float64(len(sparkLevels)-1)))
+		}
+		b.WriteRune(sparkLevels[level])
+	}
+	return b.String()
+}
+
+// BlockChart renders candles as height rows of at most width characters, top row first:
+// '│' spans each column's low to high and '█' marks its close
+// Longer series are compressed so each column covers the range of its candles and closes at the last one
+func BlockChart(candles []model.Candle, width, height int) []string {
+	if len(candles) == 0 || width <= 0 || height < 2 {
+		return nil
+	}
+
+	type column struct{ high, low, close float64 }
+	spans := columnSpans(len(candles), width)
+	cols := make([]column, len(spans))
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for c, span := range spans {
+		col := column{high: math.Inf(-1), low: math.Inf(1), close: candles[span[1]-1].Close}
+		for _, candle := range candles[span[0]:span[1]] {
+			col.high = math.Max(col.high, candle.High)
+			col.low = math.Min(col.low, candle.Low)
+		}
+		cols[c] = col
+		lo, hi = math.Min(lo, col.low), math.Max(hi, col.high)
+	}
+	if hi == lo {
+		hi = lo + 1e-9
+	}
+
+	// row maps a price to a row index, with row 0 at the top
+	row := func(v float64) int {
+		return int(math.Round((hi - v) / (hi - lo) * float64(height-1)))
+	}
+
+	grid := make([][]rune, height)
+	for r := range grid {
+		grid[r] = []rune(strings.Repeat(" ", len(cols)))
+	}
+	for c, col := range cols {
+		for r := row(col.high); r <= row(col.low); r++ {
+			grid[r][c] = '│'
+		}
+		grid[row(col.close)][c] = '█'
+	}
+
+	lines := make([]string, height)
+	for r := range grid {
+		lines[r] = string(grid[r])
+	}
+	return lines
+}
+
+// columnSpans splits n points into at most width contiguous [start, end) spans of near-equal size
+func columnSpans(n, width int) [][2]int {
+	if n < width {
+		width = n
+	}
+	spans := make([][2]int, width)
+	for c := range spans {
+		spans[c] = [2]int{c * n / width, (c + 1) * n / width}
+	}
+	return spans
+}
@@ -0,0 +1,239 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// File holds the settings shared by etna commands, as read from a YAML or JSON config file
+// Zero values mean unset, leaving the command's flag default in place
+type File struct {
+	DuckDB DuckDB `yaml:"duckdb" json:"duckdb"`
+	Milvus Milvus `yaml:"milvus" json:"milvus"`
+	NATS   NATS   `yaml:"nats" json:"nats"`
+	Market Market `yaml:"market" json:"market"`
+	Window Window `yaml:"window" json:"window"`
+}
+
+// DuckDB holds DuckDB settings
+type DuckDB struct {
+	Path string `yaml:"path" json:"path"`
+}
+
+// Milvus holds Milvus settings
+type Milvus struct {
+	Addr      string `yaml:"addr" json:"addr"`
+	Partition string `yaml:"partition" json:"partition"` // year, quarter, or empty for none
+	Dim       int    `yaml:"dim" json:"dim"`
+}
+
+// NATS holds NATS settings
+type NATS struct {
+	URL string `yaml:"url" json:"url"`
+}
+
+// Market selects the symbol and timeframe
+type Market struct {
+	Symbol    string `yaml:"symbol" json:"symbol"`
+	Timeframe string `yaml:"timeframe" json:"timeframe"`
+}
+
+// Window holds window builder settings
+type Window struct {
+	Length         int     `yaml:"length" json:"length"`
+	Step           int     `yaml:"step" json:"step"`
+	Overlap        float64 `yaml:"overlap" json:"overlap"`
+	FeatureVersion int     `yaml:"feature_version" json:"feature_version"`
+}
+
+// setting binds a config file key to its environment override and command flag
+type setting struct {
+	key   string // Dotted file key, e.g. "milvus.addr"
+	env   string
+	flag  string
+	value func(f *File) string // File value, empty if unset
+	check func(v string) error // Optional validation beyond the flag's own parsing
+}
+
+// settings lists every shared setting; flags use the same names in every command
+var settings = []setting{
+	{"duckdb.path", "ETNA_DUCKDB_PATH", "duckdb", func(f *File) string { return f.DuckDB.Path }, nil},
+	{"milvus.addr", "ETNA_MILVUS_ADDR", "milvus", func(f *File) string { return f.Milvus.Addr }, nil},
+	{"milvus.partition", "ETNA_MILVUS_PARTITION", "milvus-partition", func(f *File) string { return f.Milvus.Partition }, checkPartition},
+	{"milvus.dim", "ETNA_MILVUS_DIM", "dim", func(f *File) string { return itoa(f.Milvus.Dim) }, checkPositive},
+	{"nats.url", "ETNA_NATS_URL", "nats", func(f *File) string { return f.NATS.URL }, nil},
+	{"market.symbol", "ETNA_SYMBOL", "symbol", func(f *File) string { return f.Market.Symbol }, nil},
+	{"market.timeframe", "ETNA_TIMEFRAME", "timeframe", func(f *File) string { return f.Market.Timeframe }, checkTimeframe},
+	{"window.length", "ETNA_WINDOW_LENGTH", "window", func(f *File) string { return itoa(f.Window.Length) }, checkPositive},
+	{"window.step", "ETNA_WINDOW_STEP", "step", func(f *File) string { return itoa(f.Window.Step) }, checkPositive},
+	{"window.overlap", "ETNA_WINDOW_OVERLAP", "overlap", func(f *File) string { return ftoa(f.Window.Overlap) }, checkOverlap},
+	{"window.feature_version", "ETNA_FEATURE_VERSION", "version", func(f *File) string { return itoa(f.Window.FeatureVersion) }, checkPositive},
+}
+
+// Example returns a config file holding the commands' flag defaults
+func Example() *File {
+	return &File{
+		DuckDB: DuckDB{Path: "etna.duckdb"},
+		Milvus: Milvus{Addr: "localhost:19530", Dim: 96},
+		NATS:   NATS{URL: "nats://localhost:4222"},
+		Market: Market{Symbol: "BTCUSDT", Timeframe: "1d"},
+		Window: Window{Length: 7, Step: 1, FeatureVersion: 1},
+	}
+}
+
+// Load reads a config file, as JSON if its extension is .json and as YAML otherwise
+// Unknown keys are rejected so typos do not silently fall back to defaults
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var f File
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&f)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&f)
+		var typeErr *yaml.TypeError
+		switch {
+		case errors.Is(err, io.EOF):
+			err = nil // Empty file
+		case errors.As(err, &typeErr):
+			err = errors.New(strings.Join(typeErr.Errors, "; ")) // One line per offending field otherwise
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	if err := f.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// Validate checks every set value, naming the first offending key
+func (f *File) Validate() error {
+	for _, s := range settings {
+		if v := s.value(f); v != "" && s.check != nil {
+			if err := s.check(v); err != nil {
+				return fmt.Errorf("%s: %w", s.key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Encode renders f as "yaml" or "json"
+func (f *File) Encode(format string) ([]byte, error) {
+	switch format {
+	case "yaml":
+		return yaml.Marshal(f)
+	case "json":
+		data, err := json.MarshalIndent(f, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	default:
+		return nil, fmt.Errorf("unknown config format %q (want yaml or json)", format)
+	}
+}
+
+// ApplyFlags fills parsed flags of fs from the config file at path and ETNA_* environment variables
+// Precedence is explicit flag, then environment, then file, then the flag default. An empty path skips the file.
+// Only settings whose flag fs defines are applied; flags named in exclude are left alone, for commands where a
+// shared flag name has a different meaning. Errors name the offending config key and where its value came from
+func ApplyFlags(fs *flag.FlagSet, path string, exclude ...string) error {
+	file := &File{}
+	if path != "" {
+		loaded, err := Load(path)
+		if err != nil {
+			return err
+		}
+		file = loaded
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	for _, name := range exclude {
+		explicit[name] = true
+	}
+
+	for _, s := range settings {
+		value, source := s.value(file), path
+		if env := os.Getenv(s.env); env != "" {
+			value, source = env, s.env
+		}
+		if value == "" || explicit[s.flag] || fs.Lookup(s.flag) == nil {
+			continue
+		}
+
+		if s.check != nil {
+			if err := s.check(value); err != nil {
+				return fmt.Errorf("invalid %s from %s: %w", s.key, source, err)
+			}
+		}
+		if err := fs.Set(s.flag, value); err != nil {
+			return fmt.Errorf("invalid %s from %s: %w", s.key, source, err)
+		}
+	}
+	return nil
+}
+
+func itoa(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func ftoa(x float64) string {
+	if x == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(x, 'g', -1, 64)
+}
+
+func checkPositive(v string) error {
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return fmt.Errorf("must be a positive integer, got %q", v)
+	}
+	return nil
+}
+
+func checkOverlap(v string) error {
+	x, err := strconv.ParseFloat(v, 64)
+	if err != nil || x < 0 || x >= 1 {
+		return fmt.Errorf("must be in [0, 1), got %q", v)
+	}
+	return nil
+}
+
+func checkPartition(v string) error {
+	if v != "year" && v != "quarter" {
+		return fmt.Errorf("must be year or quarter, got %q", v)
+	}
+	return nil
+}
+
+func checkTimeframe(v string) error {
+	_, err := model.TimeframeDuration(v)
+	return err
+}
@@ -0,0 +1,22 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// ParseTimeFlag parses a time flag value given as RFC3339 or as a YYYY-MM-DD date in UTC
+// An empty value gives the zero time, leaving that bound unset
+func ParseTimeFlag(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither RFC3339 nor YYYY-MM-DD", s)
+	}
+	return t, nil
+}
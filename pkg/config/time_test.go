@@ -0,0 +1,30 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeFlag(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"", time.Time{}, false},
+		{"2024-03-01", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), false},
+		{"2024-03-01T12:30:00Z", time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC), false},
+		{"2024-03-01T12:30:00+02:00", time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC), false},
+		{"03/01/2024", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTimeFlag(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTimeFlag(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseTimeFlag(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
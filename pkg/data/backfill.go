@@ -14,6 +14,9 @@ import (
 
 // CSVProvider implements CandleProvider for CSV files
 type CSVProvider struct {
+	// ComputeMissingVWAP fills VWAP with the typical price for candles without one
+	ComputeMissingVWAP bool
+
 	filePath string
 	candles  []model.Candle
 	loaded   bool
@@ -100,6 +103,7 @@ func (p *CSVProvider) parseRecord(record []string, colMap map[string]int) (model
 	close, _ := strconv.ParseFloat(getValue("close"), 64)
 	volume, _ := strconv.ParseFloat(getValue("volume"), 64)
 	trades, _ := strconv.ParseInt(getValue("trades"), 10, 64)
+	vwap, _ := strconv.ParseFloat(getValue("vwap"), 64)
 
 	return model.Candle{
 		Symbol:    getValue("symbol"),
@@ -112,9 +116,21 @@ func (p *CSVProvider) parseRecord(record []string, colMap map[string]int) (model
 		Close:     close,
 		Volume:    volume,
 		Trades:    trades,
+		VWAP:      vwap,
 	}, nil
 }
 
+// ComputeVWAP sets VWAP to the typical price (High + Low + Close) / 3 for every candle whose VWAP is 0
+// candles is modified in place and returned
+func ComputeVWAP(candles []model.Candle) []model.Candle {
+	for i := range candles {
+		if candles[i].VWAP == 0 {
+			candles[i].VWAP = candles[i].TypicalPrice()
+		}
+	}
+	return candles
+}
+
 // FetchCandles retrieves candles within the specified time range
 func (p *CSVProvider) FetchCandles(ctx context.Context, symbol, timeframe string, start, end time.Time) ([]model.Candle, error) {
 	if err := p.loadIfNeeded(); err != nil {
@@ -135,6 +151,9 @@ func (p *CSVProvider) FetchCandles(ctx context.Context, symbol, timeframe string
 		result = append(result, c)
 	}
 
+	if p.ComputeMissingVWAP {
+		ComputeVWAP(result)
+	}
 	return result, nil
 }
 
@@ -155,11 +174,14 @@ func (p *CSVProvider) FetchLatestCandles(ctx context.Context, symbol, timeframe
 		filtered = append(filtered, c)
 	}
 
-	if len(filtered) <= limit {
-		return filtered, nil
+	if len(filtered) > limit {
+		filtered = filtered[len(filtered)-limit:]
 	}
 
-	return filtered[len(filtered)-limit:], nil
+	if p.ComputeMissingVWAP {
+		ComputeVWAP(filtered)
+	}
+	return filtered, nil
 }
 
 // MemoryProvider implements CandleProvider with in-memory storage
@@ -0,0 +1,363 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/tunogya/etna/pkg/model"
+	"github.com/tunogya/etna/pkg/queue/nats"
+)
+
+const (
+	// KlinePageLimit is the maximum number of klines returned per klines request
+	KlinePageLimit = 1000
+
+	// kvKlineCheckpointPrefix prefixes kline checkpoint keys in the NATS KV bucket; keys are prefix.symbol.timeframe
+	kvKlineCheckpointPrefix = "checkpoint.kline"
+)
+
+// KlineWeight returns the REST request weight Binance charges a klines request of limit klines
+// A limit of 0 means Binance's default of 500
+func KlineWeight(limit int) int {
+	switch {
+	case limit <= 0:
+		return KlineWeight(500)
+	case limit < 100:
+		return 1
+	case limit < 500:
+		return 2
+	case limit <= KlinePageLimit:
+		return 5
+	default:
+		return 10
+	}
+}
+
+// Checkpoint records the last kline handed to the caller for a symbol/timeframe
+type Checkpoint struct {
+	Symbol        string `json:"symbol"`
+	Timeframe     string `json:"timeframe"`
+	LastCloseTime int64  `json:"last_close_time"` // Close time of the last handled kline, in milliseconds
+}
+
+// CheckpointStore persists kline fetch checkpoints
+type CheckpointStore interface {
+	// Load returns the checkpoint of symbol/timeframe, or nil if none has been saved
+	Load(ctx context.Context, symbol, timeframe string) (*Checkpoint, error)
+	// Save stores cp, replacing any previous checkpoint of its symbol/timeframe
+	Save(ctx context.Context, cp Checkpoint) error
+}
+
+// FileCheckpointStore keeps checkpoints of every symbol/timeframe in one local JSON file
+type FileCheckpointStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileCheckpointStore creates a checkpoint store backed by the JSON file at path, created on first save
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path}
+}
+
+// Load returns the checkpoint of symbol/timeframe, or nil if none has been saved
+func (s *FileCheckpointStore) Load(ctx context.Context, symbol, timeframe string) (*Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	checkpoints, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	cp, ok := checkpoints[checkpointKey(symbol, timeframe)]
+	if !ok {
+		return nil, nil
+	}
+	return &cp, nil
+}
+
+// Save stores cp, replacing the file atomically so a crash mid-write keeps the previous checkpoints
+func (s *FileCheckpointStore) Save(ctx context.Context, cp Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	checkpoints, err := s.read()
+	if err != nil {
+		return err
+	}
+	checkpoints[checkpointKey(cp.Symbol, cp.Timeframe)] = cp
+
+	data, err := json.MarshalIndent(checkpoints, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoints: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace checkpoint file: %w", err)
+	}
+	return nil
+}
+
+// read returns the checkpoints in the file, empty if it does not exist yet
+func (s *FileCheckpointStore) read() (map[string]Checkpoint, error) {
+	checkpoints := make(map[string]Checkpoint)
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return checkpoints, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint file: %w", err)
+	}
+	if err := json.Unmarshal(data, &checkpoints); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint file %s: %w", s.path, err)
+	}
+	return checkpoints, nil
+}
+
+// checkpointKey returns the key symbol.timeframe
+func checkpointKey(symbol, timeframe string) string {
+	return symbol + "." + timeframe
+}
+
+// NATSCheckpointStore keeps checkpoints in the client's JetStream KV bucket
+type NATSCheckpointStore struct {
+	client *nats.Client
+}
+
+// NewNATSCheckpointStore creates a checkpoint store backed by the KV bucket of client
+func NewNATSCheckpointStore(client *nats.Client) *NATSCheckpointStore {
+	return &NATSCheckpointStore{client: client}
+}
+
+// Load returns the checkpoint of symbol/timeframe, or nil if none has been saved
+func (s *NATSCheckpointStore) Load(ctx context.Context, symbol, timeframe string) (*Checkpoint, error) {
+	data, err := s.client.KVGet(ctx, kvKlineCheckpointPrefix+"."+checkpointKey(symbol, timeframe))
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to decode checkpoint: %w", err)
+	}
+	return &cp, nil
+}
+
+// Save stores cp under its symbol/timeframe
+func (s *NATSCheckpointStore) Save(ctx context.Context, cp Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	_, err = s.client.KVPut(ctx, kvKlineCheckpointPrefix+"."+checkpointKey(cp.Symbol, cp.Timeframe), data)
+	return err
+}
+
+// KlineBatchFetcher pages spot klines from Binance, resuming from a stored checkpoint
+type KlineBatchFetcher struct {
+	baseURL    string
+	httpClient *http.Client
+	store      CheckpointStore
+}
+
+// NewKlineBatchFetcher creates a kline fetcher against the public Binance spot API
+func NewKlineBatchFetcher(store CheckpointStore) *KlineBatchFetcher {
+	return NewKlineBatchFetcherWithURL(DefaultSpotBaseURL, store)
+}
+
+// NewKlineBatchFetcherWithURL creates a kline fetcher against a custom base URL
+func NewKlineBatchFetcherWithURL(baseURL string, store CheckpointStore) *KlineBatchFetcher {
+	return &KlineBatchFetcher{
+		baseURL:    baseURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		store:      store,
+	}
+}
+
+// Fetch passes the closed klines of symbol/timeframe within [start, end] to handle, one page at a time
+// A stored checkpoint overrides start, resuming just after the last handled kline. The checkpoint advances only
+// once handle returns nil, so a restart neither repeats nor skips klines. A zero end means now; klines still
+// open are left for a later fetch. Returns the number of klines handled
+func (f *KlineBatchFetcher) Fetch(ctx context.Context, symbol, timeframe string, start, end time.Time, handle func([]model.Candle) error) (int, error) {
+	cursor := start.UnixMilli()
+	cp, err := f.store.Load(ctx, symbol, timeframe)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+	if cp != nil {
+		cursor = cp.LastCloseTime + 1
+	}
+
+	now := time.Now()
+	if end.IsZero() || end.After(now) {
+		end = now
+	}
+	endMs := end.UnixMilli()
+
+	handled := 0
+	for cursor <= endMs {
+		page, err := f.fetchPage(ctx, symbol, timeframe, cursor, endMs)
+		if err != nil {
+			return handled, err
+		}
+		full := len(page) == KlinePageLimit
+
+		// The latest kline is still forming until its close time passes
+		for len(page) > 0 && page[len(page)-1].CloseTime.After(now) {
+			page = page[:len(page)-1]
+		}
+		if len(page) == 0 {
+			break
+		}
+
+		if err := handle(page); err != nil {
+			return handled, err
+		}
+		handled += len(page)
+
+		last := page[len(page)-1].CloseTime.UnixMilli()
+		if err := f.store.Save(ctx, Checkpoint{Symbol: symbol, Timeframe: timeframe, LastCloseTime: last}); err != nil {
+			return handled, fmt.Errorf("failed to save checkpoint: %w", err)
+		}
+
+		if !full {
+			break
+		}
+		cursor = last + 1
+	}
+
+	return handled, nil
+}
+
+// FetchCandleBatch fetches one page of closed klines opening at or after startTime
+// The page token is the close time of the last kline plus 1ms, in Unix milliseconds. The checkpoint store is not
+// consulted, as the caller owns the cursor
+func (f *KlineBatchFetcher) FetchCandleBatch(ctx context.Context, symbol, timeframe string, startTime time.Time) ([]model.Candle, string, error) {
+	now := time.Now()
+	page, err := f.fetchPage(ctx, symbol, timeframe, startTime.UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The latest kline is still forming until its close time passes
+	for len(page) > 0 && page[len(page)-1].CloseTime.After(now) {
+		page = page[:len(page)-1]
+	}
+	if len(page) == 0 {
+		return nil, "", nil
+	}
+
+	next := page[len(page)-1].CloseTime.UnixMilli() + 1
+	return page, strconv.FormatInt(next, 10), nil
+}
+
+// fetchPage fetches a single page of klines opening within [startMs, endMs]
+func (f *KlineBatchFetcher) fetchPage(ctx context.Context, symbol, timeframe string, startMs, endMs int64) ([]model.Candle, error) {
+	params := url.Values{}
+	params.Set("symbol", symbol)
+	params.Set("interval", timeframe)
+	params.Set("startTime", strconv.FormatInt(startMs, 10))
+	params.Set("endTime", strconv.FormatInt(endMs, 10))
+	params.Set("limit", strconv.Itoa(KlinePageLimit))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.baseURL+"/api/v3/klines?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch klines: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("klines request failed: %s: %s", resp.Status, string(body))
+	}
+
+	// Each kline is [open time, open, high, low, close, volume, close time, quote volume, trades, ...]
+	var rows [][]json.RawMessage
+	if err := json.Unmarshal(body, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse klines: %w", err)
+	}
+
+	candles := make([]model.Candle, 0, len(rows))
+	for _, row := range rows {
+		candle, err := parseKline(row)
+		if err != nil {
+			return nil, err
+		}
+		candle.Symbol = symbol
+		candle.Timeframe = timeframe
+		candles = append(candles, candle)
+	}
+
+	return candles, nil
+}
+
+// parseKline decodes one kline row of the klines endpoint
+func parseKline(row []json.RawMessage) (model.Candle, error) {
+	var c model.Candle
+	if len(row) < 9 {
+		return c, fmt.Errorf("invalid kline: want at least 9 fields, got %d", len(row))
+	}
+
+	var openMs, closeMs int64
+	if err := json.Unmarshal(row[0], &openMs); err != nil {
+		return c, fmt.Errorf("invalid kline open time: %w", err)
+	}
+	if err := json.Unmarshal(row[6], &closeMs); err != nil {
+		return c, fmt.Errorf("invalid kline close time: %w", err)
+	}
+	if err := json.Unmarshal(row[8], &c.Trades); err != nil {
+		return c, fmt.Errorf("invalid kline trades: %w", err)
+	}
+
+	// Prices and volume are decimal strings
+	var values [5]float64
+	for i := range values {
+		var s string
+		if err := json.Unmarshal(row[i+1], &s); err != nil {
+			return c, fmt.Errorf("invalid kline field %d: %w", i+1, err)
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return c, fmt.Errorf("invalid kline field %d %q: %w", i+1, s, err)
+		}
+		values[i] = v
+	}
+
+	c.OpenTime = time.UnixMilli(openMs)
+	c.CloseTime = time.UnixMilli(closeMs)
+	c.Open, c.High, c.Low, c.Close, c.Volume = values[0], values[1], values[2], values[3], values[4]
+	return c, nil
+}
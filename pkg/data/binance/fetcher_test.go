@@ -0,0 +1,167 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+func TestKlineWeight(t *testing.T) {
+	tests := []struct {
+		limit int
+		want  int
+	}{
+		{0, 5}, // Binance's default limit of 500
+		{1, 1},
+		{99, 1},
+		{100, 2},
+		{499, 2},
+		{500, 5},
+		{KlinePageLimit, 5},
+		{1500, 10},
+	}
+	for _, tt := range tests {
+		if got := KlineWeight(tt.limit); got != tt.want {
+			t.Errorf("KlineWeight(%d) = %d, want %d", tt.limit, got, tt.want)
+		}
+	}
+}
+
+// klineServer serves n closed 1m klines from start, honoring startTime, endTime and limit
+func klineServer(t *testing.T, start time.Time, n int, requests *int) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if r.URL.Path != "/api/v3/klines" {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query()
+		startMs, _ := strconv.ParseInt(q.Get("startTime"), 10, 64)
+		endMs, _ := strconv.ParseInt(q.Get("endTime"), 10, 64)
+		limit, _ := strconv.Atoi(q.Get("limit"))
+
+		rows := [][]interface{}{}
+		for i := 0; i < n && len(rows) < limit; i++ {
+			open := start.Add(time.Duration(i) * time.Minute)
+			if open.UnixMilli() < startMs || open.UnixMilli() > endMs {
+				continue
+			}
+			price := strconv.Itoa(100 + i)
+			rows = append(rows, []interface{}{
+				open.UnixMilli(), price, price, price, price, "1", open.Add(time.Minute - time.Millisecond).UnixMilli(), price, 10,
+			})
+		}
+		json.NewEncoder(w).Encode(rows)
+	}))
+}
+
+func TestKlineBatchFetcherResumesAfterRestart(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	const n = 2500 // Three pages
+	requests := 0
+	srv := klineServer(t, start, n, &requests)
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "checkpoints.json")
+	end := start.Add(n * time.Minute)
+	seen := make(map[time.Time]int)
+	collect := func(page []model.Candle) error {
+		for _, c := range page {
+			seen[c.OpenTime]++
+		}
+		return nil
+	}
+
+	// The first run dies while handling its second page
+	pages := 0
+	crash := errors.New("process killed")
+	handled, err := NewKlineBatchFetcherWithURL(srv.URL, NewFileCheckpointStore(path)).Fetch(context.Background(), "BTCUSDT", "1m", start, end, func(page []model.Candle) error {
+		if pages++; pages == 2 {
+			return crash
+		}
+		return collect(page)
+	})
+	if !errors.Is(err, crash) || handled != KlinePageLimit {
+		t.Fatalf("first run handled %d klines with error %v, want %d and the crash", handled, err, KlinePageLimit)
+	}
+
+	// A restarted process reads the checkpoint file afresh
+	handled, err = NewKlineBatchFetcherWithURL(srv.URL, NewFileCheckpointStore(path)).Fetch(context.Background(), "BTCUSDT", "1m", start, end, collect)
+	if err != nil {
+		t.Fatalf("resumed Fetch: %v", err)
+	}
+	if handled != n-KlinePageLimit {
+		t.Errorf("resumed run handled %d klines, want %d", handled, n-KlinePageLimit)
+	}
+
+	if len(seen) != n {
+		t.Errorf("handled %d distinct klines, want %d", len(seen), n)
+	}
+	for open, count := range seen {
+		if count != 1 {
+			t.Errorf("kline at %s handled %d times", open, count)
+		}
+	}
+
+	// Once caught up, fetching again handles nothing
+	requests = 0
+	handled, err = NewKlineBatchFetcherWithURL(srv.URL, NewFileCheckpointStore(path)).Fetch(context.Background(), "BTCUSDT", "1m", start, end, collect)
+	if err != nil || handled != 0 {
+		t.Errorf("caught-up Fetch handled %d klines (%v), want 0", handled, err)
+	}
+}
+
+func TestFileCheckpointStore(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "checkpoints.json")
+	ctx := context.Background()
+	store := NewFileCheckpointStore(path)
+
+	if cp, err := store.Load(ctx, "BTCUSDT", "1m"); err != nil || cp != nil {
+		t.Fatalf("Load before any save = %+v, %v; want nil, nil", cp, err)
+	}
+
+	saves := []Checkpoint{
+		{Symbol: "BTCUSDT", Timeframe: "1m", LastCloseTime: 1000},
+		{Symbol: "BTCUSDT", Timeframe: "1h", LastCloseTime: 2000},
+		{Symbol: "BTCUSDT", Timeframe: "1m", LastCloseTime: 3000}, // Replaces the first
+	}
+	for _, cp := range saves {
+		if err := store.Save(ctx, cp); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	reopened := NewFileCheckpointStore(path)
+	for _, want := range saves[1:] {
+		cp, err := reopened.Load(ctx, want.Symbol, want.Timeframe)
+		if err != nil || cp == nil || *cp != want {
+			t.Errorf("Load(%s, %s) = %+v, %v; want %+v", want.Symbol, want.Timeframe, cp, err, want)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("checkpoint dir holds %d files, want only the checkpoint file", len(entries))
+	}
+
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := reopened.Load(ctx, "BTCUSDT", "1m"); err == nil {
+		t.Error("Load of a corrupt checkpoint file succeeded")
+	}
+}
@@ -0,0 +1,126 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+const (
+	// DefaultFuturesBaseURL is the Binance USDⓈ-M futures REST endpoint
+	DefaultFuturesBaseURL = "https://fapi.binance.com"
+
+	// fundingRatePageLimit is the maximum number of rows returned per fundingRate request
+	fundingRatePageLimit = 1000
+)
+
+// FundingRate is a perpetual funding rate settlement
+type FundingRate = model.FundingRate
+
+// FundingRateProvider fetches perpetual futures funding rate history from Binance
+type FundingRateProvider struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewFundingRateProvider creates a funding rate provider against the public Binance futures API
+func NewFundingRateProvider() *FundingRateProvider {
+	return NewFundingRateProviderWithURL(DefaultFuturesBaseURL)
+}
+
+// NewFundingRateProviderWithURL creates a funding rate provider against a custom base URL
+func NewFundingRateProviderWithURL(baseURL string) *FundingRateProvider {
+	return &FundingRateProvider{
+		baseURL:    baseURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// fundingRateResponse mirrors a single row of the fundingRate endpoint
+type fundingRateResponse struct {
+	Symbol      string `json:"symbol"`
+	FundingTime int64  `json:"fundingTime"`
+	FundingRate string `json:"fundingRate"`
+}
+
+// FetchFundingRates retrieves funding rates within [start, end], following pages as needed
+// Returns rates ordered by funding time (oldest first)
+func (p *FundingRateProvider) FetchFundingRates(ctx context.Context, symbol string, start, end time.Time) ([]FundingRate, error) {
+	var rates []FundingRate
+	cursor := start.UnixMilli()
+	endMs := end.UnixMilli()
+
+	for cursor <= endMs {
+		page, err := p.fetchPage(ctx, symbol, cursor, endMs)
+		if err != nil {
+			return nil, err
+		}
+		if len(page) == 0 {
+			break
+		}
+
+		rates = append(rates, page...)
+
+		if len(page) < fundingRatePageLimit {
+			break
+		}
+		cursor = page[len(page)-1].FundingTime.UnixMilli() + 1
+	}
+
+	return rates, nil
+}
+
+// fetchPage fetches a single page of funding rates starting at startMs
+func (p *FundingRateProvider) fetchPage(ctx context.Context, symbol string, startMs, endMs int64) ([]FundingRate, error) {
+	params := url.Values{}
+	params.Set("symbol", symbol)
+	params.Set("startTime", strconv.FormatInt(startMs, 10))
+	params.Set("endTime", strconv.FormatInt(endMs, 10))
+	params.Set("limit", strconv.Itoa(fundingRatePageLimit))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/fapi/v1/fundingRate?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch funding rates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("funding rate request failed: %s: %s", resp.Status, string(body))
+	}
+
+	var rows []fundingRateResponse
+	if err := json.Unmarshal(body, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse funding rates: %w", err)
+	}
+
+	rates := make([]FundingRate, 0, len(rows))
+	for _, row := range rows {
+		rate, err := strconv.ParseFloat(row.FundingRate, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid funding rate %q: %w", row.FundingRate, err)
+		}
+		rates = append(rates, FundingRate{
+			Symbol:      row.Symbol,
+			FundingTime: time.UnixMilli(row.FundingTime),
+			Rate:        rate,
+		})
+	}
+
+	return rates, nil
+}
@@ -0,0 +1,81 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fundingServer serves n funding rates, one every 8 hours from start, honoring startTime, endTime and limit
+func fundingServer(t *testing.T, start time.Time, n int, requests *int) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if r.URL.Path != "/fapi/v1/fundingRate" {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query()
+		startMs, _ := strconv.ParseInt(q.Get("startTime"), 10, 64)
+		endMs, _ := strconv.ParseInt(q.Get("endTime"), 10, 64)
+		limit, _ := strconv.Atoi(q.Get("limit"))
+
+		var rows []fundingRateResponse
+		for i := 0; i < n && len(rows) < limit; i++ {
+			ms := start.Add(time.Duration(i) * 8 * time.Hour).UnixMilli()
+			if ms < startMs || ms > endMs {
+				continue
+			}
+			rows = append(rows, fundingRateResponse{
+				Symbol:      q.Get("symbol"),
+				FundingTime: ms,
+				FundingRate: strconv.FormatFloat(float64(i)*1e-6, 'f', -1, 64),
+			})
+		}
+		json.NewEncoder(w).Encode(rows)
+	}))
+}
+
+func TestFetchFundingRatesPaginates(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	requests := 0
+	srv := fundingServer(t, start, 1500, &requests)
+	defer srv.Close()
+
+	p := NewFundingRateProviderWithURL(srv.URL)
+	rates, err := p.FetchFundingRates(context.Background(), "BTCUSDT", start, start.Add(2000*8*time.Hour))
+	if err != nil {
+		t.Fatalf("FetchFundingRates: %v", err)
+	}
+	if len(rates) != 1500 {
+		t.Fatalf("got %d rates, want 1500", len(rates))
+	}
+	if requests != 2 {
+		t.Errorf("made %d requests, want 2 pages", requests)
+	}
+	for i, r := range rates {
+		if want := start.Add(time.Duration(i) * 8 * time.Hour); !r.FundingTime.Equal(want) {
+			t.Fatalf("rate %d at %s, want %s", i, r.FundingTime, want)
+		}
+	}
+	if last := rates[1499]; last.Symbol != "BTCUSDT" || last.Rate != 1499e-6 {
+		t.Errorf("last rate = %+v, want BTCUSDT at 0.001499", last)
+	}
+}
+
+func TestFetchFundingRatesHTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"code":-1121,"msg":"Invalid symbol."}`, http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, err := NewFundingRateProviderWithURL(srv.URL).FetchFundingRates(context.Background(), "NOPE", start, start.Add(time.Hour))
+	if err == nil {
+		t.Fatal("FetchFundingRates succeeded against a 400 response")
+	}
+}
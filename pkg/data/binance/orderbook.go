@@ -0,0 +1,131 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+const (
+	// DefaultSpotBaseURL is the Binance spot REST endpoint
+	DefaultSpotBaseURL = "https://api.binance.com"
+
+	// orderBookDepthLimit is the number of levels requested per side
+	orderBookDepthLimit = 20
+)
+
+// OrderBookSnapshot summarizes the top order book levels
+type OrderBookSnapshot = model.OrderBookSnapshot
+
+// OrderBookSnapshotProvider fetches spot order book snapshots from Binance
+type OrderBookSnapshotProvider struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewOrderBookSnapshotProvider creates an order book provider against the public Binance spot API
+func NewOrderBookSnapshotProvider() *OrderBookSnapshotProvider {
+	return NewOrderBookSnapshotProviderWithURL(DefaultSpotBaseURL)
+}
+
+// NewOrderBookSnapshotProviderWithURL creates an order book provider against a custom base URL
+func NewOrderBookSnapshotProviderWithURL(baseURL string) *OrderBookSnapshotProvider {
+	return &OrderBookSnapshotProvider{
+		baseURL:    baseURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// depthResponse mirrors the depth endpoint; each level is a [price, quantity] string pair
+type depthResponse struct {
+	LastUpdateID int64       `json:"lastUpdateId"`
+	Bids         [][2]string `json:"bids"`
+	Asks         [][2]string `json:"asks"`
+}
+
+// FetchSnapshot retrieves the top 20 levels of symbol's order book
+// The depth endpoint carries no timestamp, so the snapshot is stamped with the local time of the response
+func (p *OrderBookSnapshotProvider) FetchSnapshot(ctx context.Context, symbol string) (*OrderBookSnapshot, error) {
+	params := url.Values{}
+	params.Set("symbol", symbol)
+	params.Set("limit", strconv.Itoa(orderBookDepthLimit))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/api/v3/depth?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch order book: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("order book request failed: %s: %s", resp.Status, string(body))
+	}
+
+	var depth depthResponse
+	if err := json.Unmarshal(body, &depth); err != nil {
+		return nil, fmt.Errorf("failed to parse order book: %w", err)
+	}
+
+	return parseDepth(symbol, time.Now(), &depth)
+}
+
+// parseDepth summarizes a depth response into a snapshot
+func parseDepth(symbol string, ts time.Time, depth *depthResponse) (*OrderBookSnapshot, error) {
+	if len(depth.Bids) == 0 || len(depth.Asks) == 0 {
+		return nil, fmt.Errorf("order book for %s has an empty side", symbol)
+	}
+
+	bidPrice, bidDepth, err := sumLevels(depth.Bids)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bid level: %w", err)
+	}
+	askPrice, askDepth, err := sumLevels(depth.Asks)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ask level: %w", err)
+	}
+
+	return &OrderBookSnapshot{
+		Symbol:     symbol,
+		Timestamp:  ts,
+		BidPrice:   bidPrice,
+		AskPrice:   askPrice,
+		MidPrice:   (bidPrice + askPrice) / 2,
+		Spread:     askPrice - bidPrice,
+		BidDepth20: bidDepth,
+		AskDepth20: askDepth,
+	}, nil
+}
+
+// sumLevels returns the best (first) price of levels and their total quantity
+func sumLevels(levels [][2]string) (best, quantity float64, err error) {
+	for i, level := range levels {
+		price, err := strconv.ParseFloat(level[0], 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("price %q: %w", level[0], err)
+		}
+		qty, err := strconv.ParseFloat(level[1], 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("quantity %q: %w", level[1], err)
+		}
+		if i == 0 {
+			best = price
+		}
+		quantity += qty
+	}
+	return best, quantity, nil
+}
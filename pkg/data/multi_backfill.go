@@ -0,0 +1,220 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+	"github.com/tunogya/etna/pkg/store/duckdb"
+)
+
+// BackfillState is the lifecycle state of a single symbol's backfill
+type BackfillState string
+
+const (
+	BackfillPending BackfillState = "pending"
+	BackfillRunning BackfillState = "running"
+	BackfillDone    BackfillState = "done"
+	BackfillFailed  BackfillState = "failed"
+)
+
+// BackfillStatus reports the progress of one symbol/timeframe series in a MultiSymbolBackfiller
+type BackfillStatus struct {
+	Symbol     string
+	Timeframe  string
+	State      BackfillState
+	Candles    int // Candles stored so far
+	Err        error
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// MultiSymbolBackfiller ingests candles for several symbols concurrently
+// Each worker processes one symbol/timeframe series at a time; status is tracked per series
+type MultiSymbolBackfiller struct {
+	configs  []BackfillConfig
+	provider CandleProvider
+	repo     *duckdb.CandleRepo
+	workers  int
+
+	mu        sync.RWMutex
+	StatusMap map[string]BackfillStatus // Keyed by statusKey(symbol, timeframe)
+}
+
+// statusKey returns the StatusMap key of a symbol/timeframe series
+func statusKey(symbol, timeframe string) string {
+	return symbol + "/" + timeframe
+}
+
+// NewMultiSymbolBackfiller creates a backfiller running configs across workers goroutines
+// Configs repeating a symbol/timeframe series run once, with the first such config
+func NewMultiSymbolBackfiller(configs []BackfillConfig, provider CandleProvider, repo *duckdb.CandleRepo, workers int) *MultiSymbolBackfiller {
+	if workers < 1 {
+		workers = 1
+	}
+
+	status := make(map[string]BackfillStatus, len(configs))
+	unique := make([]BackfillConfig, 0, len(configs))
+	for _, c := range configs {
+		key := statusKey(c.Symbol, c.Timeframe)
+		if _, ok := status[key]; ok {
+			continue
+		}
+		status[key] = BackfillStatus{Symbol: c.Symbol, Timeframe: c.Timeframe, State: BackfillPending}
+		unique = append(unique, c)
+	}
+
+	return &MultiSymbolBackfiller{
+		configs:   unique,
+		provider:  provider,
+		repo:      repo,
+		workers:   workers,
+		StatusMap: status,
+	}
+}
+
+// GetStatus returns the current status of the symbol/timeframe series
+func (b *MultiSymbolBackfiller) GetStatus(symbol, timeframe string) BackfillStatus {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.StatusMap[statusKey(symbol, timeframe)]
+}
+
+// Statuses returns a snapshot of every series' status, ordered by symbol and timeframe
+func (b *MultiSymbolBackfiller) Statuses() []BackfillStatus {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	statuses := make([]BackfillStatus, 0, len(b.StatusMap))
+	for _, s := range b.StatusMap {
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Symbol != statuses[j].Symbol {
+			return statuses[i].Symbol < statuses[j].Symbol
+		}
+		return statuses[i].Timeframe < statuses[j].Timeframe
+	})
+	return statuses
+}
+
+// Run backfills every configured series and blocks until all finish
+// A failing series does not stop the others; their errors are joined in the result
+func (b *MultiSymbolBackfiller) Run(ctx context.Context) error {
+	jobs := make(chan BackfillConfig)
+
+	var wg sync.WaitGroup
+	for i := 0; i < b.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for cfg := range jobs {
+				b.runSymbol(ctx, cfg)
+			}
+		}()
+	}
+
+	for _, cfg := range b.configs {
+		select {
+		case jobs <- cfg:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	var errs []error
+	for _, s := range b.Statuses() {
+		if s.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", statusKey(s.Symbol, s.Timeframe), s.Err))
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// runSymbol fetches and stores one series' candles, updating its status throughout
+func (b *MultiSymbolBackfiller) runSymbol(ctx context.Context, cfg BackfillConfig) {
+	b.update(cfg, func(s *BackfillStatus) {
+		s.State = BackfillRunning
+		s.StartedAt = time.Now()
+	})
+
+	err := b.backfill(ctx, cfg)
+
+	b.update(cfg, func(s *BackfillStatus) {
+		s.FinishedAt = time.Now()
+		if err != nil {
+			s.State = BackfillFailed
+			s.Err = err
+			return
+		}
+		s.State = BackfillDone
+	})
+}
+
+// backfill fetches cfg's range with retries and inserts it in BatchSize chunks
+func (b *MultiSymbolBackfiller) backfill(ctx context.Context, cfg BackfillConfig) error {
+	candles, err := b.fetchWithRetry(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	batchSize := cfg.BatchSize
+	if batchSize < 1 {
+		batchSize = len(candles)
+	}
+	for start := 0; start < len(candles); start += batchSize {
+		end := start + batchSize
+		if end > len(candles) {
+			end = len(candles)
+		}
+		if err := b.repo.InsertBatch(ctx, candles[start:end]); err != nil {
+			return fmt.Errorf("failed to store candles: %w", err)
+		}
+		stored := end
+		b.update(cfg, func(s *BackfillStatus) {
+			s.Candles = stored
+		})
+	}
+
+	return nil
+}
+
+// fetchWithRetry fetches cfg's range, retrying up to RetryAttempts times
+func (b *MultiSymbolBackfiller) fetchWithRetry(ctx context.Context, cfg BackfillConfig) ([]model.Candle, error) {
+	var lastErr error
+	for attempt := 0; attempt <= cfg.RetryAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(cfg.RetryDelay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		candles, err := b.provider.FetchCandles(ctx, cfg.Symbol, cfg.Timeframe, cfg.StartTime, cfg.EndTime)
+		if err == nil {
+			return candles, nil
+		}
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("failed to fetch candles after %d attempts: %w", cfg.RetryAttempts+1, lastErr)
+}
+
+// update applies fn to the status of cfg's series under the lock
+func (b *MultiSymbolBackfiller) update(cfg BackfillConfig, fn func(s *BackfillStatus)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key := statusKey(cfg.Symbol, cfg.Timeframe)
+	s := b.StatusMap[key]
+	fn(&s)
+	b.StatusMap[key] = s
+}
@@ -0,0 +1,157 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+	"github.com/tunogya/etna/pkg/store/duckdb"
+)
+
+// newTestCandleRepo returns a candle repo over an in-memory DuckDB with the schema initialized
+func newTestCandleRepo(t testing.TB) *duckdb.CandleRepo {
+	t.Helper()
+	client, err := duckdb.NewClient("")
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	if err := duckdb.InitializeSchema(client); err != nil {
+		t.Fatalf("InitializeSchema: %v", err)
+	}
+	return duckdb.NewCandleRepo(client)
+}
+
+// mockProvider serves every bar of [start, end) for any symbol and timeframe
+// Each series fails its first failures fetches, and symbols in failing always fail
+type mockProvider struct {
+	failures int
+	failing  map[string]bool
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (p *mockProvider) FetchCandles(ctx context.Context, symbol, timeframe string, start, end time.Time) ([]model.Candle, error) {
+	p.mu.Lock()
+	key := symbol + "/" + timeframe
+	if p.calls == nil {
+		p.calls = make(map[string]int)
+	}
+	p.calls[key]++
+	call := p.calls[key]
+	p.mu.Unlock()
+
+	if p.failing[symbol] || call <= p.failures {
+		return nil, fmt.Errorf("%s unavailable", key)
+	}
+
+	bar, err := model.TimeframeDuration(timeframe)
+	if err != nil {
+		return nil, err
+	}
+	var candles []model.Candle
+	for open := start; open.Before(end); open = open.Add(bar) {
+		candles = append(candles, model.Candle{
+			Symbol:    symbol,
+			Timeframe: timeframe,
+			OpenTime:  open,
+			CloseTime: open.Add(bar - time.Millisecond),
+			Open:      100,
+			High:      101,
+			Low:       99,
+			Close:     100,
+		})
+	}
+	return candles, nil
+}
+
+func (p *mockProvider) FetchLatestCandles(ctx context.Context, symbol, timeframe string, limit int) ([]model.Candle, error) {
+	return nil, errors.New("not supported")
+}
+
+func TestMultiSymbolBackfillerFiveSymbols(t *testing.T) {
+	repo := newTestCandleRepo(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	symbols := []string{"ADAUSDT", "BNBUSDT", "BTCUSDT", "ETHUSDT", "SOLUSDT"}
+	var configs []BackfillConfig
+	for _, symbol := range symbols {
+		cfg := DefaultBackfillConfig(symbol, "1h")
+		cfg.StartTime = start
+		cfg.EndTime = start.Add(30 * time.Hour)
+		cfg.BatchSize = 7
+		cfg.RetryDelay = time.Millisecond
+		configs = append(configs, cfg)
+	}
+
+	// Every series fails its first fetch and recovers on retry
+	provider := &mockProvider{failures: 1}
+	b := NewMultiSymbolBackfiller(configs, provider, repo, 3)
+	if err := b.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	statuses := b.Statuses()
+	if len(statuses) != len(symbols) {
+		t.Fatalf("got %d statuses, want %d", len(statuses), len(symbols))
+	}
+	for i, s := range statuses {
+		if s.Symbol != symbols[i] || s.State != BackfillDone || s.Candles != 30 || s.Err != nil {
+			t.Errorf("status %d = %+v, want %s done with 30 candles", i, s, symbols[i])
+		}
+		if s.StartedAt.IsZero() || s.FinishedAt.Before(s.StartedAt) {
+			t.Errorf("%s ran from %s to %s", s.Symbol, s.StartedAt, s.FinishedAt)
+		}
+		n, err := repo.Count(context.Background(), s.Symbol, "1h")
+		if err != nil {
+			t.Fatalf("Count: %v", err)
+		}
+		if n != 30 {
+			t.Errorf("%s stored %d candles, want 30", s.Symbol, n)
+		}
+	}
+}
+
+func TestMultiSymbolBackfillerTracksSeriesSeparately(t *testing.T) {
+	repo := newTestCandleRepo(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var configs []BackfillConfig
+	for _, c := range []struct {
+		symbol, timeframe string
+	}{{"BTCUSDT", "1h"}, {"BTCUSDT", "4h"}, {"ETHUSDT", "1h"}, {"BTCUSDT", "1h"}} {
+		cfg := DefaultBackfillConfig(c.symbol, c.timeframe)
+		cfg.StartTime = start
+		cfg.EndTime = start.Add(24 * time.Hour)
+		cfg.RetryAttempts = 0
+		configs = append(configs, cfg)
+	}
+
+	provider := &mockProvider{failing: map[string]bool{"ETHUSDT": true}}
+	b := NewMultiSymbolBackfiller(configs, provider, repo, 2)
+	err := b.Run(context.Background())
+	if err == nil {
+		t.Fatal("Run succeeded with a failing series")
+	}
+
+	if got := len(b.Statuses()); got != 3 {
+		t.Errorf("got %d statuses, want 3 distinct series", got)
+	}
+	if s := b.GetStatus("BTCUSDT", "1h"); s.State != BackfillDone || s.Candles != 24 {
+		t.Errorf("BTCUSDT/1h = %s with %d candles, want done with 24", s.State, s.Candles)
+	}
+	if s := b.GetStatus("BTCUSDT", "4h"); s.State != BackfillDone || s.Candles != 6 {
+		t.Errorf("BTCUSDT/4h = %s with %d candles, want done with 6", s.State, s.Candles)
+	}
+	if s := b.GetStatus("ETHUSDT", "1h"); s.State != BackfillFailed || s.Err == nil {
+		t.Errorf("ETHUSDT/1h = %s (%v), want failed", s.State, s.Err)
+	}
+	if provider.calls["BTCUSDT/1h"] != 1 {
+		t.Errorf("BTCUSDT/1h fetched %d times, want once", provider.calls["BTCUSDT/1h"])
+	}
+}
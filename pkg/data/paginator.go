@@ -0,0 +1,64 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// Paginator follows the pages of a BatchCandleProvider across a time range
+type Paginator struct {
+	provider BatchCandleProvider
+}
+
+// NewPaginator creates a paginator over provider
+func NewPaginator(provider BatchCandleProvider) *Paginator {
+	return &Paginator{provider: provider}
+}
+
+// Paginate sends the candles of symbol/timeframe opening within [start, end) on out, one batch per page, and
+// closes out when it returns. It follows each page's token until the next start reaches end or the provider
+// returns no token. Returns the number of candles sent
+func (p *Paginator) Paginate(ctx context.Context, symbol, timeframe string, start, end time.Time, out chan<- []model.Candle) (int, error) {
+	defer close(out)
+
+	sent := 0
+	for start.Before(end) {
+		batch, token, err := p.provider.FetchCandleBatch(ctx, symbol, timeframe, start)
+		if err != nil {
+			return sent, fmt.Errorf("failed to fetch candles from %s: %w", start.Format(time.RFC3339), err)
+		}
+
+		// The last page may run past end
+		n := len(batch)
+		for n > 0 && !batch[n-1].OpenTime.Before(end) {
+			n--
+		}
+		if n > 0 {
+			select {
+			case out <- batch[:n]:
+				sent += n
+			case <-ctx.Done():
+				return sent, ctx.Err()
+			}
+		}
+
+		if token == "" {
+			break
+		}
+		nextMs, err := strconv.ParseInt(token, 10, 64)
+		if err != nil {
+			return sent, fmt.Errorf("invalid page token %q: %w", token, err)
+		}
+		next := time.UnixMilli(nextMs)
+		if !next.After(start) {
+			return sent, fmt.Errorf("page token %q does not advance past %s", token, start.Format(time.RFC3339))
+		}
+		start = next
+	}
+
+	return sent, nil
+}
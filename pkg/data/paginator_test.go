@@ -0,0 +1,130 @@
+package data
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// pagedProvider serves candles in pages of pageSize, recording the start of every page requested
+type pagedProvider struct {
+	candles  []model.Candle
+	pageSize int
+
+	mu     sync.Mutex
+	starts []time.Time
+}
+
+func (p *pagedProvider) FetchCandleBatch(ctx context.Context, symbol, timeframe string, startTime time.Time) ([]model.Candle, string, error) {
+	p.mu.Lock()
+	p.starts = append(p.starts, startTime)
+	p.mu.Unlock()
+
+	i := 0
+	for i < len(p.candles) && p.candles[i].OpenTime.Before(startTime) {
+		i++
+	}
+	end := min(i+p.pageSize, len(p.candles))
+	if end == len(p.candles) {
+		return p.candles[i:end], "", nil
+	}
+	return p.candles[i:end], strconv.FormatInt(p.candles[end].OpenTime.UnixMilli(), 10), nil
+}
+
+// newPagedProvider creates a provider of n consecutive 1m candles from start in pages of pageSize
+func newPagedProvider(start time.Time, n, pageSize int) *pagedProvider {
+	candles := make([]model.Candle, n)
+	for i := range candles {
+		open := start.Add(time.Duration(i) * time.Minute)
+		candles[i] = model.Candle{Symbol: "BTCUSDT", Timeframe: "1m", OpenTime: open, CloseTime: open.Add(time.Minute), Close: float64(100 + i)}
+	}
+	return &pagedProvider{candles: candles, pageSize: pageSize}
+}
+
+// paginate runs Paginate over [start, end) and collects the batches it sends
+func paginate(t *testing.T, provider BatchCandleProvider, start, end time.Time) ([][]model.Candle, int) {
+	t.Helper()
+	out := make(chan []model.Candle)
+	var batches [][]model.Candle
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for batch := range out {
+			batches = append(batches, batch)
+		}
+	}()
+
+	sent, err := NewPaginator(provider).Paginate(context.Background(), "BTCUSDT", "1m", start, end, out)
+	<-done
+	if err != nil {
+		t.Fatalf("Paginate: %v", err)
+	}
+	return batches, sent
+}
+
+func TestPaginatorFollowsThreePages(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	provider := newPagedProvider(start, 15, 5)
+
+	batches, sent := paginate(t, provider, start, start.Add(time.Hour))
+	if sent != 15 {
+		t.Errorf("sent %d candles, want 15", sent)
+	}
+	if len(batches) != 3 {
+		t.Fatalf("got %d batches, want 3", len(batches))
+	}
+
+	// Pages arrive in order, each picking up where the previous one's token pointed
+	want := start
+	for i, batch := range batches {
+		if len(batch) != 5 {
+			t.Errorf("batch %d holds %d candles, want 5", i, len(batch))
+		}
+		for _, c := range batch {
+			if !c.OpenTime.Equal(want) {
+				t.Fatalf("batch %d: candle at %s, want %s", i, c.OpenTime, want)
+			}
+			want = want.Add(time.Minute)
+		}
+	}
+
+	// The third page's empty token ends pagination without another request
+	if len(provider.starts) != 3 {
+		t.Errorf("provider served %d pages, want 3", len(provider.starts))
+	}
+	for i, s := range provider.starts {
+		if want := start.Add(time.Duration(5*i) * time.Minute); !s.Equal(want) {
+			t.Errorf("page %d requested from %s, want %s", i, s, want)
+		}
+	}
+}
+
+func TestPaginatorTrimsPastEnd(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	provider := newPagedProvider(start, 15, 5)
+
+	// end falls inside the second page, so its tail is dropped and the third page is never fetched
+	batches, sent := paginate(t, provider, start, start.Add(7*time.Minute))
+	if sent != 7 {
+		t.Errorf("sent %d candles, want 7", sent)
+	}
+	if len(batches) != 2 || len(batches[1]) != 2 {
+		t.Errorf("got batches %v, want 5 then 2 candles", batchSizes(batches))
+	}
+	if len(provider.starts) != 2 {
+		t.Errorf("provider served %d pages, want 2", len(provider.starts))
+	}
+}
+
+// batchSizes returns the number of candles in each batch
+func batchSizes(batches [][]model.Candle) []int {
+	sizes := make([]int, len(batches))
+	for i, b := range batches {
+		sizes[i] = len(b)
+	}
+	return sizes
+}
@@ -0,0 +1,162 @@
+package data
+
+import (
+	"sync"
+	"time"
+)
+
+// StageTiming records how long a backfill stage took
+type StageTiming struct {
+	Stage    string
+	Duration time.Duration
+}
+
+// ProgressTracker counts backfill work and reports it, with rates and ETA, to a ProgressCallback on an interval
+// Rates, Percent, and ETA cover the current stage; the counters are totals since the tracker was created
+type ProgressTracker struct {
+	mu       sync.Mutex
+	callback ProgressCallback
+	interval time.Duration
+	started  time.Time
+	progress BackfillProgress
+	stages   []StageTiming
+	stop     chan struct{}
+	done     chan struct{}
+
+	// Current stage
+	stageStarted time.Time
+	stageCandles int64 // ProcessedCandles when the stage started
+	stageWindows int64
+	stageRows    int64
+}
+
+// NewProgressTracker creates a tracker reporting to callback every interval once started
+func NewProgressTracker(interval time.Duration, callback ProgressCallback) *ProgressTracker {
+	now := time.Now()
+	return &ProgressTracker{
+		callback: callback,
+		interval: interval,
+		started:  now,
+		progress: BackfillProgress{StartTime: now},
+	}
+}
+
+// Start begins periodic reporting; it does nothing if the interval is not positive
+func (t *ProgressTracker) Start() {
+	if t.interval <= 0 || t.callback == nil {
+		return
+	}
+	t.stop = make(chan struct{})
+	t.done = make(chan struct{})
+	go func() {
+		defer close(t.done)
+		ticker := time.NewTicker(t.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				t.callback(t.Snapshot())
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the current stage and periodic reporting, and returns the timing of every stage
+func (t *ProgressTracker) Stop() []StageTiming {
+	if t.stop != nil {
+		close(t.stop)
+		<-t.done
+		t.stop = nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.endStage(time.Now())
+	return append([]StageTiming(nil), t.stages...)
+}
+
+// StartStage ends the current stage and begins stage with total units of work, 0 if unknown
+func (t *ProgressTracker) StartStage(stage string, total int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.endStage(now)
+	t.progress.Stage = stage
+	t.progress.StageTotal = total
+	t.progress.StageDone = 0
+	t.stageStarted = now
+	t.stageCandles = t.progress.ProcessedCandles
+	t.stageWindows = t.progress.ProcessedWindows
+	t.stageRows = t.progress.MilvusRows
+}
+
+// endStage records the timing of the current stage, if any
+func (t *ProgressTracker) endStage(now time.Time) {
+	if t.progress.Stage == "" {
+		return
+	}
+	t.stages = append(t.stages, StageTiming{Stage: t.progress.Stage, Duration: now.Sub(t.stageStarted)})
+	t.progress.Stage = ""
+	t.progress.StageTotal = 0
+	t.progress.StageDone = 0
+}
+
+// Advance marks n units of the current stage done
+func (t *ProgressTracker) Advance(n int64) {
+	t.mu.Lock()
+	t.progress.StageDone += n
+	t.mu.Unlock()
+}
+
+// AddCandles counts n stored candles
+func (t *ProgressTracker) AddCandles(n int64) {
+	t.mu.Lock()
+	t.progress.ProcessedCandles += n
+	t.mu.Unlock()
+}
+
+// AddWindows counts n stored windows
+func (t *ProgressTracker) AddWindows(n int64) {
+	t.mu.Lock()
+	t.progress.ProcessedWindows += n
+	t.mu.Unlock()
+}
+
+// AddMilvusRows counts n vectors written to Milvus
+func (t *ProgressTracker) AddMilvusRows(n int64) {
+	t.mu.Lock()
+	t.progress.MilvusRows += n
+	t.mu.Unlock()
+}
+
+// Snapshot returns the current progress with rates and ETA filled in
+func (t *ProgressTracker) Snapshot() BackfillProgress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	p := t.progress
+	p.CurrentTime = now
+	p.Elapsed = now.Sub(t.started)
+	if p.Stage == "" {
+		return p
+	}
+
+	secs := now.Sub(t.stageStarted).Seconds()
+	if secs > 0 {
+		p.CandlesPerSec = float64(p.ProcessedCandles-t.stageCandles) / secs
+		p.WindowsPerSec = float64(p.ProcessedWindows-t.stageWindows) / secs
+		p.MilvusRowsPerSec = float64(p.MilvusRows-t.stageRows) / secs
+	}
+	if p.StageTotal > 0 {
+		p.Percent = 100 * float64(p.StageDone) / float64(p.StageTotal)
+		if p.StageDone > 0 && secs > 0 {
+			remaining := float64(p.StageTotal-p.StageDone) / (float64(p.StageDone) / secs)
+			p.ETA = time.Duration(remaining * float64(time.Second))
+		}
+	}
+	return p
+}
@@ -17,6 +17,13 @@ type CandleProvider interface {
 	FetchLatestCandles(ctx context.Context, symbol, timeframe string, limit int) ([]model.Candle, error)
 }
 
+// BatchCandleProvider defines the interface for sources that return fixed-size pages with a continuation token
+type BatchCandleProvider interface {
+	// FetchCandleBatch retrieves one page of candles opening at or after startTime, ordered by time (oldest first)
+	// nextPageToken is the start time of the following page in Unix milliseconds, empty when there are no more candles
+	FetchCandleBatch(ctx context.Context, symbol, timeframe string, startTime time.Time) ([]model.Candle, string, error)
+}
+
 // StreamProvider defines the interface for real-time candle subscription
 type StreamProvider interface {
 	// Subscribe starts a real-time subscription to K-line data
@@ -64,6 +71,19 @@ type BackfillProgress struct {
 	StartTime        time.Time
 	EndTime          time.Time
 	Errors           []error
+
+	// Filled in by ProgressTracker
+	Stage            string        // Current stage, empty once stopped
+	StageTotal       int64         // Units of work in the current stage, 0 if unknown
+	StageDone        int64         // Units of work of the current stage done
+	ProcessedWindows int64         // Windows stored
+	MilvusRows       int64         // Vectors written to Milvus
+	Elapsed          time.Duration // Since the tracker was created
+	CandlesPerSec    float64       // Over the current stage
+	WindowsPerSec    float64       // Over the current stage
+	MilvusRowsPerSec float64       // Over the current stage
+	Percent          float64       // Of the current stage, 0 if StageTotal is unknown
+	ETA              time.Duration // Until the current stage completes at its rate so far, 0 if unknown
 }
 
 // ProgressCallback is called during backfill to report progress
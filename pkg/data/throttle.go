@@ -0,0 +1,69 @@
+package data
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/tunogya/etna/pkg/data/binance"
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// binanceWeightPerMinute is Binance's REST request weight limit per minute
+const binanceWeightPerMinute = 1200
+
+// ThrottledProvider wraps a CandleProvider and rate-limits its requests
+type ThrottledProvider struct {
+	inner   CandleProvider
+	limiter *rate.Limiter
+	weight  func(limit int) int // Limiter tokens a request of limit candles costs; 0 means a full page
+}
+
+// NewThrottledProvider creates a provider allowing at most requestsPerSecond calls to inner
+// The burst equals the per-second rate (at least 1), so idle time does not accumulate a large backlog
+func NewThrottledProvider(inner CandleProvider, requestsPerSecond float64) *ThrottledProvider {
+	burst := int(math.Max(1, math.Floor(requestsPerSecond)))
+	return &ThrottledProvider{
+		inner:   inner,
+		limiter: rate.NewLimiter(rate.Limit(requestsPerSecond), burst),
+		weight:  func(int) int { return 1 },
+	}
+}
+
+// NewBinanceThrottledProvider wraps inner with Binance's REST request weight limit
+// The limiter counts request weight rather than requests: FetchCandles is charged a full page of
+// binance.KlinePageLimit klines, FetchLatestCandles the weight of its limit
+func NewBinanceThrottledProvider(inner CandleProvider) *ThrottledProvider {
+	p := NewThrottledProvider(inner, float64(binanceWeightPerMinute)/60)
+	p.weight = func(limit int) int {
+		if limit <= 0 {
+			limit = binance.KlinePageLimit
+		}
+		return binance.KlineWeight(limit)
+	}
+	return p
+}
+
+// wait blocks until the limiter admits a request of limit candles, 0 meaning a full page
+// A weight above the burst is capped at it, as the limiter would otherwise reject the request outright
+func (p *ThrottledProvider) wait(ctx context.Context, limit int) error {
+	return p.limiter.WaitN(ctx, min(p.weight(limit), p.limiter.Burst()))
+}
+
+// FetchCandles waits for the rate limiter before delegating to the wrapped provider
+func (p *ThrottledProvider) FetchCandles(ctx context.Context, symbol, timeframe string, start, end time.Time) ([]model.Candle, error) {
+	if err := p.wait(ctx, 0); err != nil {
+		return nil, err
+	}
+	return p.inner.FetchCandles(ctx, symbol, timeframe, start, end)
+}
+
+// FetchLatestCandles waits for the rate limiter before delegating to the wrapped provider
+func (p *ThrottledProvider) FetchLatestCandles(ctx context.Context, symbol, timeframe string, limit int) ([]model.Candle, error) {
+	if err := p.wait(ctx, limit); err != nil {
+		return nil, err
+	}
+	return p.inner.FetchLatestCandles(ctx, symbol, timeframe, limit)
+}
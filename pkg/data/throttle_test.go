@@ -0,0 +1,44 @@
+package data
+
+import (
+	"testing"
+
+	"golang.org/x/time/rate"
+)
+
+func TestBinanceThrottledProviderWeights(t *testing.T) {
+	p := NewBinanceThrottledProvider(nil)
+	if got, want := p.limiter.Limit(), rate.Limit(binanceWeightPerMinute/60); got != want {
+		t.Errorf("limiter rate = %v weight/s, want %v", got, want)
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		want  int
+	}{
+		{"FetchCandles full page", 0, 5},
+		{"latest 50", 50, 1},
+		{"latest 200", 200, 2},
+		{"latest 1000", 1000, 5},
+	}
+	for _, tt := range tests {
+		if got := p.weight(tt.limit); got != tt.want {
+			t.Errorf("%s: weight = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	// 1200 weight per minute admits 240 full pages, not the 600 a weight of 2 would
+	if pages := binanceWeightPerMinute / p.weight(0); pages != 240 {
+		t.Errorf("full pages per minute = %d, want 240", pages)
+	}
+}
+
+func TestThrottledProviderCountsRequests(t *testing.T) {
+	p := NewThrottledProvider(nil, 5)
+	for _, limit := range []int{0, 1, 1000} {
+		if got := p.weight(limit); got != 1 {
+			t.Errorf("weight(%d) = %d, want 1", limit, got)
+		}
+	}
+}
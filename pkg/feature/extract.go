@@ -6,22 +6,98 @@ import (
 	"github.com/tunogya/etna/pkg/model"
 )
 
+// NormalizationStrategy selects how shape vector series are scaled to [-1, 1]
+// Changing it changes the embeddings, so stored vectors should be rebuilt under a new feature version
+type NormalizationStrategy int
+
+const (
+	// ClipZScore clips z-scores at ±ClipStd and divides by ClipStd
+	ClipZScore NormalizationStrategy = iota
+	// Winsorize caps values at the WinsorLower/WinsorUpper percentiles of the window, then rescales them
+	Winsorize
+)
+
+// NewestCandlesDataVersion is the minimum data version of vectors built from the newest VectorDim/4 candles
+// Older vectors averaged longer windows down to that length, so the two are not comparable
+const NewestCandlesDataVersion = 2
+
+// RecencyBiasDataVersion is the minimum data version of vectors built with a RecencyBias
+const RecencyBiasDataVersion = 3
+
+// Default Winsorize percentiles
+const (
+	defaultWinsorLower = 1.0
+	defaultWinsorUpper = 99.0
+)
+
 // Extractor extracts features from windows
 type Extractor struct {
-	DataVersion int
-	VectorDim   int     // Target dimension for ShapeVector (96 or 128)
-	ClipStd     float64 // Standard deviations for clipping (default 3.0)
+	DataVersion   int
+	VectorDim     int     // Target dimension for ShapeVector (96 or 128)
+	ClipStd       float64 // Standard deviations for clipping (default 3.0)
+	Normalization NormalizationStrategy
+	WinsorLower   float64 // Lower percentile (0-100) for Winsorize (default 1)
+	WinsorUpper   float64 // Upper percentile (0-100) for Winsorize (default 99)
+	RecencyBias   float64 // Share of vector slots (0-1) spent on the newest candles; 0 keeps the newest VectorDim/4
+}
+
+// ExtractorConfig holds feature extractor configuration
+type ExtractorConfig struct {
+	DataVersion   int
+	VectorDim     int     // Target dimension for ShapeVector (96 or 128)
+	ClipStd       float64 // Standard deviations for clipping
+	Normalization NormalizationStrategy
+	WinsorLower   float64 // Lower percentile (0-100) for Winsorize
+	WinsorUpper   float64 // Upper percentile (0-100) for Winsorize
+
+	// RecencyBias resamples the whole window, spending this share of slots on its newest (1-RecencyBias)×W
+	// candles; 0 disables it. Setting it raises DataVersion to at least RecencyBiasDataVersion
+	RecencyBias float64
+}
+
+// DefaultExtractorConfig returns the default extractor configuration for dataVersion
+func DefaultExtractorConfig(dataVersion int) ExtractorConfig {
+	return ExtractorConfig{
+		DataVersion: dataVersion,
+		VectorDim:   96,
+		ClipStd:     3.0,
+		WinsorLower: defaultWinsorLower,
+		WinsorUpper: defaultWinsorUpper,
+	}
 }
 
 // NewExtractor creates a new feature extractor
 func NewExtractor(dataVersion, vectorDim int) *Extractor {
 	return &Extractor{
-		DataVersion: dataVersion,
+		DataVersion: vectorDataVersion(dataVersion, 0),
 		VectorDim:   vectorDim,
 		ClipStd:     3.0,
+		WinsorLower: defaultWinsorLower,
+		WinsorUpper: defaultWinsorUpper,
 	}
 }
 
+// NewExtractorWithConfig creates a feature extractor from cfg
+func NewExtractorWithConfig(cfg ExtractorConfig) *Extractor {
+	return &Extractor{
+		DataVersion:   vectorDataVersion(cfg.DataVersion, cfg.RecencyBias),
+		VectorDim:     cfg.VectorDim,
+		ClipStd:       cfg.ClipStd,
+		Normalization: cfg.Normalization,
+		WinsorLower:   cfg.WinsorLower,
+		WinsorUpper:   cfg.WinsorUpper,
+		RecencyBias:   cfg.RecencyBias,
+	}
+}
+
+// vectorDataVersion raises dataVersion to the minimum version of the shape vectors the extractor builds
+func vectorDataVersion(dataVersion int, recencyBias float64) int {
+	if recencyBias > 0 {
+		return max(dataVersion, RecencyBiasDataVersion)
+	}
+	return max(dataVersion, NewestCandlesDataVersion)
+}
+
 // Extract extracts features from a window and returns FeatureRow and ShapeVector
 func (e *Extractor) Extract(w *model.Window) (*model.FeatureRow, model.ShapeVector, error) {
 	if !w.IsComplete() {
@@ -36,6 +112,15 @@ func (e *Extractor) Extract(w *model.Window) (*model.FeatureRow, model.ShapeVect
 	mdd := calculateMaxDrawdown(candles)
 	atr := calculateATR(candles)
 	volZScore := calculateVolumeZScore(candles)
+	keltnerPos := calculateKeltnerPosition(candles)
+	williamsR := calculateWilliamsR(candles, williamsRPeriod)
+	aroonUp, aroonDown := calculateAroon(candles, aroonPeriod)
+	entropy := calculateReturnEntropy(candles, entropyBins)
+	roc10 := calculateROC(candles, rocShortPeriod)
+	roc20 := calculateROC(candles, rocLongPeriod)
+	bullPower, bearPower := calculateElderRay(candles)
+	fractalDim := calculateHiguchiDimension(candles, min(higuchiKMax, len(candles)/2))
+	pivotPos := calculatePivotPosition(candles)
 
 	featureRow := &model.FeatureRow{
 		WindowID:           w.WindowID,
@@ -47,6 +132,18 @@ func (e *Extractor) Extract(w *model.Window) (*model.FeatureRow, model.ShapeVect
 		VolBucket:          model.ClassifyVolBucket(volZScore),
 		TrendBucket:        model.ClassifyTrendBucket(trendSlope),
 		DataVersion:        e.DataVersion,
+		KeltnerPosition:    keltnerPos,
+		WilliamsR14:        williamsR,
+		AroonUp:            aroonUp,
+		AroonDown:          aroonDown,
+		AroonOscillator:    aroonUp - aroonDown,
+		ReturnEntropy:      entropy,
+		ROC10:              roc10,
+		ROC20:              roc20,
+		BullPower:          bullPower,
+		BearPower:          bearPower,
+		FractalDim:         fractalDim,
+		PivotPosition:      pivotPos,
 	}
 
 	// Build shape vector
@@ -58,25 +155,41 @@ func (e *Extractor) Extract(w *model.Window) (*model.FeatureRow, model.ShapeVect
 // buildShapeVector creates a fixed-length vector from candle data
 func (e *Extractor) buildShapeVector(candles []model.Candle) model.ShapeVector {
 	// Normalize different aspects
-	returns := NormalizeReturns(candles, e.ClipStd)
-	ranges := NormalizeRanges(candles, e.ClipStd)
+	var returns, ranges, volumes []float64
+	if e.Normalization == Winsorize {
+		series := model.CandleSeries(candles)
+		returns = WinsorizeNormalize(series.Returns(), e.WinsorLower, e.WinsorUpper)
+		ranges = WinsorizeNormalize(series.Ranges(), e.WinsorLower, e.WinsorUpper)
+		volumes = WinsorizeNormalize(series.Volumes(), e.WinsorLower, e.WinsorUpper)
+	} else {
+		returns = NormalizeReturns(candles, e.ClipStd)
+		ranges = NormalizeRanges(candles, e.ClipStd)
+		volumes = NormalizeVolumes(candles, e.ClipStd)
+	}
 	upperWicks, lowerWicks := NormalizeWicks(candles)
-	volumes := NormalizeVolumes(candles, e.ClipStd)
 
 	// Calculate how many candles to use based on target dimension
-	// For dim=96: use 24 candles × 4 features (returns, range, upperWick, lowerWick)
-	// For dim=128: use 32 candles × 4 features
+	// For dim=96: use the newest 24 candles × 4 features (returns, range, upperWick, lowerWick)
+	// For dim=128: use the newest 32 candles × 4 features
+	// Normalization above still sees the whole window, so the kept candles are scaled against it
 	samplesPerFeature := e.VectorDim / 4
 	if samplesPerFeature > len(candles) {
 		samplesPerFeature = len(candles)
 	}
 
-	// Downsample if needed
-	returns = downsample(returns, samplesPerFeature)
-	ranges = downsample(ranges, samplesPerFeature)
-	upperWicks = downsample(upperWicks, samplesPerFeature)
-	lowerWicks = downsample(lowerWicks, samplesPerFeature)
-	volumes = downsample(volumes, samplesPerFeature)
+	if e.RecencyBias > 0 {
+		returns = downsampleRecencyBiased(returns, samplesPerFeature, e.RecencyBias)
+		ranges = downsampleRecencyBiased(ranges, samplesPerFeature, e.RecencyBias)
+		upperWicks = downsampleRecencyBiased(upperWicks, samplesPerFeature, e.RecencyBias)
+		lowerWicks = downsampleRecencyBiased(lowerWicks, samplesPerFeature, e.RecencyBias)
+		volumes = downsampleRecencyBiased(volumes, samplesPerFeature, e.RecencyBias)
+	} else {
+		returns = lastN(returns, samplesPerFeature)
+		ranges = lastN(ranges, samplesPerFeature)
+		upperWicks = lastN(upperWicks, samplesPerFeature)
+		lowerWicks = lastN(lowerWicks, samplesPerFeature)
+		volumes = lastN(volumes, samplesPerFeature)
+	}
 
 	// Concatenate into shape vector
 	vector := model.NewShapeVector(e.VectorDim)
@@ -117,34 +230,12 @@ func (e *Extractor) buildShapeVector(candles []model.Candle) model.ShapeVector {
 	return vector
 }
 
-// downsample reduces the number of samples using simple averaging
-func downsample(values []float64, targetLen int) []float64 {
-	if len(values) <= targetLen {
+// lastN returns the newest n values without copying
+func lastN(values []float64, n int) []float64 {
+	if len(values) <= n {
 		return values
 	}
-
-	result := make([]float64, targetLen)
-	ratio := float64(len(values)) / float64(targetLen)
-
-	for i := 0; i < targetLen; i++ {
-		start := int(float64(i) * ratio)
-		end := int(float64(i+1) * ratio)
-		if end > len(values) {
-			end = len(values)
-		}
-
-		sum := 0.0
-		count := 0
-		for j := start; j < end; j++ {
-			sum += values[j]
-			count++
-		}
-		if count > 0 {
-			result[i] = sum / float64(count)
-		}
-	}
-
-	return result
+	return values[len(values)-n:]
 }
 
 // calculateTrendSlope calculates linear regression slope of close prices
@@ -153,18 +244,19 @@ func calculateTrendSlope(candles []model.Candle) float64 {
 		return 0
 	}
 
-	n := float64(len(candles))
+	closes := model.CandleSeries(candles).Closes()
+	n := float64(len(closes))
 	var sumX, sumY, sumXY, sumX2 float64
 
 	// Normalize prices to percentage change from first close
-	basePrice := candles[0].Close
+	basePrice := closes[0]
 	if basePrice == 0 {
 		return 0
 	}
 
-	for i, c := range candles {
+	for i, close := range closes {
 		x := float64(i)
-		y := (c.Close - basePrice) / basePrice // Percentage change
+		y := (close - basePrice) / basePrice // Percentage change
 		sumX += x
 		sumY += y
 		sumXY += x * y
@@ -186,14 +278,7 @@ func calculateRealizedVolatility(candles []model.Candle) float64 {
 		return 0
 	}
 
-	returns := make([]float64, len(candles)-1)
-	for i := 1; i < len(candles); i++ {
-		if candles[i-1].Close != 0 {
-			returns[i-1] = (candles[i].Close - candles[i-1].Close) / candles[i-1].Close
-		}
-	}
-
-	_, std := meanStd(returns)
+	_, std := meanStd(model.CandleSeries(candles).CloseReturns())
 	return std
 }
 
@@ -203,15 +288,16 @@ func calculateMaxDrawdown(candles []model.Candle) float64 {
 		return 0
 	}
 
-	peak := candles[0].Close
+	closes := model.CandleSeries(candles).Closes()
+	peak := closes[0]
 	maxDD := 0.0
 
-	for _, c := range candles {
-		if c.Close > peak {
-			peak = c.Close
+	for _, close := range closes {
+		if close > peak {
+			peak = close
 		}
 		if peak > 0 {
-			dd := (peak - c.Close) / peak
+			dd := (peak - close) / peak
 			if dd > maxDD {
 				maxDD = dd
 			}
@@ -227,23 +313,23 @@ func calculateATR(candles []model.Candle) float64 {
 		return 0
 	}
 
-	var sumTR float64
-	for i := 1; i < len(candles); i++ {
-		curr := candles[i]
-		prev := candles[i-1]
+	series := model.CandleSeries(candles)
+	highs, lows, closes := series.Highs(), series.Lows(), series.Closes()
 
+	var sumTR float64
+	for i := 1; i < len(closes); i++ {
 		tr := math.Max(
-			curr.High-curr.Low,
+			highs[i]-lows[i],
 			math.Max(
-				math.Abs(curr.High-prev.Close),
-				math.Abs(curr.Low-prev.Close),
+				math.Abs(highs[i]-closes[i-1]),
+				math.Abs(lows[i]-closes[i-1]),
 			),
 		)
 		sumTR += tr
 	}
 
 	// Normalize by first candle's close price
-	basePrice := candles[0].Close
+	basePrice := closes[0]
 	if basePrice == 0 {
 		return 0
 	}
@@ -258,16 +344,112 @@ func calculateVolumeZScore(candles []model.Candle) float64 {
 		return 0
 	}
 
-	volumes := make([]float64, len(candles))
-	for i, c := range candles {
-		volumes[i] = c.Volume
-	}
+	volumes := model.CandleSeries(candles).Volumes()
 
 	mean, std := meanStd(volumes)
 	if std == 0 {
 		return 0
 	}
 
-	lastVolume := candles[len(candles)-1].Volume
+	lastVolume := volumes[len(volumes)-1]
 	return (lastVolume - mean) / std
 }
+
+// calculateHiguchiDimension estimates the Higuchi fractal dimension of min-max normalized closes
+// Curve lengths L(k) are averaged over the k offset subseries for k = 1..kMax, and D is the
+// least-squares slope of log L(k) against log(1/k). Returns 0 unless kMax >= 2 and len(candles) >= 2*kMax
+func calculateHiguchiDimension(candles []model.Candle, kMax int) float64 {
+	if kMax < 2 || len(candles) < 2*kMax {
+		return 0
+	}
+
+	closes := MinMaxNormalize(model.CandleSeries(candles).Closes())
+	n := len(closes)
+
+	var sumX, sumY, sumXY, sumX2, points float64
+	for k := 1; k <= kMax; k++ {
+		var lk float64
+		for m := 0; m < k; m++ {
+			steps := (n - 1 - m) / k
+			if steps == 0 {
+				continue
+			}
+			var length float64
+			for i := 1; i <= steps; i++ {
+				length += math.Abs(closes[m+i*k] - closes[m+(i-1)*k])
+			}
+			// Rescale to the full series length, then by 1/k
+			lk += length * float64(n-1) / float64(steps*k) / float64(k)
+		}
+		lk /= float64(k)
+		if lk <= 0 {
+			continue
+		}
+
+		x := math.Log(1 / float64(k))
+		y := math.Log(lk)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumX2 += x * x
+		points++
+	}
+
+	denominator := points*sumX2 - sumX*sumX
+	if points < 2 || denominator == 0 {
+		return 0
+	}
+	return (points*sumXY - sumX*sumY) / denominator
+}
+
+// PivotPoints holds support/resistance levels derived from a prior candle
+type PivotPoints struct {
+	// Classic pivots
+	Pivot, R1, R2, R3, S1, S2, S3 float64
+
+	// Fibonacci pivots: Pivot ± 0.382 × range
+	FibR1, FibS1 float64
+
+	// Camarilla pivots: Close ± 1.1/4 × range
+	CamR3, CamS3 float64
+}
+
+// calculatePivotPoints calculates classic, Fibonacci, and Camarilla pivots from prev, the preceding window's last candle
+func calculatePivotPoints(prev *model.Candle) PivotPoints {
+	if prev == nil {
+		return PivotPoints{}
+	}
+
+	h, l, c := prev.High, prev.Low, prev.Close
+	p := (h + l + c) / 3
+	rng := h - l
+
+	return PivotPoints{
+		Pivot: p,
+		R1:    2*p - l,
+		R2:    p + rng,
+		R3:    h + 2*(p-l),
+		S1:    2*p - h,
+		S2:    p - rng,
+		S3:    l - 2*(h-p),
+		FibR1: p + 0.382*rng,
+		FibS1: p - 0.382*rng,
+		CamR3: c + rng*1.1/4,
+		CamS3: c - rng*1.1/4,
+	}
+}
+
+// calculatePivotPosition calculates (lastClose - Pivot) / (R1 - S1) against the classic pivots of the previous candle
+// With step-1 windows the previous candle is the preceding window's last candle
+func calculatePivotPosition(candles []model.Candle) float64 {
+	if len(candles) < 2 {
+		return 0
+	}
+
+	pivots := calculatePivotPoints(&candles[len(candles)-2])
+	width := pivots.R1 - pivots.S1
+	if width == 0 {
+		return 0
+	}
+	return (candles[len(candles)-1].Close - pivots.Pivot) / width
+}
@@ -0,0 +1,143 @@
+package feature
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// testWindow returns a complete window of n 1m candles with a sawtooth close
+func testWindow(n int) *model.Window {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := make([]model.Candle, n)
+	for i := range candles {
+		open := start.Add(time.Duration(i) * time.Minute)
+		price := 100 + float64(i%5)
+		candles[i] = model.Candle{
+			Symbol:    "BTCUSDT",
+			Timeframe: "1m",
+			OpenTime:  open,
+			CloseTime: open.Add(time.Minute - time.Millisecond),
+			Open:      price - 0.5,
+			High:      price + 1,
+			Low:       price - 1,
+			Close:     price,
+			Volume:    float64(10 + i),
+		}
+	}
+	return model.NewWindow("BTCUSDT", "1m", candles[n-1].CloseTime, n, 1, candles)
+}
+
+func TestExtractorDataVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  ExtractorConfig
+		want int
+	}{
+		{"legacy version is raised", ExtractorConfig{DataVersion: 1, VectorDim: 96}, NewestCandlesDataVersion},
+		{"newer version is kept", ExtractorConfig{DataVersion: 5, VectorDim: 96}, 5},
+		{"recency bias", ExtractorConfig{DataVersion: 1, VectorDim: 96, RecencyBias: 0.5}, RecencyBiasDataVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewExtractorWithConfig(tt.cfg).DataVersion; got != tt.want {
+				t.Errorf("DataVersion = %d, want %d", got, tt.want)
+			}
+		})
+	}
+	if got := NewExtractor(1, 96).DataVersion; got != NewestCandlesDataVersion {
+		t.Errorf("NewExtractor DataVersion = %d, want %d", got, NewestCandlesDataVersion)
+	}
+}
+
+func TestExtractShapeVectorKeepsNewestCandles(t *testing.T) {
+	extractor := NewExtractor(1, 96)
+	long := testWindow(60)
+
+	row, vector, err := extractor.Extract(long)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if row.DataVersion != NewestCandlesDataVersion {
+		t.Errorf("feature row DataVersion = %d, want %d", row.DataVersion, NewestCandlesDataVersion)
+	}
+	if len(vector) != 96 {
+		t.Fatalf("vector length = %d, want 96", len(vector))
+	}
+
+	// Returns occupy the first 24 slots and must follow the newest 24 candles of the window
+	want := NormalizeReturns(long.Candles, extractor.ClipStd)[60-24:]
+	for i, v := range want {
+		if vector[i] != float32(v) {
+			t.Fatalf("vector[%d] = %v, want newest return %v", i, vector[i], v)
+		}
+	}
+}
+
+func TestCalculateHiguchiDimensionGeometric(t *testing.T) {
+	geometric := make([]float64, 60)
+	for i := range geometric {
+		geometric[i] = 100 * math.Pow(1.01, float64(i))
+	}
+
+	d := calculateHiguchiDimension(closeCandles(geometric...), higuchiKMax)
+	if math.Abs(d-1) > 0.05 {
+		t.Errorf("D = %v, want ≈ 1 for a smooth trend", d)
+	}
+	if got := model.ClassifyComplexity(d); got != model.ComplexityTrending {
+		t.Errorf("ClassifyComplexity(%v) = %d, want trending", d, got)
+	}
+
+	// Preconditions: kMax >= 2 and at least 2*kMax candles
+	if got := calculateHiguchiDimension(closeCandles(geometric...), 1); got != 0 {
+		t.Errorf("kMax 1 gave D = %v, want 0", got)
+	}
+	if got := calculateHiguchiDimension(closeCandles(geometric[:9]...), 5); got != 0 {
+		t.Errorf("9 candles with kMax 5 gave D = %v, want 0", got)
+	}
+}
+
+func TestCalculatePivotPoints(t *testing.T) {
+	prev := &model.Candle{High: 110, Low: 90, Close: 106}
+	p := calculatePivotPoints(prev)
+
+	// Classic P = (H+L+C)/3 = 102, range 20
+	want := PivotPoints{
+		Pivot: 102,
+		R1:    114, S1: 94,
+		R2: 122, S2: 82,
+		R3: 134, S3: 74,
+		FibR1: 102 + 0.382*20, FibS1: 102 - 0.382*20,
+		CamR3: 106 + 20*1.1/4, CamS3: 106 - 20*1.1/4,
+	}
+	for _, c := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"Pivot", p.Pivot, want.Pivot},
+		{"R1", p.R1, want.R1}, {"S1", p.S1, want.S1},
+		{"R2", p.R2, want.R2}, {"S2", p.S2, want.S2},
+		{"R3", p.R3, want.R3}, {"S3", p.S3, want.S3},
+		{"FibR1", p.FibR1, want.FibR1}, {"FibS1", p.FibS1, want.FibS1},
+		{"CamR3", p.CamR3, want.CamR3}, {"CamS3", p.CamS3, want.CamS3},
+	} {
+		if math.Abs(c.got-c.want) > 1e-9 {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+
+	if got := calculatePivotPoints(nil); got != (PivotPoints{}) {
+		t.Errorf("nil previous candle gave %+v, want zero pivots", got)
+	}
+}
+
+func TestCalculatePivotPosition(t *testing.T) {
+	candles := closeCandles(100, 103)
+	candles[0].High, candles[0].Low, candles[0].Close = 110, 90, 100 // P = 100, R1 - S1 = 20
+
+	if got := calculatePivotPosition(candles); math.Abs(got-0.15) > 1e-9 {
+		t.Errorf("PivotPosition = %v, want (103 - 100) / 20 = 0.15", got)
+	}
+}
@@ -0,0 +1,241 @@
+package feature
+
+import (
+	"math"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// Default indicator parameters
+const (
+	keltnerEMAPeriod     = 20
+	keltnerATRMultiplier = 2
+	williamsRPeriod      = 14
+	aroonPeriod          = 25
+	entropyBins          = model.ReturnEntropyBins
+	rocShortPeriod       = 10
+	rocLongPeriod        = 20
+	elderRayPeriod       = 13
+	higuchiKMax          = 8 // Capped at half the window length
+)
+
+// calculateEMA calculates the exponential moving average of values, returning the final value
+// The EMA is seeded with the simple average of the first period values (or all values if fewer)
+func calculateEMA(values []float64, period int) float64 {
+	if len(values) == 0 || period <= 0 {
+		return 0
+	}
+
+	seedLen := period
+	if seedLen > len(values) {
+		seedLen = len(values)
+	}
+
+	ema := 0.0
+	for _, v := range values[:seedLen] {
+		ema += v
+	}
+	ema /= float64(seedLen)
+
+	alpha := 2.0 / float64(period+1)
+	for _, v := range values[seedLen:] {
+		ema = alpha*v + (1-alpha)*ema
+	}
+
+	return ema
+}
+
+// calculateAbsoluteATR calculates the average true range in price units over the last period candles
+func calculateAbsoluteATR(candles []model.Candle, period int) float64 {
+	if len(candles) < 2 {
+		return 0
+	}
+
+	start := len(candles) - period
+	if start < 1 {
+		start = 1
+	}
+
+	var sumTR float64
+	for i := start; i < len(candles); i++ {
+		curr := candles[i]
+		prev := candles[i-1]
+		sumTR += math.Max(
+			curr.High-curr.Low,
+			math.Max(
+				math.Abs(curr.High-prev.Close),
+				math.Abs(curr.Low-prev.Close),
+			),
+		)
+	}
+
+	return sumTR / float64(len(candles)-start)
+}
+
+// calculateKeltnerChannels calculates Keltner Channel bands at the last candle
+// middle = EMA(close, emaPeriod), upper/lower = middle ± atrMultiplier × ATR(emaPeriod)
+func calculateKeltnerChannels(candles []model.Candle, emaPeriod, atrMultiplier int) (upper, middle, lower float64) {
+	if len(candles) == 0 {
+		return 0, 0, 0
+	}
+
+	middle = calculateEMA(model.CandleSeries(candles).Closes(), emaPeriod)
+	atr := calculateAbsoluteATR(candles, emaPeriod)
+
+	upper = middle + float64(atrMultiplier)*atr
+	lower = middle - float64(atrMultiplier)*atr
+	return upper, middle, lower
+}
+
+// calculateKeltnerPosition returns (close - middle) / (upper - lower) clamped to [-1, 1]
+// Negative values are below the middle line, positive values above it
+func calculateKeltnerPosition(candles []model.Candle) float64 {
+	if len(candles) == 0 {
+		return 0
+	}
+
+	upper, middle, lower := calculateKeltnerChannels(candles, keltnerEMAPeriod, keltnerATRMultiplier)
+	width := upper - lower
+	if width == 0 {
+		return 0
+	}
+
+	pos := (candles[len(candles)-1].Close - middle) / width
+	return math.Max(-1, math.Min(1, pos))
+}
+
+// calculateWilliamsR calculates Williams %R over the last period candles
+// %R = (highest_high - close) / (highest_high - lowest_low) × -100, ranging from -100 (oversold) to 0
+func calculateWilliamsR(candles []model.Candle, period int) float64 {
+	if len(candles) == 0 || period <= 0 {
+		return 0
+	}
+
+	start := len(candles) - period
+	if start < 0 {
+		start = 0
+	}
+
+	recent := model.CandleSeries(candles).Slice(start, len(candles))
+	highest, lowest := recent[0].High, recent[0].Low
+	for _, c := range recent[1:] {
+		highest = math.Max(highest, c.High)
+		lowest = math.Min(lowest, c.Low)
+	}
+
+	rangeVal := highest - lowest
+	if rangeVal == 0 {
+		return 0
+	}
+
+	return (highest - recent[len(recent)-1].Close) / rangeVal * -100
+}
+
+// calculateAroon calculates Aroon Up/Down over the last period+1 candles
+// Up = 100 × (period - bars since highest high) / period; ties resolve to the most recent bar
+func calculateAroon(candles []model.Candle, period int) (up, down float64) {
+	if len(candles) == 0 || period <= 0 {
+		return 0, 0
+	}
+
+	start := len(candles) - period - 1
+	if start < 0 {
+		start = 0
+	}
+
+	recent := model.CandleSeries(candles).Slice(start, len(candles))
+	highIdx, lowIdx := 0, 0
+	for i, c := range recent {
+		if c.High >= recent[highIdx].High {
+			highIdx = i
+		}
+		if c.Low <= recent[lowIdx].Low {
+			lowIdx = i
+		}
+	}
+
+	last := len(recent) - 1
+	up = 100 * float64(period-(last-highIdx)) / float64(period)
+	down = 100 * float64(period-(last-lowIdx)) / float64(period)
+	return up, down
+}
+
+// calculateROC calculates the rate of change (percent) between the last close and the close period candles earlier
+// With fewer than period+1 candles, the first candle's close is used as the base
+func calculateROC(candles []model.Candle, period int) float64 {
+	if len(candles) < 2 || period <= 0 {
+		return 0
+	}
+
+	baseIdx := len(candles) - 1 - period
+	if baseIdx < 0 {
+		baseIdx = 0
+	}
+
+	base := candles[baseIdx].Close
+	if base == 0 {
+		return 0
+	}
+	return (candles[len(candles)-1].Close - base) / base * 100
+}
+
+// calculateElderRay calculates Elder Ray bull and bear power of the last candle against the EMA13 of close
+// bullPower = (high - EMA) / EMA and bearPower = (low - EMA) / EMA
+func calculateElderRay(candles []model.Candle) (bullPower, bearPower float64) {
+	if len(candles) == 0 {
+		return 0, 0
+	}
+
+	ema := calculateEMA(model.CandleSeries(candles).Closes(), elderRayPeriod)
+	if ema == 0 {
+		return 0, 0
+	}
+
+	last := candles[len(candles)-1]
+	return (last.High - ema) / ema, (last.Low - ema) / ema
+}
+
+// flatReturnTolerance is the return range below which a series counts as constant
+const flatReturnTolerance = 1e-12
+
+// calculateReturnEntropy calculates the Shannon entropy (bits) of the close-to-close return histogram
+// Returns are binned into numBins equal-width buckets spanning [min, max]; a constant series yields 0
+func calculateReturnEntropy(candles []model.Candle, numBins int) float64 {
+	if len(candles) < 2 || numBins <= 0 {
+		return 0
+	}
+
+	returns := model.CandleSeries(candles).CloseReturns()
+
+	lo, hi := returns[0], returns[0]
+	for _, r := range returns[1:] {
+		lo = math.Min(lo, r)
+		hi = math.Max(hi, r)
+	}
+	// Rounding spreads a constant return series by ~1e-17, which would otherwise fill every bin
+	if hi-lo < flatReturnTolerance {
+		return 0
+	}
+
+	counts := make([]int, numBins)
+	width := (hi - lo) / float64(numBins)
+	for _, r := range returns {
+		bin := int((r - lo) / width)
+		if bin >= numBins {
+			bin = numBins - 1 // the maximum falls on the upper edge
+		}
+		counts[bin]++
+	}
+
+	entropy := 0.0
+	n := float64(len(returns))
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / n
+		entropy -= p * math.Log2(p)
+	}
+
+	return entropy
+}
@@ -0,0 +1,130 @@
+package feature
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// closeCandles returns 1m candles closing at closes, with a one-unit range around each close
+func closeCandles(closes ...float64) []model.Candle {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := make([]model.Candle, len(closes))
+	for i, c := range closes {
+		open := start.Add(time.Duration(i) * time.Minute)
+		candles[i] = model.Candle{
+			Symbol:    "BTCUSDT",
+			Timeframe: "1m",
+			OpenTime:  open,
+			CloseTime: open.Add(time.Minute - time.Millisecond),
+			Open:      c,
+			High:      c + 0.5,
+			Low:       c - 0.5,
+			Close:     c,
+			Volume:    1,
+		}
+	}
+	return candles
+}
+
+func TestCalculateReturnEntropy(t *testing.T) {
+	geometric := make([]float64, 20)
+	for i := range geometric {
+		geometric[i] = 100 * math.Pow(1.01, float64(i))
+	}
+
+	tests := []struct {
+		name   string
+		closes []float64
+		want   float64
+	}{
+		{"constant returns", geometric, 0},
+		{"flat prices", []float64{100, 100, 100, 100}, 0},
+		{"two equal bins", []float64{100, 110, 100, 110, 100}, 1},
+		{"single candle", []float64{100}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calculateReturnEntropy(closeCandles(tt.closes...), entropyBins); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("entropy = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateAroon(t *testing.T) {
+	rising := make([]float64, 30)
+	for i := range rising {
+		rising[i] = 100 + float64(i)
+	}
+
+	// The highest high is on the last bar and the lowest low period bars before it
+	up, down := calculateAroon(closeCandles(rising...), aroonPeriod)
+	if up != 100 {
+		t.Errorf("AroonUp = %v, want 100 with the high on the last bar", up)
+	}
+	if down != 0 {
+		t.Errorf("AroonDown = %v, want 0 with the low %d bars ago", down, aroonPeriod)
+	}
+
+	// Mirrored for a falling series
+	falling := make([]float64, len(rising))
+	for i, c := range rising {
+		falling[len(falling)-1-i] = c
+	}
+	up, down = calculateAroon(closeCandles(falling...), aroonPeriod)
+	if up != 0 || down != 100 {
+		t.Errorf("falling Aroon up/down = %v/%v, want 0/100", up, down)
+	}
+}
+
+func TestCalculateROC(t *testing.T) {
+	// The close 10 candles before the last is 100 and the last is 110
+	closes := []float64{90, 95, 100, 101, 102, 103, 104, 105, 106, 107, 108, 109, 110}
+	if got := calculateROC(closeCandles(closes...), rocShortPeriod); math.Abs(got-10) > 1e-9 {
+		t.Errorf("ROC10 = %v, want 10", got)
+	}
+
+	// Too few candles fall back to the first close as the base
+	if got := calculateROC(closeCandles(100, 105), rocShortPeriod); math.Abs(got-5) > 1e-9 {
+		t.Errorf("short ROC10 = %v, want 5", got)
+	}
+}
+
+func TestCalculateElderRayRisingEMA(t *testing.T) {
+	// Closes rise one unit a bar, so EMA13 rises too, lagging about 6 units behind the close;
+	// lows 10 units below the close stay under the EMA while highs 10 above stay over it
+	candles := make([]model.Candle, 40)
+	for i, c := range closeCandles(make([]float64, 40)...) {
+		price := 100 + float64(i)
+		c.Open, c.Close = price, price
+		c.High, c.Low = price+10, price-10
+		candles[i] = c
+	}
+
+	closes := model.CandleSeries(candles).Closes()
+	ema := calculateEMA(closes, elderRayPeriod)
+	if prev := calculateEMA(closes[:len(closes)-1], elderRayPeriod); ema <= prev {
+		t.Fatalf("EMA13 %v does not rise from %v", ema, prev)
+	}
+	last := candles[len(candles)-1]
+	if last.Low >= ema {
+		t.Fatalf("last low %v is not below EMA13 %v", last.Low, ema)
+	}
+
+	bull, bear := calculateElderRay(candles)
+	if bear >= 0 {
+		t.Errorf("bearPower = %v, want negative with the low below the EMA", bear)
+	}
+	if want := (last.Low - ema) / ema; math.Abs(bear-want) > 1e-12 {
+		t.Errorf("bearPower = %v, want (low - EMA) / EMA = %v", bear, want)
+	}
+	if bull <= 0 {
+		t.Errorf("bullPower = %v, want positive with the high above the EMA", bull)
+	}
+	if got := model.ClassifyElderRaySignal(bull, bear); got != model.ElderRayMixed {
+		t.Errorf("ClassifyElderRaySignal = %d, want mixed", got)
+	}
+}
@@ -2,6 +2,7 @@ package feature
 
 import (
 	"math"
+	"sort"
 
 	"github.com/tunogya/etna/pkg/model"
 )
@@ -15,10 +16,7 @@ func NormalizeReturns(candles []model.Candle, clipStd float64) []float64 {
 		return nil
 	}
 
-	returns := make([]float64, len(candles))
-	for i, c := range candles {
-		returns[i] = c.Returns()
-	}
+	returns := model.CandleSeries(candles).Returns()
 
 	mean, std := meanStd(returns)
 	if std == 0 {
@@ -148,6 +146,93 @@ func MinMaxNormalize(values []float64) []float64 {
 	return result
 }
 
+// WinsorizeNormalize caps values at their own lower and upper percentiles (0-100), then scales them to [-1, 1]
+// Outliers take the percentile value rather than a fixed clip constant, so their ordering relative to the
+// remaining values is kept. Returns zeros if the capped values are all equal
+func WinsorizeNormalize(values []float64, lower, upper float64) []float64 {
+	if len(values) == 0 {
+		return nil
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	lo, hi := percentile(sorted, lower), percentile(sorted, upper)
+
+	result := make([]float64, len(values))
+	if hi <= lo {
+		return result
+	}
+	for i, v := range values {
+		v = math.Max(lo, math.Min(hi, v))
+		result[i] = 2*(v-lo)/(hi-lo) - 1
+	}
+	return result
+}
+
+// downsampleRecencyBiased resamples values to targetLen slots, giving the newest candles more slots
+// The newest max(1, n×(1-bias)) values fill bias×targetLen slots and the older values fill the rest,
+// each segment averaged (or repeated) evenly. bias 0.5 is uniform; bias 1 repeats the last value in
+// every slot. A bias of 0 or less returns values unchanged
+// The recent segment shrinks as bias grows, rather than spanning the newest n×bias values: with n×bias,
+// bias 1 would cover the whole window and resample it uniformly instead of keeping only the last value
+func downsampleRecencyBiased(values []float64, targetLen int, bias float64) []float64 {
+	n := len(values)
+	if n == 0 || targetLen <= 0 || bias <= 0 {
+		return values
+	}
+	bias = math.Min(bias, 1)
+
+	recentLen := max(1, int(math.Round(float64(n)*(1-bias))))
+	recentSlots := int(math.Round(float64(targetLen) * bias))
+	if recentLen == n {
+		recentSlots = targetLen
+	}
+	olderSlots := targetLen - recentSlots
+
+	result := make([]float64, 0, targetLen)
+	result = append(result, resample(values[:n-recentLen], olderSlots)...)
+	return append(result, resample(values[n-recentLen:], recentSlots)...)
+}
+
+// resample maps values onto targetLen evenly sized buckets, averaging each bucket and repeating values
+// when there are fewer values than buckets
+func resample(values []float64, targetLen int) []float64 {
+	if len(values) == 0 || targetLen <= 0 {
+		return nil
+	}
+
+	result := make([]float64, targetLen)
+	ratio := float64(len(values)) / float64(targetLen)
+	for i := range result {
+		start := int(float64(i) * ratio)
+		end := max(start+1, int(float64(i+1)*ratio))
+
+		sum := 0.0
+		for _, v := range values[start:end] {
+			sum += v
+		}
+		result[i] = sum / float64(end-start)
+	}
+	return result
+}
+
+// percentile returns the p-th percentile (p in 0-100) of sorted values, interpolating linearly
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+
+	rank := (p / 100) * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper {
+		return sorted[lower]
+	}
+
+	fraction := rank - float64(lower)
+	return sorted[lower] + fraction*(sorted[upper]-sorted[lower])
+}
+
 // meanStd calculates mean and standard deviation
 func meanStd(values []float64) (mean, std float64) {
 	if len(values) == 0 {
@@ -0,0 +1,100 @@
+package feature
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWinsorizeNormalizeTwoOutliers(t *testing.T) {
+	// 1..20 plus one extreme outlier on each side
+	values := []float64{-500}
+	for i := 1; i <= 20; i++ {
+		values = append(values, float64(i))
+	}
+	values = append(values, 500)
+
+	got := WinsorizeNormalize(values, 5, 95)
+	if len(got) != len(values) {
+		t.Fatalf("got %d values, want %d", len(got), len(values))
+	}
+
+	// Over 22 sorted values the 5th and 95th percentiles interpolate to 1.05 and 19.95
+	lo, hi := 1.05, 19.95
+	scale := func(v float64) float64 { return 2*(v-lo)/(hi-lo) - 1 }
+
+	// The outliers take the percentile values, so they land exactly on the ends of the range
+	if got[0] != -1 || got[len(got)-1] != 1 {
+		t.Errorf("outliers scaled to %v and %v, want -1 and 1", got[0], got[len(got)-1])
+	}
+	// The remaining values keep their spread instead of being squeezed toward 0 by the outliers
+	for i, v := range values[1 : len(values)-1] {
+		want := scale(math.Max(lo, math.Min(hi, v)))
+		if math.Abs(got[i+1]-want) > 1e-9 {
+			t.Errorf("value %v scaled to %v, want %v", v, got[i+1], want)
+		}
+	}
+	if mid := got[10]; math.Abs(mid) > 0.1 {
+		t.Errorf("middle value 10 scaled to %v, want near 0", mid)
+	}
+}
+
+func TestWinsorizeNormalizeConstant(t *testing.T) {
+	for i, v := range WinsorizeNormalize([]float64{3, 3, 3}, 1, 99) {
+		if v != 0 {
+			t.Errorf("constant value %d scaled to %v, want 0", i, v)
+		}
+	}
+	if got := WinsorizeNormalize(nil, 1, 99); got != nil {
+		t.Errorf("empty input gave %v, want nil", got)
+	}
+}
+
+func TestDownsampleRecencyBiasedFullBias(t *testing.T) {
+	for _, tc := range []struct{ n, targetLen int }{{7, 24}, {60, 24}, {24, 24}, {1, 8}} {
+		values := make([]float64, tc.n)
+		for i := range values {
+			values[i] = float64(i + 1)
+		}
+
+		got := downsampleRecencyBiased(values, tc.targetLen, 1)
+		if len(got) != tc.targetLen {
+			t.Fatalf("n=%d: got %d slots, want %d", tc.n, len(got), tc.targetLen)
+		}
+		// Only the last value lands in any slot
+		for i, v := range got {
+			if v != values[tc.n-1] {
+				t.Errorf("n=%d: slot %d = %v, want the last value %v", tc.n, i, v, values[tc.n-1])
+			}
+		}
+	}
+}
+
+func TestDownsampleRecencyBiasedSplit(t *testing.T) {
+	values := make([]float64, 20)
+	for i := range values {
+		values[i] = float64(i)
+	}
+
+	// bias 0.7 spends 7 of 10 slots on the newest 20×0.3 = 6 values and the other 3 on the older 14
+	got := downsampleRecencyBiased(values, 10, 0.7)
+	if len(got) != 10 {
+		t.Fatalf("got %d slots, want 10", len(got))
+	}
+	for i, v := range got[:3] {
+		if v >= 14 {
+			t.Errorf("older slot %d = %v, want a mean of values before 14", i, v)
+		}
+	}
+	for i, v := range got[3:] {
+		if v < 14 {
+			t.Errorf("recent slot %d = %v, want a value from the newest 6", i+3, v)
+		}
+	}
+	if got[9] != 19 {
+		t.Errorf("last slot = %v, want 19", got[9])
+	}
+
+	if got := downsampleRecencyBiased(values, 10, 0); len(got) != len(values) {
+		t.Errorf("bias 0 returned %d values, want the %d values unchanged", len(got), len(values))
+	}
+}
@@ -0,0 +1,123 @@
+package feature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/tunogya/etna/pkg/model"
+	"github.com/tunogya/etna/pkg/window"
+)
+
+// StreamedFeature is a window completed by a StreamExtractor with its extracted features
+type StreamedFeature struct {
+	Window *model.Window
+	Row    *model.FeatureRow
+	Vector model.ShapeVector
+}
+
+// StreamExtractor extracts features continuously from candles pushed into its ring buffer
+// Each pushed candle is fed to a window builder; every window it completes is extracted and emitted
+type StreamExtractor struct {
+	buffer     *window.RingBuffer
+	extractor  *Extractor
+	builderCfg window.Config
+
+	mu      sync.Mutex
+	started bool
+	err     error // Last extraction error
+}
+
+// NewStreamExtractor creates a stream extractor whose ring buffer holds bufCap candles
+func NewStreamExtractor(bufCap int, extractorCfg ExtractorConfig, builderCfg window.Config) *StreamExtractor {
+	return &StreamExtractor{
+		buffer:     window.NewRingBuffer(bufCap),
+		extractor:  NewExtractorWithConfig(extractorCfg),
+		builderCfg: builderCfg,
+	}
+}
+
+// Buffer returns the ring buffer candles are pushed into
+func (s *StreamExtractor) Buffer() *window.RingBuffer {
+	return s.buffer
+}
+
+// Push pushes a candle into the ring buffer
+// Push blocks while the subscription is full, so the output channel must be drained
+func (s *StreamExtractor) Push(c model.Candle) {
+	s.buffer.Push(c)
+}
+
+// Start subscribes to the ring buffer and returns the channel of extracted windows
+// The channel is closed when ctx is done; Start may only be called once
+func (s *StreamExtractor) Start(ctx context.Context) (<-chan StreamedFeature, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return nil, errors.New("stream extractor already started")
+	}
+
+	builder, err := window.NewBuilder(s.builderCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create window builder: %w", err)
+	}
+	s.started = true
+
+	candles, cancel := s.buffer.Subscribe()
+	out := make(chan StreamedFeature, s.buffer.Capacity())
+
+	go func() {
+		defer close(out)
+		defer cancel()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case c, ok := <-candles:
+				if !ok {
+					return
+				}
+				w, ready := builder.Push(c)
+				if !ready {
+					continue
+				}
+
+				row, vector, err := s.extractor.Extract(w)
+				if err != nil {
+					log.Printf("Failed to extract features of window %s: %v", w.WindowID, err)
+					s.setErr(err)
+					continue
+				}
+				if row == nil {
+					continue
+				}
+
+				select {
+				case out <- StreamedFeature{Window: w, Row: row, Vector: vector}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// Err returns the last error extracting a completed window, or nil if every window was extracted
+// Windows that fail extraction are logged and skipped, so the stream keeps running
+func (s *StreamExtractor) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// setErr records an extraction error
+func (s *StreamExtractor) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
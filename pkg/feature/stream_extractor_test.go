@@ -0,0 +1,60 @@
+package feature
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tunogya/etna/pkg/window"
+)
+
+func TestStreamExtractorEndToEnd(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s := NewStreamExtractor(16, DefaultExtractorConfig(1), window.Config{
+		W:         60,
+		S:         1,
+		Symbol:    "BTCUSDT",
+		Timeframe: "1m",
+	})
+	out, err := s.Start(ctx)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if _, err := s.Start(ctx); err == nil {
+		t.Error("second Start succeeded, want an error")
+	}
+
+	// Push blocks while the subscription is full, so feed candles while draining the output
+	candles := testWindow(120).Candles
+	go func() {
+		for _, c := range candles {
+			s.Push(c)
+		}
+	}()
+
+	// 120 candles complete one window of 60 and one more per candle after it
+	const want = 61
+	got := 0
+	for got < want {
+		select {
+		case f, ok := <-out:
+			if !ok {
+				t.Fatalf("output closed after %d features", got)
+			}
+			if f.Row == nil || len(f.Vector) != 96 {
+				t.Fatalf("feature %d has row %v and %d-dim vector", got, f.Row, len(f.Vector))
+			}
+			if f.Row.WindowID != f.Window.WindowID {
+				t.Errorf("feature row of %s emitted for window %s", f.Row.WindowID, f.Window.WindowID)
+			}
+			got++
+		case <-ctx.Done():
+			t.Fatalf("got %d features before timing out, want at least 60", got)
+		}
+	}
+	if err := s.Err(); err != nil {
+		t.Errorf("Err() = %v, want nil", err)
+	}
+}
@@ -1,6 +1,9 @@
 package model
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // Candle represents a single K-line (candlestick) data point
 type Candle struct {
@@ -33,6 +36,26 @@ func (c *Candle) Range() float64 {
 	return (c.High - c.Low) / c.Open
 }
 
+// TypicalPrice returns (High + Low + Close) / 3
+func (c *Candle) TypicalPrice() float64 {
+	return (c.High + c.Low + c.Close) / 3
+}
+
+// WeightedClose returns (High + Low + 2 × Close) / 4
+func (c *Candle) WeightedClose() float64 {
+	return (c.High + c.Low + 2*c.Close) / 4
+}
+
+// Midpoint returns the midpoint of the high-low range
+func (c *Candle) Midpoint() float64 {
+	return (c.High + c.Low) / 2
+}
+
+// BodyMidpoint returns the midpoint of the open-close body
+func (c *Candle) BodyMidpoint() float64 {
+	return (c.Open + c.Close) / 2
+}
+
 // UpperWick calculates the upper wick as a percentage of the range
 func (c *Candle) UpperWick() float64 {
 	rangeVal := c.High - c.Low
@@ -68,3 +91,208 @@ func (c *Candle) IsBullish() bool {
 func (c *Candle) IsBearish() bool {
 	return c.Close < c.Open
 }
+
+// CandleSeries is an ordered slice of candles with bulk field accessors
+type CandleSeries []Candle
+
+// Returns extracts the open-to-close return of every candle, as Candle.Returns does one at a time
+// See CloseReturns for returns between consecutive closes
+func (s CandleSeries) Returns() []float64 {
+	result := make([]float64, len(s))
+	for i := range s {
+		if s[i].Open != 0 {
+			result[i] = (s[i].Close - s[i].Open) / s[i].Open
+		}
+	}
+	return result
+}
+
+// CloseReturns extracts the close-to-close return between each pair of consecutive candles,
+// one fewer than the series length; a zero previous close gives a zero return
+func (s CandleSeries) CloseReturns() []float64 {
+	if len(s) < 2 {
+		return nil
+	}
+	result := make([]float64, len(s)-1)
+	for i := 1; i < len(s); i++ {
+		if s[i-1].Close != 0 {
+			result[i-1] = (s[i].Close - s[i-1].Close) / s[i-1].Close
+		}
+	}
+	return result
+}
+
+// Highs extracts the high price of every candle
+func (s CandleSeries) Highs() []float64 {
+	result := make([]float64, len(s))
+	for i := range s {
+		result[i] = s[i].High
+	}
+	return result
+}
+
+// Lows extracts the low price of every candle
+func (s CandleSeries) Lows() []float64 {
+	result := make([]float64, len(s))
+	for i := range s {
+		result[i] = s[i].Low
+	}
+	return result
+}
+
+// Closes extracts the close price of every candle
+func (s CandleSeries) Closes() []float64 {
+	result := make([]float64, len(s))
+	for i := range s {
+		result[i] = s[i].Close
+	}
+	return result
+}
+
+// Volumes extracts the volume of every candle
+func (s CandleSeries) Volumes() []float64 {
+	result := make([]float64, len(s))
+	for i := range s {
+		result[i] = s[i].Volume
+	}
+	return result
+}
+
+// Ranges extracts the high-low range of every candle as a percentage of open
+func (s CandleSeries) Ranges() []float64 {
+	result := make([]float64, len(s))
+	for i := range s {
+		if s[i].Open != 0 {
+			result[i] = (s[i].High - s[i].Low) / s[i].Open
+		}
+	}
+	return result
+}
+
+// Slice returns the sub-series [start, end), clamped to the series bounds
+func (s CandleSeries) Slice(start, end int) CandleSeries {
+	if start < 0 {
+		start = 0
+	}
+	if end > len(s) {
+		end = len(s)
+	}
+	if start >= end {
+		return CandleSeries{}
+	}
+	return s[start:end]
+}
+
+// Reverse returns a copy of the series in reverse order
+func (s CandleSeries) Reverse() CandleSeries {
+	result := make(CandleSeries, len(s))
+	for i := range s {
+		result[len(s)-1-i] = s[i]
+	}
+	return result
+}
+
+// TimeRange returns the first candle's open time and the last candle's close time, or zero times if empty
+func (s CandleSeries) TimeRange() (start, end time.Time) {
+	if len(s) == 0 {
+		return time.Time{}, time.Time{}
+	}
+	return s[0].OpenTime, s[len(s)-1].CloseTime
+}
+
+// Duration returns the time from the first candle's open to the last candle's close, or 0 if empty
+func (s CandleSeries) Duration() time.Duration {
+	start, end := s.TimeRange()
+	return end.Sub(start)
+}
+
+// Resample aggregates the series into bars of targetDuration, with buckets aligned by OpenTime.Truncate
+// A candle spanning a bucket boundary is counted in its dominant bucket, the one holding its midpoint.
+// Each bar takes the first open, highest high, lowest low and last close of its candles, summed volume and
+// trades, and the volume-weighted VWAP of candles that have one. Buckets only partly covered, typically at
+// the ends, are aggregated from the candles they have. The series must be in chronological order; a
+// non-positive targetDuration returns nil
+func (s CandleSeries) Resample(targetDuration time.Duration) CandleSeries {
+	if targetDuration <= 0 {
+		return nil
+	}
+
+	timeframe := formatTimeframe(targetDuration)
+	var result CandleSeries
+	var vwapVolume float64 // Volume of the current bar's candles that have a VWAP
+	for _, c := range s {
+		bucket := dominantBucket(c, targetDuration)
+		last := len(result) - 1
+		if last < 0 || !result[last].OpenTime.Equal(bucket) {
+			if last >= 0 {
+				finishVWAP(&result[last], vwapVolume)
+			}
+			result = append(result, Candle{
+				Symbol:    c.Symbol,
+				Timeframe: timeframe,
+				OpenTime:  bucket,
+				CloseTime: bucket.Add(targetDuration - time.Millisecond),
+				Open:      c.Open,
+				High:      c.High,
+				Low:       c.Low,
+			})
+			vwapVolume = 0
+			last++
+		}
+
+		bar := &result[last]
+		bar.High = max(bar.High, c.High)
+		bar.Low = min(bar.Low, c.Low)
+		bar.Close = c.Close
+		bar.Volume += c.Volume
+		bar.Trades += c.Trades
+		if c.VWAP != 0 {
+			bar.VWAP += c.VWAP * c.Volume // Summed until finishVWAP divides it
+			vwapVolume += c.Volume
+		}
+	}
+	if len(result) > 0 {
+		finishVWAP(&result[len(result)-1], vwapVolume)
+	}
+
+	return result
+}
+
+// dominantBucket returns the start of the targetDuration bucket holding the midpoint of c
+func dominantBucket(c Candle, targetDuration time.Duration) time.Time {
+	mid := c.OpenTime
+	if c.CloseTime.After(c.OpenTime) {
+		mid = c.OpenTime.Add(c.CloseTime.Sub(c.OpenTime) / 2)
+	}
+	return mid.Truncate(targetDuration)
+}
+
+// finishVWAP turns a bar's summed VWAP × volume into the volume-weighted VWAP
+func finishVWAP(bar *Candle, vwapVolume float64) {
+	if vwapVolume == 0 {
+		bar.VWAP = 0
+		return
+	}
+	bar.VWAP /= vwapVolume
+}
+
+// formatTimeframe formats d as a timeframe string in its largest whole unit, inverting TimeframeDuration
+// Durations that are not a whole number of seconds fall back to d.String()
+func formatTimeframe(d time.Duration) string {
+	units := []struct {
+		suffix string
+		unit   time.Duration
+	}{
+		{"w", 7 * 24 * time.Hour},
+		{"d", 24 * time.Hour},
+		{"h", time.Hour},
+		{"m", time.Minute},
+		{"s", time.Second},
+	}
+	for _, u := range units {
+		if d%u.unit == 0 {
+			return fmt.Sprintf("%d%s", d/u.unit, u.suffix)
+		}
+	}
+	return d.String()
+}
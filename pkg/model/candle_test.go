@@ -0,0 +1,119 @@
+package model
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// benchSeries returns n consecutive 1m candles with a slowly oscillating price
+func benchSeries(n int) CandleSeries {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := make(CandleSeries, n)
+	for i := range s {
+		open := start.Add(time.Duration(i) * time.Minute)
+		price := 100 + 10*math.Sin(float64(i)/20)
+		s[i] = Candle{
+			OpenTime:  open,
+			CloseTime: open.Add(time.Minute - time.Millisecond),
+			Open:      price - 0.2,
+			High:      price + 1,
+			Low:       price - 1,
+			Close:     price,
+			Volume:    float64(100 + i%7),
+		}
+	}
+	return s
+}
+
+func TestCandleSeriesMatchesCandleMethods(t *testing.T) {
+	s := benchSeries(50)
+	returns, ranges := s.Returns(), s.Ranges()
+	for i := range s {
+		if returns[i] != s[i].Returns() {
+			t.Errorf("Returns()[%d] = %v, Candle.Returns() = %v", i, returns[i], s[i].Returns())
+		}
+		if ranges[i] != s[i].Range() {
+			t.Errorf("Ranges()[%d] = %v, Candle.Range() = %v", i, ranges[i], s[i].Range())
+		}
+	}
+}
+
+func TestCandleSeriesCloseReturns(t *testing.T) {
+	s := CandleSeries{{Open: 1, Close: 100}, {Open: 100, Close: 110}, {Open: 110, Close: 99}}
+	got := s.CloseReturns()
+	want := []float64{0.1, -0.1}
+	if len(got) != len(want) {
+		t.Fatalf("got %d returns, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-12 {
+			t.Errorf("CloseReturns()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if got := s[:1].CloseReturns(); got != nil {
+		t.Errorf("single candle CloseReturns() = %v, want nil", got)
+	}
+}
+
+var benchSink []float64
+
+func BenchmarkReturnsPerCandle(b *testing.B) {
+	s := benchSeries(1000)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		returns := make([]float64, len(s))
+		for i := range s {
+			returns[i] = s[i].Returns()
+		}
+		benchSink = returns
+	}
+}
+
+func BenchmarkReturnsSeries(b *testing.B) {
+	s := benchSeries(1000)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		benchSink = s.Returns()
+	}
+}
+
+func BenchmarkRangesPerCandle(b *testing.B) {
+	s := benchSeries(1000)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		ranges := make([]float64, len(s))
+		for i := range s {
+			ranges[i] = s[i].Range()
+		}
+		benchSink = ranges
+	}
+}
+
+func BenchmarkRangesSeries(b *testing.B) {
+	s := benchSeries(1000)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		benchSink = s.Ranges()
+	}
+}
+
+func BenchmarkClosesPerCandle(b *testing.B) {
+	s := benchSeries(1000)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		closes := make([]float64, 0, len(s))
+		for _, c := range s {
+			closes = append(closes, c.Close)
+		}
+		benchSink = closes
+	}
+}
+
+func BenchmarkClosesSeries(b *testing.B) {
+	s := benchSeries(1000)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		benchSink = s.Closes()
+	}
+}
@@ -1,5 +1,7 @@
 package model
 
+import "math"
+
 // FeatureRow contains structured features extracted from a window
 // These features are used for filtering and statistical analysis
 type FeatureRow struct {
@@ -12,6 +14,143 @@ type FeatureRow struct {
 	VolBucket          int     `json:"vol_bucket"`          // volume bucket (0-9)
 	TrendBucket        int     `json:"trend_bucket"`        // trend bucket (-2 to +2)
 	DataVersion        int     `json:"data_version"`        // schema version for compatibility
+	KeltnerPosition    float64 `json:"keltner_position"`    // (close - middle) / (upper - lower), clamped to [-1, 1]
+	WilliamsR14        float64 `json:"williams_r14"`        // Williams %R over 14 periods (-100 to 0)
+	AroonUp            float64 `json:"aroon_up"`            // 100 × (period - bars since highest high) / period
+	AroonDown          float64 `json:"aroon_down"`          // 100 × (period - bars since lowest low) / period
+	AroonOscillator    float64 `json:"aroon_oscillator"`    // AroonUp - AroonDown (-100 to 100)
+	ReturnEntropy      float64 `json:"return_entropy"`      // Shannon entropy (bits) of the 10-bin return histogram
+	ROC10              float64 `json:"roc10"`               // 10-period rate of change of close, in percent
+	ROC20              float64 `json:"roc20"`               // 20-period rate of change of close, in percent
+	BullPower          float64 `json:"bull_power"`          // Elder Ray (high - EMA13) / EMA13 of the last candle
+	BearPower          float64 `json:"bear_power"`          // Elder Ray (low - EMA13) / EMA13 of the last candle
+	FractalDim         float64 `json:"fractal_dim"`         // Higuchi fractal dimension of close, ~1 trending to ~2 noisy
+	PivotPosition      float64 `json:"pivot_position"`      // (close - pivot) / (R1 - S1) against the previous candle's classic pivots
+	BidAskSpread       float64 `json:"bid_ask_spread"`      // Relative spread of the last order book snapshot at or before TEnd (0 without one)
+}
+
+// AsVector returns the core structured features [TrendSlope, RealizedVolatility, MaxDrawdown, ATR, VolZScore]
+// for lightweight in-memory screening, optionally followed by VolBucket and TrendBucket
+func (f *FeatureRow) AsVector(includeBuckets bool) []float64 {
+	v := []float64{f.TrendSlope, f.RealizedVolatility, f.MaxDrawdown, f.ATR, f.VolZScore}
+	if includeBuckets {
+		v = append(v, float64(f.VolBucket), float64(f.TrendBucket))
+	}
+	return v
+}
+
+// Value returns the feature stored under a window_features column name
+func (f *FeatureRow) Value(column string) (float64, bool) {
+	switch column {
+	case "trend_slope":
+		return f.TrendSlope, true
+	case "realized_volatility":
+		return f.RealizedVolatility, true
+	case "max_drawdown":
+		return f.MaxDrawdown, true
+	case "atr":
+		return f.ATR, true
+	case "vol_z_score":
+		return f.VolZScore, true
+	case "keltner_position":
+		return f.KeltnerPosition, true
+	case "williams_r14":
+		return f.WilliamsR14, true
+	case "aroon_up":
+		return f.AroonUp, true
+	case "aroon_down":
+		return f.AroonDown, true
+	case "aroon_oscillator":
+		return f.AroonOscillator, true
+	case "return_entropy":
+		return f.ReturnEntropy, true
+	case "roc10":
+		return f.ROC10, true
+	case "roc20":
+		return f.ROC20, true
+	case "bull_power":
+		return f.BullPower, true
+	case "bear_power":
+		return f.BearPower, true
+	case "fractal_dim":
+		return f.FractalDim, true
+	case "pivot_position":
+		return f.PivotPosition, true
+	case "bid_ask_spread":
+		return f.BidAskSpread, true
+	}
+	return 0, false
+}
+
+// NormalizationFactors holds the typical magnitude of each feature, used to bring features to a common scale
+type NormalizationFactors struct {
+	TrendSlope         float64
+	RealizedVolatility float64
+	MaxDrawdown        float64
+	ATR                float64
+	VolZScore          float64
+	KeltnerPosition    float64
+	WilliamsR14        float64
+	Aroon              float64 // Shared by AroonUp, AroonDown, and AroonOscillator
+	ReturnEntropy      float64
+	ROC                float64 // Shared by ROC10 and ROC20, in percent
+	ElderRay           float64 // Shared by BullPower and BearPower, as a fraction of EMA13
+	FractalDim         float64
+	PivotPosition      float64
+	BidAskSpread       float64 // As a fraction of the mid price
+}
+
+// DefaultNormalizationFactors returns typical feature magnitudes
+// Continuous scales are roughly one standard deviation on BTCUSDT 1d; bounded features use their range
+func DefaultNormalizationFactors() NormalizationFactors {
+	return NormalizationFactors{
+		TrendSlope:         0.01,
+		RealizedVolatility: 0.03,
+		MaxDrawdown:        0.15,
+		ATR:                0.05,
+		VolZScore:          1,
+		KeltnerPosition:    1,
+		WilliamsR14:        100,
+		Aroon:              100,
+		ReturnEntropy:      math.Log2(ReturnEntropyBins),
+		ROC:                10,
+		ElderRay:           0.05,
+		FractalDim:         2,
+		PivotPosition:      1,
+		BidAskSpread:       0.001,
+	}
+}
+
+// Normalize returns a copy of the row with every feature divided by its factor
+// Fields with a zero factor are copied unchanged, as are the identifying and bucket fields
+func (f *FeatureRow) Normalize(factors NormalizationFactors) *FeatureRow {
+	scale := func(v, factor float64) float64 {
+		if factor == 0 {
+			return v
+		}
+		return v / factor
+	}
+
+	n := *f
+	n.TrendSlope = scale(f.TrendSlope, factors.TrendSlope)
+	n.RealizedVolatility = scale(f.RealizedVolatility, factors.RealizedVolatility)
+	n.MaxDrawdown = scale(f.MaxDrawdown, factors.MaxDrawdown)
+	n.ATR = scale(f.ATR, factors.ATR)
+	n.VolZScore = scale(f.VolZScore, factors.VolZScore)
+	n.KeltnerPosition = scale(f.KeltnerPosition, factors.KeltnerPosition)
+	n.WilliamsR14 = scale(f.WilliamsR14, factors.WilliamsR14)
+	n.AroonUp = scale(f.AroonUp, factors.Aroon)
+	n.AroonDown = scale(f.AroonDown, factors.Aroon)
+	n.AroonOscillator = scale(f.AroonOscillator, factors.Aroon)
+	n.ReturnEntropy = scale(f.ReturnEntropy, factors.ReturnEntropy)
+	n.ROC10 = scale(f.ROC10, factors.ROC)
+	n.ROC20 = scale(f.ROC20, factors.ROC)
+	n.BullPower = scale(f.BullPower, factors.ElderRay)
+	n.BearPower = scale(f.BearPower, factors.ElderRay)
+	n.FractalDim = scale(f.FractalDim, factors.FractalDim)
+	n.PivotPosition = scale(f.PivotPosition, factors.PivotPosition)
+	n.BidAskSpread = scale(f.BidAskSpread, factors.BidAskSpread)
+	return &n
 }
 
 // ShapeVector is a fixed-length float32 vector for similarity search
@@ -59,6 +198,32 @@ func FromFloat64(data []float64) ShapeVector {
 	return result
 }
 
+// Norm returns the Euclidean length of the shape vector
+func (sv ShapeVector) Norm() float64 {
+	var sum float64
+	for _, v := range sv {
+		sum += float64(v) * float64(v)
+	}
+	return math.Sqrt(sum)
+}
+
+// CosineSimilarity returns the cosine of the angle between two shape vectors
+// Mismatched dimensions or zero-length vectors return 0
+func (sv ShapeVector) CosineSimilarity(other ShapeVector) float64 {
+	if len(sv) != len(other) || len(sv) == 0 {
+		return 0
+	}
+	var dot float64
+	for i := range sv {
+		dot += float64(sv[i]) * float64(other[i])
+	}
+	denom := sv.Norm() * other.Norm()
+	if denom == 0 {
+		return 0
+	}
+	return dot / denom
+}
+
 // Outcome represents the forward-looking statistics for a window
 type Outcome struct {
 	WindowID   string  `json:"window_id"`
@@ -68,6 +233,13 @@ type Outcome struct {
 	FwdRetP50  float64 `json:"fwd_ret_p50"`  // median
 	FwdRetP90  float64 `json:"fwd_ret_p90"`  // 90th percentile
 	MDDP95     float64 `json:"mdd_p95"`      // 95th percentile max drawdown
+	FwdRetEnd  float64 `json:"fwd_ret_end"`  // return at the horizon bar
+	HitRate    float64 `json:"hit_rate"`     // fraction of forward paths ending positive
+	MFE        float64 `json:"mfe"`          // max favorable excursion: highest high relative to base
+	MAE        float64 `json:"mae"`          // max adverse excursion: lowest low below base (positive = loss)
+
+	BenchmarkSymbol string  `json:"benchmark_symbol,omitempty"` // benchmark used for ExcessRetEnd
+	ExcessRetEnd    float64 `json:"excess_ret_end,omitempty"`   // FwdRetEnd minus the benchmark's return
 }
 
 // TrendBucket constants
@@ -95,6 +267,100 @@ func ClassifyTrendBucket(slope float64) int {
 	}
 }
 
+// KeltnerZone constants
+const (
+	KeltnerBelow  = -1
+	KeltnerInside = 0
+	KeltnerAbove  = 1
+)
+
+// ClassifyKeltnerZone classifies a Keltner position into below/inside/above the channel
+// The bands sit at ±0.5 since position is measured in units of the full channel width
+func ClassifyKeltnerZone(pos float64) int {
+	switch {
+	case pos > 0.5:
+		return KeltnerAbove
+	case pos < -0.5:
+		return KeltnerBelow
+	default:
+		return KeltnerInside
+	}
+}
+
+// WilliamsZone constants
+const (
+	WilliamsOversold   = -1
+	WilliamsNeutral    = 0
+	WilliamsOverbought = 1
+)
+
+// ClassifyWilliamsZone classifies a Williams %R value into oversold (< -80), neutral, or overbought (> -20)
+func ClassifyWilliamsZone(r float64) int {
+	switch {
+	case r < -80:
+		return WilliamsOversold
+	case r > -20:
+		return WilliamsOverbought
+	default:
+		return WilliamsNeutral
+	}
+}
+
+// AroonSignal constants
+const (
+	AroonBearish = -1
+	AroonNeutral = 0
+	AroonBullish = 1
+)
+
+// ClassifyAroonSignal classifies an Aroon oscillator value into bearish (< -50), neutral, or bullish (> 50)
+func ClassifyAroonSignal(osc float64) int {
+	switch {
+	case osc > 50:
+		return AroonBullish
+	case osc < -50:
+		return AroonBearish
+	default:
+		return AroonNeutral
+	}
+}
+
+// EntropyLevel constants
+const (
+	EntropyLow    = -1
+	EntropyMedium = 0
+	EntropyHigh   = 1
+)
+
+// ReturnEntropyBins is the number of histogram bins ReturnEntropy is computed over
+const ReturnEntropyBins = 10
+
+// Entropy level thresholds as fractions of the maximum entropy: 33rd/66th percentiles of 10-bin return entropy
+// calibrated on BTCUSDT 1d windows of 60 candles (2017-2026), where they were 2.53 and 2.78 bits of log2(10)
+const (
+	entropyLowThreshold  = 0.762
+	entropyHighThreshold = 0.837
+)
+
+// ClassifyEntropyLevel classifies the return entropy h of a window of w candles into low (trending), medium, or high (noisy)
+// h is divided by its maximum log2(min(ReturnEntropyBins, w-1)), since w-1 returns fill at most that many bins,
+// so short windows are not classified as trending just for lacking returns to spread over the bins
+func ClassifyEntropyLevel(h float64, w int) int {
+	maxEntropy := math.Log2(float64(min(ReturnEntropyBins, w-1)))
+	if maxEntropy <= 0 {
+		return EntropyMedium
+	}
+
+	switch normalized := h / maxEntropy; {
+	case normalized < entropyLowThreshold:
+		return EntropyLow
+	case normalized > entropyHighThreshold:
+		return EntropyHigh
+	default:
+		return EntropyMedium
+	}
+}
+
 // ClassifyVolBucket classifies a volume z-score into a bucket (0-9)
 func ClassifyVolBucket(zScore float64) int {
 	// Map z-score to bucket 0-9
@@ -108,3 +374,79 @@ func ClassifyVolBucket(zScore float64) int {
 	}
 	return bucket
 }
+
+// Momentum constants
+const (
+	MomentumStrongDown = -2
+	MomentumDown       = -1
+	MomentumNeutral    = 0
+	MomentumUp         = 1
+	MomentumStrongUp   = 2
+)
+
+// Momentum thresholds (percent) for strong moves over 10 and 20 periods
+const (
+	momentumStrongROC10 = 5
+	momentumStrongROC20 = 10
+)
+
+// ClassifyMomentum classifies short and long ROC into strong-down (-2) through strong-up (+2)
+// Both periods must agree in direction; strong requires both to exceed their strong thresholds
+func ClassifyMomentum(roc10, roc20 float64) int {
+	switch {
+	case roc10 > momentumStrongROC10 && roc20 > momentumStrongROC20:
+		return MomentumStrongUp
+	case roc10 < -momentumStrongROC10 && roc20 < -momentumStrongROC20:
+		return MomentumStrongDown
+	case roc10 > 0 && roc20 > 0:
+		return MomentumUp
+	case roc10 < 0 && roc20 < 0:
+		return MomentumDown
+	default:
+		return MomentumNeutral
+	}
+}
+
+// Elder Ray signal constants
+const (
+	ElderRayBearish = -1
+	ElderRayMixed   = 0
+	ElderRayBullish = 1
+)
+
+// ClassifyElderRaySignal returns 1 when bull and bear power are both positive, -1 when both are negative, else 0
+func ClassifyElderRaySignal(bull, bear float64) int {
+	switch {
+	case bull > 0 && bear > 0:
+		return ElderRayBullish
+	case bull < 0 && bear < 0:
+		return ElderRayBearish
+	default:
+		return ElderRayMixed
+	}
+}
+
+// Complexity constants
+const (
+	ComplexityTrending = -1
+	ComplexityRandom   = 0
+	ComplexityNoisy    = 1
+)
+
+// Fractal dimension band around the random-walk value 1.5 classified as random
+const (
+	complexityTrendingMax = 1.4
+	complexityNoisyMin    = 1.6
+)
+
+// ClassifyComplexity classifies a fractal dimension as trending (-1), random-walk (0), or noisy (1)
+func ClassifyComplexity(d float64) int {
+	switch {
+	case d < complexityTrendingMax:
+		return ComplexityTrending
+	case d > complexityNoisyMin:
+		return ComplexityNoisy
+	default:
+		return ComplexityRandom
+	}
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+func TestClassifyEntropyLevel(t *testing.T) {
+	tests := []struct {
+		name string
+		h    float64
+		w    int
+		want int
+	}{
+		{"calibrated low", 2.4, 60, EntropyLow},
+		{"calibrated medium", 2.65, 60, EntropyMedium},
+		{"calibrated high", 2.9, 60, EntropyHigh},
+		// 7 candles give 6 returns, so at most log2(6) ≈ 2.58 bits; raw W=60 thresholds would call this low
+		{"short window at its maximum", math.Log2(6), 7, EntropyHigh},
+		{"short window trending", 1.5, 7, EntropyLow},
+		{"too short to measure", 0, 2, EntropyMedium},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyEntropyLevel(tt.h, tt.w); got != tt.want {
+				t.Errorf("ClassifyEntropyLevel(%v, %d) = %d, want %d", tt.h, tt.w, got, tt.want)
+			}
+		})
+	}
+}
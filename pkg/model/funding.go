@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+// FundingRate represents a single perpetual futures funding rate settlement
+type FundingRate struct {
+	Symbol      string    `json:"symbol"`
+	FundingTime time.Time `json:"funding_time"`
+	Rate        float64   `json:"rate"`
+}
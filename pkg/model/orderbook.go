@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// OrderBookSnapshot summarizes the top levels of an order book at a point in time
+type OrderBookSnapshot struct {
+	Symbol     string    `json:"symbol"`
+	Timestamp  time.Time `json:"timestamp"`
+	BidPrice   float64   `json:"bid_price"`    // Best bid
+	AskPrice   float64   `json:"ask_price"`    // Best ask
+	MidPrice   float64   `json:"mid_price"`    // (BidPrice + AskPrice) / 2
+	Spread     float64   `json:"spread"`       // AskPrice - BidPrice
+	BidDepth20 float64   `json:"bid_depth_20"` // Total base quantity over the top 20 bid levels
+	AskDepth20 float64   `json:"ask_depth_20"` // Total base quantity over the top 20 ask levels
+}
+
+// RelativeSpread returns the spread as a fraction of the mid price, or 0 without a mid price
+func (s *OrderBookSnapshot) RelativeSpread() float64 {
+	if s.MidPrice == 0 {
+		return 0
+	}
+	return s.Spread / s.MidPrice
+}
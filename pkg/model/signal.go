@@ -0,0 +1,40 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// Signal directions
+const (
+	SignalLong  = "long"
+	SignalShort = "short"
+)
+
+// Signal is a trading signal derived from the outcomes of windows similar to a query window
+type Signal struct {
+	SignalID       string    `json:"signal_id"`
+	Symbol         string    `json:"symbol"`
+	Timeframe      string    `json:"timeframe"`
+	SignalTime     time.Time `json:"signal_time"` // end of the query window
+	WindowID       string    `json:"window_id"`   // query window the signal was generated for
+	Direction      string    `json:"direction"`   // SignalLong or SignalShort
+	Confidence     float64   `json:"confidence"`  // 0-1
+	Horizon        int       `json:"horizon"`     // bars the signal looks ahead
+	ExpectedReturn float64   `json:"expected_return"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// GenerateSignalID creates a deterministic signal ID, so regenerating a window's signal replaces it
+func GenerateSignalID(windowID string, horizon int) string {
+	return fmt.Sprintf("%s-h%d", windowID, horizon)
+}
+
+// ForWindow sets the signal's identity from the query window w
+func (s *Signal) ForWindow(w *Window) {
+	s.SignalID = GenerateSignalID(w.WindowID, s.Horizon)
+	s.Symbol = w.Symbol
+	s.Timeframe = w.Timeframe
+	s.SignalTime = w.TEnd
+	s.WindowID = w.WindowID
+}
@@ -4,6 +4,8 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"math"
+	"strconv"
 	"time"
 )
 
@@ -19,10 +21,30 @@ type Window struct {
 	CreatedAt      time.Time `json:"created_at"`
 }
 
+// WindowWithFeatures pairs a window with its extracted feature row
+// Both embedded types carry WindowID; use w.Window.WindowID or w.FeatureRow.WindowID explicitly
+type WindowWithFeatures struct {
+	Window     `json:"window"`
+	FeatureRow `json:"features"`
+}
+
 // GenerateWindowID creates a deterministic window ID based on key parameters
 // Format: hash(symbol|tf|t_end|W|feature_version)
 // This ensures idempotent writes - same parameters always produce same ID
 func GenerateWindowID(symbol, timeframe string, tEnd time.Time, w, featureVersion int) string {
+	hash := windowHash(symbol, timeframe, tEnd, w, featureVersion)
+	return hex.EncodeToString(hash[:16]) // use first 16 bytes (32 hex chars)
+}
+
+// GenerateWindowIDv2 creates a deterministic window ID from the full SHA-256 hash (64 hex chars)
+// Used for feature version 2 and above to make collisions negligible on large datasets
+func GenerateWindowIDv2(symbol, timeframe string, tEnd time.Time, w, featureVersion int) string {
+	hash := windowHash(symbol, timeframe, tEnd, w, featureVersion)
+	return hex.EncodeToString(hash[:])
+}
+
+// windowHash hashes the window key parameters shared by all ID versions
+func windowHash(symbol, timeframe string, tEnd time.Time, w, featureVersion int) [sha256.Size]byte {
 	data := fmt.Sprintf("%s|%s|%d|%d|%d",
 		symbol,
 		timeframe,
@@ -30,14 +52,41 @@ func GenerateWindowID(symbol, timeframe string, tEnd time.Time, w, featureVersio
 		w,
 		featureVersion,
 	)
-	hash := sha256.Sum256([]byte(data))
-	return hex.EncodeToString(hash[:16]) // use first 16 bytes (32 hex chars)
+	return sha256.Sum256([]byte(data))
+}
+
+// WindowIDVersion detects the ID format by length: 1 for 32 hex chars, 2 for 64, 0 if unknown
+func WindowIDVersion(id string) int {
+	switch len(id) {
+	case 32:
+		return 1
+	case 64:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// CollisionProbability approximates the chance of any v1 window ID collision among n windows
+// Uses the birthday bound 1 - e^(-n(n-1)/2^129) for 128-bit IDs: n(n-1)/2 pairs, each colliding with chance 2^-128
+func CollisionProbability(n int64) float64 {
+	if n <= 1 {
+		return 0
+	}
+	x := float64(n) * float64(n-1) / math.Pow(2, 129)
+	return -math.Expm1(-x)
 }
 
 // NewWindow creates a new Window with generated ID
+// Feature version 2 and above use full-length (v2) window IDs
 func NewWindow(symbol, timeframe string, tEnd time.Time, w, featureVersion int, candles []Candle) *Window {
+	id := GenerateWindowID(symbol, timeframe, tEnd, w, featureVersion)
+	if featureVersion >= 2 {
+		id = GenerateWindowIDv2(symbol, timeframe, tEnd, w, featureVersion)
+	}
+
 	return &Window{
-		WindowID:       GenerateWindowID(symbol, timeframe, tEnd, w, featureVersion),
+		WindowID:       id,
 		Symbol:         symbol,
 		Timeframe:      timeframe,
 		TEnd:           tEnd,
@@ -76,3 +125,115 @@ func (w *Window) TStart() time.Time {
 	}
 	return time.Time{}
 }
+
+// Subsample returns a new window of the last targetLen candles, with W = targetLen
+// Returns nil if targetLen is not positive or the window has no candles
+func (w *Window) Subsample(targetLen int) *Window {
+	return w.subsample(len(w.Candles)-targetLen, targetLen)
+}
+
+// SubsampleFirst returns a new window of the first targetLen candles, with W = targetLen
+func (w *Window) SubsampleFirst(targetLen int) *Window {
+	return w.subsample(0, targetLen)
+}
+
+// SubsampleCenter returns a new window of the middle targetLen candles, with W = targetLen
+// When the candles cannot be split evenly, the extra candle is dropped from the front
+func (w *Window) SubsampleCenter(targetLen int) *Window {
+	return w.subsample((len(w.Candles)-targetLen+1)/2, targetLen)
+}
+
+// subsample builds a window from up to targetLen candles starting at start
+// The ID and TEnd are derived from the sub-range, so TEnd is its last candle's close time;
+// if fewer than targetLen candles are available the result is not IsComplete
+func (w *Window) subsample(start, targetLen int) *Window {
+	if targetLen <= 0 || len(w.Candles) == 0 {
+		return nil
+	}
+	start = max(start, 0)
+	end := min(start+targetLen, len(w.Candles))
+
+	candles := make([]Candle, end-start)
+	copy(candles, w.Candles[start:end])
+	return NewWindow(w.Symbol, w.Timeframe, candles[len(candles)-1].CloseTime, targetLen, w.FeatureVersion, candles)
+}
+
+// TimeframeDuration converts a timeframe string (e.g. "1m", "4h", "1d", "1w") into a bar duration
+func TimeframeDuration(timeframe string) (time.Duration, error) {
+	if len(timeframe) < 2 {
+		return 0, fmt.Errorf("invalid timeframe %q", timeframe)
+	}
+
+	n, err := strconv.Atoi(timeframe[:len(timeframe)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid timeframe %q", timeframe)
+	}
+
+	var unit time.Duration
+	switch timeframe[len(timeframe)-1] {
+	case 's':
+		unit = time.Second
+	case 'm':
+		unit = time.Minute
+	case 'h':
+		unit = time.Hour
+	case 'd':
+		unit = 24 * time.Hour
+	case 'w':
+		unit = 7 * 24 * time.Hour
+	default:
+		return 0, fmt.Errorf("invalid timeframe %q", timeframe)
+	}
+
+	return time.Duration(n) * unit, nil
+}
+
+// OverlapFraction returns the overlap of two time intervals as a fraction of the shorter one
+// Returns 0 for disjoint intervals and 1 when one interval contains the other
+func OverlapFraction(aStart, aEnd, bStart, bEnd time.Time) float64 {
+	start := aStart
+	if bStart.After(start) {
+		start = bStart
+	}
+	end := aEnd
+	if bEnd.Before(end) {
+		end = bEnd
+	}
+	if !end.After(start) {
+		return 0
+	}
+
+	shorter := aEnd.Sub(aStart)
+	if d := bEnd.Sub(bStart); d < shorter {
+		shorter = d
+	}
+	if shorter <= 0 {
+		return 0
+	}
+
+	return float64(end.Sub(start)) / float64(shorter)
+}
+
+// OverlapWithPrevious returns the temporal overlap of curr with prev as a fraction of curr's duration
+// Durations run from each window's first candle open to its last candle close; returns 0 for disjoint or empty windows
+func OverlapWithPrevious(prev, curr *Window) float64 {
+	if prev == nil || curr == nil || len(prev.Candles) == 0 || len(curr.Candles) == 0 {
+		return 0
+	}
+
+	prevEnd, currEnd := prev.LastCandle().CloseTime, curr.LastCandle().CloseTime
+	start := prev.TStart()
+	if curr.TStart().After(start) {
+		start = curr.TStart()
+	}
+	end := prevEnd
+	if currEnd.Before(end) {
+		end = currEnd
+	}
+
+	duration := currEnd.Sub(curr.TStart())
+	if !end.After(start) || duration <= 0 {
+		return 0
+	}
+	return float64(end.Sub(start)) / float64(duration)
+}
@@ -0,0 +1,130 @@
+package model
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWindowIDsUnique(t *testing.T) {
+	n := 1_000_000
+	if testing.Short() {
+		n = 10_000
+	}
+
+	// One window per minute, alternating between two symbols and two lengths
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	seen := make(map[string]struct{}, 2*n)
+	for i := 0; i < n; i++ {
+		symbol := []string{"BTCUSDT", "ETHUSDT"}[i%2]
+		tEnd := start.Add(time.Duration(i/2) * time.Minute)
+		w := 60 + 30*(i/2%2)
+		for _, id := range []string{
+			GenerateWindowID(symbol, "1m", tEnd, w, 1),
+			GenerateWindowIDv2(symbol, "1m", tEnd, w, 2),
+		} {
+			if _, dup := seen[id]; dup {
+				t.Fatalf("duplicate window ID %s at window %d", id, i)
+			}
+			seen[id] = struct{}{}
+		}
+	}
+}
+
+func TestWindowIDVersion(t *testing.T) {
+	tEnd := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		id   string
+		want int
+	}{
+		{"v1", GenerateWindowID("BTCUSDT", "1d", tEnd, 60, 1), 1},
+		{"v2", GenerateWindowIDv2("BTCUSDT", "1d", tEnd, 60, 2), 2},
+		{"NewWindow v1", NewWindow("BTCUSDT", "1d", tEnd, 60, 1, nil).WindowID, 1},
+		{"NewWindow v2", NewWindow("BTCUSDT", "1d", tEnd, 60, 2, nil).WindowID, 2},
+		{"unknown", "abc", 0},
+	}
+	for _, tt := range tests {
+		if got := WindowIDVersion(tt.id); got != tt.want {
+			t.Errorf("%s: WindowIDVersion = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	// The v1 ID is the v2 ID's prefix
+	v1 := GenerateWindowID("BTCUSDT", "1d", tEnd, 60, 1)
+	if v2 := GenerateWindowIDv2("BTCUSDT", "1d", tEnd, 60, 1); v2[:32] != v1 {
+		t.Errorf("v1 ID %s is not the prefix of v2 ID %s", v1, v2)
+	}
+}
+
+func TestCollisionProbability(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want float64
+	}{
+		{0, 0},
+		{1, 0},
+		{2, math.Pow(2, -128)},                // One pair
+		{1 << 32, 0x1p63 / 0x1p128},           // ≈ n²/2^129
+		{1 << 62, 1 - math.Exp(-1.0/32)},      // 2^124/2^129
+		{math.MaxInt64, 1 - math.Exp(-0.125)}, // ≈ 2^126/2^129
+	}
+	for _, tt := range tests {
+		got := CollisionProbability(tt.n)
+		if math.Abs(got-tt.want) > 1e-6*tt.want {
+			t.Errorf("CollisionProbability(%d) = %g, want %g", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestSubsample(t *testing.T) {
+	candles := []Candle(benchSeries(10))
+	w := NewWindow("BTCUSDT", "1m", candles[9].CloseTime, 10, 2, candles)
+
+	tests := []struct {
+		name       string
+		sub        func(targetLen int) *Window
+		targetLen  int
+		first      int // Index of the first kept candle
+		n          int // Candles kept
+		isComplete bool
+	}{
+		{"last", w.Subsample, 4, 6, 4, true},
+		{"first", w.SubsampleFirst, 4, 0, 4, true},
+		{"center even split", w.SubsampleCenter, 4, 3, 4, true},
+		{"center drops the extra candle from the front", w.SubsampleCenter, 5, 3, 5, true},
+		{"last of every candle", w.Subsample, 10, 0, 10, true},
+		{"first of every candle", w.SubsampleFirst, 10, 0, 10, true},
+		{"center of every candle", w.SubsampleCenter, 10, 0, 10, true},
+		{"last beyond the window", w.Subsample, 12, 0, 10, false},
+		{"first beyond the window", w.SubsampleFirst, 12, 0, 10, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := tt.sub(tt.targetLen)
+			if len(sub.Candles) != tt.n || !sub.Candles[0].OpenTime.Equal(candles[tt.first].OpenTime) {
+				t.Fatalf("kept %d candles from %s, want %d from candle %d", len(sub.Candles), sub.Candles[0].OpenTime, tt.n, tt.first)
+			}
+			if last := candles[tt.first+tt.n-1]; !sub.TEnd.Equal(last.CloseTime) {
+				t.Errorf("TEnd = %s, want the last kept candle's close %s", sub.TEnd, last.CloseTime)
+			}
+			if sub.W != tt.targetLen || sub.IsComplete() != tt.isComplete {
+				t.Errorf("W = %d, IsComplete = %v; want %d, %v", sub.W, sub.IsComplete(), tt.targetLen, tt.isComplete)
+			}
+			if want := NewWindow("BTCUSDT", "1m", sub.TEnd, tt.targetLen, 2, nil).WindowID; sub.WindowID != want {
+				t.Errorf("WindowID = %s, want the ID of the sub-range %s", sub.WindowID, want)
+			}
+		})
+	}
+
+	if w.Subsample(0) != nil || (&Window{}).Subsample(3) != nil {
+		t.Error("Subsample of a non-positive length or an empty window is not nil")
+	}
+
+	// Subsampled candles are copies
+	sub := w.Subsample(2)
+	sub.Candles[0].Close = -1
+	if w.Candles[8].Close == -1 {
+		t.Error("Subsample shares candles with the source window")
+	}
+}
@@ -0,0 +1,71 @@
+package outcome
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// benchmarkPath holds a benchmark's base price and forward candles aligned to a window's end
+type benchmarkPath struct {
+	base    float64
+	candles []model.Candle
+}
+
+// fetchBenchmark loads the configured benchmark's base candle and the forward candles horizons may need for a window
+// Returns nil when no benchmark is configured or its data is unavailable
+func (e *Engine) fetchBenchmark(ctx context.Context, w *model.Window, horizons []int) *benchmarkPath {
+	last := w.LastCandle()
+	if e.config.BenchmarkSymbol == "" || last == nil {
+		return nil
+	}
+
+	barDuration, err := model.TimeframeDuration(w.Timeframe)
+	if err != nil {
+		return nil
+	}
+
+	lookahead := forwardLookahead(w.Timeframe, horizons)
+	series, err := e.candleRepo.GetByTimeRange(ctx, e.config.BenchmarkSymbol, w.Timeframe,
+		last.CloseTime.Add(-barDuration), last.CloseTime.Add(lookahead))
+	if err != nil {
+		return nil
+	}
+
+	return benchmarkFromSeries(series, last.CloseTime, lookahead)
+}
+
+// benchmarkFromSeries extracts the benchmark path starting at from, at most lookahead long, out of a longer candle series
+// The base price is the close of the last candle opening before from
+func benchmarkFromSeries(series []model.Candle, from time.Time, lookahead time.Duration) *benchmarkPath {
+	idx := sort.Search(len(series), func(i int) bool {
+		return !series[i].OpenTime.Before(from)
+	})
+	if idx == 0 || series[idx-1].Close == 0 {
+		return nil
+	}
+
+	return &benchmarkPath{
+		base:    series[idx-1].Close,
+		candles: sliceForward(series, from, lookahead),
+	}
+}
+
+// applyBenchmark fills the excess-return fields of a result computed over assetForward
+// When the benchmark lacks aligned data at the last bar, the raw return is used and BenchmarkMissing is set
+func applyBenchmark(r *Result, symbol string, bench *benchmarkPath, assetForward []model.Candle) {
+	r.BenchmarkSymbol = symbol
+
+	h := len(assetForward)
+	if bench == nil || h == 0 || len(bench.candles) < h ||
+		!bench.candles[h-1].OpenTime.Equal(assetForward[h-1].OpenTime) {
+		r.BenchmarkMissing = true
+		r.ExcessRetEnd = r.FwdRetEnd
+		return
+	}
+
+	r.BenchmarkRetEnd = (bench.candles[h-1].Close - bench.base) / bench.base
+	r.ExcessRetEnd = r.FwdRetEnd - r.BenchmarkRetEnd
+}
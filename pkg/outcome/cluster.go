@@ -0,0 +1,60 @@
+package outcome
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tunogya/etna/pkg/store/duckdb"
+)
+
+// ClusterKey identifies a volatility/trend regime by its feature buckets
+type ClusterKey struct {
+	VolBucket   int
+	TrendBucket int
+}
+
+// CalculateByFeatureCluster aggregates stored outcomes for every window in one volatility/trend bucket combination
+// Only outcomes already persisted in outcomeRepo are used; run the refresh job first to populate them
+func (e *Engine) CalculateByFeatureCluster(ctx context.Context, symbol, timeframe string, horizons []int, featureRepo *duckdb.FeatureRepo, outcomeRepo *duckdb.OutcomeRepo, volBucket, trendBucket int) (map[int]AggregatedOutcome, error) {
+	ids, err := featureRepo.GetWindowIDsByBuckets(ctx, symbol, timeframe, volBucket, trendBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for start := 0; start < len(ids); start += refreshBatchSize {
+		end := start + refreshBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		stored, err := outcomeRepo.GetByWindowIDs(ctx, ids[start:end], horizons)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load outcomes for cluster (%d, %d): %w", volBucket, trendBucket, err)
+		}
+		for _, o := range stored {
+			results = append(results, ResultFromOutcome(o))
+		}
+	}
+
+	return AggregateResults(results), nil
+}
+
+// CalculateAllClusters runs CalculateByFeatureCluster for every populated bucket combination
+func (e *Engine) CalculateAllClusters(ctx context.Context, symbol, timeframe string, horizons []int, featureRepo *duckdb.FeatureRepo, outcomeRepo *duckdb.OutcomeRepo) (map[ClusterKey]map[int]AggregatedOutcome, error) {
+	buckets, err := featureRepo.GetBucketCombinations(ctx, symbol, timeframe)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := make(map[ClusterKey]map[int]AggregatedOutcome, len(buckets))
+	for _, b := range buckets {
+		aggregated, err := e.CalculateByFeatureCluster(ctx, symbol, timeframe, horizons, featureRepo, outcomeRepo, b.VolBucket, b.TrendBucket)
+		if err != nil {
+			return nil, err
+		}
+		clusters[ClusterKey{VolBucket: b.VolBucket, TrendBucket: b.TrendBucket}] = aggregated
+	}
+
+	return clusters, nil
+}
@@ -0,0 +1,86 @@
+package outcome
+
+import (
+	"sort"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// Neighbor identifies a search neighbor's time span and similarity for de-duplication
+type Neighbor struct {
+	WindowID   string
+	Symbol     string
+	Similarity float64
+	TStart     time.Time
+	TEnd       time.Time
+}
+
+// DedupNeighbors groups neighbors whose windows overlap in time by more than maxOverlapFrac
+// and keeps the most similar neighbor of each group. Neighbors of different symbols never overlap.
+// A maxOverlapFrac >= 1 disables de-duplication.
+func DedupNeighbors(neighbors []Neighbor, maxOverlapFrac float64) []Neighbor {
+	if maxOverlapFrac >= 1 {
+		return neighbors
+	}
+
+	sorted := make([]Neighbor, len(neighbors))
+	copy(sorted, neighbors)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Similarity > sorted[j].Similarity
+	})
+
+	var kept []Neighbor
+	for _, n := range sorted {
+		duplicate := false
+		for _, k := range kept {
+			if k.Symbol != n.Symbol {
+				continue
+			}
+			if model.OverlapFraction(k.TStart, k.TEnd, n.TStart, n.TEnd) > maxOverlapFrac {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			kept = append(kept, n)
+		}
+	}
+
+	return kept
+}
+
+// AggregateResultsDedup aggregates results after collapsing time-overlapping neighbors
+// SampleCount reports the de-duplicated count and RawSampleCount the count before de-duplication,
+// both counting only results with statistics
+func AggregateResultsDedup(results []Result, neighbors []Neighbor, maxOverlapFrac float64) map[int]AggregatedOutcome {
+	kept := keptWindowIDs(neighbors, maxOverlapFrac)
+
+	rawCounts := make(map[int]int)
+	var filtered []Result
+	for _, r := range results {
+		if r.HasStatistics() {
+			rawCounts[r.Horizon]++
+		}
+		if kept[r.WindowID] {
+			filtered = append(filtered, r)
+		}
+	}
+
+	aggregated := AggregateResults(filtered)
+	for horizon, agg := range aggregated {
+		agg.RawSampleCount = rawCounts[horizon]
+		aggregated[horizon] = agg
+	}
+
+	return aggregated
+}
+
+// keptWindowIDs returns the set of window IDs surviving DedupNeighbors
+func keptWindowIDs(neighbors []Neighbor, maxOverlapFrac float64) map[string]bool {
+	kept := make(map[string]bool)
+	for _, n := range DedupNeighbors(neighbors, maxOverlapFrac) {
+		kept[n.WindowID] = true
+	}
+	return kept
+}
@@ -0,0 +1,85 @@
+package outcome
+
+import (
+	"testing"
+	"time"
+)
+
+// shiftedNeighbors returns n BTCUSDT neighbors of 10 daily bars, each shifted one bar later and slightly less similar
+func shiftedNeighbors(n int) []Neighbor {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	neighbors := make([]Neighbor, n)
+	for i := range neighbors {
+		tStart := start.AddDate(0, 0, i)
+		neighbors[i] = Neighbor{
+			WindowID:   string(rune('a' + i)),
+			Symbol:     "BTCUSDT",
+			Similarity: 0.9 - 0.01*float64(i),
+			TStart:     tStart,
+			TEnd:       tStart.AddDate(0, 0, 10),
+		}
+	}
+	return neighbors
+}
+
+func TestDedupNeighbors(t *testing.T) {
+	neighbors := shiftedNeighbors(4)
+	other := neighbors[1]
+	other.WindowID, other.Symbol = "eth", "ETHUSDT"
+	neighbors = append(neighbors, other)
+
+	tests := []struct {
+		name    string
+		maxFrac float64
+		want    []string
+	}{
+		{"disabled", 1, []string{"a", "b", "c", "d", "eth"}},
+		{"shifted copies collapse", 0.5, []string{"a", "eth"}},
+		{"one-bar shifts only", 0.85, []string{"a", "c", "eth"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept := DedupNeighbors(neighbors, tt.maxFrac)
+			got := make(map[string]bool)
+			for _, n := range kept {
+				got[n.WindowID] = true
+			}
+			if len(kept) != len(tt.want) {
+				t.Fatalf("kept %d neighbors, want %v", len(kept), tt.want)
+			}
+			for _, id := range tt.want {
+				if !got[id] {
+					t.Errorf("%s was dropped, want %v", id, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAggregateResultsDedupCountsCompleteSamples(t *testing.T) {
+	neighbors := shiftedNeighbors(4)
+	results := []Result{
+		{WindowID: "a", Horizon: 5, FwdCandles: 5, FwdRetEnd: 0.01, HitRate: 1},
+		{WindowID: "b", Horizon: 5, FwdCandles: 5, FwdRetEnd: 0.02, HitRate: 1},
+		{WindowID: "c", Horizon: 5, FwdCandles: 2}, // Skipped horizon, no statistics
+		{WindowID: "d", Horizon: 5},                // No forward candles
+	}
+
+	tests := []struct {
+		name        string
+		maxFrac     float64
+		wantSamples int
+		wantRaw     int
+	}{
+		{"no dedup", 1, 2, 2},
+		{"dedup", 0.5, 1, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agg := AggregateResultsDedup(results, neighbors, tt.maxFrac)[5]
+			if agg.SampleCount != tt.wantSamples || agg.RawSampleCount != tt.wantRaw {
+				t.Errorf("samples %d/%d raw, want %d/%d", agg.SampleCount, agg.RawSampleCount, tt.wantSamples, tt.wantRaw)
+			}
+		})
+	}
+}
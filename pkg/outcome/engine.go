@@ -11,25 +11,80 @@ import (
 	"github.com/tunogya/etna/pkg/store/duckdb"
 )
 
+// Forward candles are fetched for the longest horizon plus slack, so gaps in candle data do not cut it short
+const (
+	lookaheadSlackFrac = 0.5 // Extra fraction of the longest horizon
+	lookaheadSlackBars = 5   // Minimum extra bars
+
+	// defaultLookahead bounds the fetch when the timeframe has no known bar duration
+	defaultLookahead = time.Hour * 24 * 30
+)
+
+// forwardLookahead returns how far past a window's end forward candles are fetched for horizons
+func forwardLookahead(timeframe string, horizons []int) time.Duration {
+	barDuration, err := model.TimeframeDuration(timeframe)
+	if err != nil {
+		return defaultLookahead
+	}
+	bars := maxHorizon(horizons)
+	slack := max(int(math.Ceil(float64(bars)*lookaheadSlackFrac)), lookaheadSlackBars)
+	return time.Duration(bars+slack) * barDuration
+}
+
 // Engine calculates forward-looking statistics for windows
 type Engine struct {
 	candleRepo *duckdb.CandleRepo
+	config     Config
 }
 
 // NewEngine creates a new outcome engine
 func NewEngine(candleRepo *duckdb.CandleRepo) *Engine {
-	return &Engine{candleRepo: candleRepo}
+	return &Engine{candleRepo: candleRepo, config: DefaultConfig()}
+}
+
+// NewEngineWithConfig creates a new outcome engine with the given configuration
+func NewEngineWithConfig(candleRepo *duckdb.CandleRepo, cfg Config) *Engine {
+	return &Engine{candleRepo: candleRepo, config: cfg}
 }
 
 // Config holds configuration for outcome calculation
 type Config struct {
-	Horizons []int // Forward horizons (number of bars)
+	Horizons  []int   // Forward horizons (number of bars)
+	TargetPct float64 // Time-to-target threshold as a fraction (0.02 = ±2%); 0 disables
+
+	// BenchmarkSymbol, when set, adds excess returns relative to this symbol's forward path
+	BenchmarkSymbol string
+
+	// Partial controls horizons with fewer forward bars than requested (empty means PartialSkip)
+	Partial PartialPolicy
+
+	// IncludePath attaches the bar-by-bar cumulative return path up to the max horizon to each result
+	IncludePath bool
+
+	// Progress, when set, is called after each page of ComputeAndCacheAll
+	Progress ProgressCallback
+}
+
+// CacheProgress reports how far ComputeAndCacheAll has got
+type CacheProgress struct {
+	Total   int64 // Windows of the symbol/timeframe
+	Windows int64 // Windows examined so far
+	Skipped int64 // Windows already cached at every horizon
+	Cached  int64 // Outcomes inserted so far
+
+	// Of the windows computed, those now cached at every horizon and those lacking forward data for some horizon
+	Complete int64
+	Partial  int64
 }
 
+// ProgressCallback receives ComputeAndCacheAll progress
+type ProgressCallback func(progress CacheProgress)
+
 // DefaultConfig returns default configuration
 func DefaultConfig() Config {
 	return Config{
 		Horizons: []int{5, 20, 60},
+		Partial:  PartialSkip,
 	}
 }
 
@@ -42,7 +97,88 @@ type Result struct {
 	FwdRetP50  float64
 	FwdRetP90  float64
 	MDDP95     float64
-	FwdCandles int // Number of forward candles actually found
+	FwdRetEnd  float64 // Return at the horizon bar
+	HitRate    float64 // 1 if the forward path ended positive, 0 otherwise
+	MFE        float64 // Max favorable excursion: (highest high - base) / base
+	MAE        float64 // Max adverse excursion: (base - lowest low) / base
+	FwdCandles int     // Number of forward candles actually found; below Horizon without Partial, the result has no statistics
+	Partial    bool    // Statistics cover only FwdCandles bars (< Horizon) under PartialCompute
+
+	// Time-to-target (only populated when TargetPct > 0)
+	TargetPct        float64 // Threshold used, as a fraction of base price
+	BarsToTargetUp   int     // Index of the first forward bar whose high reaches base×(1+TargetPct), -1 if never
+	BarsToTargetDown int     // Index of the first forward bar whose low reaches base×(1-TargetPct), -1 if never
+
+	// Benchmark-relative returns (only populated when BenchmarkSymbol is configured)
+	BenchmarkSymbol  string
+	BenchmarkRetEnd  float64 // Benchmark return over the same bars
+	ExcessRetEnd     float64 // FwdRetEnd - BenchmarkRetEnd (raw return when the benchmark is missing)
+	BenchmarkMissing bool    // True when benchmark data was unavailable and ExcessRetEnd fell back to raw
+
+	// ReturnPath holds cumulative returns for each forward bar up to the max horizon (only with IncludePath)
+	// Shared by all results of the same window and shorter than the max horizon when forward data runs out
+	ReturnPath []float64
+}
+
+// HasStatistics reports whether r has forward candles and either covers its horizon or was computed as Partial
+// Aggregates take their samples, and their sample counts, from these results only
+func (r Result) HasStatistics() bool {
+	return r.FwdCandles > 0 && (r.FwdCandles >= r.Horizon || r.Partial)
+}
+
+// PctAboveThreshold returns 1 if the return at the horizon exceeds x, 0 otherwise
+func (r Result) PctAboveThreshold(x float64) float64 {
+	if r.FwdCandles > 0 && r.FwdRetEnd > x {
+		return 1
+	}
+	return 0
+}
+
+// ToOutcome converts a Result into a model.Outcome for persistence
+func (r Result) ToOutcome() *model.Outcome {
+	o := &model.Outcome{
+		WindowID:   r.WindowID,
+		Horizon:    r.Horizon,
+		FwdRetMean: r.FwdRetMean,
+		FwdRetP10:  r.FwdRetP10,
+		FwdRetP50:  r.FwdRetP50,
+		FwdRetP90:  r.FwdRetP90,
+		MDDP95:     r.MDDP95,
+		FwdRetEnd:  r.FwdRetEnd,
+		HitRate:    r.HitRate,
+		MFE:        r.MFE,
+		MAE:        r.MAE,
+	}
+	if r.BenchmarkSymbol != "" && !r.BenchmarkMissing {
+		o.BenchmarkSymbol = r.BenchmarkSymbol
+		o.ExcessRetEnd = r.ExcessRetEnd
+	}
+	return o
+}
+
+// ResultFromOutcome converts a cached model.Outcome back into a Result
+// Cached outcomes are only stored for complete horizons, so FwdCandles is set to the horizon
+func ResultFromOutcome(o *model.Outcome) Result {
+	r := Result{
+		WindowID:   o.WindowID,
+		Horizon:    o.Horizon,
+		FwdRetMean: o.FwdRetMean,
+		FwdRetP10:  o.FwdRetP10,
+		FwdRetP50:  o.FwdRetP50,
+		FwdRetP90:  o.FwdRetP90,
+		MDDP95:     o.MDDP95,
+		FwdRetEnd:  o.FwdRetEnd,
+		HitRate:    o.HitRate,
+		MFE:        o.MFE,
+		MAE:        o.MAE,
+		FwdCandles: o.Horizon,
+	}
+	if o.BenchmarkSymbol != "" {
+		r.BenchmarkSymbol = o.BenchmarkSymbol
+		r.ExcessRetEnd = o.ExcessRetEnd
+		r.BenchmarkRetEnd = o.FwdRetEnd - o.ExcessRetEnd
+	}
+	return r
 }
 
 // Calculate computes outcome statistics for the given windows
@@ -50,43 +186,109 @@ func (e *Engine) Calculate(ctx context.Context, windows []*model.Window, horizon
 	var results []Result
 
 	for _, w := range windows {
-		last := w.LastCandle()
-		if last == nil {
+		if w.LastCandle() == nil {
 			continue
 		}
 
-		// Fetch forward candles from the window's end time
-		endTime := last.CloseTime.Add(time.Hour * 24 * 30) // Look up to 30 days ahead
-		candles, err := e.candleRepo.GetByTimeRange(ctx, w.Symbol, w.Timeframe, last.CloseTime, endTime)
+		candles, err := e.forwardCandles(ctx, w, horizons)
 		if err != nil {
 			continue
 		}
 
-		basePrice := last.Close
-		if basePrice == 0 {
-			continue
+		windowResults, err := e.calculateWindow(w, candles, e.fetchBenchmark(ctx, w, horizons), horizons)
+		if err != nil {
+			return nil, err
 		}
+		results = append(results, windowResults...)
+	}
+
+	return results, nil
+}
+
+// calculateWindow computes results for every horizon of a window given its forward candles
+// bench may be nil when no benchmark is configured or its data is unavailable
+// Incomplete horizons are handled according to the configured PartialPolicy; those it skips still yield a
+// skippedResult, which AggregateResults counts in PartialExcluded
+func (e *Engine) calculateWindow(w *model.Window, candles []model.Candle, bench *benchmarkPath, horizons []int) ([]Result, error) {
+	last := w.LastCandle()
+	if last == nil {
+		return nil, nil
+	}
 
-		for _, horizon := range horizons {
-			if len(candles) < horizon {
-				// Not enough forward data
-				results = append(results, Result{
-					WindowID:   w.WindowID,
-					Horizon:    horizon,
-					FwdCandles: len(candles),
-				})
+	basePrice := last.Close
+	if basePrice == 0 {
+		return nil, nil
+	}
+
+	var path []float64
+	if e.config.IncludePath {
+		path = returnPath(basePrice, candles, maxHorizon(horizons))
+	}
+
+	results := make([]Result, 0, len(horizons))
+	for _, horizon := range horizons {
+		partial := len(candles) < horizon
+		if partial {
+			// Not enough forward data
+			switch e.config.Partial {
+			case PartialError:
+				return nil, fmt.Errorf("window %s horizon %d has %d forward bars: %w",
+					w.WindowID, horizon, len(candles), ErrIncompleteHorizon)
+			case PartialCompute:
+				if len(candles) == 0 {
+					results = append(results, skippedResult(w.WindowID, horizon, 0))
+					continue
+				}
+			default:
+				results = append(results, skippedResult(w.WindowID, horizon, len(candles)))
 				continue
 			}
+		}
 
-			forwardCandles := candles[:horizon]
-			result := calculateStats(w.WindowID, horizon, basePrice, forwardCandles)
-			results = append(results, result)
+		forwardCandles := candles
+		if !partial {
+			forwardCandles = candles[:horizon]
 		}
+		result := calculateStats(w.WindowID, horizon, basePrice, forwardCandles)
+		result.Partial = partial
+		if e.config.TargetPct > 0 {
+			result.TargetPct = e.config.TargetPct
+			result.BarsToTargetUp, result.BarsToTargetDown = calculateBarsToTarget(basePrice, e.config.TargetPct, forwardCandles)
+		}
+		if e.config.BenchmarkSymbol != "" {
+			applyBenchmark(&result, e.config.BenchmarkSymbol, bench, forwardCandles)
+		}
+		result.ReturnPath = path
+		results = append(results, result)
 	}
 
 	return results, nil
 }
 
+// skippedResult is the statistics-free result of a horizon left incomplete, so aggregates can count it as excluded
+func skippedResult(windowID string, horizon, fwdCandles int) Result {
+	return Result{WindowID: windowID, Horizon: horizon, FwdCandles: fwdCandles}
+}
+
+// forwardCandles fetches the candles following the window's last candle that horizons may need
+func (e *Engine) forwardCandles(ctx context.Context, w *model.Window, horizons []int) ([]model.Candle, error) {
+	last := w.LastCandle()
+	if last == nil {
+		return nil, fmt.Errorf("window %s has no candles", w.WindowID)
+	}
+
+	// Fetch candles closing after the window's last candle; the range is inclusive, so drop the last candle itself
+	endTime := last.CloseTime.Add(forwardLookahead(w.Timeframe, horizons))
+	candles, err := e.candleRepo.GetByCloseTimeRange(ctx, w.Symbol, w.Timeframe, last.CloseTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	for len(candles) > 0 && !candles[0].CloseTime.After(last.CloseTime) {
+		candles = candles[1:]
+	}
+	return candles, nil
+}
+
 // CalculateForWindowIDs computes outcomes for window IDs (requires fetching windows first)
 func (e *Engine) CalculateForWindowIDs(ctx context.Context, windowIDs []string, symbol, timeframe string, horizons []int, windowRepo *duckdb.WindowRepo) ([]Result, error) {
 	var windows []*model.Window
@@ -96,12 +298,155 @@ func (e *Engine) CalculateForWindowIDs(ctx context.Context, windowIDs []string,
 		if err != nil {
 			continue
 		}
+
+		// Stored windows carry no candles; hydrate them so the base price is known
+		candles, err := e.candleRepo.GetWindowCandles(ctx, w.Symbol, w.Timeframe, w.TEnd, w.W)
+		if err != nil || len(candles) == 0 {
+			continue
+		}
+		w.Candles = candles
+
 		windows = append(windows, w)
 	}
 
 	return e.Calculate(ctx, windows, horizons)
 }
 
+// LoadOrCalculate returns cached outcomes from outcomeRepo and computes any missing window-horizon pairs
+// Time-to-target statistics and return paths are not cached, so a configured TargetPct or IncludePath always recomputes
+// With a BenchmarkSymbol configured, only cached rows computed against that benchmark are reused
+func (e *Engine) LoadOrCalculate(ctx context.Context, windowIDs []string, horizons []int, windowRepo *duckdb.WindowRepo, outcomeRepo *duckdb.OutcomeRepo) ([]Result, error) {
+	if e.config.TargetPct > 0 || e.config.IncludePath {
+		return e.CalculateForWindowIDs(ctx, windowIDs, "", "", horizons, windowRepo)
+	}
+
+	cached, err := outcomeRepo.GetByWindowIDs(ctx, windowIDs, horizons)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cached outcomes: %w", err)
+	}
+
+	var results []Result
+	have := make(map[string]map[int]bool)
+	for _, o := range cached {
+		if e.config.BenchmarkSymbol != "" && o.BenchmarkSymbol != e.config.BenchmarkSymbol {
+			continue
+		}
+		if have[o.WindowID] == nil {
+			have[o.WindowID] = make(map[int]bool)
+		}
+		have[o.WindowID][o.Horizon] = true
+		results = append(results, ResultFromOutcome(o))
+	}
+
+	var missing []string
+	for _, id := range windowIDs {
+		if len(have[id]) < len(horizons) {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return results, nil
+	}
+
+	computed, err := e.CalculateForWindowIDs(ctx, missing, "", "", horizons, windowRepo)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range computed {
+		if have[r.WindowID][r.Horizon] {
+			continue
+		}
+		results = append(results, r)
+	}
+
+	return results, nil
+}
+
+// ComputeAndCacheAll computes and stores the outcomes of every symbol/timeframe window, batchSize windows per page
+// Window-horizon pairs already in window_outcomes are skipped, as are horizons lacking enough forward candles,
+// so running it again inserts nothing new. It returns the number of outcomes inserted
+func (e *Engine) ComputeAndCacheAll(ctx context.Context, symbol, timeframe string, horizons []int, windowRepo *duckdb.WindowRepo, outcomeRepo *duckdb.OutcomeRepo, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		return 0, fmt.Errorf("batch size must be positive, got %d", batchSize)
+	}
+
+	total, err := windowRepo.Count(ctx, symbol, timeframe)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count windows: %w", err)
+	}
+
+	progress := CacheProgress{Total: total}
+	for offset := 0; ; offset += batchSize {
+		if err := ctx.Err(); err != nil {
+			return progress.Cached, err
+		}
+
+		ids, err := windowRepo.GetWindowIDsPage(ctx, symbol, timeframe, offset, batchSize)
+		if err != nil {
+			return progress.Cached, fmt.Errorf("failed to list windows: %w", err)
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		cached, err := outcomeRepo.GetByWindowIDs(ctx, ids, horizons)
+		if err != nil {
+			return progress.Cached, fmt.Errorf("failed to load cached outcomes: %w", err)
+		}
+		have := make(map[string]map[int]bool)
+		for _, o := range cached {
+			if have[o.WindowID] == nil {
+				have[o.WindowID] = make(map[int]bool)
+			}
+			have[o.WindowID][o.Horizon] = true
+		}
+
+		var missing []string
+		for _, id := range ids {
+			if len(have[id]) < len(horizons) {
+				missing = append(missing, id)
+			}
+		}
+		progress.Windows += int64(len(ids))
+		progress.Skipped += int64(len(ids) - len(missing))
+
+		if len(missing) > 0 {
+			results, err := e.CalculateForWindowIDs(ctx, missing, symbol, timeframe, horizons, windowRepo)
+			if err != nil {
+				return progress.Cached, fmt.Errorf("failed to calculate outcomes: %w", err)
+			}
+
+			var outcomes []*model.Outcome
+			stored := make(map[string]int)
+			for _, r := range results {
+				if r.FwdCandles < r.Horizon || have[r.WindowID][r.Horizon] {
+					continue
+				}
+				outcomes = append(outcomes, r.ToOutcome())
+				stored[r.WindowID]++
+			}
+			if err := outcomeRepo.InsertBatch(ctx, outcomes); err != nil {
+				return progress.Cached, fmt.Errorf("failed to store outcomes: %w", err)
+			}
+			progress.Cached += int64(len(outcomes))
+
+			for _, id := range missing {
+				if len(have[id])+stored[id] == len(horizons) {
+					progress.Complete++
+				} else {
+					progress.Partial++
+				}
+			}
+		}
+
+		if e.config.Progress != nil {
+			e.config.Progress(progress)
+		}
+	}
+
+	return progress.Cached, nil
+}
+
 // calculateStats computes statistics for a set of forward candles
 func calculateStats(windowID string, horizon int, basePrice float64, candles []model.Candle) Result {
 	if len(candles) == 0 {
@@ -121,6 +466,16 @@ func calculateStats(windowID string, horizon int, basePrice float64, candles []m
 	// Calculate MDD (maximum drawdown from base price)
 	mdd := calculateMDD(basePrice, candles)
 
+	// Excursions within the horizon
+	mfe, mae := calculateExcursions(basePrice, candles)
+
+	// Return at the horizon bar decides whether the path was a "hit"
+	endReturn := returns[len(returns)-1]
+	hitRate := 0.0
+	if endReturn > 0 {
+		hitRate = 1
+	}
+
 	// Sort returns for percentile calculation
 	sortedReturns := make([]float64, len(returns))
 	copy(sortedReturns, returns)
@@ -134,6 +489,10 @@ func calculateStats(windowID string, horizon int, basePrice float64, candles []m
 		FwdRetP50:  percentile(sortedReturns, 50),
 		FwdRetP90:  percentile(sortedReturns, 90),
 		MDDP95:     mdd, // For single window, just use the actual MDD
+		FwdRetEnd:  endReturn,
+		HitRate:    hitRate,
+		MFE:        mfe,
+		MAE:        mae,
 		FwdCandles: len(candles),
 	}
 }
@@ -160,6 +519,52 @@ func calculateMDD(basePrice float64, candles []model.Candle) float64 {
 	return maxDD
 }
 
+// calculateExcursions computes the max favorable and max adverse excursion from a base price
+// MFE uses the highest high and MAE the lowest low; both are expressed as positive fractions
+// when price moved in that direction, and may be negative if it never did
+func calculateExcursions(basePrice float64, candles []model.Candle) (mfe, mae float64) {
+	if len(candles) == 0 || basePrice == 0 {
+		return 0, 0
+	}
+
+	highest := candles[0].High
+	lowest := candles[0].Low
+	for _, c := range candles[1:] {
+		if c.High > highest {
+			highest = c.High
+		}
+		if c.Low < lowest {
+			lowest = c.Low
+		}
+	}
+
+	mfe = (highest - basePrice) / basePrice
+	mae = (basePrice - lowest) / basePrice
+	return mfe, mae
+}
+
+// calculateBarsToTarget finds the first forward bar crossing base×(1±targetPct)
+// Returns the 0-based bar index for each direction, or -1 if the target is never reached
+func calculateBarsToTarget(basePrice, targetPct float64, candles []model.Candle) (up, down int) {
+	up, down = -1, -1
+	upper := basePrice * (1 + targetPct)
+	lower := basePrice * (1 - targetPct)
+
+	for i, c := range candles {
+		if up < 0 && c.High >= upper {
+			up = i
+		}
+		if down < 0 && c.Low <= lower {
+			down = i
+		}
+		if up >= 0 && down >= 0 {
+			break
+		}
+	}
+
+	return up, down
+}
+
 // mean calculates the arithmetic mean
 func mean(values []float64) float64 {
 	if len(values) == 0 {
@@ -203,7 +608,21 @@ func AggregateResults(results []Result) map[int]AggregatedOutcome {
 	}
 
 	aggregated := make(map[int]AggregatedOutcome)
-	for horizon, horizonResults := range byHorizon {
+	for horizon, all := range byHorizon {
+		// Results without forward candles, and incomplete ones not computed under PartialCompute, carry no
+		// statistics; every statistic below is taken over the same remaining samples
+		horizonResults := make([]Result, 0, len(all))
+		var partialIncluded, partialExcluded int
+		for _, r := range all {
+			switch {
+			case !r.HasStatistics():
+				partialExcluded++
+				continue
+			case r.Partial:
+				partialIncluded++
+			}
+			horizonResults = append(horizonResults, r)
+		}
 		if len(horizonResults) == 0 {
 			continue
 		}
@@ -213,6 +632,17 @@ func AggregateResults(results []Result) map[int]AggregatedOutcome {
 		p50s := make([]float64, len(horizonResults))
 		p90s := make([]float64, len(horizonResults))
 		mdds := make([]float64, len(horizonResults))
+		hits := make([]float64, len(horizonResults))
+		endReturns := make([]float64, 0, len(horizonResults))
+		mfes := make([]float64, 0, len(horizonResults))
+		maes := make([]float64, 0, len(horizonResults))
+
+		var excess []float64
+		benchmarkMissing := 0
+
+		var targetPct float64
+		var targeted int
+		var barsUp, barsDown []float64
 
 		for i, r := range horizonResults {
 			means[i] = r.FwdRetMean
@@ -220,19 +650,70 @@ func AggregateResults(results []Result) map[int]AggregatedOutcome {
 			p50s[i] = r.FwdRetP50
 			p90s[i] = r.FwdRetP90
 			mdds[i] = r.MDDP95
+			hits[i] = r.HitRate
+			endReturns = append(endReturns, r.FwdRetEnd)
+			mfes = append(mfes, r.MFE)
+			maes = append(maes, r.MAE)
+			if r.BenchmarkSymbol != "" {
+				if r.BenchmarkMissing {
+					benchmarkMissing++
+				} else {
+					excess = append(excess, r.ExcessRetEnd)
+				}
+			}
+			if r.TargetPct > 0 {
+				targetPct = r.TargetPct
+				targeted++
+				if r.BarsToTargetUp >= 0 {
+					barsUp = append(barsUp, float64(r.BarsToTargetUp))
+				}
+				if r.BarsToTargetDown >= 0 {
+					barsDown = append(barsDown, float64(r.BarsToTargetDown))
+				}
+			}
 		}
 
 		sort.Float64s(mdds)
+		sort.Float64s(endReturns)
+		sort.Float64s(mfes)
+		sort.Float64s(maes)
+		sort.Float64s(barsUp)
+		sort.Float64s(barsDown)
+
+		agg := AggregatedOutcome{
+			Horizon:        horizon,
+			SampleCount:    len(horizonResults),
+			RawSampleCount: len(horizonResults),
+			MeanReturn:     mean(means),
+			MedianP10:      mean(p10s),
+			MedianP50:      mean(p50s),
+			MedianP90:      mean(p90s),
+			MDDP95:         percentile(mdds, 95),
+			HitRate:        mean(hits),
+			MFEP50:         percentile(mfes, 50),
+			MFEP95:         percentile(mfes, 95),
+			MAEP50:         percentile(maes, 50),
+			MAEP95:         percentile(maes, 95),
+
+			PartialIncluded: partialIncluded,
+			PartialExcluded: partialExcluded,
+
+			MeanExcessReturn:      mean(excess),
+			BenchmarkSampleCount:  len(excess),
+			BenchmarkMissingCount: benchmarkMissing,
+
+			endReturns: endReturns,
+		}
 
-		aggregated[horizon] = AggregatedOutcome{
-			Horizon:     horizon,
-			SampleCount: len(horizonResults),
-			MeanReturn:  mean(means),
-			MedianP10:   mean(p10s),
-			MedianP50:   mean(p50s),
-			MedianP90:   mean(p90s),
-			MDDP95:      percentile(mdds, 95),
+		if targeted > 0 {
+			agg.TargetPct = targetPct
+			agg.PctReachedUp = float64(len(barsUp)) / float64(targeted)
+			agg.PctReachedDown = float64(len(barsDown)) / float64(targeted)
+			agg.MedianBarsToTargetUp = medianOrNone(barsUp)
+			agg.MedianBarsToTargetDown = medianOrNone(barsDown)
 		}
+
+		aggregated[horizon] = agg
 	}
 
 	return aggregated
@@ -240,19 +721,63 @@ func AggregateResults(results []Result) map[int]AggregatedOutcome {
 
 // AggregatedOutcome represents aggregated statistics across multiple windows
 type AggregatedOutcome struct {
-	Horizon     int
-	SampleCount int
-	MeanReturn  float64
-	MedianP10   float64
-	MedianP50   float64
-	MedianP90   float64
-	MDDP95      float64
+	Horizon        int
+	SampleCount    int
+	RawSampleCount int // Sample count before overlap de-duplication
+	MeanReturn     float64
+	MedianP10      float64
+	MedianP50      float64
+	MedianP90      float64
+	MDDP95         float64
+	HitRate        float64 // Fraction of forward paths ending positive at the horizon
+	MFEP50         float64 // Median max favorable excursion
+	MFEP95         float64 // 95th percentile max favorable excursion
+	MAEP50         float64 // Median max adverse excursion
+	MAEP95         float64 // 95th percentile max adverse excursion
+
+	PartialIncluded int // Partial results (fewer bars than the horizon) included in the statistics
+	PartialExcluded int // Incomplete results without statistics that were left out
+
+	// Benchmark-relative statistics (only populated when results carry a BenchmarkSymbol)
+	MeanExcessReturn      float64 // Mean excess return at the horizon over samples with benchmark data
+	BenchmarkSampleCount  int     // Samples contributing to MeanExcessReturn
+	BenchmarkMissingCount int     // Samples whose benchmark data was missing
+
+	// Time-to-target (only populated when results carry a TargetPct)
+	TargetPct              float64
+	MedianBarsToTargetUp   float64 // Median bar index reaching +TargetPct among paths that did, -1 if none
+	MedianBarsToTargetDown float64 // Median bar index reaching -TargetPct among paths that did, -1 if none
+	PctReachedUp           float64 // Fraction of paths reaching +TargetPct within the horizon
+	PctReachedDown         float64 // Fraction of paths reaching -TargetPct within the horizon
+
+	endReturns []float64 // Sorted returns at the horizon bar, one per sample
+}
+
+// medianOrNone returns the median of sorted values, or -1 when empty
+func medianOrNone(sorted []float64) float64 {
+	if len(sorted) == 0 {
+		return -1
+	}
+	return percentile(sorted, 50)
+}
+
+// PctAboveThreshold returns the fraction of forward paths whose return at the horizon exceeds x
+// It is taken over the same samples as HitRate, so PctAboveThreshold(0) equals HitRate
+func (a AggregatedOutcome) PctAboveThreshold(x float64) float64 {
+	if len(a.endReturns) == 0 {
+		return 0
+	}
+	// endReturns is sorted ascending; find the first value strictly above x
+	idx := sort.Search(len(a.endReturns), func(i int) bool {
+		return a.endReturns[i] > x
+	})
+	return float64(len(a.endReturns)-idx) / float64(len(a.endReturns))
 }
 
 // String returns a formatted string representation
 func (a AggregatedOutcome) String() string {
 	return fmt.Sprintf(
-		"Horizon: %d bars | Samples: %d | Mean: %.4f | P10: %.4f | P50: %.4f | P90: %.4f | MDD95: %.4f",
-		a.Horizon, a.SampleCount, a.MeanReturn, a.MedianP10, a.MedianP50, a.MedianP90, a.MDDP95,
+		"Horizon: %d bars | Samples: %d | Mean: %.4f | P10: %.4f | P50: %.4f | P90: %.4f | MDD95: %.4f | HitRate: %.2f | MFE50: %.4f | MAE95: %.4f",
+		a.Horizon, a.SampleCount, a.MeanReturn, a.MedianP10, a.MedianP50, a.MedianP90, a.MDDP95, a.HitRate, a.MFEP50, a.MAEP95,
 	)
 }
@@ -0,0 +1,277 @@
+package outcome
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+	"github.com/tunogya/etna/pkg/store/duckdb"
+)
+
+// newTestCandleRepo returns a candle repo over an in-memory DuckDB with the schema initialized
+func newTestCandleRepo(t testing.TB) *duckdb.CandleRepo {
+	t.Helper()
+	return duckdb.NewCandleRepo(newTestClient(t))
+}
+
+// newTestClient returns a client of an in-memory DuckDB with the schema initialized, closed when the test ends
+func newTestClient(t testing.TB) *duckdb.Client {
+	t.Helper()
+	client, err := duckdb.NewClient("")
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	if err := duckdb.InitializeSchema(client); err != nil {
+		t.Fatalf("InitializeSchema: %v", err)
+	}
+	return client
+}
+
+// testCandles returns n consecutive candles of timeframe tf starting at start, closing at 100, 101, ...
+func testCandles(t testing.TB, symbol, tf string, start time.Time, n int) []model.Candle {
+	t.Helper()
+	bar, err := model.TimeframeDuration(tf)
+	if err != nil {
+		t.Fatalf("TimeframeDuration(%q): %v", tf, err)
+	}
+	candles := make([]model.Candle, n)
+	for i := range candles {
+		open := start.Add(time.Duration(i) * bar)
+		price := 100 + float64(i)
+		candles[i] = model.Candle{
+			Symbol:    symbol,
+			Timeframe: tf,
+			OpenTime:  open,
+			CloseTime: open.Add(bar - time.Millisecond),
+			Open:      price,
+			High:      price + 1,
+			Low:       price - 1,
+			Close:     price,
+			Volume:    1,
+		}
+	}
+	return candles
+}
+
+func TestForwardLookahead(t *testing.T) {
+	tests := []struct {
+		timeframe string
+		horizons  []int
+		want      time.Duration
+	}{
+		{"1d", []int{5, 20, 60}, 90 * 24 * time.Hour},
+		{"1w", []int{60}, 90 * 7 * 24 * time.Hour},
+		{"1m", []int{4}, 9 * time.Minute}, // Slack never drops below lookaheadSlackBars
+		{"bogus", []int{60}, defaultLookahead},
+	}
+	for _, tt := range tests {
+		if got := forwardLookahead(tt.timeframe, tt.horizons); got != tt.want {
+			t.Errorf("forwardLookahead(%q, %v) = %s, want %s", tt.timeframe, tt.horizons, got, tt.want)
+		}
+	}
+}
+
+func TestCalculateCompletesLongHorizons(t *testing.T) {
+	for _, tf := range []string{"1d", "1w"} {
+		t.Run(tf, func(t *testing.T) {
+			repo := newTestCandleRepo(t)
+			ctx := context.Background()
+
+			// A 7-candle window followed by 70 forward candles
+			candles := testCandles(t, "BTCUSDT", tf, time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC), 77)
+			if err := repo.InsertBatch(ctx, candles); err != nil {
+				t.Fatalf("InsertBatch: %v", err)
+			}
+			w := &model.Window{WindowID: "w", Symbol: "BTCUSDT", Timeframe: tf, W: 7, Candles: candles[:7]}
+
+			results, err := NewEngine(repo).Calculate(ctx, []*model.Window{w}, []int{5, 20, 60})
+			if err != nil {
+				t.Fatalf("Calculate: %v", err)
+			}
+			if len(results) != 3 {
+				t.Fatalf("got %d results, want 3 complete horizons", len(results))
+			}
+			for _, r := range results {
+				if r.FwdCandles != r.Horizon {
+					t.Errorf("horizon %d: %d forward candles", r.Horizon, r.FwdCandles)
+				}
+			}
+		})
+	}
+}
+
+func TestAggregateCountsIncompleteHorizons(t *testing.T) {
+	tests := []struct {
+		policy       PartialPolicy
+		wantSamples  int
+		wantIncluded int
+		wantExcluded int
+	}{
+		{PartialSkip, 1, 0, 1},
+		{PartialCompute, 2, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			repo := newTestCandleRepo(t)
+			ctx := context.Background()
+
+			// Window a has 23 forward candles, window b only 10
+			candles := testCandles(t, "BTCUSDT", "1d", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), 30)
+			if err := repo.InsertBatch(ctx, candles); err != nil {
+				t.Fatalf("InsertBatch: %v", err)
+			}
+			windows := []*model.Window{
+				{WindowID: "a", Symbol: "BTCUSDT", Timeframe: "1d", W: 7, Candles: candles[:7]},
+				{WindowID: "b", Symbol: "BTCUSDT", Timeframe: "1d", W: 7, Candles: candles[13:20]},
+			}
+
+			cfg := DefaultConfig()
+			cfg.Partial = tt.policy
+			results, err := NewEngineWithConfig(repo, cfg).Calculate(ctx, windows, []int{5, 20})
+			if err != nil {
+				t.Fatalf("Calculate: %v", err)
+			}
+			aggregated := AggregateResults(results)
+
+			if got := aggregated[5].SampleCount; got != 2 {
+				t.Errorf("horizon 5 SampleCount = %d, want 2", got)
+			}
+			long := aggregated[20]
+			if long.SampleCount != tt.wantSamples {
+				t.Errorf("horizon 20 SampleCount = %d, want %d", long.SampleCount, tt.wantSamples)
+			}
+			if long.PartialIncluded != tt.wantIncluded || long.PartialExcluded != tt.wantExcluded {
+				t.Errorf("horizon 20 partial included/excluded = %d/%d, want %d/%d",
+					long.PartialIncluded, long.PartialExcluded, tt.wantIncluded, tt.wantExcluded)
+			}
+		})
+	}
+}
+
+func TestAggregateHitRateMatchesPctAboveZero(t *testing.T) {
+	complete := func(id string, ret float64) Result {
+		hit := 0.0
+		if ret > 0 {
+			hit = 1
+		}
+		return Result{WindowID: id, Horizon: 5, FwdRetEnd: ret, HitRate: hit, FwdCandles: 5}
+	}
+	tests := []struct {
+		name        string
+		results     []Result
+		wantHitRate float64
+		wantSamples int
+	}{
+		{"complete only", []Result{complete("a", 0.02), complete("b", -0.01), complete("c", 0.03), complete("d", 0)}, 0.5, 4},
+		{"partial without forward candles", []Result{complete("a", 0.02), complete("b", -0.01), {WindowID: "c", Horizon: 5, Partial: true}}, 0.5, 2},
+		{"skipped horizon", []Result{complete("a", 0.02), {WindowID: "b", Horizon: 5, FwdCandles: 3}}, 1, 1},
+		{"partial included", []Result{complete("a", -0.02), {WindowID: "b", Horizon: 5, FwdCandles: 3, Partial: true, FwdRetEnd: 0.01, HitRate: 1}}, 0.5, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agg := AggregateResults(tt.results)[5]
+			if agg.SampleCount != tt.wantSamples {
+				t.Errorf("SampleCount = %d, want %d", agg.SampleCount, tt.wantSamples)
+			}
+			if agg.HitRate != tt.wantHitRate {
+				t.Errorf("HitRate = %v, want %v", agg.HitRate, tt.wantHitRate)
+			}
+			if got := agg.PctAboveThreshold(0); got != agg.HitRate {
+				t.Errorf("PctAboveThreshold(0) = %v, HitRate = %v", got, agg.HitRate)
+			}
+		})
+	}
+}
+
+// bar returns a forward candle with the given high, low and close
+func bar(high, low, close float64) model.Candle {
+	return model.Candle{Open: close, High: high, Low: low, Close: close}
+}
+
+func TestCalculateExcursions(t *testing.T) {
+	// From a base of 100 the path peaks at 110 on bar 2, bottoms at 92 on bar 3 and ends at 104
+	path := []model.Candle{
+		bar(103, 99, 101),
+		bar(110, 96, 97),
+		bar(104, 92, 95),
+		bar(106, 98, 104),
+	}
+
+	mfe, mae := calculateExcursions(100, path)
+	if math.Abs(mfe-0.10) > 1e-12 {
+		t.Errorf("MFE = %v, want 0.10", mfe)
+	}
+	if math.Abs(mae-0.08) > 1e-12 {
+		t.Errorf("MAE = %v, want 0.08", mae)
+	}
+
+	r := calculateStats("w", len(path), 100, path)
+	if r.MFE != mfe || r.MAE != mae {
+		t.Errorf("Result MFE/MAE = %v/%v, want %v/%v", r.MFE, r.MAE, mfe, mae)
+	}
+	if math.Abs(r.FwdRetEnd-0.04) > 1e-12 {
+		t.Errorf("FwdRetEnd = %v, want 0.04", r.FwdRetEnd)
+	}
+}
+
+func TestCalculateExcursionsOneSided(t *testing.T) {
+	// Price only rises, so the adverse excursion is negative: the lowest low stayed above the base
+	mfe, mae := calculateExcursions(100, []model.Candle{bar(102, 101, 101.5), bar(105, 101.5, 104)})
+	if math.Abs(mfe-0.05) > 1e-12 || math.Abs(mae+0.01) > 1e-12 {
+		t.Errorf("MFE/MAE = %v/%v, want 0.05/-0.01", mfe, mae)
+	}
+	if mfe, mae := calculateExcursions(100, nil); mfe != 0 || mae != 0 {
+		t.Errorf("empty path MFE/MAE = %v/%v, want 0/0", mfe, mae)
+	}
+}
+
+func TestComputeAndCacheAllIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+	candleRepo := duckdb.NewCandleRepo(client)
+	windowRepo := duckdb.NewWindowRepo(client)
+	outcomeRepo := duckdb.NewOutcomeRepo(client)
+
+	// 30 windows of 7 bars; window i has 40-i forward bars, so the last 9 lack a 20-bar horizon
+	candles := testCandles(t, "BTCUSDT", "1h", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), 47)
+	if err := candleRepo.InsertBatch(ctx, candles); err != nil {
+		t.Fatalf("InsertBatch candles: %v", err)
+	}
+	windows := make([]*model.Window, 30)
+	for i := range windows {
+		wc := candles[i : i+7]
+		windows[i] = model.NewWindow("BTCUSDT", "1h", wc[6].CloseTime, 7, 1, wc)
+	}
+	if err := windowRepo.InsertBatch(ctx, windows); err != nil {
+		t.Fatalf("InsertBatch windows: %v", err)
+	}
+
+	var last CacheProgress
+	cfg := DefaultConfig()
+	cfg.Progress = func(p CacheProgress) { last = p }
+	engine := NewEngineWithConfig(candleRepo, cfg)
+	horizons := []int{5, 20}
+
+	first, err := engine.ComputeAndCacheAll(ctx, "BTCUSDT", "1h", horizons, windowRepo, outcomeRepo, 8)
+	if err != nil {
+		t.Fatalf("first ComputeAndCacheAll: %v", err)
+	}
+	if first != 51 || last.Complete != 21 || last.Partial != 9 {
+		t.Errorf("first run inserted %d outcomes (%+v), want 51 with 21 complete and 9 partial windows", first, last)
+	}
+
+	second, err := engine.ComputeAndCacheAll(ctx, "BTCUSDT", "1h", horizons, windowRepo, outcomeRepo, 8)
+	if err != nil {
+		t.Fatalf("second ComputeAndCacheAll: %v", err)
+	}
+	if second != 0 || last.Skipped != 21 {
+		t.Errorf("second run inserted %d outcomes (%+v), want 0 with the 21 complete windows skipped", second, last)
+	}
+
+	if n, err := outcomeRepo.Count(ctx); err != nil || n != 51 {
+		t.Errorf("stored outcomes = %d (%v), want 51", n, err)
+	}
+}
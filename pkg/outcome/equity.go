@@ -0,0 +1,80 @@
+package outcome
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// EquityCurve holds path-dependent statistics for a simulated long position
+type EquityCurve struct {
+	Values            []float64 // Equity after each forward bar
+	PeakValue         float64   // Highest equity reached (including starting capital)
+	TroughValue       float64   // Lowest equity reached (including starting capital)
+	MaxDrawdownBars   int       // Longest consecutive run of bars below the running peak
+	TimeUnderwaterPct float64   // Fraction of bars spent below the running peak
+	FinalValue        float64   // Equity at the horizon bar
+}
+
+// CalculateEquityCurve simulates a long-only position opened at the window's last close
+// and held for horizon bars, marking equity to each forward bar's close
+func (e *Engine) CalculateEquityCurve(ctx context.Context, window *model.Window, horizon int, capitalUSD float64) (*EquityCurve, error) {
+	last := window.LastCandle()
+	if last == nil || last.Close == 0 {
+		return nil, fmt.Errorf("window %s has no base price", window.WindowID)
+	}
+
+	candles, err := e.forwardCandles(ctx, window, []int{horizon})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch forward candles: %w", err)
+	}
+	if len(candles) < horizon {
+		return nil, fmt.Errorf("insufficient forward data: need %d bars, got %d", horizon, len(candles))
+	}
+
+	return buildEquityCurve(last.Close, capitalUSD, candles[:horizon]), nil
+}
+
+// buildEquityCurve computes equity statistics for a position of capital opened at basePrice
+func buildEquityCurve(basePrice, capital float64, candles []model.Candle) *EquityCurve {
+	curve := &EquityCurve{
+		Values:      make([]float64, len(candles)),
+		PeakValue:   capital,
+		TroughValue: capital,
+		FinalValue:  capital,
+	}
+	if len(candles) == 0 {
+		return curve
+	}
+
+	peak := capital
+	underwater, run := 0, 0
+	for i, c := range candles {
+		value := capital * c.Close / basePrice
+		curve.Values[i] = value
+
+		if value > curve.PeakValue {
+			curve.PeakValue = value
+		}
+		if value < curve.TroughValue {
+			curve.TroughValue = value
+		}
+
+		if value >= peak {
+			peak = value
+			run = 0
+			continue
+		}
+
+		underwater++
+		run++
+		if run > curve.MaxDrawdownBars {
+			curve.MaxDrawdownBars = run
+		}
+	}
+
+	curve.TimeUnderwaterPct = float64(underwater) / float64(len(candles))
+	curve.FinalValue = curve.Values[len(curve.Values)-1]
+	return curve
+}
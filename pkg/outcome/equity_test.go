@@ -0,0 +1,65 @@
+package outcome
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// closesPath returns forward candles closing at each of closes
+func closesPath(closes ...float64) []model.Candle {
+	candles := make([]model.Candle, len(closes))
+	for i, c := range closes {
+		candles[i] = bar(c, c, c)
+	}
+	return candles
+}
+
+func TestBuildEquityCurve(t *testing.T) {
+	tests := []struct {
+		name          string
+		closes        []float64
+		peak, trough  float64
+		final         float64
+		drawdownBars  int
+		underwaterPct float64
+	}{
+		{"rising", []float64{101, 102, 103, 104, 105}, 1050, 1000, 1050, 0, 0},
+		{"falling", []float64{99, 98, 97}, 1000, 970, 970, 3, 1},
+		{"V-shaped", []float64{95, 90, 95, 100, 105}, 1050, 900, 1050, 3, 0.6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			curve := buildEquityCurve(100, 1000, closesPath(tt.closes...))
+
+			if len(curve.Values) != len(tt.closes) {
+				t.Fatalf("got %d values, want %d", len(curve.Values), len(tt.closes))
+			}
+			for i, c := range tt.closes {
+				if want := 10 * c; math.Abs(curve.Values[i]-want) > 1e-9 {
+					t.Errorf("Values[%d] = %v, want %v", i, curve.Values[i], want)
+				}
+			}
+			if math.Abs(curve.PeakValue-tt.peak) > 1e-9 || math.Abs(curve.TroughValue-tt.trough) > 1e-9 {
+				t.Errorf("peak/trough = %v/%v, want %v/%v", curve.PeakValue, curve.TroughValue, tt.peak, tt.trough)
+			}
+			if math.Abs(curve.FinalValue-tt.final) > 1e-9 {
+				t.Errorf("FinalValue = %v, want %v", curve.FinalValue, tt.final)
+			}
+			if curve.MaxDrawdownBars != tt.drawdownBars {
+				t.Errorf("MaxDrawdownBars = %d, want %d", curve.MaxDrawdownBars, tt.drawdownBars)
+			}
+			if math.Abs(curve.TimeUnderwaterPct-tt.underwaterPct) > 1e-9 {
+				t.Errorf("TimeUnderwaterPct = %v, want %v", curve.TimeUnderwaterPct, tt.underwaterPct)
+			}
+		})
+	}
+}
+
+func TestBuildEquityCurveEmpty(t *testing.T) {
+	curve := buildEquityCurve(100, 1000, nil)
+	if curve.FinalValue != 1000 || curve.PeakValue != 1000 || curve.TroughValue != 1000 {
+		t.Errorf("empty curve = %+v, want flat at the starting capital", curve)
+	}
+}
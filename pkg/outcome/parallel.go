@@ -0,0 +1,141 @@
+package outcome
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// seriesKey identifies a symbol/timeframe candle series
+type seriesKey struct {
+	symbol    string
+	timeframe string
+}
+
+// CalculateParallel computes outcome statistics like Calculate, sharding windows across workers
+// Forward candles are prefetched once per symbol/timeframe, and results are returned in input order
+func (e *Engine) CalculateParallel(ctx context.Context, windows []*model.Window, horizons []int, workers int) ([]Result, error) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	series, err := e.prefetchForward(ctx, windows, horizons)
+	if err != nil {
+		return nil, err
+	}
+
+	perWindow := make([][]Result, len(windows))
+	errs := make([]error, len(windows))
+	shardSize := (len(windows) + workers - 1) / workers
+
+	var wg sync.WaitGroup
+	for start := 0; start < len(windows); start += shardSize {
+		end := start + shardSize
+		if end > len(windows) {
+			end = len(windows)
+		}
+
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				if ctx.Err() != nil {
+					return
+				}
+				w := windows[i]
+				last := w.LastCandle()
+				if last == nil {
+					continue
+				}
+				lookahead := forwardLookahead(w.Timeframe, horizons)
+				candles := sliceForward(series[seriesKey{w.Symbol, w.Timeframe}], last.CloseTime, lookahead)
+				var bench *benchmarkPath
+				if e.config.BenchmarkSymbol != "" {
+					bench = benchmarkFromSeries(series[seriesKey{e.config.BenchmarkSymbol, w.Timeframe}], last.CloseTime, lookahead)
+				}
+				perWindow[i], errs[i] = e.calculateWindow(w, candles, bench, horizons)
+			}
+		}(start, end)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var results []Result
+	for _, r := range perWindow {
+		results = append(results, r...)
+	}
+	return results, nil
+}
+
+// prefetchForward loads, per symbol/timeframe, every candle that any window's forward range for horizons may need
+func (e *Engine) prefetchForward(ctx context.Context, windows []*model.Window, horizons []int) (map[seriesKey][]model.Candle, error) {
+	type span struct{ start, end time.Time }
+	spans := make(map[seriesKey]*span)
+	extend := func(key seriesKey, start, end time.Time) {
+		if s, ok := spans[key]; ok {
+			if start.Before(s.start) {
+				s.start = start
+			}
+			if end.After(s.end) {
+				s.end = end
+			}
+			return
+		}
+		spans[key] = &span{start: start, end: end}
+	}
+
+	for _, w := range windows {
+		last := w.LastCandle()
+		if last == nil {
+			continue
+		}
+		end := last.CloseTime.Add(forwardLookahead(w.Timeframe, horizons))
+		extend(seriesKey{w.Symbol, w.Timeframe}, last.CloseTime, end)
+
+		// The benchmark also needs the candle preceding the window end for its base price
+		if e.config.BenchmarkSymbol != "" {
+			if barDuration, err := model.TimeframeDuration(w.Timeframe); err == nil {
+				extend(seriesKey{e.config.BenchmarkSymbol, w.Timeframe}, last.CloseTime.Add(-barDuration), end)
+			}
+		}
+	}
+
+	series := make(map[seriesKey][]model.Candle, len(spans))
+	for key, s := range spans {
+		candles, err := e.candleRepo.GetByTimeRange(ctx, key.symbol, key.timeframe, s.start, s.end)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prefetch forward candles for %s %s: %w", key.symbol, key.timeframe, err)
+		}
+		series[key] = candles
+	}
+
+	return series, nil
+}
+
+// sliceForward returns the candles closing after from and at most lookahead later,
+// matching the close-time range Engine.forwardCandles fetches
+func sliceForward(candles []model.Candle, from time.Time, lookahead time.Duration) []model.Candle {
+	start := sort.Search(len(candles), func(i int) bool {
+		return candles[i].CloseTime.After(from)
+	})
+	limit := from.Add(lookahead)
+	end := sort.Search(len(candles), func(i int) bool {
+		return candles[i].CloseTime.After(limit)
+	})
+	if end < start {
+		end = start
+	}
+	return candles[start:end]
+}
@@ -0,0 +1,80 @@
+package outcome
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// seedWindows stores n+w+25 1h candles and returns the n step-1 windows of w candles over their start,
+// each followed by at least 25 forward candles
+func seedWindows(t testing.TB, n, w int) (*Engine, []*model.Window) {
+	t.Helper()
+	repo := newTestCandleRepo(t)
+	candles := testCandles(t, "BTCUSDT", "1h", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), n+w+25)
+	if err := repo.InsertBatch(context.Background(), candles); err != nil {
+		t.Fatalf("InsertBatch: %v", err)
+	}
+
+	windows := make([]*model.Window, n)
+	for i := range windows {
+		wc := candles[i : i+w]
+		windows[i] = model.NewWindow("BTCUSDT", "1h", wc[w-1].CloseTime, w, 1, wc)
+	}
+	return NewEngine(repo), windows
+}
+
+func TestCalculateParallelMatchesCalculate(t *testing.T) {
+	ctx := context.Background()
+	engine, windows := seedWindows(t, 200, 7)
+	horizons := []int{5, 20}
+
+	serial, err := engine.Calculate(ctx, windows, horizons)
+	if err != nil {
+		t.Fatalf("Calculate: %v", err)
+	}
+	for _, workers := range []int{1, 3, 8} {
+		parallel, err := engine.CalculateParallel(ctx, windows, horizons, workers)
+		if err != nil {
+			t.Fatalf("CalculateParallel(%d workers): %v", workers, err)
+		}
+		if !reflect.DeepEqual(parallel, serial) {
+			t.Errorf("CalculateParallel(%d workers) differs from Calculate (%d vs %d results)", workers, len(parallel), len(serial))
+		}
+	}
+}
+
+func TestCalculateParallelCanceled(t *testing.T) {
+	engine, windows := seedWindows(t, 20, 7)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := engine.CalculateParallel(ctx, windows, []int{5}, 4); err == nil {
+		t.Error("CalculateParallel succeeded with a canceled context")
+	}
+}
+
+func BenchmarkCalculateSerial10k(b *testing.B) {
+	engine, windows := seedWindows(b, 10000, 60)
+	horizons := []int{5, 20}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := engine.Calculate(context.Background(), windows, horizons); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCalculateParallel10k(b *testing.B) {
+	engine, windows := seedWindows(b, 10000, 60)
+	horizons := []int{5, 20}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := engine.CalculateParallel(context.Background(), windows, horizons, 8); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
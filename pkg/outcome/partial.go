@@ -0,0 +1,33 @@
+package outcome
+
+import (
+	"errors"
+	"fmt"
+)
+
+// PartialPolicy controls how horizons with fewer forward bars than requested are handled
+type PartialPolicy string
+
+const (
+	// PartialSkip leaves incomplete horizons out of the statistics, returning them without statistics (the default)
+	PartialSkip PartialPolicy = "skip"
+	// PartialCompute computes statistics over the available bars and marks the result Partial
+	PartialCompute PartialPolicy = "partial"
+	// PartialError fails the calculation when any horizon is incomplete
+	PartialError PartialPolicy = "error"
+)
+
+// ErrIncompleteHorizon is returned under PartialError when a window lacks enough forward bars
+var ErrIncompleteHorizon = errors.New("incomplete forward horizon")
+
+// ParsePartialPolicy parses a policy name; an empty string selects PartialSkip
+func ParsePartialPolicy(s string) (PartialPolicy, error) {
+	switch p := PartialPolicy(s); p {
+	case "":
+		return PartialSkip, nil
+	case PartialSkip, PartialCompute, PartialError:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid partial policy %q: must be skip, partial, or error", s)
+	}
+}
@@ -0,0 +1,113 @@
+package outcome
+
+import (
+	"sort"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// PathEnvelope holds per-bar percentiles of cumulative return paths across neighbors
+// Index i corresponds to forward bar i+1; paths shorter than i+1 bars do not contribute to bar i
+type PathEnvelope struct {
+	P10    []float64
+	P50    []float64
+	P90    []float64
+	Counts []int // Number of paths contributing at each bar
+}
+
+// Len returns the number of bars covered by the envelope
+func (e PathEnvelope) Len() int {
+	return len(e.P50)
+}
+
+// returnPath computes cumulative returns relative to basePrice for up to maxBars forward candles
+func returnPath(basePrice float64, candles []model.Candle, maxBars int) []float64 {
+	n := len(candles)
+	if maxBars < n {
+		n = maxBars
+	}
+
+	path := make([]float64, n)
+	for i := 0; i < n; i++ {
+		path[i] = (candles[i].Close - basePrice) / basePrice
+	}
+	return path
+}
+
+// maxHorizon returns the largest horizon, or 0 if there are none
+func maxHorizon(horizons []int) int {
+	max := 0
+	for _, h := range horizons {
+		if h > max {
+			max = h
+		}
+	}
+	return max
+}
+
+// AggregatePaths computes the per-bar P10/P50/P90 envelope of the return paths in results
+// Each window contributes once, using the longest path among its results; paths may be ragged
+func AggregatePaths(results []Result) PathEnvelope {
+	byWindow := make(map[string][]float64)
+	for _, r := range results {
+		if len(r.ReturnPath) > len(byWindow[r.WindowID]) {
+			byWindow[r.WindowID] = r.ReturnPath
+		}
+	}
+
+	paths := make([][]float64, 0, len(byWindow))
+	for _, p := range byWindow {
+		paths = append(paths, p)
+	}
+
+	return computeEnvelope(paths)
+}
+
+// AggregatePathsDedup computes the path envelope after collapsing time-overlapping neighbors
+func AggregatePathsDedup(results []Result, neighbors []Neighbor, maxOverlapFrac float64) PathEnvelope {
+	kept := keptWindowIDs(neighbors, maxOverlapFrac)
+
+	var filtered []Result
+	for _, r := range results {
+		if kept[r.WindowID] {
+			filtered = append(filtered, r)
+		}
+	}
+
+	return AggregatePaths(filtered)
+}
+
+// computeEnvelope computes per-bar percentiles over ragged paths
+func computeEnvelope(paths [][]float64) PathEnvelope {
+	longest := 0
+	for _, p := range paths {
+		if len(p) > longest {
+			longest = len(p)
+		}
+	}
+
+	env := PathEnvelope{
+		P10:    make([]float64, longest),
+		P50:    make([]float64, longest),
+		P90:    make([]float64, longest),
+		Counts: make([]int, longest),
+	}
+
+	values := make([]float64, 0, len(paths))
+	for i := 0; i < longest; i++ {
+		values = values[:0]
+		for _, p := range paths {
+			if i < len(p) {
+				values = append(values, p[i])
+			}
+		}
+		sort.Float64s(values)
+
+		env.P10[i] = percentile(values, 10)
+		env.P50[i] = percentile(values, 50)
+		env.P90[i] = percentile(values, 90)
+		env.Counts[i] = len(values)
+	}
+
+	return env
+}
@@ -0,0 +1,56 @@
+package outcome
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestComputeEnvelopeRaggedPaths(t *testing.T) {
+	env := computeEnvelope([][]float64{
+		{0.01, 0.02, 0.03, 0.04},
+		{-0.01, 0.00},
+		{0.05, 0.06, 0.07},
+	})
+
+	if env.Len() != 4 {
+		t.Fatalf("envelope covers %d bars, want the longest path's 4", env.Len())
+	}
+	if want := []int{3, 3, 2, 1}; !slices.Equal(env.Counts, want) {
+		t.Errorf("Counts = %v, want %v", env.Counts, want)
+	}
+
+	wantP50 := []float64{0.01, 0.02, 0.05, 0.04}
+	for i, want := range wantP50 {
+		if math.Abs(env.P50[i]-want) > 1e-12 {
+			t.Errorf("P50[%d] = %v, want %v", i, env.P50[i], want)
+		}
+	}
+
+	// A bar covered by a single path collapses the band onto it
+	if env.P10[3] != 0.04 || env.P90[3] != 0.04 {
+		t.Errorf("bar 4 band = [%v, %v], want [0.04, 0.04]", env.P10[3], env.P90[3])
+	}
+	for i := 0; i < env.Len(); i++ {
+		if env.P10[i] > env.P50[i] || env.P50[i] > env.P90[i] {
+			t.Errorf("bar %d percentiles out of order: %v %v %v", i+1, env.P10[i], env.P50[i], env.P90[i])
+		}
+	}
+}
+
+func TestAggregatePathsUsesLongestPathPerWindow(t *testing.T) {
+	env := AggregatePaths([]Result{
+		{WindowID: "a", Horizon: 2, ReturnPath: []float64{0.01, 0.02}},
+		{WindowID: "a", Horizon: 3, ReturnPath: []float64{0.01, 0.02, 0.03}},
+		{WindowID: "b", Horizon: 2, ReturnPath: []float64{-0.01, -0.02}},
+	})
+	if want := []int{2, 2, 1}; !slices.Equal(env.Counts, want) {
+		t.Errorf("Counts = %v, want %v: each window should count once", env.Counts, want)
+	}
+}
+
+func TestComputeEnvelopeEmpty(t *testing.T) {
+	if env := computeEnvelope(nil); env.Len() != 0 {
+		t.Errorf("empty envelope covers %d bars", env.Len())
+	}
+}
@@ -0,0 +1,127 @@
+package outcome
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+	"github.com/tunogya/etna/pkg/store/duckdb"
+)
+
+// refreshBatchSize bounds the number of windows hydrated and persisted per round trip
+const refreshBatchSize = 500
+
+// RefreshStats summarizes a RefreshPending run for one horizon
+type RefreshStats struct {
+	Horizon    int
+	Pending    int // Windows whose horizon has elapsed but had no stored outcome
+	Computed   int // Outcomes computed and persisted
+	Incomplete int // Windows still lacking enough forward candles (e.g. gaps in candle data)
+}
+
+// RefreshPending computes and persists outcomes for windows whose horizons have completed by asOf
+// A window is pending for horizon h when t_end + h×bar <= asOf and no outcome is stored for h
+func (e *Engine) RefreshPending(ctx context.Context, symbol, timeframe string, horizons []int, asOf time.Time, windowRepo *duckdb.WindowRepo, outcomeRepo *duckdb.OutcomeRepo) ([]RefreshStats, error) {
+	barDuration, err := model.TimeframeDuration(timeframe)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]RefreshStats, 0, len(horizons))
+	for _, h := range horizons {
+		cutoff := asOf.Add(-time.Duration(h) * barDuration)
+		ids, err := outcomeRepo.GetPendingWindowIDs(ctx, symbol, timeframe, h, cutoff)
+		if err != nil {
+			return stats, err
+		}
+
+		s := RefreshStats{Horizon: h, Pending: len(ids)}
+		for start := 0; start < len(ids); start += refreshBatchSize {
+			if err := ctx.Err(); err != nil {
+				return append(stats, s), err
+			}
+
+			end := start + refreshBatchSize
+			if end > len(ids) {
+				end = len(ids)
+			}
+
+			computed, err := e.refreshWindowIDs(ctx, ids[start:end], symbol, timeframe, []int{h}, windowRepo, outcomeRepo)
+			if err != nil {
+				return append(stats, s), err
+			}
+
+			s.Computed += computed
+			s.Incomplete += end - start - computed
+		}
+
+		stats = append(stats, s)
+	}
+
+	return stats, nil
+}
+
+// RefreshWindow computes and persists the outcomes of one window at horizons
+// Horizons lacking enough forward candles are skipped; outcomes are upserted, so repeating a refresh is harmless
+func (e *Engine) RefreshWindow(ctx context.Context, windowID, symbol, timeframe string, horizons []int, windowRepo *duckdb.WindowRepo, outcomeRepo *duckdb.OutcomeRepo) (int, error) {
+	return e.refreshWindowIDs(ctx, []string{windowID}, symbol, timeframe, horizons, windowRepo, outcomeRepo)
+}
+
+// refreshWindowIDs computes outcomes for windowIDs and persists those whose horizon has enough forward candles
+func (e *Engine) refreshWindowIDs(ctx context.Context, windowIDs []string, symbol, timeframe string, horizons []int, windowRepo *duckdb.WindowRepo, outcomeRepo *duckdb.OutcomeRepo) (int, error) {
+	results, err := e.CalculateForWindowIDs(ctx, windowIDs, symbol, timeframe, horizons, windowRepo)
+	if err != nil {
+		return 0, fmt.Errorf("failed to calculate outcomes: %w", err)
+	}
+
+	outcomes := make([]*model.Outcome, 0, len(results))
+	for _, r := range results {
+		if r.FwdCandles < r.Horizon {
+			continue
+		}
+		outcomes = append(outcomes, r.ToOutcome())
+	}
+
+	if err := outcomeRepo.InsertBatch(ctx, outcomes); err != nil {
+		return 0, fmt.Errorf("failed to store outcomes: %w", err)
+	}
+	return len(outcomes), nil
+}
+
+// PendingWindow is a window together with the horizons whose outcomes are due but not stored
+type PendingWindow struct {
+	WindowID string
+	Horizons []int
+}
+
+// ListPending returns the windows with outcomes pending as of asOf, as in RefreshPending
+// Windows are ordered by when they first become pending across horizons, each listing all of its pending horizons
+func ListPending(ctx context.Context, symbol, timeframe string, horizons []int, asOf time.Time, outcomeRepo *duckdb.OutcomeRepo) ([]PendingWindow, error) {
+	barDuration, err := model.TimeframeDuration(timeframe)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []PendingWindow
+	index := make(map[string]int)
+	for _, h := range horizons {
+		cutoff := asOf.Add(-time.Duration(h) * barDuration)
+		ids, err := outcomeRepo.GetPendingWindowIDs(ctx, symbol, timeframe, h, cutoff)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, id := range ids {
+			i, ok := index[id]
+			if !ok {
+				i = len(pending)
+				index[id] = i
+				pending = append(pending, PendingWindow{WindowID: id})
+			}
+			pending[i].Horizons = append(pending[i].Horizons, h)
+		}
+	}
+
+	return pending, nil
+}
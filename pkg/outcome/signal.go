@@ -0,0 +1,90 @@
+package outcome
+
+import (
+	"math"
+	"sort"
+
+	"github.com/tunogya/etna/pkg/model"
+	"github.com/tunogya/etna/pkg/rerank"
+)
+
+// SignalConfig holds the thresholds a horizon's aggregated outcome must pass to become a signal
+type SignalConfig struct {
+	MinExpectedReturn float64 // Minimum |MeanReturn| as a fraction (0.01 = 1%)
+	MinConfidence     float64 // Minimum signal confidence in [0, 1]
+	MinSamples        int     // Minimum neighbors with outcomes at the horizon
+}
+
+// DefaultSignalConfig returns default signal thresholds
+func DefaultSignalConfig() SignalConfig {
+	return SignalConfig{
+		MinExpectedReturn: 0.005,
+		MinConfidence:     0.5,
+		MinSamples:        5,
+	}
+}
+
+// SignalGenerator turns the outcomes of a query's similar windows into trading signals
+type SignalGenerator struct {
+	config SignalConfig
+}
+
+// NewSignalGenerator creates a signal generator with the given thresholds
+func NewSignalGenerator(cfg SignalConfig) *SignalGenerator {
+	return &SignalGenerator{config: cfg}
+}
+
+// Generate returns one signal per horizon whose aggregated outcome passes the thresholds, shortest horizon first
+// The direction follows the sign of the mean return. Confidence is the fraction of neighbors ending in that
+// direction scaled by the neighbors' mean similarity (calibrated MatchStrength when set, else the raw score).
+// Signals carry no query identity; set it with Signal.ForWindow before storing them
+func (g *SignalGenerator) Generate(ranked []rerank.RankedResult, outcomes map[int]AggregatedOutcome) []model.Signal {
+	if len(ranked) == 0 {
+		return nil
+	}
+	similarity := meanSimilarity(ranked)
+
+	horizons := make([]int, 0, len(outcomes))
+	for h := range outcomes {
+		horizons = append(horizons, h)
+	}
+	sort.Ints(horizons)
+
+	var signals []model.Signal
+	for _, h := range horizons {
+		a := outcomes[h]
+		if a.SampleCount < g.config.MinSamples || a.MeanReturn == 0 || math.Abs(a.MeanReturn) < g.config.MinExpectedReturn {
+			continue
+		}
+
+		direction, hitRate := model.SignalLong, a.HitRate
+		if a.MeanReturn < 0 {
+			direction, hitRate = model.SignalShort, 1-a.HitRate
+		}
+		confidence := hitRate * similarity
+		if confidence < g.config.MinConfidence {
+			continue
+		}
+
+		signals = append(signals, model.Signal{
+			Direction:      direction,
+			Confidence:     confidence,
+			Horizon:        h,
+			ExpectedReturn: a.MeanReturn,
+		})
+	}
+	return signals
+}
+
+// meanSimilarity averages the similarity of ranked results, clamped to [0, 1]
+func meanSimilarity(ranked []rerank.RankedResult) float64 {
+	sum := 0.0
+	for _, r := range ranked {
+		s := float64(r.OriginalScore)
+		if r.MatchStrength > 0 {
+			s = r.MatchStrength / 100
+		}
+		sum += math.Max(0, math.Min(1, s))
+	}
+	return sum / float64(len(ranked))
+}
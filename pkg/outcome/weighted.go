@@ -0,0 +1,139 @@
+package outcome
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+)
+
+// bootstrapSeed fixes the bootstrap resampling so repeated searches print the same interval
+const bootstrapSeed = 1
+
+// WeightedOutcome summarizes neighbor outcomes at one horizon, each neighbor weighted by its similarity
+type WeightedOutcome struct {
+	Horizon      int
+	SampleCount  int
+	MeanReturn   float64 // Weighted mean return at the horizon bar
+	MedianReturn float64 // Weighted median return at the horizon bar
+	HitRate      float64 // Weighted fraction of paths ending positive
+	MDDP95       float64 // Weighted 95th percentile max drawdown
+
+	// Bootstrap confidence interval of MeanReturn (only populated when resampling is enabled)
+	CILevel float64 // e.g. 0.95
+	CILow   float64
+	CIHigh  float64
+}
+
+// AggregateWeighted aggregates results per horizon weighting each neighbor by its similarity, after the same
+// overlap de-duplication as AggregateResultsDedup. Results without forward data are left out, and
+// non-positive similarities get no weight. With bootstrap > 0, that many resamples of the neighbors give a
+// 95% confidence interval of the weighted mean return
+func AggregateWeighted(results []Result, neighbors []Neighbor, maxOverlapFrac float64, bootstrap int) map[int]WeightedOutcome {
+	kept := keptWindowIDs(neighbors, maxOverlapFrac)
+	similarity := make(map[string]float64, len(neighbors))
+	for _, n := range neighbors {
+		similarity[n.WindowID] = math.Max(n.Similarity, 0)
+	}
+
+	byHorizon := make(map[int][]Result)
+	for _, r := range results {
+		if !kept[r.WindowID] || !r.HasStatistics() {
+			continue
+		}
+		byHorizon[r.Horizon] = append(byHorizon[r.Horizon], r)
+	}
+
+	rng := rand.New(rand.NewPCG(bootstrapSeed, bootstrapSeed))
+	aggregated := make(map[int]WeightedOutcome)
+	for horizon, rs := range byHorizon {
+		returns := make([]float64, len(rs))
+		mdds := make([]float64, len(rs))
+		hits := make([]float64, len(rs))
+		weights := make([]float64, len(rs))
+		for i, r := range rs {
+			returns[i] = r.FwdRetEnd
+			mdds[i] = r.MDDP95
+			hits[i] = r.HitRate
+			weights[i] = similarity[r.WindowID]
+		}
+		if sum(weights) == 0 {
+			continue
+		}
+
+		agg := WeightedOutcome{
+			Horizon:      horizon,
+			SampleCount:  len(rs),
+			MeanReturn:   weightedMean(returns, weights),
+			MedianReturn: weightedPercentile(returns, weights, 50),
+			HitRate:      weightedMean(hits, weights),
+			MDDP95:       weightedPercentile(mdds, weights, 95),
+		}
+		if bootstrap > 0 {
+			agg.CILevel = 0.95
+			agg.CILow, agg.CIHigh = bootstrapMeanCI(returns, weights, bootstrap, rng)
+		}
+		aggregated[horizon] = agg
+	}
+
+	return aggregated
+}
+
+// bootstrapMeanCI returns the 2.5th and 97.5th percentiles of the weighted mean over resamples of the samples
+// Resamples whose drawn weights sum to zero are skipped
+func bootstrapMeanCI(values, weights []float64, resamples int, rng *rand.Rand) (low, high float64) {
+	means := make([]float64, 0, resamples)
+	sampleValues := make([]float64, len(values))
+	sampleWeights := make([]float64, len(values))
+	for b := 0; b < resamples; b++ {
+		for i := range values {
+			j := rng.IntN(len(values))
+			sampleValues[i], sampleWeights[i] = values[j], weights[j]
+		}
+		if sum(sampleWeights) > 0 {
+			means = append(means, weightedMean(sampleValues, sampleWeights))
+		}
+	}
+	if len(means) == 0 {
+		return 0, 0
+	}
+
+	sort.Float64s(means)
+	return percentile(means, 2.5), percentile(means, 97.5)
+}
+
+// weightedMean returns the weighted mean of values; weights must not sum to zero
+func weightedMean(values, weights []float64) float64 {
+	total := 0.0
+	for i, v := range values {
+		total += v * weights[i]
+	}
+	return total / sum(weights)
+}
+
+// weightedPercentile returns the smallest value whose cumulative weight reaches p percent of the total
+func weightedPercentile(values, weights []float64, p float64) float64 {
+	idx := make([]int, len(values))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(a, b int) bool { return values[idx[a]] < values[idx[b]] })
+
+	target := sum(weights) * p / 100
+	cumulative := 0.0
+	for _, i := range idx {
+		cumulative += weights[i]
+		if cumulative >= target && weights[i] > 0 {
+			return values[i]
+		}
+	}
+	return values[idx[len(idx)-1]]
+}
+
+// sum returns the sum of values
+func sum(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
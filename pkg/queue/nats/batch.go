@@ -0,0 +1,181 @@
+package nats
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Content-Encoding header flagging compressed payloads
+const (
+	HeaderContentEncoding = "Content-Encoding"
+	encodingGzip          = "gzip"
+)
+
+// payloadHeadroom is reserved below the payload limit for message headers
+const payloadHeadroom = 1024
+
+// BatchMessage is a queue batch that can be split when its payload is too large
+type BatchMessage interface {
+	protoMessage
+	Len() int                            // Number of items in the batch
+	Split() (BatchMessage, BatchMessage) // Halves of the batch, in order
+}
+
+// Len returns the number of candles
+func (m *CandleBatchMsg) Len() int { return len(m.Candles) }
+
+// Split halves the batch
+func (m *CandleBatchMsg) Split() (BatchMessage, BatchMessage) {
+	mid := len(m.Candles) / 2
+	return &CandleBatchMsg{Candles: m.Candles[:mid]}, &CandleBatchMsg{Candles: m.Candles[mid:]}
+}
+
+// Len returns the number of windows
+func (m *WindowBatchMsg) Len() int { return len(m.Windows) }
+
+// Split halves the windows, sending each feature row with its window
+func (m *WindowBatchMsg) Split() (BatchMessage, BatchMessage) {
+	mid := len(m.Windows) / 2
+	first := &WindowBatchMsg{Windows: m.Windows[:mid]}
+	second := &WindowBatchMsg{Windows: m.Windows[mid:]}
+
+	inFirst := make(map[string]bool, mid)
+	for _, w := range first.Windows {
+		inFirst[w.WindowID] = true
+	}
+	for _, f := range m.Features {
+		if inFirst[f.WindowID] {
+			first.Features = append(first.Features, f)
+		} else {
+			second.Features = append(second.Features, f)
+		}
+	}
+	return first, second
+}
+
+// Len returns the number of vectors
+func (m *MilvusBatchMsg) Len() int { return len(m.Vectors) }
+
+// Split halves the batch
+func (m *MilvusBatchMsg) Split() (BatchMessage, BatchMessage) {
+	mid := len(m.Vectors) / 2
+	return &MilvusBatchMsg{Vectors: m.Vectors[:mid]}, &MilvusBatchMsg{Vectors: m.Vectors[mid:]}
+}
+
+// PublishBatch encodes batch with the client's codec, gzips it if Compress is set, and publishes it
+// Batches whose payload exceeds the limit are split in halves until every part fits
+// Returns the number of messages published
+func (c *Client) PublishBatch(ctx context.Context, subject string, batch BatchMessage) (int, error) {
+	msgs, err := c.batchMsgs(subject, batch, c.payloadLimit())
+	if err != nil {
+		return 0, err
+	}
+
+	for i, msg := range msgs {
+		if err := c.publishMsgWithConfirm(ctx, msg, c.config.RetryAttempts, c.config.RetryDelay); err != nil {
+			return i, err
+		}
+	}
+
+	if len(msgs) > 1 {
+		log.Printf("Published %d items to %s in %d messages", batch.Len(), subject, len(msgs))
+	}
+	return len(msgs), nil
+}
+
+// payloadLimit returns the max payload bytes of a published message
+func (c *Client) payloadLimit() int {
+	limit := c.config.MaxPayload
+	if limit <= 0 && c.nc != nil {
+		limit = int(c.nc.MaxPayload())
+	}
+	return limit - payloadHeadroom
+}
+
+// batchMsgs encodes batch into messages of at most limit payload bytes, splitting as needed
+func (c *Client) batchMsgs(subject string, batch BatchMessage, limit int) ([]*nats.Msg, error) {
+	msg, err := c.encodeMsg(subject, batch)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 || len(msg.Data) <= limit {
+		return []*nats.Msg{msg}, nil
+	}
+	if batch.Len() < 2 {
+		return nil, fmt.Errorf("failed to fit message of %d bytes within payload limit of %d bytes", len(msg.Data), limit)
+	}
+
+	first, second := batch.Split()
+	firstMsgs, err := c.batchMsgs(subject, first, limit)
+	if err != nil {
+		return nil, err
+	}
+	secondMsgs, err := c.batchMsgs(subject, second, limit)
+	if err != nil {
+		return nil, err
+	}
+	return append(firstMsgs, secondMsgs...), nil
+}
+
+// encodeMsg encodes v into a schema-versioned message for subject, gzipping the payload if Compress is set
+func (c *Client) encodeMsg(subject string, v interface{}) (*nats.Msg, error) {
+	data, err := c.Encode(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	msg := newMsg(subject, data)
+	if !c.config.Compress {
+		return msg, nil
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress message: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress message: %w", err)
+	}
+
+	msg.Data = buf.Bytes()
+	msg.Header.Set(HeaderContentEncoding, encodingGzip)
+	return msg, nil
+}
+
+// decompressedMsg presents the decompressed payload of a compressed message
+type decompressedMsg struct {
+	jetstream.Msg
+	data []byte
+}
+
+// Data returns the decompressed payload
+func (m *decompressedMsg) Data() []byte {
+	return m.data
+}
+
+// decompressMsg returns msg with its payload decompressed if its Content-Encoding header is gzip
+// A corrupt payload is a permanent error
+func decompressMsg(msg jetstream.Msg) (jetstream.Msg, error) {
+	if msg.Headers().Get(HeaderContentEncoding) != encodingGzip {
+		return msg, nil
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(msg.Data()))
+	if err != nil {
+		return msg, Permanent(fmt.Errorf("failed to decompress message: %w", err))
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return msg, Permanent(fmt.Errorf("failed to decompress message: %w", err))
+	}
+
+	return &decompressedMsg{Msg: msg, data: data}, nil
+}
@@ -2,7 +2,10 @@ package nats
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"sync"
 	"time"
 
 	"github.com/nats-io/nats.go"
@@ -15,6 +18,10 @@ type Config struct {
 	StreamName    string
 	RetryAttempts int
 	RetryDelay    time.Duration
+	Codec         Codec  // Encoding of published messages; JSON if empty
+	Compress      bool   // Gzip published batch payloads, flagged by the Content-Encoding header
+	MaxPayload    int    // Max published payload bytes; the server's max_payload if 0
+	KVBucket      string // JetStream KV bucket for pipeline state; DefaultKVBucket if empty
 }
 
 // DefaultConfig returns sensible defaults
@@ -24,6 +31,8 @@ func DefaultConfig() Config {
 		StreamName:    "etna",
 		RetryAttempts: 3,
 		RetryDelay:    time.Second,
+		Codec:         CodecJSON,
+		KVBucket:      DefaultKVBucket,
 	}
 }
 
@@ -32,6 +41,21 @@ type Client struct {
 	nc     *nats.Conn
 	js     jetstream.JetStream
 	config Config
+
+	mu       sync.Mutex
+	draining bool               // Set by Drain; new deliveries are nakked instead of handled
+	subs     []subscription     // Subscriptions stopped by Drain
+	inflight sync.WaitGroup     // Handlers currently running
+	kv       jetstream.KeyValue // Opened on first use by the KV helpers
+
+	// Consumer health
+	consumers    []string             // Durable consumers created by Subscribe and SubscribeBatch
+	pendingSince map[string]time.Time // When each consumer was first seen with pending messages
+}
+
+// subscription is a running consumer that Drain stops
+type subscription interface {
+	Stop()
 }
 
 // NewClient creates a new NATS client with JetStream support
@@ -52,9 +76,10 @@ func NewClient(cfg Config) (*Client, error) {
 	}
 
 	return &Client{
-		nc:     nc,
-		js:     js,
-		config: cfg,
+		nc:           nc,
+		js:           js,
+		config:       cfg,
+		pendingSince: make(map[string]time.Time),
 	}, nil
 }
 
@@ -82,36 +107,506 @@ func (c *Client) Publish(ctx context.Context, subject string, data []byte) error
 	return nil
 }
 
+// maxPublishBackoff caps the delay between PublishWithConfirm attempts
+const maxPublishBackoff = 30 * time.Second
+
+// PublishWithConfirm publishes a message and waits for the JetStream ack, retrying timeouts and nacks
+// Up to maxRetries retries are made, doubling backoff after each attempt (capped at 30s)
+func (c *Client) PublishWithConfirm(ctx context.Context, subject string, data []byte, maxRetries int, backoff time.Duration) error {
+	return c.publishMsgWithConfirm(ctx, &nats.Msg{Subject: subject, Data: data}, maxRetries, backoff)
+}
+
+// publishMsgWithConfirm is PublishWithConfirm for a message that may carry headers
+func (c *Client) publishMsgWithConfirm(ctx context.Context, msg *nats.Msg, maxRetries int, backoff time.Duration) error {
+	subject := msg.Subject
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			log.Printf("Retrying publish to %s (attempt %d/%d) in %s: %v", subject, attempt, maxRetries, backoff, lastErr)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return fmt.Errorf("failed to publish message: %w", ctx.Err())
+			}
+			backoff *= 2
+			if backoff > maxPublishBackoff {
+				backoff = maxPublishBackoff
+			}
+		}
+
+		_, err := c.js.PublishMsg(ctx, msg)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("failed to publish message: %w", err)
+		}
+		lastErr = err
+	}
+
+	return fmt.Errorf("failed to publish message after %d attempts: %w", maxRetries+1, lastErr)
+}
+
+// PublishBatchWithConfirm publishes payloads in order with PublishWithConfirm, using the client's retry settings
+// It stops at the first payload that still fails after retries and returns how many were published
+func (c *Client) PublishBatchWithConfirm(ctx context.Context, subject string, payloads [][]byte) (int, error) {
+	for i, data := range payloads {
+		if err := c.PublishWithConfirm(ctx, subject, data, c.config.RetryAttempts, c.config.RetryDelay); err != nil {
+			return i, err
+		}
+	}
+	return len(payloads), nil
+}
+
+// Encode serializes a queue message with the client's configured codec
+func (c *Client) Encode(v interface{}) ([]byte, error) {
+	return EncodeWith(c.config.Codec, v)
+}
+
+// PublishCandleBatch publishes a candle write batch to its symbol-routed SubjectCandleWrite subjects and waits for the acks
+// Candles are grouped by symbol and timeframe; groups over the payload limit are split across several messages
+func (c *Client) PublishCandleBatch(ctx context.Context, batch *CandleBatchMsg) error {
+	subjects, groups := candleGroups(batch)
+	return c.publishRouted(ctx, subjects, groups)
+}
+
+// PublishWindowBatch publishes a window write batch to its symbol-routed SubjectWindowWrite subjects and waits for the acks
+// Windows are grouped by symbol and timeframe, each feature row following its window
+func (c *Client) PublishWindowBatch(ctx context.Context, batch *WindowBatchMsg) error {
+	subjects, groups, err := windowGroups(batch)
+	if err != nil {
+		return err
+	}
+	return c.publishRouted(ctx, subjects, groups)
+}
+
+// PublishMilvusBatch publishes a vector write batch to its symbol-routed SubjectMilvusWrite subjects and waits for the acks
+// Vectors are grouped by symbol and timeframe; groups over the payload limit are split across several messages
+func (c *Client) PublishMilvusBatch(ctx context.Context, batch *MilvusBatchMsg) error {
+	subjects, groups := milvusGroups(batch)
+	return c.publishRouted(ctx, subjects, groups)
+}
+
+// candleGroups splits a candle batch by routed subject, returning the subjects in first-seen order
+func candleGroups(batch *CandleBatchMsg) ([]string, map[string]BatchMessage) {
+	var subjects []string
+	groups := make(map[string]BatchMessage)
+	for _, candle := range batch.Candles {
+		subject := SubjectFor(SubjectCandleWrite, candle.Symbol, candle.Timeframe)
+		group, ok := groups[subject].(*CandleBatchMsg)
+		if !ok {
+			subjects = append(subjects, subject)
+			group = &CandleBatchMsg{}
+			groups[subject] = group
+		}
+		group.Candles = append(group.Candles, candle)
+	}
+	return subjects, groups
+}
+
+// windowGroups splits a window batch by routed subject, each feature row following its window
+func windowGroups(batch *WindowBatchMsg) ([]string, map[string]BatchMessage, error) {
+	var subjects []string
+	groups := make(map[string]BatchMessage)
+	windowSubjects := make(map[string]string, len(batch.Windows))
+	for _, w := range batch.Windows {
+		subject := SubjectFor(SubjectWindowWrite, w.Symbol, w.Timeframe)
+		group, ok := groups[subject].(*WindowBatchMsg)
+		if !ok {
+			subjects = append(subjects, subject)
+			group = &WindowBatchMsg{}
+			groups[subject] = group
+		}
+		group.Windows = append(group.Windows, w)
+		windowSubjects[w.WindowID] = subject
+	}
+	for _, f := range batch.Features {
+		subject, ok := windowSubjects[f.WindowID]
+		if !ok {
+			return nil, nil, fmt.Errorf("feature row %s has no window in the batch", f.WindowID)
+		}
+		group := groups[subject].(*WindowBatchMsg)
+		group.Features = append(group.Features, f)
+	}
+	return subjects, groups, nil
+}
+
+// milvusGroups splits a vector batch by routed subject, returning the subjects in first-seen order
+func milvusGroups(batch *MilvusBatchMsg) ([]string, map[string]BatchMessage) {
+	var subjects []string
+	groups := make(map[string]BatchMessage)
+	for _, v := range batch.Vectors {
+		subject := SubjectFor(SubjectMilvusWrite, v.Symbol, v.Timeframe)
+		group, ok := groups[subject].(*MilvusBatchMsg)
+		if !ok {
+			subjects = append(subjects, subject)
+			group = &MilvusBatchMsg{}
+			groups[subject] = group
+		}
+		group.Vectors = append(group.Vectors, v)
+	}
+	return subjects, groups
+}
+
+// publishRouted publishes each subject's batch, in subject order
+func (c *Client) publishRouted(ctx context.Context, subjects []string, groups map[string]BatchMessage) error {
+	for _, subject := range subjects {
+		if _, err := c.PublishBatch(ctx, subject, groups[subject]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // MessageHandler is called when a message is received
 type MessageHandler func(msg jetstream.Msg) error
 
-// Subscribe creates a durable consumer and subscribes to messages
-func (c *Client) Subscribe(ctx context.Context, subject string, consumerName string, handler MessageHandler) (jetstream.ConsumeContext, error) {
-	consumer, err := c.js.CreateOrUpdateConsumer(ctx, c.config.StreamName, jetstream.ConsumerConfig{
-		Durable:       consumerName,
-		FilterSubject: subject,
-		AckPolicy:     jetstream.AckExplicitPolicy,
-		AckWait:       30 * time.Second,
-		MaxDeliver:    3,
-	})
+// maxDeliver is the number of delivery attempts before a failing message is dead-lettered
+const maxDeliver = 3
+
+// Redelivery delay after a failed attempt, doubling with each delivery up to maxRetryDelay
+const (
+	retryDelay    = time.Second
+	maxRetryDelay = 30 * time.Second
+)
+
+// redeliveryDelay returns the nak delay after the given number of failed deliveries
+func redeliveryDelay(deliveries uint64) time.Duration {
+	delay := retryDelay
+	for i := uint64(1); i < deliveries && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// consumerConfig returns the durable consumer configuration filtering on subjects
+func consumerConfig(subjects []string, consumerName string) jetstream.ConsumerConfig {
+	cfg := jetstream.ConsumerConfig{
+		Durable:    consumerName,
+		AckPolicy:  jetstream.AckExplicitPolicy,
+		AckWait:    30 * time.Second,
+		MaxDeliver: maxDeliver,
+	}
+	if len(subjects) == 1 {
+		cfg.FilterSubject = subjects[0]
+	} else {
+		cfg.FilterSubjects = subjects
+	}
+	return cfg
+}
+
+// Subscribe creates a durable consumer filtering on subjects and subscribes to messages
+// Messages whose handler returns a Permanent error, or fails on the last of maxDeliver attempts,
+// are published to the DLQ subject with the error before being acked
+// Compressed payloads are decompressed before reaching handler
+func (c *Client) Subscribe(ctx context.Context, subjects []string, consumerName string, handler MessageHandler) (jetstream.ConsumeContext, error) {
+	consumer, err := c.js.CreateOrUpdateConsumer(ctx, c.config.StreamName, consumerConfig(subjects, consumerName))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create consumer: %w", err)
 	}
+	c.registerConsumer(consumerName)
 
-	consumeCtx, err := consumer.Consume(func(msg jetstream.Msg) {
-		if err := handler(msg); err != nil {
-			msg.Nak()
+	consumeCtx, err := consumer.Consume(func(raw jetstream.Msg) {
+		if !c.beginHandler() {
+			raw.Nak()
+			return
+		}
+		defer c.inflight.Done()
+
+		msg, err := prepareMsg(raw)
+		if err == nil {
+			err = handler(msg)
+		}
+		if err == nil {
+			msg.Ack()
 			return
 		}
-		msg.Ack()
+		c.settleFailed(ctx, msg, consumerName, err)
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to start consuming: %w", err)
 	}
 
+	c.track(drainingConsumer{consumeCtx})
 	return consumeCtx, nil
 }
 
+// drainingConsumer stops a ConsumeContext by draining it, so buffered messages reach the
+// callback (and are nakked for prompt redelivery) instead of waiting out their ack wait
+type drainingConsumer struct {
+	jetstream.ConsumeContext
+}
+
+// Stop drains the consumer and waits until it has closed
+func (d drainingConsumer) Stop() {
+	d.Drain()
+	<-d.Closed()
+}
+
+// settleFailed naks a message whose handler failed for redelivery after a backoff, or dead-letters
+// and acks it if the error is permanent or this was the last of maxDeliver attempts
+func (c *Client) settleFailed(ctx context.Context, msg jetstream.Msg, consumerName string, err error) {
+	var deliveries uint64
+	if meta, metaErr := msg.Metadata(); metaErr == nil {
+		deliveries = meta.NumDelivered
+	}
+	if !IsPermanent(err) && deliveries < maxDeliver {
+		msg.NakWithDelay(redeliveryDelay(deliveries))
+		return
+	}
+
+	if dlqErr := c.publishDeadLetter(ctx, msg, consumerName, deliveries, err); dlqErr != nil {
+		log.Printf("Failed to dead-letter message on %s: %v", msg.Subject(), dlqErr)
+		msg.Nak()
+		return
+	}
+	log.Printf("Dead-lettered message on %s after %d deliveries: %v", msg.Subject(), deliveries, err)
+	msg.Ack()
+}
+
+// BatchHandler is called with a batch of fetched messages
+type BatchHandler func(msgs []jetstream.Msg) error
+
+// BatchSubscription is a running SubscribeBatch fetch loop
+type BatchSubscription struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Stop stops fetching and waits for the in-flight batch to be settled
+func (s *BatchSubscription) Stop() {
+	s.cancel()
+	<-s.done
+}
+
+// SubscribeBatch creates a durable consumer filtering on subjects and fetches up to batchSize messages at a time,
+// waiting at most maxWait for a batch to fill, and passes each batch to handler
+// The batch is acked if handler succeeds and nakked if it fails; a Permanent error is
+// isolated by retrying the batch one message at a time, so only the poisoned messages are dead-lettered
+// Compressed payloads are decompressed before reaching handler; batchSize and maxWait must be positive
+func (c *Client) SubscribeBatch(ctx context.Context, subjects []string, consumerName string, batchSize int, maxWait time.Duration, handler BatchHandler) (*BatchSubscription, error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("batch size must be positive, got %d", batchSize)
+	}
+	if maxWait <= 0 {
+		return nil, fmt.Errorf("batch max wait must be positive, got %s", maxWait)
+	}
+
+	consumerCfg := consumerConfig(subjects, consumerName)
+	consumerCfg.MaxAckPending = batchSize * 2
+	consumer, err := c.js.CreateOrUpdateConsumer(ctx, c.config.StreamName, consumerCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer: %w", err)
+	}
+	c.registerConsumer(consumerName)
+
+	fetchCtx, cancel := context.WithCancel(ctx)
+	sub := &BatchSubscription{cancel: cancel, done: make(chan struct{})}
+
+	go func() {
+		defer close(sub.done)
+		for fetchCtx.Err() == nil {
+			batch, err := consumer.Fetch(batchSize, jetstream.FetchMaxWait(maxWait))
+			if err != nil {
+				log.Printf("Failed to fetch from %s: %v", consumerName, err)
+				select {
+				case <-fetchCtx.Done():
+				case <-time.After(maxWait):
+				}
+				continue
+			}
+
+			var msgs []jetstream.Msg
+			for raw := range batch.Messages() {
+				msg, err := prepareMsg(raw)
+				if err != nil {
+					c.settleFailed(ctx, msg, consumerName, err)
+					continue
+				}
+				msgs = append(msgs, msg)
+			}
+			if err := batch.Error(); err != nil && !errors.Is(err, nats.ErrTimeout) {
+				log.Printf("Fetch from %s ended early: %v", consumerName, err)
+			}
+
+			if len(msgs) == 0 {
+				continue
+			}
+			if !c.beginHandler() {
+				for _, msg := range msgs {
+					msg.Nak()
+				}
+				continue
+			}
+			c.handleBatch(ctx, msgs, consumerName, handler)
+			c.inflight.Done()
+		}
+	}()
+
+	c.track(sub)
+	return sub, nil
+}
+
+// handleBatch runs handler on msgs and settles them
+func (c *Client) handleBatch(ctx context.Context, msgs []jetstream.Msg, consumerName string, handler BatchHandler) {
+	err := handler(msgs)
+	if err == nil {
+		for _, msg := range msgs {
+			msg.Ack()
+		}
+		return
+	}
+
+	if IsPermanent(err) && len(msgs) > 1 {
+		log.Printf("Batch of %d messages failed permanently, retrying individually: %v", len(msgs), err)
+		for _, msg := range msgs {
+			c.handleBatch(ctx, []jetstream.Msg{msg}, consumerName, handler)
+		}
+		return
+	}
+
+	for _, msg := range msgs {
+		c.settleFailed(ctx, msg, consumerName, err)
+	}
+}
+
+// track registers a subscription to be stopped by Drain
+func (c *Client) track(sub subscription) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subs = append(c.subs, sub)
+}
+
+// registerConsumer records a durable consumer for ConsumersHealth
+func (c *Client) registerConsumer(consumerName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, name := range c.consumers {
+		if name == consumerName {
+			return
+		}
+	}
+	c.consumers = append(c.consumers, consumerName)
+}
+
+// LagThreshold is how long a consumer may have pending messages before it counts as lagging
+const LagThreshold = 30 * time.Second
+
+// ConsumerHealthStats reports how far a durable consumer is behind its stream
+type ConsumerHealthStats struct {
+	PendingMessages  int64     `json:"pending_messages"`   // Messages matching the filter not yet delivered
+	AckPending       int64     `json:"ack_pending"`        // Messages delivered but not yet acked
+	RedeliveredCount int64     `json:"redelivered_count"`  // Messages delivered more than once and not yet acked
+	LastDeliveryTime time.Time `json:"last_delivery_time"` // Zero if nothing was delivered yet
+	IsLagging        bool      `json:"is_lagging"`         // Pending messages for longer than LagThreshold
+}
+
+// ConsumerHealth reports the backlog and last delivery of a durable consumer
+// Lag is measured from the first call that saw pending messages, so health must be polled to detect it
+func (c *Client) ConsumerHealth(ctx context.Context, streamName, consumerName string) (*ConsumerHealthStats, error) {
+	consumer, err := c.js.Consumer(ctx, streamName, consumerName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consumer %s: %w", consumerName, err)
+	}
+	info, err := consumer.Info(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consumer %s info: %w", consumerName, err)
+	}
+
+	stats := &ConsumerHealthStats{
+		PendingMessages:  int64(info.NumPending),
+		AckPending:       int64(info.NumAckPending),
+		RedeliveredCount: int64(info.NumRedelivered),
+	}
+	if info.Delivered.Last != nil {
+		stats.LastDeliveryTime = *info.Delivered.Last
+	}
+	stats.IsLagging = c.observePending(streamName+"/"+consumerName, stats.PendingMessages, time.Now())
+	return stats, nil
+}
+
+// ConsumersHealth reports the health of every consumer created by Subscribe and SubscribeBatch, keyed by name
+func (c *Client) ConsumersHealth(ctx context.Context) (map[string]*ConsumerHealthStats, error) {
+	c.mu.Lock()
+	consumers := append([]string(nil), c.consumers...)
+	c.mu.Unlock()
+
+	health := make(map[string]*ConsumerHealthStats, len(consumers))
+	for _, name := range consumers {
+		stats, err := c.ConsumerHealth(ctx, c.config.StreamName, name)
+		if err != nil {
+			return nil, err
+		}
+		health[name] = stats
+	}
+	return health, nil
+}
+
+// observePending records whether a consumer has pending messages at now and returns true once
+// they have been pending continuously for longer than LagThreshold
+func (c *Client) observePending(key string, pending int64, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if pending <= 0 {
+		delete(c.pendingSince, key)
+		return false
+	}
+	since, ok := c.pendingSince[key]
+	if !ok {
+		c.pendingSince[key] = now
+		return false
+	}
+	return now.Sub(since) > LagThreshold
+}
+
+// beginHandler registers an in-flight handler, or returns false if the client is draining
+// Callers that get true must call c.inflight.Done when the handler returns
+func (c *Client) beginHandler() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.draining {
+		return false
+	}
+	c.inflight.Add(1)
+	return true
+}
+
+// Drain shuts the client down without abandoning work: it stops delivery to every subscription,
+// waits for in-flight handlers to finish and settle their messages, flushes pending publishes,
+// and closes the connection. The wait is bounded by ctx; on timeout the connection is still
+// closed and unacked messages are redelivered after their ack wait
+func (c *Client) Drain(ctx context.Context) error {
+	c.mu.Lock()
+	c.draining = true
+	subs := c.subs
+	c.subs = nil
+	c.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		for _, sub := range subs {
+			sub.Stop()
+		}
+		c.inflight.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("failed to wait for in-flight handlers: %w", ctx.Err())
+	}
+
+	if c.nc != nil {
+		if flushErr := c.nc.FlushWithContext(ctx); flushErr != nil && err == nil {
+			err = fmt.Errorf("failed to flush pending publishes: %w", flushErr)
+		}
+		c.nc.Close()
+	}
+	return err
+}
+
 // Close closes the NATS connection
 func (c *Client) Close() {
 	if c.nc != nil {
@@ -0,0 +1,393 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// newTestClient starts an embedded JetStream server and returns a client connected to it with the work stream created
+func newTestClient(tb testing.TB) *Client {
+	tb.Helper()
+	return connectTestClient(tb, newTestServer(tb).ClientURL())
+}
+
+// newTestServer starts an embedded JetStream server, shut down when the test ends
+func newTestServer(tb testing.TB) *server.Server {
+	tb.Helper()
+	srv, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: tb.TempDir(), NoLog: true, NoSigs: true})
+	if err != nil {
+		tb.Fatalf("NewServer: %v", err)
+	}
+	go srv.Start()
+	if !srv.ReadyForConnections(5 * time.Second) {
+		tb.Fatal("embedded NATS server did not start")
+	}
+	tb.Cleanup(srv.Shutdown)
+	return srv
+}
+
+// connectTestClient returns a client connected to url with the work stream created
+func connectTestClient(tb testing.TB, url string) *Client {
+	tb.Helper()
+	cfg := DefaultConfig()
+	cfg.URL = url
+	cfg.RetryDelay = 10 * time.Millisecond
+	client, err := NewClient(cfg)
+	if err != nil {
+		tb.Fatalf("NewClient: %v", err)
+	}
+	tb.Cleanup(client.Close)
+
+	if err := client.CreateStream(context.Background(), StreamSubjects); err != nil {
+		tb.Fatalf("CreateStream: %v", err)
+	}
+	return client
+}
+
+// publishN publishes n small messages to subject
+func publishN(tb testing.TB, client *Client, subject string, n int) {
+	tb.Helper()
+	ctx := context.Background()
+	for i := 0; i < n; i++ {
+		if err := client.PublishWithConfirm(ctx, subject, []byte(fmt.Sprintf(`{"i":%d}`, i)), 0, time.Millisecond); err != nil {
+			tb.Fatalf("PublishWithConfirm: %v", err)
+		}
+	}
+}
+
+// waitFor polls until cond holds or fails the test after timeout
+func waitFor(tb testing.TB, timeout time.Duration, cond func() bool) {
+	tb.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			tb.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSubscribeBatchValidatesArguments(t *testing.T) {
+	client := newTestClient(t)
+	noop := func(msgs []jetstream.Msg) error { return nil }
+	subjects := []string{SubjectFor(SubjectCandleWrite, "", "")}
+
+	tests := []struct {
+		name      string
+		batchSize int
+		maxWait   time.Duration
+	}{
+		{"zero batch size", 0, time.Second},
+		{"zero max wait", 10, 0},
+		{"negative max wait", 10, -time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := client.SubscribeBatch(context.Background(), subjects, "validate", tt.batchSize, tt.maxWait, noop); err == nil {
+				t.Error("SubscribeBatch succeeded, want an argument error")
+			}
+		})
+	}
+}
+
+func TestSubscribeBatchDeliversAll(t *testing.T) {
+	client := newTestClient(t)
+	subject := SubjectFor(SubjectCandleWrite, "BTCUSDT", "1m")
+	publishN(t, client, subject, 25)
+
+	var received, batches atomic.Int64
+	sub, err := client.SubscribeBatch(context.Background(), []string{subject}, "deliver", 10, 50*time.Millisecond, func(msgs []jetstream.Msg) error {
+		received.Add(int64(len(msgs)))
+		batches.Add(1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("SubscribeBatch: %v", err)
+	}
+	defer sub.Stop()
+
+	waitFor(t, 5*time.Second, func() bool { return received.Load() == 25 })
+	if got := batches.Load(); got < 3 || got > 25 {
+		t.Errorf("25 messages arrived in %d batches, want batches of up to 10", got)
+	}
+}
+
+// BenchmarkConsumeThroughput compares per-message consumption with batched fetches of the same backlog
+// Each handler call pays commitCost, standing in for the DuckDB transaction the writer commits per call
+func BenchmarkConsumeThroughput(b *testing.B) {
+	const (
+		backlog    = 500
+		commitCost = 200 * time.Microsecond
+	)
+
+	run := func(b *testing.B, subscribe func(client *Client, name string, count *atomic.Int64) (subscription, error)) {
+		client := newTestClient(b)
+		subject := SubjectFor(SubjectCandleWrite, "BTCUSDT", "1m")
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			publishN(b, client, subject, backlog)
+			b.StartTimer()
+
+			var count atomic.Int64
+			sub, err := subscribe(client, "bench", &count) // Work-queue streams allow one consumer per filter
+			if err != nil {
+				b.Fatalf("subscribe: %v", err)
+			}
+			waitFor(b, 30*time.Second, func() bool { return count.Load() == backlog })
+			sub.Stop()
+		}
+		b.ReportMetric(float64(backlog*b.N)/b.Elapsed().Seconds(), "msgs/s")
+	}
+
+	b.Run("per-message", func(b *testing.B) {
+		run(b, func(client *Client, name string, count *atomic.Int64) (subscription, error) {
+			consumeCtx, err := client.Subscribe(context.Background(), []string{SubjectFor(SubjectCandleWrite, "", "")}, name, func(msg jetstream.Msg) error {
+				time.Sleep(commitCost)
+				count.Add(1)
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			return drainingConsumer{consumeCtx}, nil
+		})
+	})
+	b.Run("batch", func(b *testing.B) {
+		run(b, func(client *Client, name string, count *atomic.Int64) (subscription, error) {
+			return client.SubscribeBatch(context.Background(), []string{SubjectFor(SubjectCandleWrite, "", "")}, name, 100, 10*time.Millisecond, func(msgs []jetstream.Msg) error {
+				time.Sleep(commitCost)
+				count.Add(int64(len(msgs)))
+				return nil
+			})
+		})
+	})
+}
+
+// limitStream caps the work stream at maxMsgs, rejecting publishes beyond it until messages are acked
+func limitStream(tb testing.TB, client *Client, maxMsgs int64) {
+	tb.Helper()
+	_, err := client.js.UpdateStream(context.Background(), jetstream.StreamConfig{
+		Name:      client.config.StreamName,
+		Subjects:  StreamSubjects,
+		Retention: jetstream.WorkQueuePolicy,
+		Storage:   jetstream.FileStorage,
+		MaxAge:    24 * time.Hour,
+		MaxMsgs:   maxMsgs,
+		Discard:   jetstream.DiscardNew,
+	})
+	if err != nil {
+		tb.Fatalf("UpdateStream: %v", err)
+	}
+}
+
+func TestPublishWithConfirmRetriesUntilSlowConsumerFreesSpace(t *testing.T) {
+	client := newTestClient(t)
+	limitStream(t, client, 1)
+	subject := SubjectFor(SubjectCandleWrite, "BTCUSDT", "1m")
+	ctx := context.Background()
+
+	if err := client.PublishWithConfirm(ctx, subject, []byte(`{"i":0}`), 0, time.Millisecond); err != nil {
+		t.Fatalf("first PublishWithConfirm: %v", err)
+	}
+	// The full stream nacks a second message until the slow consumer acks the first
+	if err := client.PublishWithConfirm(ctx, subject, []byte(`{"i":1}`), 0, time.Millisecond); err == nil {
+		t.Fatal("publish to a full stream succeeded without retries")
+	}
+
+	const handlerDelay = 200 * time.Millisecond
+	var handled atomic.Int64
+	if _, err := client.Subscribe(ctx, []string{subject}, "slow", func(msg jetstream.Msg) error {
+		time.Sleep(handlerDelay)
+		handled.Add(1)
+		return nil
+	}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	start := time.Now()
+	if err := client.PublishWithConfirm(ctx, subject, []byte(`{"i":1}`), 10, 10*time.Millisecond); err != nil {
+		t.Fatalf("PublishWithConfirm with retries: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < handlerDelay/2 {
+		t.Errorf("publish confirmed after %s, before the consumer could free space", elapsed)
+	}
+	waitFor(t, 5*time.Second, func() bool { return handled.Load() == 2 })
+}
+
+func TestPublishBatchWithConfirmStopsAtFailure(t *testing.T) {
+	client := newTestClient(t)
+	client.config.RetryAttempts = 2
+	client.config.RetryDelay = time.Millisecond
+	limitStream(t, client, 2)
+
+	payloads := [][]byte{[]byte(`{"i":0}`), []byte(`{"i":1}`), []byte(`{"i":2}`), []byte(`{"i":3}`)}
+	n, err := client.PublishBatchWithConfirm(context.Background(), SubjectFor(SubjectCandleWrite, "BTCUSDT", "1m"), payloads)
+	if err == nil {
+		t.Fatal("PublishBatchWithConfirm succeeded past the stream limit")
+	}
+	if n != 2 {
+		t.Errorf("published %d payloads, want 2", n)
+	}
+}
+
+// streamMsgs returns the number of messages stored in the client's work stream
+func streamMsgs(tb testing.TB, client *Client) uint64 {
+	tb.Helper()
+	stream, err := client.js.Stream(context.Background(), client.config.StreamName)
+	if err != nil {
+		tb.Fatalf("Stream: %v", err)
+	}
+	info, err := stream.Info(context.Background())
+	if err != nil {
+		tb.Fatalf("Stream info: %v", err)
+	}
+	return info.State.Msgs
+}
+
+func TestDrainWaitsForSlowHandler(t *testing.T) {
+	srv := newTestServer(t)
+	client := connectTestClient(t, srv.ClientURL())
+	subject := SubjectFor(SubjectCandleWrite, "BTCUSDT", "1m")
+	publishN(t, client, subject, 3)
+
+	var started, finished atomic.Int64
+	if _, err := client.Subscribe(context.Background(), []string{subject}, "slow", func(msg jetstream.Msg) error {
+		started.Add(1)
+		time.Sleep(300 * time.Millisecond)
+		finished.Add(1)
+		return nil
+	}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	waitFor(t, 5*time.Second, func() bool { return started.Load() > 0 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if s, f := started.Load(), finished.Load(); s != f {
+		t.Errorf("Drain returned with %d of %d handlers unfinished", s-f, s)
+	}
+	if client.IsConnected() {
+		t.Error("client still connected after Drain")
+	}
+
+	// Handled messages were acked and the rest stay queued for the next consumer
+	other := connectTestClient(t, srv.ClientURL())
+	if got, want := streamMsgs(t, other), uint64(3-finished.Load()); got != want {
+		t.Errorf("stream holds %d messages after drain, want %d", got, want)
+	}
+}
+
+func TestDrainTimesOut(t *testing.T) {
+	client := newTestClient(t)
+	subject := SubjectFor(SubjectCandleWrite, "BTCUSDT", "1m")
+	publishN(t, client, subject, 1)
+
+	var started atomic.Int64
+	release := make(chan struct{})
+	defer close(release)
+	if _, err := client.Subscribe(context.Background(), []string{subject}, "stuck", func(msg jetstream.Msg) error {
+		started.Add(1)
+		<-release
+		return nil
+	}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	waitFor(t, 5*time.Second, func() bool { return started.Load() == 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := client.Drain(ctx); err == nil {
+		t.Error("Drain succeeded with a handler still running")
+	}
+	if client.IsConnected() {
+		t.Error("client still connected after a timed-out Drain")
+	}
+}
+
+func TestObservePending(t *testing.T) {
+	client := &Client{pendingSince: make(map[string]time.Time)}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		name    string
+		key     string
+		pending int64
+		at      time.Duration
+		lagging bool
+	}{
+		{"first sighting starts the clock", "etna/writer", 5, 0, false},
+		{"pending at the threshold", "etna/writer", 5, LagThreshold, false},
+		{"pending past the threshold", "etna/writer", 3, LagThreshold + time.Second, true},
+		{"other consumers lag on their own clock", "etna/other", 5, LagThreshold + time.Second, false},
+		{"caught up resets", "etna/writer", 0, LagThreshold + 2*time.Second, false},
+		{"pending again restarts the clock", "etna/writer", 1, LagThreshold + 3*time.Second, false},
+		{"lagging again a full threshold later", "etna/writer", 1, 2*LagThreshold + 4*time.Second, true},
+	}
+	for _, s := range steps {
+		if got := client.observePending(s.key, s.pending, start.Add(s.at)); got != s.lagging {
+			t.Errorf("%s: lagging = %v, want %v", s.name, got, s.lagging)
+		}
+	}
+}
+
+func TestConsumerHealthReportsLaggingConsumer(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	subject := SubjectFor(SubjectCandleWrite, "BTCUSDT", "1m")
+
+	// A consumer that takes its first message and never acks it
+	release := make(chan struct{})
+	defer close(release)
+	var delivered atomic.Int64
+	if _, err := client.SubscribeBatch(ctx, []string{subject}, "lagging", 1, 10*time.Millisecond, func(msgs []jetstream.Msg) error {
+		delivered.Add(1)
+		<-release
+		return nil
+	}); err != nil {
+		t.Fatalf("SubscribeBatch: %v", err)
+	}
+	publishN(t, client, subject, 5)
+	waitFor(t, 5*time.Second, func() bool { return delivered.Load() == 1 })
+
+	health, err := client.ConsumersHealth(ctx)
+	if err != nil {
+		t.Fatalf("ConsumersHealth: %v", err)
+	}
+	stats, ok := health["lagging"]
+	if !ok {
+		t.Fatalf("ConsumersHealth = %v, want the lagging consumer", health)
+	}
+	if stats.PendingMessages == 0 || stats.AckPending == 0 || stats.LastDeliveryTime.IsZero() {
+		t.Errorf("stats = %+v, want pending and unacked messages after a delivery", stats)
+	}
+	if stats.IsLagging {
+		t.Error("consumer lagging on the first observation")
+	}
+
+	// Backdate the first observation instead of waiting out LagThreshold
+	client.mu.Lock()
+	client.pendingSince[client.config.StreamName+"/lagging"] = time.Now().Add(-LagThreshold - time.Second)
+	client.mu.Unlock()
+
+	stats, err = client.ConsumerHealth(ctx, client.config.StreamName, "lagging")
+	if err != nil {
+		t.Fatalf("ConsumerHealth: %v", err)
+	}
+	if !stats.IsLagging {
+		t.Errorf("stats = %+v, want lagging after pending past LagThreshold", stats)
+	}
+
+	if _, err := client.ConsumerHealth(ctx, client.config.StreamName, "missing"); err == nil {
+		t.Error("ConsumerHealth of a missing consumer succeeded")
+	}
+}
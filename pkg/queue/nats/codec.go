@@ -0,0 +1,65 @@
+package nats
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Codec selects the wire encoding of published queue messages
+type Codec string
+
+const (
+	CodecJSON  Codec = "json"
+	CodecProto Codec = "proto"
+)
+
+// Format prefixes written as the first byte of codec-encoded payloads
+// Payloads without a prefix (starting with '{') are legacy unprefixed JSON
+const (
+	formatJSON  byte = 0x01
+	formatProto byte = 0x02
+)
+
+// protoMessage is implemented by queue messages with a protobuf wire encoding (see messages.proto)
+type protoMessage interface {
+	appendProto(b []byte) []byte
+	unmarshalProto(b []byte) error
+}
+
+// EncodeWith serializes a queue message with codec, prefixed by its format byte
+// An empty codec encodes JSON
+func EncodeWith(codec Codec, v interface{}) ([]byte, error) {
+	switch codec {
+	case CodecJSON, "":
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{formatJSON}, data...), nil
+	case CodecProto:
+		msg, ok := v.(protoMessage)
+		if !ok {
+			return nil, fmt.Errorf("no protobuf encoding for %T", v)
+		}
+		return msg.appendProto([]byte{formatProto}), nil
+	default:
+		return nil, fmt.Errorf("unknown codec %q", codec)
+	}
+}
+
+// decode deserializes a JSON, prefixed JSON, or prefixed protobuf payload into msg
+func decode(data []byte, msg protoMessage) error {
+	if len(data) == 0 {
+		return errors.New("empty payload")
+	}
+
+	switch data[0] {
+	case formatProto:
+		return msg.unmarshalProto(data[1:])
+	case formatJSON:
+		return json.Unmarshal(data[1:], msg)
+	default:
+		return json.Unmarshal(data, msg)
+	}
+}
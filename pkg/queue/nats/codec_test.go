@@ -0,0 +1,317 @@
+package nats
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// codecMessages returns one of each queue message with a protobuf encoding, keyed by its messages.proto name
+func codecMessages() map[string]func() protoMessage {
+	return map[string]func() protoMessage{
+		"CandleWriteMsg": func() protoMessage { return &CandleWriteMsg{} },
+		"CandleBatchMsg": func() protoMessage { return &CandleBatchMsg{} },
+		"WindowWriteMsg": func() protoMessage { return &WindowWriteMsg{} },
+		"WindowBatchMsg": func() protoMessage { return &WindowBatchMsg{} },
+		"MilvusWriteMsg": func() protoMessage { return &MilvusWriteMsg{} },
+		"MilvusBatchMsg": func() protoMessage { return &MilvusBatchMsg{} },
+	}
+}
+
+// fill sets every exported field reachable from v to a distinct non-zero value, so a field missing from
+// either codec fails the round trip. Odd seeds produce negative numbers to exercise zigzag encoding
+func fill(v reflect.Value, seed *int) {
+	*seed++
+	n := *seed
+	sign := 1
+	if n%2 == 1 {
+		sign = -1
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+		fill(v.Elem(), seed)
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 2, 2))
+		for i := 0; i < v.Len(); i++ {
+			fill(v.Index(i), seed)
+		}
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(time.Time{}) {
+			v.Set(reflect.ValueOf(time.Date(2024, 1, 1, 0, n, 0, n, time.UTC)))
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				fill(v.Field(i), seed)
+			}
+		}
+	case reflect.String:
+		v.SetString(fmt.Sprintf("s%d", n))
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		v.SetInt(int64(sign * n))
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float64(sign*n) + 0.25)
+	}
+}
+
+// filled returns a fresh message of newMsg with every field set
+func filled(newMsg func() protoMessage) protoMessage {
+	msg := newMsg()
+	seed := 0
+	fill(reflect.ValueOf(msg).Elem(), &seed)
+	return msg
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	encodings := []struct {
+		name   string
+		encode func(v interface{}) ([]byte, error)
+	}{
+		{"json", func(v interface{}) ([]byte, error) { return EncodeWith(CodecJSON, v) }},
+		{"proto", func(v interface{}) ([]byte, error) { return EncodeWith(CodecProto, v) }},
+		{"legacy json", Encode},
+	}
+
+	for name, newMsg := range codecMessages() {
+		for _, enc := range encodings {
+			t.Run(name+"/"+enc.name, func(t *testing.T) {
+				want := filled(newMsg)
+				data, err := enc.encode(want)
+				if err != nil {
+					t.Fatalf("encode: %v", err)
+				}
+				got := newMsg()
+				if err := decode(data, got); err != nil {
+					t.Fatalf("decode: %v", err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("round trip changed the message\n got %+v\nwant %+v", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestDecodeRejectsBadPayloads(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated proto", []byte{formatProto, 0x0a, 0x10, 0x01}},
+		{"bad json", []byte{formatJSON, '{'}},
+	}
+	for _, tt := range tests {
+		if err := decode(tt.data, &CandleBatchMsg{}); err == nil {
+			t.Errorf("%s: decode succeeded, want an error", tt.name)
+		}
+	}
+	if _, err := EncodeWith(CodecProto, OutcomeJobMsg{}); err == nil {
+		t.Error("EncodeWith(CodecProto) of a message without a protobuf encoding succeeded")
+	}
+	if _, err := EncodeWith("msgpack", &CandleBatchMsg{}); err == nil {
+		t.Error("EncodeWith of an unknown codec succeeded")
+	}
+}
+
+// TestProtoMatchesSchema checks the hand-written encoding against messages.proto with a reflection-based
+// protobuf implementation: every schema field is written with its declared number and type, and payloads the
+// schema encodes decode back to the same message
+func TestProtoMatchesSchema(t *testing.T) {
+	file := loadSchema(t, "messages.proto")
+
+	for name, newMsg := range codecMessages() {
+		t.Run(name, func(t *testing.T) {
+			desc := file.Messages().ByName(protoreflect.Name(name))
+			if desc == nil {
+				t.Fatalf("messages.proto has no message %s", name)
+			}
+
+			want := filled(newMsg)
+			data := want.appendProto(nil)
+			dyn := dynamicpb.NewMessage(desc)
+			if err := proto.Unmarshal(data, dyn); err != nil {
+				t.Fatalf("schema rejects the encoding: %v", err)
+			}
+			checkAllFieldsKnown(t, dyn.ProtoReflect(), name)
+
+			reencoded, err := proto.MarshalOptions{Deterministic: true}.Marshal(dyn)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			got := newMsg()
+			if err := got.unmarshalProto(reencoded); err != nil {
+				t.Fatalf("unmarshalProto: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("schema-encoded payload decodes differently\n got %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+// checkAllFieldsKnown fails unless every field of m and its submessages is set and none was left unknown
+func checkAllFieldsKnown(t *testing.T, m protoreflect.Message, path string) {
+	t.Helper()
+	if len(m.GetUnknown()) > 0 {
+		t.Errorf("%s: fields with undeclared numbers or mismatched types", path)
+	}
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		p := path + "." + string(fd.Name())
+		if !m.Has(fd) {
+			t.Errorf("%s is never written", p)
+			continue
+		}
+		if fd.Kind() != protoreflect.MessageKind {
+			continue
+		}
+		if fd.IsList() {
+			list := m.Get(fd).List()
+			for j := 0; j < list.Len(); j++ {
+				checkAllFieldsKnown(t, list.Get(j).Message(), fmt.Sprintf("%s[%d]", p, j))
+			}
+		} else {
+			checkAllFieldsKnown(t, m.Get(fd).Message(), p)
+		}
+	}
+}
+
+// loadSchema builds a file descriptor from the message and field declarations of a flat proto3 file
+func loadSchema(t *testing.T, path string) protoreflect.FileDescriptor {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open schema: %v", err)
+	}
+	defer f.Close()
+
+	scalars := map[string]descriptorpb.FieldDescriptorProto_Type{
+		"string": descriptorpb.FieldDescriptorProto_TYPE_STRING,
+		"sint64": descriptorpb.FieldDescriptorProto_TYPE_SINT64,
+		"double": descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+		"float":  descriptorpb.FieldDescriptorProto_TYPE_FLOAT,
+	}
+
+	fd := &descriptorpb.FileDescriptorProto{Name: proto.String(path), Syntax: proto.String("proto3")}
+	var msg *descriptorpb.DescriptorProto
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "//") || strings.HasPrefix(line, "syntax"):
+		case strings.HasPrefix(line, "package "):
+			fd.Package = proto.String(strings.TrimSuffix(strings.TrimPrefix(line, "package "), ";"))
+		case strings.HasPrefix(line, "message "):
+			msg = &descriptorpb.DescriptorProto{Name: proto.String(strings.Fields(line)[1])}
+			fd.MessageType = append(fd.MessageType, msg)
+		case line == "}":
+			msg = nil
+		default:
+			// [repeated] type name = number;
+			words := strings.Fields(strings.TrimSuffix(line, ";"))
+			label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+			if words[0] == "repeated" {
+				label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+				words = words[1:]
+			}
+			if msg == nil || len(words) != 4 || words[2] != "=" {
+				t.Fatalf("unsupported schema line %q", line)
+			}
+			num, err := strconv.Atoi(words[3])
+			if err != nil {
+				t.Fatalf("bad field number in %q", line)
+			}
+			field := &descriptorpb.FieldDescriptorProto{
+				Name:     proto.String(words[1]),
+				JsonName: proto.String(words[1]),
+				Number:   proto.Int32(int32(num)),
+				Label:    label.Enum(),
+			}
+			if typ, ok := scalars[words[0]]; ok {
+				field.Type = typ.Enum()
+			} else {
+				field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+				field.TypeName = proto.String("." + fd.GetPackage() + "." + words[0])
+			}
+			msg.Field = append(msg.Field, field)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("failed to read schema: %v", err)
+	}
+
+	file, err := protodesc.NewFile(fd, nil)
+	if err != nil {
+		t.Fatalf("invalid schema: %v", err)
+	}
+	return file
+}
+
+// benchWindowBatch returns a batch of 1m windows shaped like the builder's output
+func benchWindowBatch(windows, candles int) *WindowBatchMsg {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	batch := &WindowBatchMsg{}
+	for i := 0; i < windows; i++ {
+		w := &model.Window{WindowID: fmt.Sprintf("BTCUSDT-1m-%d", i), Symbol: "BTCUSDT", Timeframe: "1m", W: candles, FeatureVersion: 1, CreatedAt: start}
+		for j := 0; j < candles; j++ {
+			open := start.Add(time.Duration(i+j) * time.Minute)
+			price := 42000 + float64(i+j)*1.37
+			w.Candles = append(w.Candles, model.Candle{
+				Symbol: "BTCUSDT", Timeframe: "1m", OpenTime: open, CloseTime: open.Add(time.Minute - time.Millisecond),
+				Open: price, High: price + 12.5, Low: price - 8.25, Close: price + 3.1, Volume: 12.345 + float64(j), Trades: int64(300 + j),
+			})
+		}
+		w.TEnd = w.Candles[candles-1].CloseTime
+		batch.Windows = append(batch.Windows, w)
+		batch.Features = append(batch.Features, &model.FeatureRow{WindowID: w.WindowID, TrendSlope: 0.12, RealizedVolatility: 0.004, ATR: 15.2, VolBucket: 3, DataVersion: 2})
+	}
+	return batch
+}
+
+// BenchmarkCodec compares encoded size and encode/decode speed of JSON and protobuf for a window batch,
+// the largest message the pipeline publishes
+func BenchmarkCodec(b *testing.B) {
+	batch := benchWindowBatch(10, 60)
+	for _, codec := range []Codec{CodecJSON, CodecProto} {
+		data, err := EncodeWith(codec, batch)
+		if err != nil {
+			b.Fatalf("EncodeWith: %v", err)
+		}
+
+		b.Run(string(codec)+"/encode", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := EncodeWith(codec, batch); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(len(data)), "bytes/msg")
+		})
+		b.Run(string(codec)+"/decode", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := DecodeWindowBatch(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(len(data)), "bytes/msg")
+		})
+	}
+}
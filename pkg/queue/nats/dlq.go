@@ -0,0 +1,190 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// DLQ subject prefix; dead letters of subject X are published to DLQPrefix + X
+const (
+	DLQPrefix     = "etna.dlq."
+	SubjectDLQAll = DLQPrefix + ">"
+)
+
+// dlqMaxAge is how long dead letters are retained
+const dlqMaxAge = 7 * 24 * time.Hour
+
+// DLQSubject returns the dead-letter subject for subject
+func DLQSubject(subject string) string {
+	return DLQPrefix + subject
+}
+
+// permanentError marks a handler error that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so Subscribe dead-letters the message immediately instead of redelivering it
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was wrapped by Permanent
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// DeadLetter is a failed message with its error metadata, as published to the DLQ
+type DeadLetter struct {
+	Subject    string    `json:"subject"`  // Original subject
+	Consumer   string    `json:"consumer"` // Consumer whose handler failed
+	Error      string    `json:"error"`
+	Permanent  bool      `json:"permanent"` // Handler reported a permanent error rather than exhausting deliveries
+	Deliveries uint64    `json:"deliveries"`
+	FailedAt   time.Time `json:"failed_at"`
+	Data       []byte    `json:"data"`                     // Original payload
+	Schema     string    `json:"schema_version,omitempty"` // Original HeaderSchemaVersion, empty for legacy messages
+}
+
+// StoredDeadLetter is a dead letter together with its DLQ stream sequence
+type StoredDeadLetter struct {
+	Sequence uint64
+	DeadLetter
+}
+
+// dlqStreamName returns the name of the stream holding dead letters
+func (c *Client) dlqStreamName() string {
+	return c.config.StreamName + "-dlq"
+}
+
+// CreateDLQStream creates the stream retaining dead letters for inspection and replay
+func (c *Client) CreateDLQStream(ctx context.Context) error {
+	_, err := c.js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:      c.dlqStreamName(),
+		Subjects:  []string{SubjectDLQAll},
+		Retention: jetstream.LimitsPolicy,
+		Storage:   jetstream.FileStorage,
+		MaxAge:    dlqMaxAge,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create dlq stream: %w", err)
+	}
+	return nil
+}
+
+// publishDeadLetter publishes msg and the handler error to the DLQ subject of msg's subject
+func (c *Client) publishDeadLetter(ctx context.Context, msg jetstream.Msg, consumerName string, deliveries uint64, handlerErr error) error {
+	data, err := json.Marshal(DeadLetter{
+		Subject:    msg.Subject(),
+		Consumer:   consumerName,
+		Error:      handlerErr.Error(),
+		Permanent:  IsPermanent(handlerErr),
+		Deliveries: deliveries,
+		FailedAt:   time.Now(),
+		Data:       msg.Data(),
+		Schema:     msg.Headers().Get(HeaderSchemaVersion),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letter: %w", err)
+	}
+	return c.PublishWithConfirm(ctx, DLQSubject(msg.Subject()), data, c.config.RetryAttempts, c.config.RetryDelay)
+}
+
+// ListDeadLetters returns up to limit stored dead letters, oldest first
+// subject filters by original subject or subject base; empty lists all. limit <= 0 lists all
+func (c *Client) ListDeadLetters(ctx context.Context, subject string, limit int) ([]StoredDeadLetter, error) {
+	stream, err := c.js.Stream(ctx, c.dlqStreamName())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dlq stream: %w", err)
+	}
+
+	info, err := stream.Info(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dlq stream info: %w", err)
+	}
+
+	var letters []StoredDeadLetter
+	for seq := info.State.FirstSeq; seq <= info.State.LastSeq && info.State.Msgs > 0; seq++ {
+		if limit > 0 && len(letters) >= limit {
+			break
+		}
+
+		raw, err := stream.GetMsg(ctx, seq)
+		if errors.Is(err, jetstream.ErrMsgNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get dead letter %d: %w", seq, err)
+		}
+		if subject != "" && raw.Subject != DLQSubject(subject) && !strings.HasPrefix(raw.Subject, DLQSubject(subject)+".") {
+			continue
+		}
+
+		letter, err := decodeDeadLetter(raw)
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+
+	return letters, nil
+}
+
+// ReplayDeadLetter republishes a stored dead letter to its original subject, with its original schema version,
+// and removes it from the DLQ
+func (c *Client) ReplayDeadLetter(ctx context.Context, seq uint64) (*StoredDeadLetter, error) {
+	stream, err := c.js.Stream(ctx, c.dlqStreamName())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dlq stream: %w", err)
+	}
+
+	raw, err := stream.GetMsg(ctx, seq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter %d: %w", seq, err)
+	}
+
+	letter, err := decodeDeadLetter(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &nats.Msg{Subject: letter.Subject, Data: letter.Data}
+	if letter.Schema != "" {
+		msg.Header = nats.Header{}
+		msg.Header.Set(HeaderSchemaVersion, letter.Schema)
+	}
+	if err := c.publishMsgWithConfirm(ctx, msg, c.config.RetryAttempts, c.config.RetryDelay); err != nil {
+		return nil, err
+	}
+	if err := stream.DeleteMsg(ctx, seq); err != nil {
+		return nil, fmt.Errorf("failed to delete replayed dead letter %d: %w", seq, err)
+	}
+
+	return &letter, nil
+}
+
+// decodeDeadLetter decodes a raw DLQ stream message
+func decodeDeadLetter(raw *jetstream.RawStreamMsg) (StoredDeadLetter, error) {
+	letter := StoredDeadLetter{Sequence: raw.Sequence}
+	if err := json.Unmarshal(raw.Data, &letter.DeadLetter); err != nil {
+		return letter, fmt.Errorf("failed to decode dead letter %d: %w", raw.Sequence, err)
+	}
+	if letter.Subject == "" {
+		letter.Subject = strings.TrimPrefix(raw.Subject, DLQPrefix)
+	}
+	return letter, nil
+}
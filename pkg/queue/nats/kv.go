@@ -0,0 +1,140 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/tunogya/etna/pkg/window"
+)
+
+// DefaultKVBucket is the JetStream KV bucket holding pipeline checkpoints and builder snapshots
+const DefaultKVBucket = "etna-state"
+
+// KV key prefixes; keys are prefix.symbol.timeframe
+const (
+	kvCandleCheckpointPrefix = "checkpoint.candle"
+	kvBuilderPrefix          = "builder"
+)
+
+// keyValue opens the configured KV bucket, creating it on first use
+func (c *Client) keyValue(ctx context.Context) (jetstream.KeyValue, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.kv != nil {
+		return c.kv, nil
+	}
+
+	bucket := c.config.KVBucket
+	if bucket == "" {
+		bucket = DefaultKVBucket
+	}
+	kv, err := c.js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+		Bucket:  bucket,
+		History: 1,
+		Storage: jetstream.FileStorage,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open kv bucket %s: %w", bucket, err)
+	}
+
+	c.kv = kv
+	return kv, nil
+}
+
+// KVPut stores value under key and returns its revision
+func (c *Client) KVPut(ctx context.Context, key string, value []byte) (uint64, error) {
+	kv, err := c.keyValue(ctx)
+	if err != nil {
+		return 0, err
+	}
+	rev, err := kv.Put(ctx, key, value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to put %s: %w", key, err)
+	}
+	return rev, nil
+}
+
+// KVGet returns the value stored under key
+// A missing key returns an error wrapping jetstream.ErrKeyNotFound
+func (c *Client) KVGet(ctx context.Context, key string) ([]byte, error) {
+	kv, err := c.keyValue(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := kv.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", key, err)
+	}
+	return entry.Value(), nil
+}
+
+// KVWatch watches keys matching pattern (which may use * and > wildcards) until ctx is done
+// The watcher first delivers the current values, then a nil entry, then live updates
+func (c *Client) KVWatch(ctx context.Context, pattern string) (jetstream.KeyWatcher, error) {
+	kv, err := c.keyValue(ctx)
+	if err != nil {
+		return nil, err
+	}
+	watcher, err := kv.Watch(ctx, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch %s: %w", pattern, err)
+	}
+	return watcher, nil
+}
+
+// kvKey returns the key prefix.symbol.timeframe
+func kvKey(prefix, symbol, timeframe string) string {
+	return strings.Join([]string{prefix, symbol, timeframe}, ".")
+}
+
+// PutCandleCheckpoint records the open time of the last processed candle of symbol/timeframe
+func (c *Client) PutCandleCheckpoint(ctx context.Context, symbol, timeframe string, openTime time.Time) error {
+	data, err := openTime.UTC().MarshalText()
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	_, err = c.KVPut(ctx, kvKey(kvCandleCheckpointPrefix, symbol, timeframe), data)
+	return err
+}
+
+// GetCandleCheckpoint returns the open time of the last processed candle of symbol/timeframe
+// A missing checkpoint returns an error wrapping jetstream.ErrKeyNotFound
+func (c *Client) GetCandleCheckpoint(ctx context.Context, symbol, timeframe string) (time.Time, error) {
+	data, err := c.KVGet(ctx, kvKey(kvCandleCheckpointPrefix, symbol, timeframe))
+	if err != nil {
+		return time.Time{}, err
+	}
+	var t time.Time
+	if err := t.UnmarshalText(data); err != nil {
+		return time.Time{}, fmt.Errorf("failed to decode checkpoint: %w", err)
+	}
+	return t, nil
+}
+
+// PutBuilderSnapshot stores a window builder snapshot under its symbol/timeframe
+func (c *Client) PutBuilderSnapshot(ctx context.Context, state window.BuilderState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode builder snapshot: %w", err)
+	}
+	_, err = c.KVPut(ctx, kvKey(kvBuilderPrefix, state.Symbol, state.Timeframe), data)
+	return err
+}
+
+// GetBuilderSnapshot returns the stored window builder snapshot of symbol/timeframe
+// A missing snapshot returns an error wrapping jetstream.ErrKeyNotFound
+func (c *Client) GetBuilderSnapshot(ctx context.Context, symbol, timeframe string) (*window.BuilderState, error) {
+	data, err := c.KVGet(ctx, kvKey(kvBuilderPrefix, symbol, timeframe))
+	if err != nil {
+		return nil, err
+	}
+	var state window.BuilderState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode builder snapshot: %w", err)
+	}
+	return &state, nil
+}
@@ -0,0 +1,140 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/tunogya/etna/pkg/model"
+	"github.com/tunogya/etna/pkg/window"
+)
+
+func TestKVPutGet(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	if _, err := client.KVGet(ctx, "missing"); !errors.Is(err, jetstream.ErrKeyNotFound) {
+		t.Errorf("KVGet missing key = %v, want ErrKeyNotFound", err)
+	}
+
+	first, err := client.KVPut(ctx, "state.a", []byte("one"))
+	if err != nil {
+		t.Fatalf("KVPut: %v", err)
+	}
+	second, err := client.KVPut(ctx, "state.a", []byte("two"))
+	if err != nil {
+		t.Fatalf("KVPut: %v", err)
+	}
+	if second <= first {
+		t.Errorf("revisions %d then %d, want increasing", first, second)
+	}
+
+	got, err := client.KVGet(ctx, "state.a")
+	if err != nil {
+		t.Fatalf("KVGet: %v", err)
+	}
+	if string(got) != "two" {
+		t.Errorf("KVGet = %q, want %q", got, "two")
+	}
+}
+
+func TestKVWatchDeliversCurrentThenUpdates(t *testing.T) {
+	client := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if _, err := client.KVPut(ctx, "state.a", []byte("initial")); err != nil {
+		t.Fatalf("KVPut: %v", err)
+	}
+	watcher, err := client.KVWatch(ctx, "state.*")
+	if err != nil {
+		t.Fatalf("KVWatch: %v", err)
+	}
+	defer watcher.Stop()
+
+	next := func() jetstream.KeyValueEntry {
+		t.Helper()
+		select {
+		case entry := <-watcher.Updates():
+			return entry
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a watch update")
+			return nil
+		}
+	}
+
+	if entry := next(); entry == nil || entry.Key() != "state.a" || string(entry.Value()) != "initial" {
+		t.Fatalf("first update = %v, want the current state.a", entry)
+	}
+	if entry := next(); entry != nil {
+		t.Fatalf("second update = %s, want the nil end-of-initial-values marker", entry.Key())
+	}
+
+	if _, err := client.KVPut(ctx, "state.b", []byte("live")); err != nil {
+		t.Fatalf("KVPut: %v", err)
+	}
+	if entry := next(); entry == nil || entry.Key() != "state.b" || string(entry.Value()) != "live" {
+		t.Errorf("live update = %v, want state.b", entry)
+	}
+}
+
+func TestCandleCheckpointRoundTrip(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	if _, err := client.GetCandleCheckpoint(ctx, "BTCUSDT", "1m"); !errors.Is(err, jetstream.ErrKeyNotFound) {
+		t.Errorf("missing checkpoint = %v, want ErrKeyNotFound", err)
+	}
+
+	openTime := time.Date(2024, 3, 1, 10, 15, 0, 0, time.FixedZone("UTC+8", 8*3600))
+	if err := client.PutCandleCheckpoint(ctx, "BTCUSDT", "1m", openTime); err != nil {
+		t.Fatalf("PutCandleCheckpoint: %v", err)
+	}
+	if err := client.PutCandleCheckpoint(ctx, "BTCUSDT", "1h", openTime.Add(-time.Hour)); err != nil {
+		t.Fatalf("PutCandleCheckpoint: %v", err)
+	}
+
+	got, err := client.GetCandleCheckpoint(ctx, "BTCUSDT", "1m")
+	if err != nil {
+		t.Fatalf("GetCandleCheckpoint: %v", err)
+	}
+	if !got.Equal(openTime) || got.Location() != time.UTC {
+		t.Errorf("checkpoint = %s, want %s in UTC", got, openTime.UTC())
+	}
+}
+
+func TestBuilderSnapshotRoundTrip(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	open := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	state := window.BuilderState{
+		Symbol:         "BTCUSDT",
+		Timeframe:      "1m",
+		W:              3,
+		FeatureVersion: 2,
+		Candles: []model.Candle{
+			{Symbol: "BTCUSDT", Timeframe: "1m", OpenTime: open, CloseTime: open.Add(time.Minute - time.Millisecond), Close: 100},
+			{Symbol: "BTCUSDT", Timeframe: "1m", OpenTime: open.Add(time.Minute), CloseTime: open.Add(2*time.Minute - time.Millisecond), Close: 101},
+		},
+		StepCount: 7,
+		WarmedUp:  true,
+	}
+	if err := client.PutBuilderSnapshot(ctx, state); err != nil {
+		t.Fatalf("PutBuilderSnapshot: %v", err)
+	}
+
+	got, err := client.GetBuilderSnapshot(ctx, "BTCUSDT", "1m")
+	if err != nil {
+		t.Fatalf("GetBuilderSnapshot: %v", err)
+	}
+	if !reflect.DeepEqual(*got, state) {
+		t.Errorf("snapshot = %+v, want %+v", *got, state)
+	}
+	if _, err := client.GetBuilderSnapshot(ctx, "ETHUSDT", "1m"); !errors.Is(err, jetstream.ErrKeyNotFound) {
+		t.Errorf("missing snapshot = %v, want ErrKeyNotFound", err)
+	}
+}
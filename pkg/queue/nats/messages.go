@@ -2,17 +2,79 @@ package nats
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/tunogya/etna/pkg/model"
 )
 
-// Subject constants
+// Subject bases; messages are published to SubjectFor(base, symbol, timeframe)
 const (
 	SubjectCandleWrite = "etna.candles.write"
 	SubjectWindowWrite = "etna.windows.write"
+	SubjectMilvusWrite = "etna.milvus.write"
 )
 
+// subjectBases lists every routed subject base
+var subjectBases = []string{SubjectCandleWrite, SubjectWindowWrite, SubjectMilvusWrite, SubjectOutcomeCompute}
+
+// StreamSubjects are the subjects captured by the work stream: each base's routed subjects, plus the
+// bare base that unrouted publishers used before symbol routing
+// A single "etna.>" wildcard would overlap the DLQ stream's "etna.dlq.>"
+var StreamSubjects = []string{
+	SubjectCandleWrite, SubjectCandleWrite + ".>",
+	SubjectWindowWrite, SubjectWindowWrite + ".>",
+	SubjectMilvusWrite, SubjectMilvusWrite + ".>",
+	SubjectOutcomeCompute, SubjectOutcomeCompute + ".>",
+}
+
+// SubjectFor returns the symbol-routed subject base.symbol.timeframe, e.g. "etna.candles.write.BTCUSDT.1m"
+// An empty symbol or timeframe becomes the "*" wildcard, for use in consumer filters
+func SubjectFor(base, symbol, timeframe string) string {
+	if symbol == "" {
+		symbol = "*"
+	}
+	if timeframe == "" {
+		timeframe = "*"
+	}
+	return base + "." + symbol + "." + timeframe
+}
+
+// ConsumerSubjects returns the filter subjects of a consumer owning symbols on base
+// With no symbols and no timeframe the consumer is the wildcard default, which also receives
+// legacy messages published to the bare base. Consumers of one work-queue stream must own disjoint subjects
+func ConsumerSubjects(base string, symbols []string, timeframe string) []string {
+	if len(symbols) == 0 {
+		if timeframe == "" {
+			return []string{base, base + ".>"}
+		}
+		return []string{SubjectFor(base, "", timeframe)}
+	}
+
+	subjects := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		subjects[i] = SubjectFor(base, symbol, timeframe)
+	}
+	return subjects
+}
+
+// ParseSubject splits a symbol-routed subject into its base, symbol, and timeframe
+func ParseSubject(subject string) (base, symbol, timeframe string, err error) {
+	for _, b := range subjectBases {
+		rest, ok := strings.CutPrefix(subject, b+".")
+		if !ok {
+			continue
+		}
+		symbol, timeframe, ok = strings.Cut(rest, ".")
+		if !ok || symbol == "" || timeframe == "" || strings.Contains(timeframe, ".") {
+			break
+		}
+		return b, symbol, timeframe, nil
+	}
+	return "", "", "", fmt.Errorf("invalid routed subject %q", subject)
+}
+
 // CandleWriteMsg represents a single candle write request
 type CandleWriteMsg struct {
 	Candle *model.Candle `json:"candle"`
@@ -47,42 +109,80 @@ type MilvusWriteMsg struct {
 	DataVersion int32     `json:"data_version"`
 }
 
-// Encode serializes a message to JSON bytes
+// NewMilvusWriteMsg builds a vector write request for a window, its features, and its shape embedding
+func NewMilvusWriteMsg(w *model.Window, f *model.FeatureRow, embedding []float32) MilvusWriteMsg {
+	return MilvusWriteMsg{
+		WindowID:    w.WindowID,
+		Embedding:   embedding,
+		Symbol:      w.Symbol,
+		Timeframe:   w.Timeframe,
+		TEnd:        w.TEnd,
+		VolBucket:   int32(f.VolBucket),
+		TrendBucket: int32(f.TrendBucket),
+		DataVersion: int32(f.DataVersion),
+	}
+}
+
+// MilvusBatchMsg represents a batch Milvus vector write request
+type MilvusBatchMsg struct {
+	Vectors []MilvusWriteMsg `json:"vectors"`
+}
+
+// Encode serializes a message to unprefixed JSON bytes
+// Use EncodeWith or Client.Encode to choose a codec
 func Encode(v interface{}) ([]byte, error) {
 	return json.Marshal(v)
 }
 
-// DecodeCandleBatch deserializes a CandleBatchMsg from JSON bytes
+// DecodeCandleBatch deserializes a CandleBatchMsg from JSON or codec-encoded bytes
 func DecodeCandleBatch(data []byte) (*CandleBatchMsg, error) {
 	var msg CandleBatchMsg
-	if err := json.Unmarshal(data, &msg); err != nil {
+	if err := decode(data, &msg); err != nil {
 		return nil, err
 	}
 	return &msg, nil
 }
 
-// DecodeWindowBatch deserializes a WindowBatchMsg from JSON bytes
+// DecodeWindowBatch deserializes a WindowBatchMsg from JSON or codec-encoded bytes
 func DecodeWindowBatch(data []byte) (*WindowBatchMsg, error) {
 	var msg WindowBatchMsg
-	if err := json.Unmarshal(data, &msg); err != nil {
+	if err := decode(data, &msg); err != nil {
 		return nil, err
 	}
 	return &msg, nil
 }
 
-// DecodeCandleWrite deserializes a CandleWriteMsg from JSON bytes
+// DecodeCandleWrite deserializes a CandleWriteMsg from JSON or codec-encoded bytes
 func DecodeCandleWrite(data []byte) (*CandleWriteMsg, error) {
 	var msg CandleWriteMsg
-	if err := json.Unmarshal(data, &msg); err != nil {
+	if err := decode(data, &msg); err != nil {
 		return nil, err
 	}
 	return &msg, nil
 }
 
-// DecodeWindowWrite deserializes a WindowWriteMsg from JSON bytes
+// DecodeWindowWrite deserializes a WindowWriteMsg from JSON or codec-encoded bytes
 func DecodeWindowWrite(data []byte) (*WindowWriteMsg, error) {
 	var msg WindowWriteMsg
-	if err := json.Unmarshal(data, &msg); err != nil {
+	if err := decode(data, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// DecodeMilvusWrite deserializes a MilvusWriteMsg from JSON or codec-encoded bytes
+func DecodeMilvusWrite(data []byte) (*MilvusWriteMsg, error) {
+	var msg MilvusWriteMsg
+	if err := decode(data, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// DecodeMilvusBatch deserializes a MilvusBatchMsg from JSON or codec-encoded bytes
+func DecodeMilvusBatch(data []byte) (*MilvusBatchMsg, error) {
+	var msg MilvusBatchMsg
+	if err := decode(data, &msg); err != nil {
 		return nil, err
 	}
 	return &msg, nil
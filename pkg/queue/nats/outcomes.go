@@ -0,0 +1,46 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// SubjectOutcomeCompute is the subject base of outcome computation jobs, routed by symbol and timeframe
+const SubjectOutcomeCompute = "etna.outcomes.compute"
+
+// OutcomeJobMsg asks a worker to compute and store the outcomes of one window
+type OutcomeJobMsg struct {
+	WindowID  string `json:"window_id"`
+	Symbol    string `json:"symbol"`
+	Timeframe string `json:"timeframe"`
+	Horizons  []int  `json:"horizons"`
+}
+
+// DecodeOutcomeJob deserializes an OutcomeJobMsg from JSON bytes
+func DecodeOutcomeJob(data []byte) (*OutcomeJobMsg, error) {
+	var msg OutcomeJobMsg
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+	if msg.WindowID == "" || len(msg.Horizons) == 0 {
+		return nil, fmt.Errorf("outcome job needs a window id and horizons")
+	}
+	return &msg, nil
+}
+
+// PublishOutcomeJobs publishes one message per job to its symbol-routed SubjectOutcomeCompute subject
+// Each job is acked, retried, and dead-lettered on its own; it returns how many jobs were published
+func (c *Client) PublishOutcomeJobs(ctx context.Context, jobs []OutcomeJobMsg) (int, error) {
+	for i, job := range jobs {
+		data, err := Encode(job)
+		if err != nil {
+			return i, fmt.Errorf("failed to encode outcome job: %w", err)
+		}
+		subject := SubjectFor(SubjectOutcomeCompute, job.Symbol, job.Timeframe)
+		if err := c.publishMsgWithConfirm(ctx, newMsg(subject, data), c.config.RetryAttempts, c.config.RetryDelay); err != nil {
+			return i, err
+		}
+	}
+	return len(jobs), nil
+}
@@ -0,0 +1,432 @@
+package nats
+
+import (
+	"math"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Protobuf wire encoding of queue messages; field numbers are documented in messages.proto
+// Zero-valued fields are omitted, as in proto3
+
+// protoField is one decoded field of a protobuf message
+type protoField struct {
+	num     protowire.Number
+	typ     protowire.Type
+	varint  uint64
+	fixed64 uint64
+	bytes   []byte
+}
+
+func (f protoField) float64() float64 { return math.Float64frombits(f.fixed64) }
+func (f protoField) sint64() int64    { return protowire.DecodeZigZag(f.varint) }
+func (f protoField) string() string   { return string(f.bytes) }
+
+// time decodes a Unix-nanosecond timestamp
+func (f protoField) time() time.Time { return time.Unix(0, f.sint64()).UTC() }
+
+// parseFields calls fn for every field of an encoded message, in wire order
+func parseFields(b []byte, fn func(f protoField) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		f := protoField{num: num, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.varint, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			f.fixed64, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendDouble(b []byte, num protowire.Number, v float64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(v))
+}
+
+func appendSint(b []byte, num protowire.Number, v int64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, protowire.EncodeZigZag(v))
+}
+
+// appendTime encodes t as Unix nanoseconds; the zero time is omitted
+func appendTime(b []byte, num protowire.Number, t time.Time) []byte {
+	if t.IsZero() {
+		return b
+	}
+	return appendSint(b, num, t.UnixNano())
+}
+
+// appendMessage encodes an embedded message produced by appendFn
+func appendMessage(b []byte, num protowire.Number, appendFn func([]byte) []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, appendFn(nil))
+}
+
+// appendPackedFloats encodes a packed repeated float field
+func appendPackedFloats(b []byte, num protowire.Number, v []float32) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	packed := make([]byte, 0, 4*len(v))
+	for _, x := range v {
+		packed = protowire.AppendFixed32(packed, math.Float32bits(x))
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, packed)
+}
+
+// parsePackedFloats decodes a packed repeated float field
+func parsePackedFloats(b []byte) ([]float32, error) {
+	v := make([]float32, 0, len(b)/4)
+	for len(b) > 0 {
+		x, n := protowire.ConsumeFixed32(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		v = append(v, math.Float32frombits(x))
+		b = b[n:]
+	}
+	return v, nil
+}
+
+// candleDoubles lists Candle's double fields with their field numbers
+func candleDoubles(c *model.Candle) []struct {
+	num protowire.Number
+	v   *float64
+} {
+	return []struct {
+		num protowire.Number
+		v   *float64
+	}{
+		{5, &c.Open}, {6, &c.High}, {7, &c.Low}, {8, &c.Close}, {9, &c.Volume}, {11, &c.VWAP},
+	}
+}
+
+func appendCandle(b []byte, c *model.Candle) []byte {
+	b = appendString(b, 1, c.Symbol)
+	b = appendString(b, 2, c.Timeframe)
+	b = appendTime(b, 3, c.OpenTime)
+	b = appendTime(b, 4, c.CloseTime)
+	for _, d := range candleDoubles(c) {
+		b = appendDouble(b, d.num, *d.v)
+	}
+	return appendSint(b, 10, c.Trades)
+}
+
+func parseCandle(b []byte) (model.Candle, error) {
+	var c model.Candle
+	doubles := candleDoubles(&c)
+	err := parseFields(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			c.Symbol = f.string()
+		case 2:
+			c.Timeframe = f.string()
+		case 3:
+			c.OpenTime = f.time()
+		case 4:
+			c.CloseTime = f.time()
+		case 10:
+			c.Trades = f.sint64()
+		default:
+			for _, d := range doubles {
+				if d.num == f.num {
+					*d.v = f.float64()
+				}
+			}
+		}
+		return nil
+	})
+	return c, err
+}
+
+// featureDoubles lists FeatureRow's double fields with their field numbers
+// New features take the next unused field number
+func featureDoubles(r *model.FeatureRow) []struct {
+	num protowire.Number
+	v   *float64
+} {
+	return []struct {
+		num protowire.Number
+		v   *float64
+	}{
+		{2, &r.TrendSlope}, {3, &r.RealizedVolatility}, {4, &r.MaxDrawdown}, {5, &r.ATR}, {6, &r.VolZScore},
+		{10, &r.KeltnerPosition}, {11, &r.WilliamsR14}, {12, &r.AroonUp}, {13, &r.AroonDown},
+		{14, &r.AroonOscillator}, {15, &r.ReturnEntropy}, {16, &r.ROC10}, {17, &r.ROC20},
+		{18, &r.BullPower}, {19, &r.BearPower}, {20, &r.FractalDim},
+		{21, &r.PivotPosition}, {22, &r.BidAskSpread},
+	}
+}
+
+func appendFeature(b []byte, r *model.FeatureRow) []byte {
+	b = appendString(b, 1, r.WindowID)
+	b = appendSint(b, 7, int64(r.VolBucket))
+	b = appendSint(b, 8, int64(r.TrendBucket))
+	b = appendSint(b, 9, int64(r.DataVersion))
+	for _, d := range featureDoubles(r) {
+		b = appendDouble(b, d.num, *d.v)
+	}
+	return b
+}
+
+func parseFeature(b []byte) (*model.FeatureRow, error) {
+	r := &model.FeatureRow{}
+	doubles := featureDoubles(r)
+	err := parseFields(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			r.WindowID = f.string()
+		case 7:
+			r.VolBucket = int(f.sint64())
+		case 8:
+			r.TrendBucket = int(f.sint64())
+		case 9:
+			r.DataVersion = int(f.sint64())
+		default:
+			for _, d := range doubles {
+				if d.num == f.num {
+					*d.v = f.float64()
+				}
+			}
+		}
+		return nil
+	})
+	return r, err
+}
+
+func appendWindow(b []byte, w *model.Window) []byte {
+	b = appendString(b, 1, w.WindowID)
+	b = appendString(b, 2, w.Symbol)
+	b = appendString(b, 3, w.Timeframe)
+	b = appendTime(b, 4, w.TEnd)
+	b = appendSint(b, 5, int64(w.W))
+	b = appendSint(b, 6, int64(w.FeatureVersion))
+	for i := range w.Candles {
+		c := &w.Candles[i]
+		b = appendMessage(b, 7, func(b []byte) []byte { return appendCandle(b, c) })
+	}
+	return appendTime(b, 8, w.CreatedAt)
+}
+
+func parseWindow(b []byte) (*model.Window, error) {
+	w := &model.Window{}
+	err := parseFields(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			w.WindowID = f.string()
+		case 2:
+			w.Symbol = f.string()
+		case 3:
+			w.Timeframe = f.string()
+		case 4:
+			w.TEnd = f.time()
+		case 5:
+			w.W = int(f.sint64())
+		case 6:
+			w.FeatureVersion = int(f.sint64())
+		case 7:
+			c, err := parseCandle(f.bytes)
+			if err != nil {
+				return err
+			}
+			w.Candles = append(w.Candles, c)
+		case 8:
+			w.CreatedAt = f.time()
+		}
+		return nil
+	})
+	return w, err
+}
+
+func (m *CandleWriteMsg) appendProto(b []byte) []byte {
+	if m.Candle == nil {
+		return b
+	}
+	return appendMessage(b, 1, func(b []byte) []byte { return appendCandle(b, m.Candle) })
+}
+
+func (m *CandleWriteMsg) unmarshalProto(b []byte) error {
+	return parseFields(b, func(f protoField) error {
+		if f.num != 1 {
+			return nil
+		}
+		c, err := parseCandle(f.bytes)
+		if err != nil {
+			return err
+		}
+		m.Candle = &c
+		return nil
+	})
+}
+
+func (m *CandleBatchMsg) appendProto(b []byte) []byte {
+	for i := range m.Candles {
+		c := &m.Candles[i]
+		b = appendMessage(b, 1, func(b []byte) []byte { return appendCandle(b, c) })
+	}
+	return b
+}
+
+func (m *CandleBatchMsg) unmarshalProto(b []byte) error {
+	return parseFields(b, func(f protoField) error {
+		if f.num != 1 {
+			return nil
+		}
+		c, err := parseCandle(f.bytes)
+		if err != nil {
+			return err
+		}
+		m.Candles = append(m.Candles, c)
+		return nil
+	})
+}
+
+func (m *WindowWriteMsg) appendProto(b []byte) []byte {
+	if m.Window != nil {
+		b = appendMessage(b, 1, func(b []byte) []byte { return appendWindow(b, m.Window) })
+	}
+	if m.Feature != nil {
+		b = appendMessage(b, 2, func(b []byte) []byte { return appendFeature(b, m.Feature) })
+	}
+	return b
+}
+
+func (m *WindowWriteMsg) unmarshalProto(b []byte) error {
+	return parseFields(b, func(f protoField) error {
+		var err error
+		switch f.num {
+		case 1:
+			m.Window, err = parseWindow(f.bytes)
+		case 2:
+			m.Feature, err = parseFeature(f.bytes)
+		}
+		return err
+	})
+}
+
+func (m *WindowBatchMsg) appendProto(b []byte) []byte {
+	for _, w := range m.Windows {
+		if w != nil {
+			b = appendMessage(b, 1, func(b []byte) []byte { return appendWindow(b, w) })
+		}
+	}
+	for _, r := range m.Features {
+		if r != nil {
+			b = appendMessage(b, 2, func(b []byte) []byte { return appendFeature(b, r) })
+		}
+	}
+	return b
+}
+
+func (m *WindowBatchMsg) unmarshalProto(b []byte) error {
+	return parseFields(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			w, err := parseWindow(f.bytes)
+			if err != nil {
+				return err
+			}
+			m.Windows = append(m.Windows, w)
+		case 2:
+			r, err := parseFeature(f.bytes)
+			if err != nil {
+				return err
+			}
+			m.Features = append(m.Features, r)
+		}
+		return nil
+	})
+}
+
+func (m *MilvusWriteMsg) appendProto(b []byte) []byte {
+	b = appendString(b, 1, m.WindowID)
+	b = appendPackedFloats(b, 2, m.Embedding)
+	b = appendString(b, 3, m.Symbol)
+	b = appendString(b, 4, m.Timeframe)
+	b = appendTime(b, 5, m.TEnd)
+	b = appendSint(b, 6, int64(m.VolBucket))
+	b = appendSint(b, 7, int64(m.TrendBucket))
+	return appendSint(b, 8, int64(m.DataVersion))
+}
+
+func (m *MilvusWriteMsg) unmarshalProto(b []byte) error {
+	return parseFields(b, func(f protoField) error {
+		var err error
+		switch f.num {
+		case 1:
+			m.WindowID = f.string()
+		case 2:
+			m.Embedding, err = parsePackedFloats(f.bytes)
+		case 3:
+			m.Symbol = f.string()
+		case 4:
+			m.Timeframe = f.string()
+		case 5:
+			m.TEnd = f.time()
+		case 6:
+			m.VolBucket = int32(f.sint64())
+		case 7:
+			m.TrendBucket = int32(f.sint64())
+		case 8:
+			m.DataVersion = int32(f.sint64())
+		}
+		return err
+	})
+}
+
+func (m *MilvusBatchMsg) appendProto(b []byte) []byte {
+	for i := range m.Vectors {
+		v := &m.Vectors[i]
+		b = appendMessage(b, 1, v.appendProto)
+	}
+	return b
+}
+
+func (m *MilvusBatchMsg) unmarshalProto(b []byte) error {
+	return parseFields(b, func(f protoField) error {
+		if f.num != 1 {
+			return nil
+		}
+		var v MilvusWriteMsg
+		if err := v.unmarshalProto(f.bytes); err != nil {
+			return err
+		}
+		m.Vectors = append(m.Vectors, v)
+		return nil
+	})
+}
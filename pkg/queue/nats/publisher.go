@@ -0,0 +1,116 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+)
+
+// AsyncPublisher publishes write batches concurrently, keeping a bounded number of messages awaiting their ack
+// Every message carries a Nats-Msg-Id derived from the caller's batch ID, so a batch republished within the
+// stream's duplicate window (two minutes by default) is dropped by JetStream rather than written twice
+type AsyncPublisher struct {
+	client *Client
+	slots  chan struct{} // One token per unacknowledged message
+
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+	err       error // First failed publish
+	published int
+}
+
+// NewAsyncPublisher creates a publisher allowing at most maxInFlight unacknowledged messages (at least 1)
+func (c *Client) NewAsyncPublisher(maxInFlight int) *AsyncPublisher {
+	if maxInFlight < 1 {
+		maxInFlight = 1
+	}
+	return &AsyncPublisher{client: c, slots: make(chan struct{}, maxInFlight)}
+}
+
+// PublishCandleBatch queues a candle batch on its symbol-routed SubjectCandleWrite subjects
+// id must identify the batch's content: republishing the same candles must reuse it
+func (p *AsyncPublisher) PublishCandleBatch(ctx context.Context, id string, batch *CandleBatchMsg) error {
+	subjects, groups := candleGroups(batch)
+	return p.publishRouted(ctx, id, subjects, groups)
+}
+
+// PublishWindowBatch queues a window batch on its symbol-routed SubjectWindowWrite subjects
+func (p *AsyncPublisher) PublishWindowBatch(ctx context.Context, id string, batch *WindowBatchMsg) error {
+	subjects, groups, err := windowGroups(batch)
+	if err != nil {
+		return err
+	}
+	return p.publishRouted(ctx, id, subjects, groups)
+}
+
+// PublishMilvusBatch queues a vector batch on its symbol-routed SubjectMilvusWrite subjects
+func (p *AsyncPublisher) PublishMilvusBatch(ctx context.Context, id string, batch *MilvusBatchMsg) error {
+	subjects, groups := milvusGroups(batch)
+	return p.publishRouted(ctx, id, subjects, groups)
+}
+
+// publishRouted queues each subject's batch, split to the payload limit, with message IDs id.subject.part
+func (p *AsyncPublisher) publishRouted(ctx context.Context, id string, subjects []string, groups map[string]BatchMessage) error {
+	for _, subject := range subjects {
+		msgs, err := p.client.batchMsgs(subject, groups[subject], p.client.payloadLimit())
+		if err != nil {
+			return err
+		}
+		for i, msg := range msgs {
+			msg.Header.Set(nats.MsgIdHdr, fmt.Sprintf("%s.%s.%d", id, subject, i))
+			if err := p.publish(ctx, msg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// publish waits for a free slot, then publishes msg with the client's retries in the background
+// It returns the error of an earlier failed publish, so callers stop producing once one has failed
+func (p *AsyncPublisher) publish(ctx context.Context, msg *nats.Msg) error {
+	if err := p.firstErr(); err != nil {
+		return err
+	}
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return fmt.Errorf("failed to publish message: %w", ctx.Err())
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer func() { <-p.slots }()
+
+		err := p.client.publishMsgWithConfirm(ctx, msg, p.client.config.RetryAttempts, p.client.config.RetryDelay)
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if err != nil {
+			if p.err == nil {
+				p.err = err
+			}
+			return
+		}
+		p.published++
+	}()
+	return nil
+}
+
+// firstErr returns the first failed publish, if any
+func (p *AsyncPublisher) firstErr() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Wait blocks until every queued message is acknowledged or has failed
+// It returns the number of messages published and the first publish error
+func (p *AsyncPublisher) Wait() (int, error) {
+	p.wg.Wait()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.published, p.err
+}
@@ -0,0 +1,112 @@
+package nats
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// HeaderSchemaVersion carries the payload schema version of published queue messages
+const HeaderSchemaVersion = "Etna-Schema-Version"
+
+// SchemaVersion is the payload schema version written by this build's publishers
+// Bump it on incompatible changes to queue message fields and register a decoder for it in schemaDecoders
+const SchemaVersion = 1
+
+// legacySchemaVersion is assumed for messages published before the header existed
+const legacySchemaVersion = 1
+
+// schemaDecoders decodes a payload of each supported schema version into msg
+var schemaDecoders = map[int]func(data []byte, msg protoMessage) error{
+	1: decode,
+}
+
+// ErrUnsupportedSchema matches, via errors.Is, messages whose schema version this build cannot decode
+var ErrUnsupportedSchema = errors.New("unsupported schema version")
+
+// UnsupportedSchemaError reports a message whose schema version has no decoder
+type UnsupportedSchemaError struct {
+	Version string // Header value as received
+}
+
+func (e *UnsupportedSchemaError) Error() string {
+	return fmt.Sprintf("unsupported schema version %q (this build decodes up to %d)", e.Version, SchemaVersion)
+}
+
+// Is reports whether target is ErrUnsupportedSchema
+func (e *UnsupportedSchemaError) Is(target error) bool {
+	return target == ErrUnsupportedSchema
+}
+
+// newMsg returns a message for subject stamped with this build's schema version
+func newMsg(subject string, data []byte) *nats.Msg {
+	msg := &nats.Msg{Subject: subject, Data: data, Header: nats.Header{}}
+	msg.Header.Set(HeaderSchemaVersion, strconv.Itoa(SchemaVersion))
+	return msg
+}
+
+// MsgSchemaVersion returns the schema version of msg, or the legacy version if it has no header
+// The error is an UnsupportedSchemaError if the version has no decoder
+func MsgSchemaVersion(msg jetstream.Msg) (int, error) {
+	header := msg.Headers().Get(HeaderSchemaVersion)
+	if header == "" {
+		return legacySchemaVersion, nil
+	}
+
+	version, err := strconv.Atoi(header)
+	if err != nil {
+		return 0, &UnsupportedSchemaError{Version: header}
+	}
+	if _, ok := schemaDecoders[version]; !ok {
+		return version, &UnsupportedSchemaError{Version: header}
+	}
+	return version, nil
+}
+
+// decodeMsg decodes msg's payload into v with the decoder of its schema version
+func decodeMsg(msg jetstream.Msg, v protoMessage) error {
+	version, err := MsgSchemaVersion(msg)
+	if err != nil {
+		return err
+	}
+	return schemaDecoders[version](msg.Data(), v)
+}
+
+// DecodeCandleBatchMsg decodes a CandleBatchMsg according to the message's schema version
+func DecodeCandleBatchMsg(msg jetstream.Msg) (*CandleBatchMsg, error) {
+	var batch CandleBatchMsg
+	if err := decodeMsg(msg, &batch); err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// DecodeWindowBatchMsg decodes a WindowBatchMsg according to the message's schema version
+func DecodeWindowBatchMsg(msg jetstream.Msg) (*WindowBatchMsg, error) {
+	var batch WindowBatchMsg
+	if err := decodeMsg(msg, &batch); err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// DecodeMilvusBatchMsg decodes a MilvusBatchMsg according to the message's schema version
+func DecodeMilvusBatchMsg(msg jetstream.Msg) (*MilvusBatchMsg, error) {
+	var batch MilvusBatchMsg
+	if err := decodeMsg(msg, &batch); err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// prepareMsg checks msg's schema version and decompresses its payload before it reaches a handler
+// Unsupported versions and corrupt payloads are permanent errors, so they are dead-lettered rather than redelivered
+func prepareMsg(raw jetstream.Msg) (jetstream.Msg, error) {
+	if _, err := MsgSchemaVersion(raw); err != nil {
+		return raw, Permanent(err)
+	}
+	return decompressMsg(raw)
+}
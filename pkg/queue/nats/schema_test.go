@@ -0,0 +1,142 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// headerMsg is a delivered message carrying only a payload and headers
+type headerMsg struct {
+	jetstream.Msg
+	data   []byte
+	header nats.Header
+}
+
+func (m *headerMsg) Data() []byte         { return m.data }
+func (m *headerMsg) Headers() nats.Header { return m.header }
+
+func TestMsgSchemaVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		want        int
+		unsupported bool
+	}{
+		{"legacy message without header", "", legacySchemaVersion, false},
+		{"current version", strconv.Itoa(SchemaVersion), SchemaVersion, false},
+		{"newer producer", strconv.Itoa(SchemaVersion + 1), SchemaVersion + 1, true},
+		{"garbled header", "v2", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &headerMsg{header: nats.Header{}}
+			if tt.header != "" {
+				msg.header.Set(HeaderSchemaVersion, tt.header)
+			}
+
+			got, err := MsgSchemaVersion(msg)
+			if got != tt.want {
+				t.Errorf("version = %d, want %d", got, tt.want)
+			}
+			if unsupported := errors.Is(err, ErrUnsupportedSchema); unsupported != tt.unsupported {
+				t.Errorf("err = %v, want unsupported %v", err, tt.unsupported)
+			}
+		})
+	}
+}
+
+// schemaTestBatch returns a one-candle batch
+func schemaTestBatch() *CandleBatchMsg {
+	open := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	return &CandleBatchMsg{Candles: []model.Candle{{
+		Symbol: "BTCUSDT", Timeframe: "1m", OpenTime: open, CloseTime: open.Add(time.Minute - time.Millisecond), Close: 100,
+	}}}
+}
+
+func TestOldProducerNewConsumer(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	subject := SubjectFor(SubjectCandleWrite, "BTCUSDT", "1m")
+
+	// Producers predating the header published bare JSON
+	data, err := json.Marshal(schemaTestBatch())
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if err := client.Publish(ctx, subject, data); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	decoded := make(chan *CandleBatchMsg, 1)
+	if _, err := client.Subscribe(ctx, []string{subject}, "new-consumer", func(msg jetstream.Msg) error {
+		batch, err := DecodeCandleBatchMsg(msg)
+		if err != nil {
+			return err
+		}
+		decoded <- batch
+		return nil
+	}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	select {
+	case batch := <-decoded:
+		if len(batch.Candles) != 1 || batch.Candles[0].Close != 100 {
+			t.Errorf("decoded %+v, want the published candle", batch)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("legacy message was not decoded")
+	}
+}
+
+func TestNewProducerOldConsumer(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	if err := client.CreateDLQStream(ctx); err != nil {
+		t.Fatalf("CreateDLQStream: %v", err)
+	}
+	subject := SubjectFor(SubjectCandleWrite, "BTCUSDT", "1m")
+
+	// A producer one schema version ahead of this build
+	data, err := json.Marshal(schemaTestBatch())
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	msg := newMsg(subject, data)
+	newer := strconv.Itoa(SchemaVersion + 1)
+	msg.Header.Set(HeaderSchemaVersion, newer)
+	if err := client.publishMsgWithConfirm(ctx, msg, 0, time.Millisecond); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	var handled atomic.Int64
+	if _, err := client.Subscribe(ctx, []string{subject}, "old-consumer", func(msg jetstream.Msg) error {
+		handled.Add(1)
+		return nil
+	}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	var letters []StoredDeadLetter
+	waitFor(t, 5*time.Second, func() bool {
+		letters, err = client.ListDeadLetters(ctx, "", 0)
+		return err == nil && len(letters) > 0
+	})
+	letter := letters[0]
+	if !letter.Permanent || letter.Deliveries != 1 || letter.Schema != newer || letter.Subject != subject {
+		t.Errorf("dead letter = %+v, want a permanent first-delivery letter of %s with schema %s", letter.DeadLetter, subject, newer)
+	}
+	if handled.Load() != 0 {
+		t.Errorf("handler saw %d messages of an unsupported schema", handled.Load())
+	}
+	waitFor(t, 5*time.Second, func() bool { return streamMsgs(t, client) == 0 })
+}
@@ -0,0 +1,128 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// SubjectSearchRequest is the request-reply subject served by the searcher
+const SubjectSearchRequest = "etna.search.request"
+
+// DefaultSearchTimeout bounds SearchRequest when ctx has no deadline
+const DefaultSearchTimeout = 10 * time.Second
+
+// SearchRequestMsg asks for the nearest historical windows of a query window
+// The query is built from Candles if set, else from the latest stored candles of Symbol/Timeframe
+type SearchRequestMsg struct {
+	Symbol    string         `json:"symbol"`
+	Timeframe string         `json:"timeframe"`
+	Candles   []model.Candle `json:"candles,omitempty"` // Query candles, oldest first; the last window length of them is used
+	TopK      int            `json:"top_k,omitempty"`   // Neighbors to return; the searcher's default if 0
+}
+
+// SearchNeighbor is a ranked neighbor in a search response
+type SearchNeighbor struct {
+	WindowID   string    `json:"window_id"`
+	Symbol     string    `json:"symbol"`
+	TEnd       time.Time `json:"t_end"`
+	Similarity float64   `json:"similarity"`  // Raw vector similarity
+	FinalScore float64   `json:"final_score"` // Score after reranking
+
+	// MatchStrength is the 0-100 calibrated percentile of Similarity, omitted when the corpus has no stored calibration
+	MatchStrength float64 `json:"match_strength,omitempty"`
+}
+
+// SearchOutcome summarizes neighbor forward returns at one horizon
+type SearchOutcome struct {
+	Horizon     int     `json:"horizon"`
+	SampleCount int     `json:"sample_count"`
+	MeanReturn  float64 `json:"mean_return"`
+	P10         float64 `json:"p10"`
+	P50         float64 `json:"p50"`
+	P90         float64 `json:"p90"`
+	MDDP95      float64 `json:"mdd_p95"`
+	HitRate     float64 `json:"hit_rate"`
+}
+
+// SearchResponseMsg is the reply to a SearchRequestMsg; Error is set if the search failed
+type SearchResponseMsg struct {
+	QueryWindowID string           `json:"query_window_id,omitempty"`
+	QueryTEnd     time.Time        `json:"query_t_end,omitempty"`
+	Neighbors     []SearchNeighbor `json:"neighbors,omitempty"`
+	Outcomes      []SearchOutcome  `json:"outcomes,omitempty"`
+	Error         string           `json:"error,omitempty"`
+}
+
+// SearchHandler answers a search request
+type SearchHandler func(ctx context.Context, req *SearchRequestMsg) (*SearchResponseMsg, error)
+
+// ServeSearch answers requests on SubjectSearchRequest with handler
+// Responders sharing queue split the requests between them; handler errors are replied in SearchResponseMsg.Error
+func (c *Client) ServeSearch(ctx context.Context, queue string, handler SearchHandler) (*nats.Subscription, error) {
+	sub, err := c.nc.QueueSubscribe(SubjectSearchRequest, queue, func(msg *nats.Msg) {
+		resp, err := serveSearch(ctx, msg.Data, handler)
+		if err != nil {
+			resp = &SearchResponseMsg{Error: err.Error()}
+		}
+
+		data, err := json.Marshal(resp)
+		if err != nil {
+			data, _ = json.Marshal(SearchResponseMsg{Error: fmt.Sprintf("failed to encode response: %v", err)})
+		}
+		if err := msg.Respond(data); err != nil {
+			log.Printf("Failed to reply to search request: %v", err)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to search requests: %w", err)
+	}
+	return sub, nil
+}
+
+// serveSearch decodes a search request and runs handler on it
+func serveSearch(ctx context.Context, data []byte, handler SearchHandler) (*SearchResponseMsg, error) {
+	var req SearchRequestMsg
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("failed to decode search request: %w", err)
+	}
+	if req.Timeframe == "" || (req.Symbol == "" && len(req.Candles) == 0) {
+		return nil, errors.New("search request needs a timeframe and a symbol or candles")
+	}
+	return handler(ctx, &req)
+}
+
+// SearchRequest sends a search request and waits for the reply
+// The wait is bounded by ctx, or DefaultSearchTimeout if ctx has no deadline
+func (c *Client) SearchRequest(ctx context.Context, req *SearchRequestMsg) (*SearchResponseMsg, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultSearchTimeout)
+		defer cancel()
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode search request: %w", err)
+	}
+
+	msg, err := c.nc.RequestWithContext(ctx, SubjectSearchRequest, data)
+	if err != nil {
+		return nil, fmt.Errorf("search request failed: %w", err)
+	}
+
+	var resp SearchResponseMsg
+	if err := json.Unmarshal(msg.Data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode search response: %w", err)
+	}
+	if resp.Error != "" {
+		return &resp, fmt.Errorf("search failed: %s", resp.Error)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,55 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSearchRequestRoundTrip(t *testing.T) {
+	client := newTestClient(t)
+	sub, err := client.ServeSearch(context.Background(), "searchers", func(ctx context.Context, req *SearchRequestMsg) (*SearchResponseMsg, error) {
+		if req.TopK == 0 {
+			return nil, errors.New("top_k required")
+		}
+		return &SearchResponseMsg{QueryWindowID: req.Symbol + "/" + req.Timeframe, Neighbors: make([]SearchNeighbor, req.TopK)}, nil
+	})
+	if err != nil {
+		t.Fatalf("ServeSearch: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	resp, err := client.SearchRequest(context.Background(), &SearchRequestMsg{Symbol: "BTCUSDT", Timeframe: "1h", TopK: 3})
+	if err != nil {
+		t.Fatalf("SearchRequest: %v", err)
+	}
+	if resp.QueryWindowID != "BTCUSDT/1h" || len(resp.Neighbors) != 3 {
+		t.Errorf("response = %+v, want BTCUSDT/1h with 3 neighbors", resp)
+	}
+
+	resp, err = client.SearchRequest(context.Background(), &SearchRequestMsg{Symbol: "BTCUSDT", Timeframe: "1h"})
+	if err == nil || resp == nil || resp.Error != "top_k required" {
+		t.Errorf("handler error = %v with response %+v, want the replied error", err, resp)
+	}
+}
+
+func TestSearchRequestTimesOut(t *testing.T) {
+	client := newTestClient(t)
+	release := make(chan struct{})
+	defer close(release)
+	sub, err := client.ServeSearch(context.Background(), "searchers", func(ctx context.Context, req *SearchRequestMsg) (*SearchResponseMsg, error) {
+		<-release
+		return &SearchResponseMsg{}, nil
+	})
+	if err != nil {
+		t.Fatalf("ServeSearch: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.SearchRequest(ctx, &SearchRequestMsg{Symbol: "BTCUSDT", Timeframe: "1h"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SearchRequest to a stuck responder = %v, want a deadline error", err)
+	}
+}
@@ -0,0 +1,74 @@
+package rerank
+
+import (
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// BucketConfig holds per-bucket-distance score multipliers
+// Index i is the multiplier for an absolute bucket distance of i; larger distances use the last entry
+type BucketConfig struct {
+	VolWeights   []float64
+	TrendWeights []float64
+}
+
+// DefaultBucketConfig returns multipliers that mildly penalize regime mismatch
+func DefaultBucketConfig() BucketConfig {
+	return BucketConfig{
+		VolWeights:   []float64{1.0, 0.9, 0.75, 0.6},
+		TrendWeights: []float64{1.0, 0.85, 0.7, 0.5, 0.4},
+	}
+}
+
+// BucketReranker adjusts ranked results by how closely their vol/trend buckets match the query window
+// It composes with time-decay reranking by scaling FinalScore of already-ranked results
+type BucketReranker struct {
+	config BucketConfig
+	query  *model.FeatureRow
+}
+
+// NewBucketReranker creates a bucket reranker for the query window's features
+func NewBucketReranker(query *model.FeatureRow, config BucketConfig) *BucketReranker {
+	return &BucketReranker{config: config, query: query}
+}
+
+// Name returns the stage name
+func (b *BucketReranker) Name() string {
+	return "bucket_match"
+}
+
+// Apply scales each result's FinalScore by its bucket weight and re-sorts
+func (b *BucketReranker) Apply(ranked []RankedResult) []RankedResult {
+	out := make([]RankedResult, len(ranked))
+	copy(out, ranked)
+
+	for i := range out {
+		out[i].BucketWeight = b.weight(int(out[i].VolBucket), int(out[i].TrendBucket))
+		out[i].FinalScore *= out[i].BucketWeight
+	}
+
+	sortByFinalScore(out)
+	return out
+}
+
+// weight returns the combined multiplier for a neighbor's buckets
+func (b *BucketReranker) weight(volBucket, trendBucket int) float64 {
+	if b.query == nil {
+		return 1
+	}
+	return distanceWeight(b.config.VolWeights, volBucket-b.query.VolBucket) *
+		distanceWeight(b.config.TrendWeights, trendBucket-b.query.TrendBucket)
+}
+
+// distanceWeight looks up the multiplier for a bucket distance, clamping to the last entry
+func distanceWeight(weights []float64, distance int) float64 {
+	if len(weights) == 0 {
+		return 1
+	}
+	if distance < 0 {
+		distance = -distance
+	}
+	if distance >= len(weights) {
+		distance = len(weights) - 1
+	}
+	return weights[distance]
+}
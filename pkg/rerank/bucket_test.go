@@ -0,0 +1,59 @@
+package rerank
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tunogya/etna/pkg/model"
+	"github.com/tunogya/etna/pkg/store/milvus"
+)
+
+func TestBucketRerankerMismatchFlipsOrder(t *testing.T) {
+	query := &model.FeatureRow{VolBucket: 5, TrendBucket: 1}
+	results := []milvus.SearchResult{
+		{WindowID: "mismatched", Score: 0.9, VolBucket: 8, TrendBucket: -1},
+		{WindowID: "matched", Score: 0.8, VolBucket: 5, TrendBucket: 1},
+	}
+
+	out := NewPipeline(NewBucketReranker(query, DefaultBucketConfig())).Run(results)
+	if out[0].WindowID != "matched" || out[1].WindowID != "mismatched" {
+		t.Fatalf("order = %s, %s; want matched first", out[0].WindowID, out[1].WindowID)
+	}
+
+	// Vol distance 3 and trend distance 2 weigh 0.6 × 0.7
+	if w := out[1].BucketWeight; math.Abs(w-0.42) > 1e-9 {
+		t.Errorf("mismatched BucketWeight = %v, want 0.42", w)
+	}
+	if out[0].BucketWeight != 1 {
+		t.Errorf("matched BucketWeight = %v, want 1", out[0].BucketWeight)
+	}
+}
+
+func TestBucketRerankerWithoutQueryKeepsOrder(t *testing.T) {
+	results := []milvus.SearchResult{
+		{WindowID: "a", Score: 0.9, VolBucket: 9, TrendBucket: -2},
+		{WindowID: "b", Score: 0.8},
+	}
+
+	out := NewPipeline(NewBucketReranker(nil, DefaultBucketConfig())).Run(results)
+	if out[0].WindowID != "a" || out[0].FinalScore != float64(float32(0.9)) {
+		t.Errorf("first = %s scoring %v, want a unchanged", out[0].WindowID, out[0].FinalScore)
+	}
+}
+
+func TestDistanceWeightClampsToLastEntry(t *testing.T) {
+	weights := []float64{1, 0.5, 0.25}
+	for _, tt := range []struct {
+		distance int
+		want     float64
+	}{
+		{0, 1}, {-1, 0.5}, {2, 0.25}, {7, 0.25}, {-7, 0.25},
+	} {
+		if got := distanceWeight(weights, tt.distance); got != tt.want {
+			t.Errorf("distanceWeight(%d) = %v, want %v", tt.distance, got, tt.want)
+		}
+	}
+	if got := distanceWeight(nil, 3); got != 1 {
+		t.Errorf("distanceWeight without weights = %v, want 1", got)
+	}
+}
@@ -0,0 +1,98 @@
+package rerank
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// calibrationPoints is the number of quantiles stored by a Calibrator (every percentile from 0 to 100)
+const calibrationPoints = 101
+
+// Calibrator maps raw similarity scores to a 0-100 match strength by their percentile among corpus pair scores
+// A match strength of 95 means the score beats 95% of scores between random corpus windows
+type Calibrator struct {
+	Quantiles []float64 // Quantiles[p] is the p-th percentile of the sampled pair scores
+}
+
+// NewCalibrator creates a calibrator from previously fitted quantiles
+func NewCalibrator(quantiles []float64) (*Calibrator, error) {
+	if len(quantiles) < 2 {
+		return nil, errors.New("calibrator needs at least 2 quantiles")
+	}
+	return &Calibrator{Quantiles: quantiles}, nil
+}
+
+// FitCalibrator fits a calibrator to a sample of raw pair scores
+func FitCalibrator(scores []float64) (*Calibrator, error) {
+	if len(scores) < 2 {
+		return nil, errors.New("calibrator needs at least 2 sample scores")
+	}
+
+	sorted := make([]float64, len(scores))
+	copy(sorted, scores)
+	sort.Float64s(sorted)
+
+	quantiles := make([]float64, calibrationPoints)
+	for p := range quantiles {
+		rank := float64(p) / float64(calibrationPoints-1) * float64(len(sorted)-1)
+		lower := int(rank)
+		if lower >= len(sorted)-1 {
+			quantiles[p] = sorted[len(sorted)-1]
+			continue
+		}
+		frac := rank - float64(lower)
+		quantiles[p] = sorted[lower] + frac*(sorted[lower+1]-sorted[lower])
+	}
+
+	return &Calibrator{Quantiles: quantiles}, nil
+}
+
+// PairwiseScores returns the cosine similarity of every pair of embeddings
+func PairwiseScores(embeddings [][]float32) []float64 {
+	var scores []float64
+	for i := range embeddings {
+		a := model.ShapeVector(embeddings[i])
+		for j := i + 1; j < len(embeddings); j++ {
+			scores = append(scores, a.CosineSimilarity(embeddings[j]))
+		}
+	}
+	return scores
+}
+
+// MatchStrength maps a raw score to its interpolated percentile (0-100) among the fitted pair scores
+func (c *Calibrator) MatchStrength(score float64) float64 {
+	q := c.Quantiles
+	last := len(q) - 1
+	if score <= q[0] {
+		return 0
+	}
+	if score >= q[last] {
+		return 100
+	}
+
+	// First quantile strictly above score; score lies in [q[i-1], q[i])
+	i := sort.Search(len(q), func(i int) bool { return q[i] > score })
+	lo, hi := q[i-1], q[i]
+	pos := float64(i - 1)
+	if hi > lo {
+		pos += (score - lo) / (hi - lo)
+	}
+	return pos / float64(last) * 100
+}
+
+// Name returns the stage name
+func (c *Calibrator) Name() string {
+	return "calibration"
+}
+
+// Apply sets each result's MatchStrength from its raw similarity score, leaving scores and order unchanged
+func (c *Calibrator) Apply(ranked []RankedResult) []RankedResult {
+	out := make([]RankedResult, len(ranked))
+	copy(out, ranked)
+	for i := range out {
+		out[i].MatchStrength = c.MatchStrength(float64(out[i].OriginalScore))
+	}
+	return out
+}
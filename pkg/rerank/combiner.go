@@ -0,0 +1,119 @@
+package rerank
+
+import "fmt"
+
+// Score combination modes
+const (
+	CombineMultiplicative = "multiplicative" // FinalScore = sim × timeWeight × bucketWeight (default)
+	CombineAdditive       = "additive"       // FinalScore = α·sim + β·timeWeight + γ·bucketWeight, all normalized to [0, 1]
+)
+
+// CombinerConfig holds coefficients for additive score combination
+type CombinerConfig struct {
+	Mode  string
+	Alpha float64 // Weight of normalized similarity
+	Beta  float64 // Weight of normalized time weight
+	Gamma float64 // Weight of normalized bucket weight (ignored when bucket matching is not applied)
+}
+
+// DefaultCombinerConfig returns the multiplicative mode with additive coefficients ready for switching
+func DefaultCombinerConfig() CombinerConfig {
+	return CombinerConfig{
+		Mode:  CombineMultiplicative,
+		Alpha: 0.7,
+		Beta:  0.3,
+		Gamma: 0,
+	}
+}
+
+// ParseCombineMode validates a score combination mode name
+func ParseCombineMode(mode string) (string, error) {
+	switch mode {
+	case CombineMultiplicative, CombineAdditive:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid score mode %q: must be %s or %s", mode, CombineMultiplicative, CombineAdditive)
+	}
+}
+
+// ScoreCombiner is a final pipeline Stage that combines the weights set by earlier stages
+// In multiplicative mode scores are left as computed; in additive mode FinalScore is recomputed
+type ScoreCombiner struct {
+	config CombinerConfig
+}
+
+// NewScoreCombiner creates a score combiner
+func NewScoreCombiner(config CombinerConfig) *ScoreCombiner {
+	return &ScoreCombiner{config: config}
+}
+
+// Name returns the stage name
+func (c *ScoreCombiner) Name() string {
+	return "combine_" + c.config.Mode
+}
+
+// Apply records the combination mode and, in additive mode, recomputes and re-sorts FinalScore
+func (c *ScoreCombiner) Apply(ranked []RankedResult) []RankedResult {
+	out := make([]RankedResult, len(ranked))
+	copy(out, ranked)
+
+	if c.config.Mode != CombineAdditive {
+		for i := range out {
+			out[i].ScoreMode = CombineMultiplicative
+		}
+		return out
+	}
+
+	sims := make([]float64, len(out))
+	times := make([]float64, len(out))
+	buckets := make([]float64, len(out))
+	bucketApplied := false
+	for i, r := range out {
+		sims[i] = float64(r.OriginalScore)
+		times[i] = r.TimeWeight
+		buckets[i] = r.BucketWeight
+		if r.BucketWeight != 0 {
+			bucketApplied = true
+		}
+	}
+	normalize(sims)
+	normalize(times)
+	normalize(buckets)
+
+	for i := range out {
+		score := c.config.Alpha*sims[i] + c.config.Beta*times[i]
+		if bucketApplied {
+			score += c.config.Gamma * buckets[i]
+		}
+		out[i].FinalScore = score
+		out[i].ScoreMode = CombineAdditive
+	}
+
+	sortByFinalScore(out)
+	return out
+}
+
+// normalize min-max scales values into [0, 1] in place; a constant slice maps to 1
+func normalize(values []float64) {
+	if len(values) == 0 {
+		return
+	}
+
+	lo, hi := values[0], values[0]
+	for _, v := range values[1:] {
+		if v < lo {
+			lo = v
+		}
+		if v > hi {
+			hi = v
+		}
+	}
+
+	for i, v := range values {
+		if hi == lo {
+			values[i] = 1
+			continue
+		}
+		values[i] = (v - lo) / (hi - lo)
+	}
+}
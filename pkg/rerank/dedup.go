@@ -0,0 +1,69 @@
+package rerank
+
+import (
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// DedupOverlapping drops results whose time span overlaps an already-kept, higher-scoring result
+// of the same symbol by more than maxOverlapFrac. Spans are [TEnd - windowLen×bar, TEnd], where bar is the
+// result's own timeframe duration, or barDuration when it is unknown. Results are walked in FinalScore order; a maxOverlapFrac >= 1 disables de-duplication.
+func DedupOverlapping(results []RankedResult, windowLen int, barDuration time.Duration, maxOverlapFrac float64) []RankedResult {
+	if maxOverlapFrac >= 1 {
+		return results
+	}
+
+	sorted := make([]RankedResult, len(results))
+	copy(sorted, results)
+	sortByFinalScore(sorted)
+
+	span := func(r RankedResult) time.Duration {
+		return time.Duration(windowLen) * resultBarDuration(r, barDuration)
+	}
+
+	var kept []RankedResult
+	for _, r := range sorted {
+		duplicate := false
+		for _, k := range kept {
+			if k.Symbol != r.Symbol {
+				continue
+			}
+			if model.OverlapFraction(k.TEnd.Add(-span(k)), k.TEnd, r.TEnd.Add(-span(r)), r.TEnd) > maxOverlapFrac {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			kept = append(kept, r)
+		}
+	}
+
+	return kept
+}
+
+// DedupStage applies DedupOverlapping as a pipeline Stage
+type DedupStage struct {
+	windowLen      int
+	barDuration    time.Duration
+	maxOverlapFrac float64
+}
+
+// NewDedupStage creates a temporal de-duplication stage
+func NewDedupStage(windowLen int, barDuration time.Duration, maxOverlapFrac float64) *DedupStage {
+	return &DedupStage{
+		windowLen:      windowLen,
+		barDuration:    barDuration,
+		maxOverlapFrac: maxOverlapFrac,
+	}
+}
+
+// Name returns the stage name
+func (s *DedupStage) Name() string {
+	return "dedup_overlapping"
+}
+
+// Apply drops results overlapping a higher-scoring result
+func (s *DedupStage) Apply(ranked []RankedResult) []RankedResult {
+	return DedupOverlapping(ranked, s.windowLen, s.barDuration, s.maxOverlapFrac)
+}
@@ -0,0 +1,84 @@
+package rerank
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tunogya/etna/pkg/store/milvus"
+)
+
+func TestDedupStage(t *testing.T) {
+	// Windows of 10 one-hour bars
+	tEnd := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	result := func(id, symbol string, score float32, end time.Time) milvus.SearchResult {
+		return milvus.SearchResult{WindowID: id, Symbol: symbol, Timeframe: "1h", Score: score, TEnd: end}
+	}
+
+	tests := []struct {
+		name     string
+		maxFrac  float64
+		results  []milvus.SearchResult
+		wantKept []string
+	}{
+		{
+			"back-to-back windows share no bars",
+			0,
+			[]milvus.SearchResult{
+				result("a", "BTCUSDT", 0.9, tEnd),
+				result("b", "BTCUSDT", 0.8, tEnd.Add(10*time.Hour)),
+				result("c", "BTCUSDT", 0.7, tEnd.Add(-10*time.Hour)),
+			},
+			[]string{"a", "b", "c"},
+		},
+		{
+			"half overlap above the limit drops the lower score",
+			0.3,
+			[]milvus.SearchResult{
+				result("a", "BTCUSDT", 0.9, tEnd),
+				result("b", "BTCUSDT", 0.8, tEnd.Add(5*time.Hour)),
+			},
+			[]string{"a"},
+		},
+		{
+			"half overlap within the limit keeps both",
+			0.6,
+			[]milvus.SearchResult{
+				result("a", "BTCUSDT", 0.9, tEnd),
+				result("b", "BTCUSDT", 0.8, tEnd.Add(5*time.Hour)),
+			},
+			[]string{"a", "b"},
+		},
+		{
+			"overlap with a dropped result does not count",
+			0.3,
+			[]milvus.SearchResult{
+				result("a", "BTCUSDT", 0.9, tEnd),
+				result("b", "BTCUSDT", 0.8, tEnd.Add(5*time.Hour)),
+				result("c", "BTCUSDT", 0.7, tEnd.Add(10*time.Hour)),
+			},
+			[]string{"a", "c"},
+		},
+		{
+			"other symbols never overlap",
+			0,
+			[]milvus.SearchResult{
+				result("a", "BTCUSDT", 0.9, tEnd),
+				result("b", "ETHUSDT", 0.8, tEnd),
+			},
+			[]string{"a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := NewPipeline(NewDedupStage(10, time.Hour, tt.maxFrac)).Run(tt.results)
+			var kept []string
+			for _, r := range out {
+				kept = append(kept, r.WindowID)
+			}
+			if !reflect.DeepEqual(kept, tt.wantKept) {
+				t.Errorf("kept %v, want %v", kept, tt.wantKept)
+			}
+		})
+	}
+}
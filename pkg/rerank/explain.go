@@ -0,0 +1,105 @@
+package rerank
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RankingExplanation breaks a ranked result's final score down for debugging
+type RankingExplanation struct {
+	WindowID     string              `json:"window_id"`
+	Symbol       string              `json:"symbol"`
+	TEnd         time.Time           `json:"t_end"`
+	AgeDays      float64             `json:"age_days"`
+	CosineSim    float64             `json:"cosine_sim"`
+	TimeDecay    float64             `json:"time_decay"`
+	DecayFormula string              `json:"decay_formula"`
+	FinalScore   float64             `json:"final_score"`
+	Stages       []StageContribution `json:"stages,omitempty"`
+}
+
+// Explainer explains the final scores of results ranked by its time weighting
+type Explainer interface {
+	Explain(result RankedResult) RankingExplanation
+	ExplainRanking(result RankedResult) string
+}
+
+// Explain returns the score breakdown of result under this reranker's decay configuration
+// TimeDecay is the weight recorded on result, or the weight for its age if no decay stage ran
+func (r *Reranker) Explain(result RankedResult) RankingExplanation {
+	timeDecay := result.TimeWeight
+	if timeDecay == 0 {
+		timeDecay = r.weight(result.AgeDays)
+	}
+	return RankingExplanation{
+		WindowID:     result.WindowID,
+		Symbol:       result.Symbol,
+		TEnd:         result.TEnd,
+		AgeDays:      result.AgeDays,
+		CosineSim:    float64(result.OriginalScore),
+		TimeDecay:    timeDecay,
+		DecayFormula: r.formula(result.AgeDays),
+		FinalScore:   result.FinalScore,
+		Stages:       result.Contributions,
+	}
+}
+
+// ExplainRanking returns a one-line explanation of result's final score
+// e.g. "WindowID=abc TEnd=2023-01-15 AgeDays=487 CosineSim=0.94 TimeDecay=0.61 [exp(-0.1*487)] FinalScore=0.574"
+func (r *Reranker) ExplainRanking(result RankedResult) string {
+	return r.Explain(result).String()
+}
+
+// String formats the explanation as a single line, followed by its stage contributions if any
+func (e RankingExplanation) String() string {
+	line := fmt.Sprintf("WindowID=%s TEnd=%s AgeDays=%.0f CosineSim=%.3g TimeDecay=%.3g [%s] FinalScore=%.3g",
+		e.WindowID, e.TEnd.Format("2006-01-02"), e.AgeDays, e.CosineSim, e.TimeDecay, e.DecayFormula, e.FinalScore)
+
+	if len(e.Stages) > 0 {
+		stages := make([]string, len(e.Stages))
+		for i, c := range e.Stages {
+			stages[i] = fmt.Sprintf("%s:%.3g->%.3g", c.Stage, c.Before, c.After)
+		}
+		line += " Stages=" + strings.Join(stages, ",")
+	}
+	return line
+}
+
+// ExplainAll returns ExplainRanking for each result, in order
+func (r *Reranker) ExplainAll(results []RankedResult) []string {
+	lines := make([]string, len(results))
+	for i, result := range results {
+		lines[i] = r.ExplainRanking(result)
+	}
+	return lines
+}
+
+// JSON returns the structured explanation of result, including the decay formula used
+func (r *Reranker) JSON(result RankedResult) ([]byte, error) {
+	data, err := json.Marshal(r.Explain(result))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode ranking explanation: %w", err)
+	}
+	return data, nil
+}
+
+// formula describes how the time weight is computed for a result of the given age
+func (r *Reranker) formula(ageDays float64) string {
+	switch {
+	case r.config.UseSegments:
+		switch {
+		case ageDays <= r.config.RecentDays:
+			return fmt.Sprintf("segment(age<=%gd)=%g", r.config.RecentDays, r.config.RecentWeight)
+		case ageDays <= r.config.MediumDays:
+			return fmt.Sprintf("segment(age<=%gd)=%g", r.config.MediumDays, r.config.MediumWeight)
+		default:
+			return fmt.Sprintf("segment(age>%gd)=%g", r.config.MediumDays, r.config.OldWeight)
+		}
+	case r.config.HalfLifeBars > 0 && r.config.BarDuration > 0:
+		return fmt.Sprintf("0.5^(%.1f/%.1f bars)", AgeInBars(ageDays, r.config.BarDuration), r.config.HalfLifeBars)
+	default:
+		return fmt.Sprintf("exp(%g*%.0f)", -r.config.Lambda, ageDays)
+	}
+}
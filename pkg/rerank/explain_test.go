@@ -0,0 +1,49 @@
+package rerank
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tunogya/etna/pkg/store/milvus"
+)
+
+func TestExplainRankingIncludesAgeDays(t *testing.T) {
+	now := time.Date(2024, 5, 16, 0, 0, 0, 0, time.UTC)
+	results := []milvus.SearchResult{{WindowID: "abc", Score: 0.94, TEnd: now.AddDate(0, 0, -487)}}
+
+	r := NewReranker(DefaultTimeDecayConfig())
+	ranked := r.Rerank(results, now)
+	line := r.ExplainRanking(ranked[0])
+	for _, want := range []string{"WindowID=abc", "AgeDays=487", "exp(-0.1*487)"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("explanation %q does not contain %q", line, want)
+		}
+	}
+}
+
+func TestHybridExplainMatchesAppliedWeight(t *testing.T) {
+	now := time.Date(2024, 5, 16, 0, 0, 0, 0, time.UTC)
+	results := []milvus.SearchResult{{WindowID: "abc", Score: 0.9, TEnd: now.AddDate(0, 0, -30)}}
+
+	h := NewHybridReranker(0.6, 0.4)
+	ranked := h.Rerank(results, now)
+	e := h.Explain(ranked[0])
+	if e.AgeDays != 30 {
+		t.Errorf("AgeDays = %v, want 30", e.AgeDays)
+	}
+	if math.Abs(e.TimeDecay*e.CosineSim-e.FinalScore) > 1e-9 {
+		t.Errorf("TimeDecay %v × CosineSim %v != FinalScore %v", e.TimeDecay, e.CosineSim, e.FinalScore)
+	}
+
+	// With no decay stage run, the weight is recomputed from the blended decay
+	unweighted := ranked[0]
+	unweighted.TimeWeight = 0
+	if got := h.Explain(unweighted).TimeDecay; math.Abs(got-e.TimeDecay) > 1e-9 {
+		t.Errorf("recomputed TimeDecay = %v, want the applied %v", got, e.TimeDecay)
+	}
+	if want := "0.6*exp(-0.1*30) + 0.4*exp(0.001*30)"; e.DecayFormula != want {
+		t.Errorf("DecayFormula = %q, want %q", e.DecayFormula, want)
+	}
+}
@@ -0,0 +1,119 @@
+package rerank
+
+import (
+	"context"
+	"math"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// DefaultFeatureDistanceColumns are the scale-sensitive features compared by FeatureDistanceStage
+var DefaultFeatureDistanceColumns = []string{"realized_volatility", "atr", "max_drawdown", "trend_slope"}
+
+// FeatureSource loads stored feature rows for windows; *duckdb.FeatureRepo satisfies it
+type FeatureSource interface {
+	GetByIDs(ctx context.Context, windowIDs []string) ([]*model.FeatureRow, error)
+}
+
+// FeatureDistanceConfig configures the feature-distance stage
+type FeatureDistanceConfig struct {
+	Columns []string           // window_features columns to compare
+	Std     map[string]float64 // Corpus standard deviation per column; columns without a positive std are skipped
+	Weight  float64            // Blend weight in [0, 1]: 0 leaves scores unchanged, 1 scales fully by feature similarity
+}
+
+// FeatureDistanceStage blends structured-feature similarity to the query window into each score
+// Distance is the RMS of per-column z-differences; similarity is exp(-distance)
+type FeatureDistanceStage struct {
+	ctx    context.Context
+	source FeatureSource
+	query  *model.FeatureRow
+	config FeatureDistanceConfig
+	err    error
+}
+
+// NewFeatureDistanceStage creates a feature-distance stage for the query window's features
+func NewFeatureDistanceStage(ctx context.Context, source FeatureSource, query *model.FeatureRow, config FeatureDistanceConfig) *FeatureDistanceStage {
+	if len(config.Columns) == 0 {
+		config.Columns = DefaultFeatureDistanceColumns
+	}
+	return &FeatureDistanceStage{ctx: ctx, source: source, query: query, config: config}
+}
+
+// Name returns the stage name
+func (s *FeatureDistanceStage) Name() string {
+	return "feature_distance"
+}
+
+// Err returns the error from the last feature lookup, if any
+// A failed lookup leaves results unchanged
+func (s *FeatureDistanceStage) Err() error {
+	return s.err
+}
+
+// Apply scales each result by (1-Weight) + Weight·exp(-distance) and re-sorts
+// Results without stored features are left unchanged
+func (s *FeatureDistanceStage) Apply(ranked []RankedResult) []RankedResult {
+	if s.query == nil || s.config.Weight == 0 {
+		return ranked
+	}
+
+	ids := make([]string, len(ranked))
+	for i, r := range ranked {
+		ids[i] = r.WindowID
+	}
+
+	features, err := s.source.GetByIDs(s.ctx, ids)
+	if err != nil {
+		s.err = err
+		return ranked
+	}
+	s.err = nil
+
+	byID := make(map[string]*model.FeatureRow, len(features))
+	for _, f := range features {
+		byID[f.WindowID] = f
+	}
+
+	out := make([]RankedResult, len(ranked))
+	copy(out, ranked)
+	for i := range out {
+		f, ok := byID[out[i].WindowID]
+		if !ok {
+			continue
+		}
+		d, ok := s.distance(f)
+		if !ok {
+			continue
+		}
+		out[i].FeatureDistance = d
+		out[i].FinalScore *= (1 - s.config.Weight) + s.config.Weight*math.Exp(-d)
+	}
+
+	sortByFinalScore(out)
+	return out
+}
+
+// distance returns the RMS z-difference between f and the query over the configured columns
+func (s *FeatureDistanceStage) distance(f *model.FeatureRow) (float64, bool) {
+	var sum float64
+	n := 0
+	for _, c := range s.config.Columns {
+		std := s.config.Std[c]
+		if std <= 0 {
+			continue
+		}
+		qv, ok := s.query.Value(c)
+		if !ok {
+			continue
+		}
+		v, _ := f.Value(c)
+		z := (v - qv) / std
+		sum += z * z
+		n++
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return math.Sqrt(sum / float64(n)), true
+}
@@ -0,0 +1,74 @@
+package rerank
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tunogya/etna/pkg/store/milvus"
+)
+
+// HybridReranker blends recency decay with inverse (analogy) decay
+// finalScore = (recencyWeight * exponentialDecay(age) + analogyWeight * inverseDecay(age)) * cosineScore
+type HybridReranker struct {
+	recency       *Reranker
+	analogy       *Reranker
+	recencyWeight float64
+	analogyWeight float64
+}
+
+// NewHybridReranker creates a hybrid reranker using the default recency and inverse decay configs
+func NewHybridReranker(recencyWeight, analogyWeight float64) *HybridReranker {
+	return NewHybridRerankerWithConfig(DefaultTimeDecayConfig(), InverseDecayConfig(), recencyWeight, analogyWeight)
+}
+
+// NewHybridRerankerWithConfig creates a hybrid reranker with explicit decay configurations
+func NewHybridRerankerWithConfig(recency, analogy TimeDecayConfig, recencyWeight, analogyWeight float64) *HybridReranker {
+	return &HybridReranker{
+		recency:       NewReranker(recency),
+		analogy:       NewReranker(analogy),
+		recencyWeight: recencyWeight,
+		analogyWeight: analogyWeight,
+	}
+}
+
+// Rerank reranks search results using the blended time weight
+func (h *HybridReranker) Rerank(results []milvus.SearchResult, now time.Time) []RankedResult {
+	return NewPipeline(h.Stage(now)).Run(results)
+}
+
+// Stage returns the blended time weight evaluated at now as a pipeline Stage
+func (h *HybridReranker) Stage(now time.Time) Stage {
+	return &decayStage{name: "hybrid_decay", weight: h.weight, now: now}
+}
+
+// weight returns the blended recency/analogy weight for a result of the given age
+func (h *HybridReranker) weight(ageDays float64) float64 {
+	return h.recencyWeight*h.recency.decay(ageDays) +
+		h.analogyWeight*h.analogy.decay(ageDays)
+}
+
+// Explain returns the score breakdown of result under the blended decay
+// TimeDecay is the weight recorded on result, or the blended weight for its age if no decay stage ran
+func (h *HybridReranker) Explain(result RankedResult) RankingExplanation {
+	e := h.recency.Explain(result)
+	if result.TimeWeight == 0 {
+		e.TimeDecay = h.weight(result.AgeDays)
+	}
+	e.DecayFormula = fmt.Sprintf("%g*%s + %g*%s",
+		h.recencyWeight, h.recency.formula(result.AgeDays), h.analogyWeight, h.analogy.formula(result.AgeDays))
+	return e
+}
+
+// ExplainRanking returns a one-line explanation of result's final score under the blended decay
+func (h *HybridReranker) ExplainRanking(result RankedResult) string {
+	return h.Explain(result).String()
+}
+
+// TopN returns the top N results after hybrid reranking
+func (h *HybridReranker) TopN(results []milvus.SearchResult, now time.Time, n int) []RankedResult {
+	ranked := h.Rerank(results, now)
+	if len(ranked) <= n {
+		return ranked
+	}
+	return ranked[:n]
+}
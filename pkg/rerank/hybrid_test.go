@@ -0,0 +1,47 @@
+package rerank
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tunogya/etna/pkg/store/milvus"
+)
+
+// agedResults returns an equally similar recent and old result, recent first
+func agedResults(now time.Time) []milvus.SearchResult {
+	return []milvus.SearchResult{
+		{WindowID: "recent", Score: 0.9, TEnd: now.AddDate(0, 0, -2)},
+		{WindowID: "old", Score: 0.9, TEnd: now.AddDate(-3, 0, 0)},
+	}
+}
+
+func TestAnalogyModeRanksOlderFirst(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		ranks func([]milvus.SearchResult) []RankedResult
+		want  string
+	}{
+		{"recency", func(r []milvus.SearchResult) []RankedResult {
+			return NewReranker(DefaultTimeDecayConfig()).Rerank(r, now)
+		}, "recent"},
+		{"analogy", func(r []milvus.SearchResult) []RankedResult {
+			return NewReranker(InverseDecayConfig()).Rerank(r, now)
+		}, "old"},
+		{"hybrid leaning on analogy", func(r []milvus.SearchResult) []RankedResult {
+			return NewHybridReranker(0.1, 0.9).Rerank(r, now)
+		}, "old"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranked := tt.ranks(agedResults(now))
+			if ranked[0].WindowID != tt.want {
+				t.Errorf("top result = %s, want %s", ranked[0].WindowID, tt.want)
+			}
+			if ranked[0].FinalScore <= ranked[1].FinalScore {
+				t.Errorf("top FinalScore %v does not beat %v", ranked[0].FinalScore, ranked[1].FinalScore)
+			}
+		})
+	}
+}
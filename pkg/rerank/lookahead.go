@@ -0,0 +1,66 @@
+package rerank
+
+import (
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// ExcludeFuture drops neighbors whose outcome horizon had not fully elapsed by queryTEnd,
+// i.e. those with TEnd after queryTEnd - horizonBars×barDuration. Such neighbors end after the
+// query or overlap its future, so their outcomes would leak look-ahead information.
+// Horizons are counted in bars of each neighbor's own timeframe; barDuration applies when it is unknown.
+func ExcludeFuture(results []RankedResult, queryTEnd time.Time, horizonBars int, barDuration time.Duration) []RankedResult {
+	kept := make([]RankedResult, 0, len(results))
+	for _, r := range results {
+		cutoff := queryTEnd.Add(-time.Duration(horizonBars) * resultBarDuration(r, barDuration))
+		if r.TEnd.After(cutoff) {
+			continue
+		}
+		kept = append(kept, r)
+	}
+
+	return kept
+}
+
+// ExcludeFutureStage applies ExcludeFuture as a pipeline Stage and counts the rows it removes
+type ExcludeFutureStage struct {
+	queryTEnd   time.Time
+	horizonBars int
+	barDuration time.Duration
+	excluded    int
+}
+
+// NewExcludeFutureStage creates a look-ahead exclusion stage for a query ending at queryTEnd
+func NewExcludeFutureStage(queryTEnd time.Time, horizonBars int, barDuration time.Duration) *ExcludeFutureStage {
+	return &ExcludeFutureStage{
+		queryTEnd:   queryTEnd,
+		horizonBars: horizonBars,
+		barDuration: barDuration,
+	}
+}
+
+// Name returns the stage name
+func (s *ExcludeFutureStage) Name() string {
+	return "exclude_future"
+}
+
+// Apply drops look-ahead-contaminated neighbors
+func (s *ExcludeFutureStage) Apply(ranked []RankedResult) []RankedResult {
+	kept := ExcludeFuture(ranked, s.queryTEnd, s.horizonBars, s.barDuration)
+	s.excluded += len(ranked) - len(kept)
+	return kept
+}
+
+// Excluded returns the number of results removed across all Apply calls
+func (s *ExcludeFutureStage) Excluded() int {
+	return s.excluded
+}
+
+// resultBarDuration returns the bar duration of a result's timeframe, or fallback if it is missing or invalid
+func resultBarDuration(r RankedResult, fallback time.Duration) time.Duration {
+	if d, err := model.TimeframeDuration(r.Timeframe); err == nil {
+		return d
+	}
+	return fallback
+}
@@ -0,0 +1,103 @@
+package rerank
+
+import (
+	"math"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// MMR reorders results by maximal marginal relevance, greedily picking the result maximizing
+// λ·relevance − (1−λ)·max cosine similarity to the already-picked results.
+// Relevance is FinalScore min-max normalized to [0,1] over the candidates, so time-decayed scores
+// many orders of magnitude below 1 still weigh against similarities on a comparable scale.
+// Results without an embedding are never penalized for redundancy. k <= 0 keeps every result.
+func MMR(results []RankedResult, lambda float64, k int) []RankedResult {
+	if k <= 0 || k > len(results) {
+		k = len(results)
+	}
+
+	remaining := make([]RankedResult, len(results))
+	copy(remaining, results)
+	sortByFinalScore(remaining)
+
+	relevance := normalizedScores(remaining)
+
+	// maxSim[i] tracks the highest similarity of remaining[i] to any picked result
+	maxSim := make([]float64, len(remaining))
+
+	picked := make([]RankedResult, 0, k)
+	for len(picked) < k {
+		best := -1
+		bestScore := math.Inf(-1)
+		for i := range remaining {
+			score := lambda*relevance[i] - (1-lambda)*maxSim[i]
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		chosen := remaining[best]
+		picked = append(picked, chosen)
+		remaining = append(remaining[:best], remaining[best+1:]...)
+		maxSim = append(maxSim[:best], maxSim[best+1:]...)
+		relevance = append(relevance[:best], relevance[best+1:]...)
+
+		if len(chosen.Embedding) == 0 {
+			continue
+		}
+		chosenVec := model.ShapeVector(chosen.Embedding)
+		for i, r := range remaining {
+			if len(r.Embedding) == 0 {
+				continue
+			}
+			if sim := chosenVec.CosineSimilarity(r.Embedding); sim > maxSim[i] {
+				maxSim[i] = sim
+			}
+		}
+	}
+
+	return picked
+}
+
+// normalizedScores min-max scales each FinalScore to [0,1]; equal scores all map to 1
+func normalizedScores(ranked []RankedResult) []float64 {
+	scores := make([]float64, len(ranked))
+	if len(ranked) == 0 {
+		return scores
+	}
+	lo, hi := ranked[0].FinalScore, ranked[0].FinalScore
+	for _, r := range ranked {
+		lo = math.Min(lo, r.FinalScore)
+		hi = math.Max(hi, r.FinalScore)
+	}
+	for i, r := range ranked {
+		if hi > lo {
+			scores[i] = (r.FinalScore - lo) / (hi - lo)
+		} else {
+			scores[i] = 1
+		}
+	}
+	return scores
+}
+
+// MMRStage applies MMR as a pipeline Stage
+// It reorders without changing FinalScore, so it should run after any stage that re-sorts by score
+type MMRStage struct {
+	lambda float64
+	k      int
+}
+
+// NewMMRStage creates a diversity stage; lambda = 1 is pure relevance, lambda = 0 pure diversity
+func NewMMRStage(lambda float64, k int) *MMRStage {
+	return &MMRStage{lambda: lambda, k: k}
+}
+
+// Name returns the stage name
+func (s *MMRStage) Name() string {
+	return "mmr"
+}
+
+// Apply reorders results by maximal marginal relevance
+func (s *MMRStage) Apply(ranked []RankedResult) []RankedResult {
+	return MMR(ranked, s.lambda, s.k)
+}
@@ -0,0 +1,52 @@
+package rerank
+
+import (
+	"testing"
+
+	"github.com/tunogya/etna/pkg/store/milvus"
+)
+
+// rankedWithEmbedding returns a ranked result with the given id, final score and embedding
+func rankedWithEmbedding(id string, score float64, embedding ...float32) RankedResult {
+	return RankedResult{
+		SearchResult: milvus.SearchResult{WindowID: id, Embedding: embedding},
+		FinalScore:   score,
+	}
+}
+
+func TestMMRPromotesDissimilarNeighbor(t *testing.T) {
+	// Scores are time-decayed to ~1e-16, far below the cosine similarities they are weighed against
+	results := []RankedResult{
+		rankedWithEmbedding("cluster-a", 4e-16, 1, 0, 0),
+		rankedWithEmbedding("cluster-b", 3.9e-16, 0.99, 0.1, 0),
+		rankedWithEmbedding("cluster-c", 3.8e-16, 0.98, 0, 0.1),
+		rankedWithEmbedding("dissimilar", 3e-16, 0, 1, 0),
+		rankedWithEmbedding("weak", 1e-16, 0, 0, 1),
+	}
+
+	out := MMR(results, 0.7, 3)
+	want := []string{"cluster-a", "dissimilar", "cluster-b"}
+	if len(out) != len(want) {
+		t.Fatalf("got %d results, want %d", len(out), len(want))
+	}
+	for i, id := range want {
+		if out[i].WindowID != id {
+			t.Errorf("position %d = %s, want %s", i, out[i].WindowID, id)
+		}
+	}
+}
+
+func TestMMRPureRelevanceKeepsScoreOrder(t *testing.T) {
+	results := []RankedResult{
+		rankedWithEmbedding("b", 0.5, 1, 0),
+		rankedWithEmbedding("a", 0.9, 1, 0),
+		rankedWithEmbedding("c", 0.1, 0, 1),
+	}
+
+	out := MMR(results, 1, 0)
+	for i, id := range []string{"a", "b", "c"} {
+		if out[i].WindowID != id {
+			t.Errorf("position %d = %s, want %s", i, out[i].WindowID, id)
+		}
+	}
+}
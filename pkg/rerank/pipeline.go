@@ -0,0 +1,128 @@
+package rerank
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tunogya/etna/pkg/store/milvus"
+)
+
+// Stage is a single reranking step
+// Stages typically scale FinalScore and re-sort, but may also filter or reorder results
+type Stage interface {
+	Apply(ranked []RankedResult) []RankedResult
+}
+
+// namedStage is implemented by stages that report a name for score explanations
+type namedStage interface {
+	Name() string
+}
+
+// StageContribution records how one pipeline stage changed a result's score
+type StageContribution struct {
+	Stage  string  `json:"stage"`
+	Before float64 `json:"before"` // FinalScore entering the stage
+	After  float64 `json:"after"`  // FinalScore leaving the stage
+}
+
+// Pipeline runs reranking stages in order
+type Pipeline struct {
+	stages []Stage
+}
+
+// NewPipeline creates a pipeline running the given stages in order
+func NewPipeline(stages ...Stage) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+// Add appends a stage to the pipeline
+func (p *Pipeline) Add(stage Stage) *Pipeline {
+	p.stages = append(p.stages, stage)
+	return p
+}
+
+// Run converts search results to ranked results and applies every stage
+// Initial FinalScore is the similarity score; each stage's effect is appended to Contributions
+func (p *Pipeline) Run(results []milvus.SearchResult) []RankedResult {
+	ranked := make([]RankedResult, len(results))
+	for i, result := range results {
+		ranked[i] = RankedResult{
+			SearchResult:  result,
+			OriginalScore: result.Score,
+			FinalScore:    float64(result.Score),
+		}
+	}
+
+	return p.Apply(ranked)
+}
+
+// Apply runs every stage over already-ranked results, so a pipeline can itself be used as a Stage
+func (p *Pipeline) Apply(ranked []RankedResult) []RankedResult {
+	for _, stage := range p.stages {
+		before := make(map[string]float64, len(ranked))
+		for _, r := range ranked {
+			before[r.WindowID] = r.FinalScore
+		}
+
+		ranked = stage.Apply(ranked)
+
+		name := stageName(stage)
+		for i := range ranked {
+			ranked[i].Contributions = append(ranked[i].Contributions, StageContribution{
+				Stage:  name,
+				Before: before[ranked[i].WindowID],
+				After:  ranked[i].FinalScore,
+			})
+		}
+	}
+
+	return ranked
+}
+
+// stageName returns a stage's reported name, falling back to its type
+func stageName(s Stage) string {
+	if n, ok := s.(namedStage); ok {
+		return n.Name()
+	}
+	return fmt.Sprintf("%T", s)
+}
+
+// sortByFinalScore sorts ranked results by final score (descending), keeping ties in order
+func sortByFinalScore(ranked []RankedResult) {
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].FinalScore > ranked[j].FinalScore
+	})
+}
+
+// decayStage scales scores by an age-based time weight
+type decayStage struct {
+	name   string
+	weight func(ageDays float64) float64
+	now    time.Time
+}
+
+// Name returns the stage name
+func (s *decayStage) Name() string {
+	return s.name
+}
+
+// Apply multiplies each FinalScore by the time weight for the result's age and re-sorts
+func (s *decayStage) Apply(ranked []RankedResult) []RankedResult {
+	out := make([]RankedResult, len(ranked))
+	copy(out, ranked)
+
+	for i := range out {
+		ageDays := s.now.Sub(out[i].TEnd).Hours() / 24
+		if ageDays < 0 {
+			ageDays = 0
+		}
+
+		out[i].AgeDays = ageDays
+		out[i].TimeWeight = s.weight(ageDays)
+		out[i].FinalScore *= out[i].TimeWeight
+	}
+
+	sortByFinalScore(out)
+	return out
+}
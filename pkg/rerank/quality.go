@@ -0,0 +1,103 @@
+package rerank
+
+import (
+	"context"
+	"math"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// OutcomeSource loads stored outcomes for windows; *duckdb.OutcomeRepo satisfies it
+type OutcomeSource interface {
+	GetByWindowIDs(ctx context.Context, windowIDs []string, horizons []int) ([]*model.Outcome, error)
+}
+
+// QualityFunc maps a neighbor's forward outcome to a score multiplier
+type QualityFunc func(fwdRetP50, mddP95 float64) float64
+
+// DefaultQuality favors neighbors whose forward paths reached their median return with little drawdown
+// The multiplier ranges from 0.5 (drawdown dwarfs the return) to 1 (no drawdown)
+func DefaultQuality(fwdRetP50, mddP95 float64) float64 {
+	move := math.Abs(fwdRetP50)
+	if move+mddP95 <= 0 {
+		return 1
+	}
+	return 0.5 + 0.5*move/(move+mddP95)
+}
+
+// OutcomeQualityConfig configures the outcome-quality stage
+type OutcomeQualityConfig struct {
+	Horizon int         // Horizon whose stored outcome is used
+	Strict  bool        // Drop neighbors without a stored outcome, including those lacking a full horizon of forward data
+	Quality QualityFunc // Score multiplier; DefaultQuality when nil
+}
+
+// OutcomeQualityStage scales FinalScore by the quality of each neighbor's stored forward outcome
+// Outcomes are only stored once a window has a complete horizon of forward candles, so only those neighbors
+// are rescored; strict mode is how neighbors with insufficient forward data are filtered out
+type OutcomeQualityStage struct {
+	ctx    context.Context
+	source OutcomeSource
+	config OutcomeQualityConfig
+	err    error
+}
+
+// NewOutcomeQualityStage creates an outcome-quality stage reading outcomes from source
+func NewOutcomeQualityStage(ctx context.Context, source OutcomeSource, config OutcomeQualityConfig) *OutcomeQualityStage {
+	if config.Quality == nil {
+		config.Quality = DefaultQuality
+	}
+	return &OutcomeQualityStage{ctx: ctx, source: source, config: config}
+}
+
+// Name returns the stage name
+func (s *OutcomeQualityStage) Name() string {
+	return "outcome_quality"
+}
+
+// Err returns the error from the last outcome lookup, if any
+// A failed lookup leaves results unchanged
+func (s *OutcomeQualityStage) Err() error {
+	return s.err
+}
+
+// Apply scales each result by its outcome quality, dropping results without outcomes in strict mode, and re-sorts
+func (s *OutcomeQualityStage) Apply(ranked []RankedResult) []RankedResult {
+	ids := make([]string, len(ranked))
+	for i, r := range ranked {
+		ids[i] = r.WindowID
+	}
+
+	outcomes, err := s.source.GetByWindowIDs(s.ctx, ids, []int{s.config.Horizon})
+	if err != nil {
+		s.err = err
+		return ranked
+	}
+	s.err = nil
+
+	byID := make(map[string]*model.Outcome, len(outcomes))
+	for _, o := range outcomes {
+		if o.Horizon == s.config.Horizon {
+			byID[o.WindowID] = o
+		}
+	}
+
+	out := make([]RankedResult, 0, len(ranked))
+	for _, r := range ranked {
+		o, ok := byID[r.WindowID]
+		if !ok {
+			if s.config.Strict {
+				continue
+			}
+			out = append(out, r)
+			continue
+		}
+
+		r.QualityWeight = s.config.Quality(o.FwdRetP50, o.MDDP95)
+		r.FinalScore *= r.QualityWeight
+		out = append(out, r)
+	}
+
+	sortByFinalScore(out)
+	return out
+}
@@ -0,0 +1,138 @@
+package rerank
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/tunogya/etna/pkg/model"
+	"github.com/tunogya/etna/pkg/store/milvus"
+)
+
+// fakeOutcomeSource serves outcomes from memory and records the horizons asked for
+type fakeOutcomeSource struct {
+	outcomes []*model.Outcome
+	err      error
+	horizons []int
+}
+
+func (f *fakeOutcomeSource) GetByWindowIDs(ctx context.Context, windowIDs []string, horizons []int) ([]*model.Outcome, error) {
+	f.horizons = horizons
+	if f.err != nil {
+		return nil, f.err
+	}
+	wanted := make(map[string]bool, len(windowIDs))
+	for _, id := range windowIDs {
+		wanted[id] = true
+	}
+	var out []*model.Outcome
+	for _, o := range f.outcomes {
+		if wanted[o.WindowID] {
+			out = append(out, o)
+		}
+	}
+	return out, nil
+}
+
+// qualityResults returns neighbors "clean", "choppy" and "unknown", scoring 0.8, 0.9 and 0.7
+func qualityResults() []milvus.SearchResult {
+	return []milvus.SearchResult{
+		{WindowID: "choppy", Score: 0.9},
+		{WindowID: "clean", Score: 0.8},
+		{WindowID: "unknown", Score: 0.7},
+	}
+}
+
+func TestOutcomeQualityStage(t *testing.T) {
+	source := &fakeOutcomeSource{outcomes: []*model.Outcome{
+		{WindowID: "clean", Horizon: 24, FwdRetP50: 0.04, MDDP95: 0},
+		{WindowID: "choppy", Horizon: 24, FwdRetP50: 0.01, MDDP95: 0.09},
+		// Another horizon's outcome must not count
+		{WindowID: "unknown", Horizon: 48, FwdRetP50: 0.05},
+	}}
+
+	tests := []struct {
+		name   string
+		strict bool
+		want   []string
+	}{
+		{"lenient keeps neighbors without outcomes", false, []string{"clean", "unknown", "choppy"}},
+		{"strict drops neighbors without outcomes", true, []string{"clean", "choppy"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stage := NewOutcomeQualityStage(context.Background(), source, OutcomeQualityConfig{Horizon: 24, Strict: tt.strict})
+			out := NewPipeline(stage).Run(qualityResults())
+			if err := stage.Err(); err != nil {
+				t.Fatalf("Err() = %v", err)
+			}
+			if !reflect.DeepEqual(source.horizons, []int{24}) {
+				t.Errorf("looked up horizons %v, want [24]", source.horizons)
+			}
+
+			var ids []string
+			for _, r := range out {
+				ids = append(ids, r.WindowID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Fatalf("order = %v, want %v", ids, tt.want)
+			}
+
+			for _, r := range out {
+				var want float64
+				switch r.WindowID {
+				case "clean":
+					want = 1 // No drawdown
+				case "choppy":
+					want = 0.55 // 0.5 + 0.5 × 0.01/(0.01+0.09)
+				}
+				if math.Abs(r.QualityWeight-want) > 1e-9 {
+					t.Errorf("%s QualityWeight = %v, want %v", r.WindowID, r.QualityWeight, want)
+				}
+			}
+		})
+	}
+}
+
+func TestOutcomeQualityStageSourceError(t *testing.T) {
+	source := &fakeOutcomeSource{err: errors.New("database is closed")}
+	stage := NewOutcomeQualityStage(context.Background(), source, OutcomeQualityConfig{Horizon: 24, Strict: true})
+
+	out := NewPipeline(stage).Run(qualityResults())
+	if !errors.Is(stage.Err(), source.err) {
+		t.Errorf("Err() = %v, want %v", stage.Err(), source.err)
+	}
+	if len(out) != 3 || out[0].WindowID != "choppy" || out[0].QualityWeight != 0 {
+		t.Errorf("failed lookup changed results: %+v", out)
+	}
+}
+
+func TestOutcomeQualityStageCustomQuality(t *testing.T) {
+	source := &fakeOutcomeSource{outcomes: []*model.Outcome{
+		{WindowID: "clean", Horizon: 24, FwdRetP50: 0.04, MDDP95: 0.01},
+		{WindowID: "choppy", Horizon: 24, FwdRetP50: 0.01, MDDP95: 0.09},
+	}}
+
+	// Penalize drawdown alone, receiving each neighbor's stored statistics
+	type args struct{ ret, mdd float64 }
+	var got []args
+	quality := func(fwdRetP50, mddP95 float64) float64 {
+		got = append(got, args{fwdRetP50, mddP95})
+		return 1 - mddP95
+	}
+	stage := NewOutcomeQualityStage(context.Background(), source, OutcomeQualityConfig{Horizon: 24, Quality: quality})
+	out := NewPipeline(stage).Run(qualityResults())
+
+	want := []args{{0.01, 0.09}, {0.04, 0.01}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("quality called with %v, want %v", got, want)
+	}
+	weights := map[string]float64{"choppy": 0.91, "clean": 0.99, "unknown": 0}
+	for _, r := range out {
+		if math.Abs(r.QualityWeight-weights[r.WindowID]) > 1e-9 {
+			t.Errorf("%s QualityWeight = %v, want %v", r.WindowID, r.QualityWeight, weights[r.WindowID])
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package rerank
+
+import (
+	"math"
+	"sort"
+)
+
+// SymbolConfig configures cross-symbol reranking
+type SymbolConfig struct {
+	QuerySymbol     string   // Symbol of the query window
+	SameSymbolBonus float64  // Fractional boost for results of QuerySymbol (0.1 = +10%)
+	PerSymbolCap    float64  // Max fraction of results from any one symbol, in (0, 1]; 0 disables
+	AllowedSymbols  []string // Symbols allowed in the results; empty allows all
+
+	// TopK is the number of results finally kept, which PerSymbolCap is a fraction of;
+	// 0 applies the cap to the results entering the stage
+	TopK int
+}
+
+// SymbolStage applies same-symbol preference, symbol allow-listing, and per-symbol caps
+type SymbolStage struct {
+	config  SymbolConfig
+	allowed map[string]bool
+}
+
+// NewSymbolStage creates a cross-symbol stage
+func NewSymbolStage(config SymbolConfig) *SymbolStage {
+	var allowed map[string]bool
+	if len(config.AllowedSymbols) > 0 {
+		allowed = make(map[string]bool, len(config.AllowedSymbols))
+		for _, s := range config.AllowedSymbols {
+			allowed[s] = true
+		}
+	}
+	return &SymbolStage{config: config, allowed: allowed}
+}
+
+// Name returns the stage name
+func (s *SymbolStage) Name() string {
+	return "symbol"
+}
+
+// Apply drops disallowed symbols, boosts same-symbol results, re-sorts, and enforces the per-symbol cap
+// The cap is ceil(PerSymbolCap × TopK), keeping each symbol's best-scoring results, so a search recalling many
+// more candidates than it keeps still spreads the kept results across symbols
+func (s *SymbolStage) Apply(ranked []RankedResult) []RankedResult {
+	out := make([]RankedResult, 0, len(ranked))
+	for _, r := range ranked {
+		if s.allowed != nil && !s.allowed[r.Symbol] {
+			continue
+		}
+		if s.config.SameSymbolBonus != 0 && r.Symbol == s.config.QuerySymbol {
+			r.FinalScore *= 1 + s.config.SameSymbolBonus
+		}
+		out = append(out, r)
+	}
+
+	sortByFinalScore(out)
+
+	if s.config.PerSymbolCap <= 0 || s.config.PerSymbolCap >= 1 {
+		return out
+	}
+
+	size := len(ranked)
+	if s.config.TopK > 0 && s.config.TopK < size {
+		size = s.config.TopK
+	}
+	limit := int(math.Ceil(s.config.PerSymbolCap * float64(size)))
+	counts := make(map[string]int)
+	capped := out[:0]
+	for _, r := range out {
+		if counts[r.Symbol] >= limit {
+			continue
+		}
+		counts[r.Symbol]++
+		capped = append(capped, r)
+	}
+	return capped
+}
+
+// SymbolCount is the number of results from one symbol
+type SymbolCount struct {
+	Symbol string
+	Count  int
+}
+
+// SymbolDistribution counts results per symbol, most frequent first (ties by symbol)
+func SymbolDistribution(ranked []RankedResult) []SymbolCount {
+	counts := make(map[string]int)
+	for _, r := range ranked {
+		counts[r.Symbol]++
+	}
+
+	dist := make([]SymbolCount, 0, len(counts))
+	for symbol, n := range counts {
+		dist = append(dist, SymbolCount{Symbol: symbol, Count: n})
+	}
+	sort.Slice(dist, func(i, j int) bool {
+		if dist[i].Count != dist[j].Count {
+			return dist[i].Count > dist[j].Count
+		}
+		return dist[i].Symbol < dist[j].Symbol
+	})
+	return dist
+}
@@ -0,0 +1,79 @@
+package rerank
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/tunogya/etna/pkg/store/milvus"
+)
+
+// crossSymbolResults returns n results of symbol "AAA" outscoring n results of "BBB" and "CCC" each
+func crossSymbolResults(n int) []milvus.SearchResult {
+	tEnd := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var results []milvus.SearchResult
+	for s, symbol := range []string{"AAA", "BBB", "CCC"} {
+		for i := 0; i < n; i++ {
+			results = append(results, milvus.SearchResult{
+				WindowID: fmt.Sprintf("%s-%d", symbol, i),
+				Symbol:   symbol,
+				Score:    float32(0.99 - 0.1*float64(s) - 0.0001*float64(i)),
+				TEnd:     tEnd,
+			})
+		}
+	}
+	return results
+}
+
+func TestSymbolStagePerSymbolCap(t *testing.T) {
+	tests := []struct {
+		name      string
+		topK      int
+		wantLimit int
+	}{
+		{"cap of the recalled set", 0, 18}, // ceil(0.3 × 60)
+		{"cap of the kept top-K", 10, 3},   // ceil(0.3 × 10)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stage := NewSymbolStage(SymbolConfig{PerSymbolCap: 0.3, TopK: tt.topK})
+			out := NewPipeline(stage).Run(crossSymbolResults(20))
+			for _, c := range SymbolDistribution(out) {
+				if c.Count != tt.wantLimit {
+					t.Errorf("%s: %d results, want %d", c.Symbol, c.Count, tt.wantLimit)
+				}
+			}
+		})
+	}
+}
+
+func TestSymbolStageCapSurvivesTopK(t *testing.T) {
+	pipeline := NewPipeline(
+		NewSymbolStage(SymbolConfig{PerSymbolCap: 0.3, TopK: 10}),
+		NewTopKStage(10),
+	)
+	out := pipeline.Run(crossSymbolResults(200))
+	if len(out) != 9 {
+		t.Fatalf("got %d results, want 9 (3 per symbol)", len(out))
+	}
+	for _, c := range SymbolDistribution(out) {
+		if c.Count > 3 {
+			t.Errorf("%s has %d of the top 10, cap allows 3", c.Symbol, c.Count)
+		}
+	}
+}
+
+func TestSymbolStageAllowedAndBonus(t *testing.T) {
+	stage := NewSymbolStage(SymbolConfig{
+		QuerySymbol:     "CCC",
+		SameSymbolBonus: 0.5,
+		AllowedSymbols:  []string{"BBB", "CCC"},
+	})
+	out := NewPipeline(stage).Run(crossSymbolResults(1))
+	if len(out) != 2 {
+		t.Fatalf("got %d results, want 2 allowed", len(out))
+	}
+	if out[0].Symbol != "CCC" {
+		t.Errorf("top symbol = %s, want the boosted query symbol CCC", out[0].Symbol)
+	}
+}
@@ -2,9 +2,9 @@ package rerank
 
 import (
 	"math"
-	"sort"
 	"time"
 
+	"github.com/tunogya/etna/pkg/model"
 	"github.com/tunogya/etna/pkg/store/milvus"
 )
 
@@ -18,6 +18,9 @@ type TimeDecayConfig struct {
 	RecentWeight float64 // Weight for recent (<= RecentDays)
 	MediumWeight float64 // Weight for medium (RecentDays < x <= MediumDays)
 	OldWeight    float64 // Weight for old (> MediumDays)
+	// Bar-based decay (optional, used instead of Lambda if HalfLifeBars > 0 and BarDuration > 0)
+	HalfLifeBars float64       // Age in bars at which the weight halves
+	BarDuration  time.Duration // Duration of one bar of the searched timeframe
 }
 
 // DefaultTimeDecayConfig returns a default configuration
@@ -33,6 +36,74 @@ func DefaultTimeDecayConfig() TimeDecayConfig {
 	}
 }
 
+// defaultHalfLives sets the default half-life for bars up to each duration, in ascending order
+// Shorter bars get longer half-lives in bars but shorter ones in time: a 1m chart keeps a day of memory
+// (1440 bars) while a 1d chart keeps DefaultTimeDecayConfig's ln 2 / 0.1 ≈ 6.9 days
+var defaultHalfLives = []struct {
+	maxBar   time.Duration
+	halfLife time.Duration
+}{
+	{time.Minute, 24 * time.Hour},
+	{15 * time.Minute, 2 * 24 * time.Hour},
+	{time.Hour, 3 * 24 * time.Hour},
+	{4 * time.Hour, 5 * 24 * time.Hour},
+	{24 * time.Hour, 6931 * 24 * time.Hour / 1000}, // ln 2 / 0.1 days
+	{7 * 24 * time.Hour, 6 * 7 * 24 * time.Hour},
+}
+
+// DefaultHalfLifeBars returns the default half-life, in bars of barDuration, of DefaultForTimeframe
+// Bars longer than a week keep the weekly chart's 6 bars
+func DefaultHalfLifeBars(barDuration time.Duration) float64 {
+	if barDuration <= 0 {
+		return 0
+	}
+	for _, d := range defaultHalfLives {
+		if barDuration <= d.maxBar {
+			return float64(d.halfLife) / float64(barDuration)
+		}
+	}
+	last := defaultHalfLives[len(defaultHalfLives)-1]
+	return float64(last.halfLife) / float64(last.maxBar)
+}
+
+// DefaultForTimeframe returns a default configuration whose decay is measured in bars of timeframe,
+// with the half-life DefaultHalfLifeBars gives its bar duration
+// Unparseable timeframes fall back to DefaultTimeDecayConfig
+func DefaultForTimeframe(timeframe string) TimeDecayConfig {
+	cfg := DefaultTimeDecayConfig()
+	barDuration, err := model.TimeframeDuration(timeframe)
+	if err != nil {
+		return cfg
+	}
+	cfg.HalfLifeBars = DefaultHalfLifeBars(barDuration)
+	cfg.BarDuration = barDuration
+	cfg.Lambda = LambdaFromHalfLifeBars(cfg.HalfLifeBars, barDuration)
+	return cfg
+}
+
+// LambdaFromHalfLifeBars converts a half-life in bars to the equivalent per-day Lambda
+func LambdaFromHalfLifeBars(halfLifeBars float64, barDuration time.Duration) float64 {
+	halfLifeDays := halfLifeBars * barDuration.Hours() / 24
+	if halfLifeDays <= 0 {
+		return 0
+	}
+	return math.Ln2 / halfLifeDays
+}
+
+// HalfLifeBarsFromLambda converts a per-day Lambda to the equivalent half-life in bars
+func HalfLifeBarsFromLambda(lambda float64, barDuration time.Duration) float64 {
+	barDays := barDuration.Hours() / 24
+	if lambda <= 0 || barDays <= 0 {
+		return 0
+	}
+	return math.Ln2 / lambda / barDays
+}
+
+// AgeInBars converts an age in days to a number of bars of barDuration
+func AgeInBars(ageDays float64, barDuration time.Duration) float64 {
+	return ageDays * 24 / barDuration.Hours()
+}
+
 // SegmentConfig returns a configuration using segment-based weights
 func SegmentConfig() TimeDecayConfig {
 	return TimeDecayConfig{
@@ -45,12 +116,29 @@ func SegmentConfig() TimeDecayConfig {
 	}
 }
 
+// InverseDecayConfig returns a configuration that boosts older results
+// Lambda is negative so exp(-Lambda * age) grows with age, favoring distant analogues
+func InverseDecayConfig() TimeDecayConfig {
+	return TimeDecayConfig{
+		Lambda:      -0.001, // Gentle growth: ~1.44x after one year
+		UseSegments: false,
+	}
+}
+
 // RankedResult extends SearchResult with reranked score
 type RankedResult struct {
 	milvus.SearchResult
-	OriginalScore float32
-	TimeWeight    float64
-	FinalScore    float64
+	OriginalScore   float32
+	AgeDays         float64 // Age of TEnd in days when the time decay was applied (0 if not applied)
+	TimeWeight      float64
+	BucketWeight    float64 // Regime match multiplier applied by BucketReranker (0 if not applied)
+	QualityWeight   float64 // Outcome quality multiplier applied by OutcomeQualityStage (0 if not applied)
+	FeatureDistance float64 // RMS z-distance to the query's features from FeatureDistanceStage (0 if not applied)
+	FinalScore      float64
+	ScoreMode       string  // Score combination mode recorded by ScoreCombiner (empty if not applied)
+	MatchStrength   float64 // Calibrated 0-100 percentile of OriginalScore set by Calibrator (0 if not applied)
+
+	Contributions []StageContribution // Per-stage score changes, in pipeline order
 }
 
 // Reranker performs time-based reranking of search results
@@ -65,35 +153,28 @@ func NewReranker(config TimeDecayConfig) *Reranker {
 
 // Rerank reranks search results based on time decay
 func (r *Reranker) Rerank(results []milvus.SearchResult, now time.Time) []RankedResult {
-	ranked := make([]RankedResult, len(results))
-
-	for i, result := range results {
-		ageDays := now.Sub(result.TEnd).Hours() / 24
-		if ageDays < 0 {
-			ageDays = 0
-		}
+	return NewPipeline(r.Stage(now)).Run(results)
+}
 
-		var weight float64
-		if r.config.UseSegments {
-			weight = r.segmentWeight(ageDays)
-		} else {
-			weight = r.exponentialDecay(ageDays)
-		}
+// Stage returns the time decay evaluated at now as a pipeline Stage
+func (r *Reranker) Stage(now time.Time) Stage {
+	return &decayStage{name: "time_decay", weight: r.weight, now: now}
+}
 
-		ranked[i] = RankedResult{
-			SearchResult:  result,
-			OriginalScore: result.Score,
-			TimeWeight:    weight,
-			FinalScore:    float64(result.Score) * weight,
-		}
+// weight returns the time weight for a result of the given age
+func (r *Reranker) weight(ageDays float64) float64 {
+	if r.config.UseSegments {
+		return r.segmentWeight(ageDays)
 	}
+	return r.decay(ageDays)
+}
 
-	// Sort by final score (descending)
-	sort.Slice(ranked, func(i, j int) bool {
-		return ranked[i].FinalScore > ranked[j].FinalScore
-	})
-
-	return ranked
+// decay returns the continuous decay weight, bar-based when HalfLifeBars is configured
+func (r *Reranker) decay(ageDays float64) float64 {
+	if r.config.HalfLifeBars > 0 && r.config.BarDuration > 0 {
+		return r.halfLifeDecay(ageDays)
+	}
+	return r.exponentialDecay(ageDays)
 }
 
 // exponentialDecay calculates decay using exponential function
@@ -101,6 +182,11 @@ func (r *Reranker) exponentialDecay(ageDays float64) float64 {
 	return math.Exp(-r.config.Lambda * ageDays)
 }
 
+// halfLifeDecay halves the weight every HalfLifeBars bars of age
+func (r *Reranker) halfLifeDecay(ageDays float64) float64 {
+	return math.Pow(0.5, AgeInBars(ageDays, r.config.BarDuration)/r.config.HalfLifeBars)
+}
+
 // segmentWeight returns weight based on time segments
 func (r *Reranker) segmentWeight(ageDays float64) float64 {
 	switch {
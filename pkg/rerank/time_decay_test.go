@@ -0,0 +1,58 @@
+package rerank
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDefaultForTimeframe(t *testing.T) {
+	tests := []struct {
+		timeframe    string
+		halfLifeBars float64
+		halfLifeDays float64
+	}{
+		{"1m", 1440, 1},
+		{"5m", 576, 2},
+		{"1h", 72, 3},
+		{"4h", 30, 5},
+		{"1d", 6.931, 6.931},
+		{"1w", 6, 42},
+	}
+	for _, tt := range tests {
+		t.Run(tt.timeframe, func(t *testing.T) {
+			cfg := DefaultForTimeframe(tt.timeframe)
+			if math.Abs(cfg.HalfLifeBars-tt.halfLifeBars) > 1e-9 {
+				t.Errorf("HalfLifeBars = %v, want %v", cfg.HalfLifeBars, tt.halfLifeBars)
+			}
+
+			// A neighbor one half-life old weighs half as much under either decay form
+			r := NewReranker(cfg)
+			if w := r.weight(tt.halfLifeDays); math.Abs(w-0.5) > 1e-9 {
+				t.Errorf("weight at %v days = %v, want 0.5", tt.halfLifeDays, w)
+			}
+			if got := math.Exp(-cfg.Lambda * tt.halfLifeDays); math.Abs(got-0.5) > 1e-9 {
+				t.Errorf("Lambda %v gives %v at the half-life, want 0.5", cfg.Lambda, got)
+			}
+		})
+	}
+}
+
+func TestDefaultHalfLifeIsMonotonic(t *testing.T) {
+	// Longer bars never remember less time, nor more bars
+	prevBars, prevTime := math.Inf(1), time.Duration(0)
+	for _, bar := range []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, time.Hour, 4 * time.Hour, 24 * time.Hour, 7 * 24 * time.Hour, 30 * 24 * time.Hour} {
+		bars := DefaultHalfLifeBars(bar)
+		halfLife := time.Duration(bars * float64(bar))
+		if bars > prevBars || halfLife < prevTime {
+			t.Errorf("%s: half-life %v bars (%s) after %v bars (%s)", bar, bars, halfLife, prevBars, prevTime)
+		}
+		prevBars, prevTime = bars, halfLife
+	}
+}
+
+func TestDefaultForTimeframeFallsBack(t *testing.T) {
+	if cfg := DefaultForTimeframe("bogus"); cfg.HalfLifeBars != 0 || cfg.Lambda != DefaultTimeDecayConfig().Lambda {
+		t.Errorf("unparseable timeframe gave %+v, want DefaultTimeDecayConfig", cfg)
+	}
+}
@@ -0,0 +1,25 @@
+package rerank
+
+// TopKStage keeps the k best results, ending a two-stage search that recalls more candidates
+// than it shows so the stages before it can promote matches the raw similarity ranked lower
+type TopKStage struct {
+	k int
+}
+
+// NewTopKStage creates a truncation stage; k <= 0 keeps every result
+func NewTopKStage(k int) *TopKStage {
+	return &TopKStage{k: k}
+}
+
+// Name returns the stage name
+func (s *TopKStage) Name() string {
+	return "topk"
+}
+
+// Apply keeps the first k results in their current order
+func (s *TopKStage) Apply(ranked []RankedResult) []RankedResult {
+	if s.k <= 0 || len(ranked) <= s.k {
+		return ranked
+	}
+	return ranked[:s.k]
+}
@@ -0,0 +1,97 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Backfill run phases, in the order a run completes them
+const (
+	BackfillPhaseCandles = "candles" // Ingesting candles
+	BackfillPhaseWindows = "windows" // Storing windows and features, embedding vectors
+	BackfillPhaseIndex   = "index"   // Vectors flushed; building the Milvus index
+	BackfillPhaseDone    = "done"
+)
+
+// BackfillRun is the checkpoint of one backfill run
+type BackfillRun struct {
+	RunID              string
+	Symbol             string
+	Timeframe          string
+	Phase              string
+	LastCandleOpenTime time.Time // Zero until a candle batch has been stored
+	LastWindowTEnd     time.Time // Zero until a window batch has been stored and embedded
+	MilvusBatches      int64     // Vector batches written to Milvus
+	FlushedBatches     int64     // Vector batches covered by the last Milvus flush
+	StartedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+// BackfillRunRepo handles backfill checkpoint persistence
+type BackfillRunRepo struct {
+	client *Client
+}
+
+// NewBackfillRunRepo creates a new backfill run repository
+func NewBackfillRunRepo(client *Client) *BackfillRunRepo {
+	return &BackfillRunRepo{client: client}
+}
+
+// Save upserts run's checkpoint, stamping UpdatedAt
+func (r *BackfillRunRepo) Save(ctx context.Context, run *BackfillRun) error {
+	run.UpdatedAt = time.Now()
+	if run.StartedAt.IsZero() {
+		run.StartedAt = run.UpdatedAt
+	}
+
+	query := `
+		INSERT INTO backfill_runs (
+			run_id, symbol, timeframe, phase, last_candle_open_time, last_window_t_end,
+			milvus_batches, flushed_batches, started_at, updated_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (run_id) DO UPDATE SET
+			phase = EXCLUDED.phase,
+			last_candle_open_time = EXCLUDED.last_candle_open_time,
+			last_window_t_end = EXCLUDED.last_window_t_end,
+			milvus_batches = EXCLUDED.milvus_batches,
+			flushed_batches = EXCLUDED.flushed_batches,
+			started_at = EXCLUDED.started_at,
+			updated_at = EXCLUDED.updated_at
+	`
+	err := r.client.Exec(query, run.RunID, run.Symbol, run.Timeframe, run.Phase,
+		nullTime(run.LastCandleOpenTime), nullTime(run.LastWindowTEnd),
+		run.MilvusBatches, run.FlushedBatches, run.StartedAt, run.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save backfill run: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves the checkpoint of runID
+// Returns sql.ErrNoRows (wrapped) if the run has never been saved
+func (r *BackfillRunRepo) Get(ctx context.Context, runID string) (*BackfillRun, error) {
+	var lastCandle, lastWindow sql.NullTime
+	run := &BackfillRun{RunID: runID}
+	err := r.client.QueryRow(`
+		SELECT symbol, timeframe, phase, last_candle_open_time, last_window_t_end,
+		       milvus_batches, flushed_batches, started_at, updated_at
+		FROM backfill_runs
+		WHERE run_id = ?
+	`, runID).Scan(&run.Symbol, &run.Timeframe, &run.Phase, &lastCandle, &lastWindow,
+		&run.MilvusBatches, &run.FlushedBatches, &run.StartedAt, &run.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backfill run: %w", err)
+	}
+
+	run.LastCandleOpenTime = lastCandle.Time
+	run.LastWindowTEnd = lastWindow.Time
+	return run, nil
+}
+
+// nullTime maps the zero time to NULL
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,67 @@
+package duckdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Calibration is a persisted similarity score calibration
+type Calibration struct {
+	Key         string
+	Quantiles   []float64
+	SamplePairs int64
+	CreatedAt   time.Time
+}
+
+// CalibrationRepo handles score calibration persistence
+type CalibrationRepo struct {
+	client *Client
+}
+
+// NewCalibrationRepo creates a new calibration repository
+func NewCalibrationRepo(client *Client) *CalibrationRepo {
+	return &CalibrationRepo{client: client}
+}
+
+// Save upserts the calibration stored under key
+func (r *CalibrationRepo) Save(ctx context.Context, key string, quantiles []float64, samplePairs int64) error {
+	encoded, err := json.Marshal(quantiles)
+	if err != nil {
+		return fmt.Errorf("failed to encode quantiles: %w", err)
+	}
+
+	query := `
+		INSERT INTO score_calibrations (calibration_key, quantiles, sample_pairs, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (calibration_key) DO UPDATE SET
+			quantiles = EXCLUDED.quantiles,
+			sample_pairs = EXCLUDED.sample_pairs,
+			created_at = EXCLUDED.created_at
+	`
+	if err := r.client.Exec(query, key, string(encoded), samplePairs, time.Now()); err != nil {
+		return fmt.Errorf("failed to save calibration: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves the calibration stored under key
+// Returns sql.ErrNoRows (wrapped) if none has been saved
+func (r *CalibrationRepo) Get(ctx context.Context, key string) (*Calibration, error) {
+	var encoded string
+	c := &Calibration{Key: key}
+	err := r.client.QueryRow(`
+		SELECT quantiles, sample_pairs, created_at
+		FROM score_calibrations
+		WHERE calibration_key = ?
+	`, key).Scan(&encoded, &c.SamplePairs, &c.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calibration: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(encoded), &c.Quantiles); err != nil {
+		return nil, fmt.Errorf("failed to decode quantiles: %w", err)
+	}
+	return c, nil
+}
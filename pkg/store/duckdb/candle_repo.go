@@ -2,7 +2,10 @@ package duckdb
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/tunogya/etna/pkg/model"
@@ -123,6 +126,67 @@ func (r *CandleRepo) GetByTimeRange(ctx context.Context, symbol, timeframe strin
 	return candles, nil
 }
 
+// GetByCloseTimeRange retrieves candles whose close time lies within [start, end], ordered by open time
+// Use it when range boundaries come from close times, e.g. exchange data indexed by close time
+func (r *CandleRepo) GetByCloseTimeRange(ctx context.Context, symbol, timeframe string, start, end time.Time) ([]model.Candle, error) {
+	query := `
+		SELECT symbol, timeframe, open_time, close_time, open, high, low, close, volume, trades, vwap
+		FROM candles
+		WHERE symbol = ? AND timeframe = ? AND close_time BETWEEN ? AND ?
+		ORDER BY open_time ASC
+	`
+
+	rows, err := r.client.Query(query, symbol, timeframe, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query candles: %w", err)
+	}
+	defer rows.Close()
+
+	var candles []model.Candle
+	for rows.Next() {
+		var c model.Candle
+		var closeTime, vwap interface{}
+		var trades interface{}
+
+		err := rows.Scan(
+			&c.Symbol, &c.Timeframe, &c.OpenTime, &closeTime,
+			&c.Open, &c.High, &c.Low, &c.Close, &c.Volume, &trades, &vwap,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan candle: %w", err)
+		}
+
+		if ct, ok := closeTime.(time.Time); ok {
+			c.CloseTime = ct
+		}
+		if t, ok := trades.(int64); ok {
+			c.Trades = t
+		}
+		if v, ok := vwap.(float64); ok {
+			c.VWAP = v
+		}
+
+		candles = append(candles, c)
+	}
+
+	return candles, nil
+}
+
+// GetCandlesWithVWAPFallback retrieves candles within a time range, substituting the typical price for missing VWAP
+func (r *CandleRepo) GetCandlesWithVWAPFallback(ctx context.Context, symbol, timeframe string, start, end time.Time) ([]model.Candle, error) {
+	candles, err := r.GetByTimeRange(ctx, symbol, timeframe, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range candles {
+		if candles[i].VWAP == 0 {
+			candles[i].VWAP = candles[i].TypicalPrice()
+		}
+	}
+	return candles, nil
+}
+
 // GetLatest retrieves the most recent N candles
 func (r *CandleRepo) GetLatest(ctx context.Context, symbol, timeframe string, limit int) ([]model.Candle, error) {
 	query := `
@@ -174,6 +238,117 @@ func (r *CandleRepo) GetLatest(ctx context.Context, symbol, timeframe string, li
 	return candles, nil
 }
 
+// GetWindowCandles retrieves the N candles ending at tEnd (inclusive), in chronological order
+// Used to hydrate windows loaded from the windows table, which does not store candles
+func (r *CandleRepo) GetWindowCandles(ctx context.Context, symbol, timeframe string, tEnd time.Time, limit int) ([]model.Candle, error) {
+	query := `
+		SELECT symbol, timeframe, open_time, close_time, open, high, low, close, volume, trades, vwap
+		FROM candles
+		WHERE symbol = ? AND timeframe = ? AND open_time < ?
+		ORDER BY open_time DESC
+		LIMIT ?
+	`
+
+	rows, err := r.client.Query(query, symbol, timeframe, tEnd, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query candles: %w", err)
+	}
+	defer rows.Close()
+
+	var candles []model.Candle
+	for rows.Next() {
+		var c model.Candle
+		var closeTime, vwap interface{}
+		var trades interface{}
+
+		err := rows.Scan(
+			&c.Symbol, &c.Timeframe, &c.OpenTime, &closeTime,
+			&c.Open, &c.High, &c.Low, &c.Close, &c.Volume, &trades, &vwap,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan candle: %w", err)
+		}
+
+		if ct, ok := closeTime.(time.Time); ok {
+			c.CloseTime = ct
+		}
+		if t, ok := trades.(int64); ok {
+			c.Trades = t
+		}
+		if v, ok := vwap.(float64); ok {
+			c.VWAP = v
+		}
+
+		candles = append(candles, c)
+	}
+
+	// Reverse to get chronological order
+	for i, j := 0, len(candles)-1; i < j; i, j = i+1, j-1 {
+		candles[i], candles[j] = candles[j], candles[i]
+	}
+
+	return candles, nil
+}
+
+// GetOHLCVAggregated aggregates candles in [start, end] into bars of bucketDuration using DuckDB's time_bucket
+// Buckets are aligned to the Unix epoch; returned candles have Timeframe set to bucketDuration.String()
+func (r *CandleRepo) GetOHLCVAggregated(ctx context.Context, symbol, timeframe string, start, end time.Time, bucketDuration time.Duration) ([]model.Candle, error) {
+	if bucketDuration <= 0 {
+		return nil, fmt.Errorf("bucket duration must be positive, got %s", bucketDuration)
+	}
+
+	query := `
+		SELECT
+			time_bucket(to_microseconds(?), open_time, TIMESTAMP '1970-01-01') AS bucket,
+			MAX(close_time),
+			arg_min(open, open_time),
+			MAX(high),
+			MIN(low),
+			arg_max(close, open_time),
+			SUM(volume),
+			SUM(trades)::BIGINT,
+			SUM(vwap * volume) / NULLIF(SUM(CASE WHEN vwap IS NOT NULL THEN volume END), 0)
+		FROM candles
+		WHERE symbol = ? AND timeframe = ? AND open_time >= ? AND open_time <= ?
+		GROUP BY bucket
+		ORDER BY bucket ASC
+	`
+
+	rows, err := r.client.Query(query, bucketDuration.Microseconds(), symbol, timeframe, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query aggregated candles: %w", err)
+	}
+	defer rows.Close()
+
+	var candles []model.Candle
+	for rows.Next() {
+		c := model.Candle{Symbol: symbol, Timeframe: bucketDuration.String()}
+		var closeTime, trades, vwap interface{}
+
+		err := rows.Scan(
+			&c.OpenTime, &closeTime,
+			&c.Open, &c.High, &c.Low, &c.Close, &c.Volume, &trades, &vwap,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan aggregated candle: %w", err)
+		}
+
+		if ct, ok := closeTime.(time.Time); ok {
+			c.CloseTime = ct
+		}
+		if t, ok := trades.(int64); ok {
+			c.Trades = t
+		}
+		if v, ok := vwap.(float64); ok {
+			c.VWAP = v
+		}
+
+		candles = append(candles, c)
+	}
+
+	return candles, nil
+}
+
 // Count returns the total number of candles for a symbol/timeframe
 func (r *CandleRepo) Count(ctx context.Context, symbol, timeframe string) (int64, error) {
 	var count int64
@@ -184,3 +359,132 @@ func (r *CandleRepo) Count(ctx context.Context, symbol, timeframe string) (int64
 	err := row.Scan(&count)
 	return count, err
 }
+
+// ClosePriceMatrix holds close prices of several symbols aligned on shared open times
+type ClosePriceMatrix struct {
+	Timestamps []time.Time // Open times present for at least one symbol
+	Symbols    []string
+	Values     [][]float64 // Values[symbol][timestamp]; forward-filled, NaN before a symbol's first candle
+}
+
+// GetClosePriceMatrix pivots the close prices of symbols within [start, end] into one row per symbol
+// Gaps such as trading halts are forward-filled from the symbol's previous close
+func (r *CandleRepo) GetClosePriceMatrix(ctx context.Context, symbols []string, timeframe string, start, end time.Time) (*ClosePriceMatrix, error) {
+	if len(symbols) == 0 {
+		return nil, fmt.Errorf("close price matrix needs at least one symbol")
+	}
+
+	columns := make([]string, len(symbols))
+	placeholders := make([]string, len(symbols))
+	args := make([]interface{}, 0, 2*len(symbols)+3)
+	for i, s := range symbols {
+		columns[i] = "MAX(CASE WHEN symbol = ? THEN close END)"
+		placeholders[i] = "?"
+		args = append(args, s)
+	}
+	for _, s := range symbols {
+		args = append(args, s)
+	}
+	args = append(args, timeframe, start, end)
+
+	query := fmt.Sprintf(`
+		SELECT open_time, %s
+		FROM candles
+		WHERE symbol IN (%s) AND timeframe = ? AND open_time >= ? AND open_time <= ?
+		GROUP BY open_time
+		ORDER BY open_time ASC
+	`, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+
+	rows, err := r.client.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query close prices: %w", err)
+	}
+	defer rows.Close()
+
+	m := &ClosePriceMatrix{
+		Symbols: append([]string(nil), symbols...),
+		Values:  make([][]float64, len(symbols)),
+	}
+	closes := make([]sql.NullFloat64, len(symbols))
+	dest := make([]interface{}, len(symbols)+1)
+	for i := range closes {
+		dest[i+1] = &closes[i]
+	}
+
+	for rows.Next() {
+		var openTime time.Time
+		dest[0] = &openTime
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan close prices: %w", err)
+		}
+
+		t := len(m.Timestamps)
+		m.Timestamps = append(m.Timestamps, openTime)
+		for i, c := range closes {
+			v := math.NaN()
+			switch {
+			case c.Valid:
+				v = c.Float64
+			case t > 0:
+				v = m.Values[i][t-1] // Forward-fill
+			}
+			m.Values[i] = append(m.Values[i], v)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read close prices: %w", err)
+	}
+
+	return m, nil
+}
+
+// ComputeCorrelationMatrix returns the pairwise Pearson correlations of the symbols' close prices
+// Each pair uses the timestamps where both have a value; it is NaN with fewer than two or a constant series
+func ComputeCorrelationMatrix(m *ClosePriceMatrix) [][]float64 {
+	n := len(m.Values)
+	corr := make([][]float64, n)
+	for i := range corr {
+		corr[i] = make([]float64, n)
+	}
+
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			v := pearson(m.Values[i], m.Values[j])
+			corr[i][j] = v
+			corr[j][i] = v
+		}
+	}
+	return corr
+}
+
+// pearson returns the Pearson correlation of x and y over indexes where neither is NaN
+func pearson(x, y []float64) float64 {
+	var n, sumX, sumY float64
+	for i := range x {
+		if math.IsNaN(x[i]) || math.IsNaN(y[i]) {
+			continue
+		}
+		n++
+		sumX += x[i]
+		sumY += y[i]
+	}
+	if n < 2 {
+		return math.NaN()
+	}
+
+	meanX, meanY := sumX/n, sumY/n
+	var cov, varX, varY float64
+	for i := range x {
+		if math.IsNaN(x[i]) || math.IsNaN(y[i]) {
+			continue
+		}
+		dx, dy := x[i]-meanX, y[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return math.NaN()
+	}
+	return cov / math.Sqrt(varX*varY)
+}
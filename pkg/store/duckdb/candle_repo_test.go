@@ -0,0 +1,183 @@
+package duckdb
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// seedCandles stores the candles of makeCandles
+func seedCandles(t testing.TB, c *Client, symbol, timeframe string, start time.Time, n int) []model.Candle {
+	t.Helper()
+	candles := makeCandles(t, symbol, timeframe, start, n)
+	if err := NewCandleRepo(c).InsertBatch(context.Background(), candles); err != nil {
+		t.Fatalf("InsertBatch candles: %v", err)
+	}
+	return candles
+}
+
+// makeCandles returns n consecutive candles of symbol/timeframe from start, closing at 100, 101, ...
+// Each has a one-unit range around its close, volume 1 and 10 trades
+func makeCandles(t testing.TB, symbol, timeframe string, start time.Time, n int) []model.Candle {
+	t.Helper()
+	bar, err := model.TimeframeDuration(timeframe)
+	if err != nil {
+		t.Fatalf("TimeframeDuration(%q): %v", timeframe, err)
+	}
+
+	candles := make([]model.Candle, n)
+	for i := range candles {
+		open := start.Add(time.Duration(i) * bar)
+		price := 100 + float64(i)
+		candles[i] = model.Candle{
+			Symbol:    symbol,
+			Timeframe: timeframe,
+			OpenTime:  open,
+			CloseTime: open.Add(bar - time.Millisecond),
+			Open:      price - 0.5,
+			High:      price + 1,
+			Low:       price - 1,
+			Close:     price,
+			Volume:    1,
+			Trades:    10,
+			VWAP:      price,
+		}
+	}
+	return candles
+}
+
+func TestGetOHLCVAggregatedHourly(t *testing.T) {
+	c := newTestClient(t)
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	candles := seedCandles(t, c, "BTCUSDT", "1m", start, 60)
+
+	bars, err := NewCandleRepo(c).GetOHLCVAggregated(context.Background(), "BTCUSDT", "1m", start, start.Add(time.Hour), time.Hour)
+	if err != nil {
+		t.Fatalf("GetOHLCVAggregated: %v", err)
+	}
+	if len(bars) != 1 {
+		t.Fatalf("got %d bars, want 1", len(bars))
+	}
+
+	b := bars[0]
+	first, last := candles[0], candles[59]
+	if !b.OpenTime.Equal(start) || !b.CloseTime.Equal(last.CloseTime) {
+		t.Errorf("bar spans %s to %s, want %s to %s", b.OpenTime, b.CloseTime, start, last.CloseTime)
+	}
+	if b.Open != first.Open || b.High != last.High || b.Low != first.Low || b.Close != last.Close {
+		t.Errorf("OHLC = %v/%v/%v/%v, want %v/%v/%v/%v", b.Open, b.High, b.Low, b.Close, first.Open, last.High, first.Low, last.Close)
+	}
+	if b.Volume != 60 || b.Trades != 600 {
+		t.Errorf("volume/trades = %v/%d, want 60/600", b.Volume, b.Trades)
+	}
+	if b.VWAP != 129.5 { // Mean of closes 100..159 at equal volume
+		t.Errorf("VWAP = %v, want 129.5", b.VWAP)
+	}
+	if b.Timeframe != time.Hour.String() {
+		t.Errorf("Timeframe = %q, want %q", b.Timeframe, time.Hour.String())
+	}
+}
+
+func TestGetOHLCVAggregatedSplitsBuckets(t *testing.T) {
+	c := newTestClient(t)
+	start := time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)
+	seedCandles(t, c, "BTCUSDT", "1m", start, 90)
+
+	// 10:30-12:00 covers the second half of the 10:00 bucket and the whole 11:00 bucket
+	bars, err := NewCandleRepo(c).GetOHLCVAggregated(context.Background(), "BTCUSDT", "1m", start, start.Add(2*time.Hour), time.Hour)
+	if err != nil {
+		t.Fatalf("GetOHLCVAggregated: %v", err)
+	}
+	if len(bars) != 2 {
+		t.Fatalf("got %d bars, want 2", len(bars))
+	}
+	if want := start.Truncate(time.Hour); !bars[0].OpenTime.Equal(want) || bars[0].Volume != 30 {
+		t.Errorf("first bar opens %s with volume %v, want %s with 30", bars[0].OpenTime, bars[0].Volume, want)
+	}
+	if bars[1].Volume != 60 {
+		t.Errorf("second bar volume = %v, want 60", bars[1].Volume)
+	}
+
+	if _, err := NewCandleRepo(c).GetOHLCVAggregated(context.Background(), "BTCUSDT", "1m", start, start, 0); err == nil {
+		t.Error("zero bucket duration succeeded")
+	}
+}
+
+func TestGetByCloseTimeRangeBoundaries(t *testing.T) {
+	c := newTestClient(t)
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	candles := seedCandles(t, c, "BTCUSDT", "1m", start, 10)
+	seedCandles(t, c, "ETHUSDT", "1m", start, 10)
+	repo := NewCandleRepo(c)
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		first      int
+		n          int
+	}{
+		{"both ends inclusive", candles[2].CloseTime, candles[5].CloseTime, 2, 4},
+		{"start just after a close", candles[2].CloseTime.Add(time.Millisecond), candles[5].CloseTime, 3, 3},
+		{"end just before a close", candles[2].CloseTime, candles[5].CloseTime.Add(-time.Millisecond), 2, 3},
+		// The open time of candle 5 lies before its close, so candle 5 is left out
+		{"open time bounds", candles[2].OpenTime, candles[5].OpenTime, 2, 3},
+		{"single close", candles[7].CloseTime, candles[7].CloseTime, 7, 1},
+		{"before first close", start, candles[0].CloseTime.Add(-time.Millisecond), 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.GetByCloseTimeRange(context.Background(), "BTCUSDT", "1m", tt.start, tt.end)
+			if err != nil {
+				t.Fatalf("GetByCloseTimeRange: %v", err)
+			}
+			if len(got) != tt.n {
+				t.Fatalf("got %d candles, want %d", len(got), tt.n)
+			}
+			for i, candle := range got {
+				if want := candles[tt.first+i]; candle.Symbol != "BTCUSDT" || !candle.CloseTime.Equal(want.CloseTime) {
+					t.Errorf("candle %d = %s closing %s, want BTCUSDT closing %s", i, candle.Symbol, candle.CloseTime, want.CloseTime)
+				}
+			}
+		})
+	}
+}
+
+func TestGetClosePriceMatrixForwardFills(t *testing.T) {
+	c := newTestClient(t)
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	seedCandles(t, c, "BTCUSDT", "1m", start, 5)
+	// ETHUSDT starts a minute late and halts after two candles
+	seedCandles(t, c, "ETHUSDT", "1m", start.Add(time.Minute), 2)
+
+	m, err := NewCandleRepo(c).GetClosePriceMatrix(context.Background(), []string{"BTCUSDT", "ETHUSDT"}, "1m", start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetClosePriceMatrix: %v", err)
+	}
+	if len(m.Timestamps) != 5 || !m.Timestamps[0].Equal(start) || !m.Timestamps[4].Equal(start.Add(4*time.Minute)) {
+		t.Fatalf("timestamps = %v, want 5 minutes from %s", m.Timestamps, start)
+	}
+	if !reflect.DeepEqual(m.Symbols, []string{"BTCUSDT", "ETHUSDT"}) {
+		t.Errorf("Symbols = %v", m.Symbols)
+	}
+
+	want := [][]float64{
+		{100, 101, 102, 103, 104},
+		{math.NaN(), 100, 101, 101, 101},
+	}
+	for s := range want {
+		for i, w := range want[s] {
+			got := m.Values[s][i]
+			if got != w && !(math.IsNaN(got) && math.IsNaN(w)) {
+				t.Errorf("Values[%s][%d] = %v, want %v", m.Symbols[s], i, got, w)
+			}
+		}
+	}
+
+	if _, err := NewCandleRepo(c).GetClosePriceMatrix(context.Background(), nil, "1m", start, start); err == nil {
+		t.Error("no symbols succeeded")
+	}
+}
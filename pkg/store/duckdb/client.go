@@ -1,8 +1,15 @@
 package duckdb
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 
 	_ "github.com/marcboeker/go-duckdb"
 )
@@ -34,6 +41,40 @@ func NewClient(path string) (*Client, error) {
 	return client, nil
 }
 
+// Bulk-write and read pragmas; see ConfigureForBulkWrite and ConfigureForRead
+var (
+	bulkWritePragmas = []string{
+		"PRAGMA threads=4",
+		"PRAGMA memory_limit='2GB'",
+		"PRAGMA checkpoint_threshold='1GiB'", // Checkpoint the WAL once it reaches 1073741824 bytes
+	}
+	readPragmas = []string{
+		"PRAGMA threads=8",
+		"PRAGMA enable_progress_bar=false",
+	}
+)
+
+// ConfigureForBulkWrite tunes the database for high-volume inserts such as backfill
+// A large checkpoint threshold lets the WAL grow instead of checkpointing during the load
+func ConfigureForBulkWrite(c *Client) error {
+	return c.execPragmas(bulkWritePragmas)
+}
+
+// ConfigureForRead tunes the database for concurrent read queries
+func ConfigureForRead(c *Client) error {
+	return c.execPragmas(readPragmas)
+}
+
+// execPragmas executes pragmas in order, stopping at the first failure
+func (c *Client) execPragmas(pragmas []string) error {
+	for _, pragma := range pragmas {
+		if _, err := c.db.Exec(pragma); err != nil {
+			return fmt.Errorf("failed to execute %q: %w", pragma, err)
+		}
+	}
+	return nil
+}
+
 // DB returns the underlying sql.DB connection
 func (c *Client) DB() *sql.DB {
 	return c.db
@@ -67,3 +108,129 @@ func (c *Client) QueryRow(query string, args ...interface{}) *sql.Row {
 func (c *Client) Begin() (*sql.Tx, error) {
 	return c.db.Begin()
 }
+
+// QueryProfile summarizes an EXPLAIN ANALYZE run of a query
+type QueryProfile struct {
+	Plan         string  // Rendered analyzed plan
+	TotalTimeMs  float64 // Total execution time reported by DuckDB
+	RowsReturned int64   // Rows produced by the plan's root operator
+}
+
+// Patterns of the total time header and the per-operator row counts in DuckDB's rendered plan
+var (
+	planTotalTime = regexp.MustCompile(`Total Time: ([0-9.]+)s`)
+	planRows      = regexp.MustCompile(`(\d+) Rows`)
+)
+
+// ExplainQuery runs the query under EXPLAIN ANALYZE and returns the rendered plan
+// The query is executed, so statements with side effects take effect
+func (c *Client) ExplainQuery(query string, args ...interface{}) (string, error) {
+	rows, err := c.db.Query("EXPLAIN ANALYZE "+query, args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to explain query: %w", err)
+	}
+	defer rows.Close()
+
+	var plan []string
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return "", fmt.Errorf("failed to scan query plan: %w", err)
+		}
+		plan = append(plan, value)
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("failed to read query plan: %w", err)
+	}
+	return strings.Join(plan, "\n"), nil
+}
+
+// ProfileQuery runs the query under EXPLAIN ANALYZE and parses the total time and returned rows from the plan
+func (c *Client) ProfileQuery(query string, args ...interface{}) (*QueryProfile, error) {
+	plan, err := c.ExplainQuery(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &QueryProfile{Plan: plan}
+	if m := planTotalTime.FindStringSubmatch(plan); m != nil {
+		seconds, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse total time %q: %w", m[1], err)
+		}
+		profile.TotalTimeMs = seconds * 1000
+	}
+
+	// The EXPLAIN_ANALYZE wrapper reports 0 rows; the first operator below it is the query's root
+	operators := plan
+	if i := strings.Index(plan, "EXPLAIN_ANALYZE"); i >= 0 {
+		if m := planRows.FindStringIndex(plan[i:]); m != nil {
+			operators = plan[i+m[1]:]
+		}
+	}
+	if m := planRows.FindStringSubmatch(operators); m != nil {
+		rows, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse row count %q: %w", m[1], err)
+		}
+		profile.RowsReturned = rows
+	}
+	return profile, nil
+}
+
+// ExecuteScript reads a .sql file, splits it on ';' and executes each statement in order
+// Execution stops at the first failing statement, whose text is included in the error
+func (c *Client) ExecuteScript(ctx context.Context, scriptPath string) error {
+	content, err := os.ReadFile(scriptPath)
+	if err != nil {
+		return fmt.Errorf("failed to read script %s: %w", scriptPath, err)
+	}
+
+	for _, stmt := range strings.Split(string(content), ";") {
+		stmt = strings.TrimSpace(stmt)
+		if stmt == "" || isCommentOnly(stmt) {
+			continue
+		}
+		if _, err := c.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to execute statement in %s: %q: %w", scriptPath, stmt, err)
+		}
+	}
+
+	return nil
+}
+
+// ExecuteDirectory executes every .sql file in dirPath in lexicographic order
+func (c *Client) ExecuteDirectory(ctx context.Context, dirPath string) error {
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return fmt.Errorf("failed to read directory %s: %w", dirPath, err)
+	}
+
+	var scripts []string
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		scripts = append(scripts, entry.Name())
+	}
+	sort.Strings(scripts)
+
+	for _, name := range scripts {
+		if err := c.ExecuteScript(ctx, filepath.Join(dirPath, name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isCommentOnly returns true if every non-blank line of stmt is a "--" comment
+func isCommentOnly(stmt string) bool {
+	for _, line := range strings.Split(stmt, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,177 @@
+package duckdb
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client of a fresh in-memory database with the full schema, closed when the test ends
+func newTestClient(t testing.TB) *Client {
+	t.Helper()
+	c, err := NewClient("")
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	if err := InitializeSchema(c); err != nil {
+		t.Fatalf("InitializeSchema: %v", err)
+	}
+	return c
+}
+
+// writeScript writes a .sql file named name into dir
+func writeScript(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestExecuteScriptRunsEveryStatement(t *testing.T) {
+	c := newTestClient(t)
+	path := writeScript(t, t.TempDir(), "seed.sql", `
+		-- Two statements, the second depending on the first
+		CREATE TABLE notes (id INTEGER, body VARCHAR);
+		INSERT INTO notes VALUES (1, 'first'), (2, 'second');
+	`)
+
+	if err := c.ExecuteScript(context.Background(), path); err != nil {
+		t.Fatalf("ExecuteScript: %v", err)
+	}
+	var n int
+	if err := c.QueryRow("SELECT COUNT(*) FROM notes").Scan(&n); err != nil {
+		t.Fatalf("count notes: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("notes has %d rows, want 2", n)
+	}
+}
+
+func TestExecuteScriptStopsAtFailingStatement(t *testing.T) {
+	c := newTestClient(t)
+	path := writeScript(t, t.TempDir(), "broken.sql", `
+		CREATE TABLE notes (id INTEGER);
+		INSERT INTO missing VALUES (1);
+		CREATE TABLE never (id INTEGER);
+	`)
+
+	err := c.ExecuteScript(context.Background(), path)
+	if err == nil || !strings.Contains(err.Error(), "INSERT INTO missing") {
+		t.Fatalf("ExecuteScript error = %v, want one naming the failing statement", err)
+	}
+	if err := c.Exec("SELECT * FROM never"); err == nil {
+		t.Error("statement after the failure was executed")
+	}
+}
+
+func TestExecuteDirectoryRunsScriptsInOrder(t *testing.T) {
+	c := newTestClient(t)
+	dir := t.TempDir()
+	writeScript(t, dir, "002_insert.sql", "INSERT INTO notes VALUES (1);")
+	writeScript(t, dir, "001_create.sql", "CREATE TABLE notes (id INTEGER);")
+	writeScript(t, dir, "README.md", "not sql")
+
+	if err := c.ExecuteDirectory(context.Background(), dir); err != nil {
+		t.Fatalf("ExecuteDirectory: %v", err)
+	}
+	var n int
+	if err := c.QueryRow("SELECT COUNT(*) FROM notes").Scan(&n); err != nil || n != 1 {
+		t.Errorf("notes has %d rows (%v), want 1", n, err)
+	}
+}
+
+func TestConfigureForBulkWriteAppliesPragmas(t *testing.T) {
+	c := newTestClient(t)
+	if err := ConfigureForBulkWrite(c); err != nil {
+		t.Fatalf("ConfigureForBulkWrite: %v", err)
+	}
+
+	var threads int64
+	if err := c.QueryRow("SELECT current_setting('threads')").Scan(&threads); err != nil {
+		t.Fatalf("read threads: %v", err)
+	}
+	if threads != 4 {
+		t.Errorf("threads = %d, want 4", threads)
+	}
+}
+
+func BenchmarkCandleInsertBulkWrite(b *testing.B) {
+	candles := makeCandles(b, "BTCUSDT", "1m", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 10000)
+
+	for _, bm := range []struct {
+		name      string
+		configure func(*Client) error
+	}{
+		{"Default", func(*Client) error { return nil }},
+		{"BulkWrite", ConfigureForBulkWrite},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			// A file-backed database, since the checkpoint threshold only matters with a WAL
+			c, err := NewClient(filepath.Join(b.TempDir(), "bench.duckdb"))
+			if err != nil {
+				b.Fatalf("NewClient: %v", err)
+			}
+			defer c.Close()
+			if err := InitializeSchema(c); err != nil {
+				b.Fatalf("InitializeSchema: %v", err)
+			}
+			if err := bm.configure(c); err != nil {
+				b.Fatalf("configure: %v", err)
+			}
+
+			repo := NewCandleRepo(c)
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				if err := c.Exec("DELETE FROM candles"); err != nil {
+					b.Fatalf("clear candles: %v", err)
+				}
+				b.StartTimer()
+
+				if err := repo.InsertBatch(context.Background(), candles); err != nil {
+					b.Fatalf("InsertBatch: %v", err)
+				}
+			}
+			b.ReportMetric(float64(len(candles)*b.N)/b.Elapsed().Seconds(), "candles/s")
+		})
+	}
+}
+
+func TestExplainQueryReturnsPlan(t *testing.T) {
+	c := newTestClient(t)
+
+	plan, err := c.ExplainQuery("SELECT 1")
+	if err != nil {
+		t.Fatalf("ExplainQuery: %v", err)
+	}
+	if strings.TrimSpace(plan) == "" {
+		t.Error("ExplainQuery returned an empty plan")
+	}
+}
+
+// TestProfileQueryParsesPlan guards the plan patterns against changes in DuckDB's rendered output
+func TestProfileQueryParsesPlan(t *testing.T) {
+	c := newTestClient(t)
+	if err := c.Exec("CREATE TABLE notes AS SELECT range AS id FROM range(100)"); err != nil {
+		t.Fatalf("create notes: %v", err)
+	}
+
+	profile, err := c.ProfileQuery("SELECT id FROM notes WHERE id >= ?", 90)
+	if err != nil {
+		t.Fatalf("ProfileQuery: %v", err)
+	}
+	if !planTotalTime.MatchString(profile.Plan) {
+		t.Errorf("plan has no total time matching %s:\n%s", planTotalTime, profile.Plan)
+	}
+	if profile.RowsReturned != 10 {
+		t.Errorf("RowsReturned = %d, want 10\n%s", profile.RowsReturned, profile.Plan)
+	}
+	if profile.TotalTimeMs < 0 {
+		t.Errorf("TotalTimeMs = %v, want >= 0", profile.TotalTimeMs)
+	}
+}
@@ -2,7 +2,12 @@ package duckdb
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"time"
 
 	"github.com/tunogya/etna/pkg/model"
 )
@@ -17,28 +22,116 @@ func NewFeatureRepo(client *Client) *FeatureRepo {
 	return &FeatureRepo{client: client}
 }
 
-// Insert inserts a single feature row
-func (r *FeatureRepo) Insert(ctx context.Context, f *model.FeatureRow) error {
-	query := `
-		INSERT INTO window_features (
-			window_id, trend_slope, realized_volatility, max_drawdown,
-			atr, vol_z_score, vol_bucket, trend_bucket, data_version
-		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-		ON CONFLICT (window_id) DO UPDATE SET
-			trend_slope = EXCLUDED.trend_slope,
-			realized_volatility = EXCLUDED.realized_volatility,
-			max_drawdown = EXCLUDED.max_drawdown,
-			atr = EXCLUDED.atr,
-			vol_z_score = EXCLUDED.vol_z_score,
-			vol_bucket = EXCLUDED.vol_bucket,
-			trend_bucket = EXCLUDED.trend_bucket,
-			data_version = EXCLUDED.data_version
-	`
-	return r.client.Exec(query,
+// insertFeatureSQL is the INSERT clause of every feature row write, up to VALUES
+const insertFeatureSQL = `
+	INSERT INTO window_features (
+		window_id, trend_slope, realized_volatility, max_drawdown,
+		atr, vol_z_score, vol_bucket, trend_bucket, data_version,
+		keltner_position, williams_r14, aroon_up, aroon_down, aroon_oscillator,
+		return_entropy, roc10, roc20, bull_power, bear_power, fractal_dim,
+		pivot_position, bid_ask_spread
+	)
+	VALUES `
+
+// featureRowPlaceholders binds one row of featureArgs
+const featureRowPlaceholders = `(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// featureConflictSQL replaces any existing row for the same window
+// bid_ask_spread comes from OrderBookRepo.ApplySpreadFeatures rather than extraction, so a zero keeps the stored spread
+const featureConflictSQL = `
+	ON CONFLICT (window_id) DO UPDATE SET
+		trend_slope = EXCLUDED.trend_slope,
+		realized_volatility = EXCLUDED.realized_volatility,
+		max_drawdown = EXCLUDED.max_drawdown,
+		atr = EXCLUDED.atr,
+		vol_z_score = EXCLUDED.vol_z_score,
+		vol_bucket = EXCLUDED.vol_bucket,
+		trend_bucket = EXCLUDED.trend_bucket,
+		data_version = EXCLUDED.data_version,
+		keltner_position = EXCLUDED.keltner_position,
+		williams_r14 = EXCLUDED.williams_r14,
+		aroon_up = EXCLUDED.aroon_up,
+		aroon_down = EXCLUDED.aroon_down,
+		aroon_oscillator = EXCLUDED.aroon_oscillator,
+		return_entropy = EXCLUDED.return_entropy,
+		roc10 = EXCLUDED.roc10,
+		roc20 = EXCLUDED.roc20,
+		bull_power = EXCLUDED.bull_power,
+		bear_power = EXCLUDED.bear_power,
+		fractal_dim = EXCLUDED.fractal_dim,
+		pivot_position = EXCLUDED.pivot_position,
+		bid_ask_spread = COALESCE(NULLIF(EXCLUDED.bid_ask_spread, 0), window_features.bid_ask_spread)
+`
+
+// upsertFeatureSQL inserts a feature row, replacing any existing row for the same window
+const upsertFeatureSQL = insertFeatureSQL + featureRowPlaceholders + featureConflictSQL
+
+// upsertBatchRows is the number of rows bound per multi-row UpsertBatch statement
+// Binding cost grows faster than linearly with statement size; around 20 rows is several times
+// faster than one prepared row at a time, while 500 rows is slower than either
+const upsertBatchRows = 20
+
+// upsertBatchAttempts bounds the retries of an UpsertBatch statement after a write conflict with concurrent writers
+const upsertBatchAttempts = 8
+
+// upsertRetryDelay is the base backoff between conflicting UpsertBatch attempts, multiplied by the attempt and jittered
+const upsertRetryDelay = 5 * time.Millisecond
+
+// selectFeatureColumns lists the columns read by scanFeature, in order
+// Columns added by migrations are coalesced so older rows scan cleanly
+const selectFeatureColumns = `
+	window_id, trend_slope, realized_volatility, max_drawdown,
+	atr, vol_z_score, vol_bucket, trend_bucket, data_version,
+	COALESCE(keltner_position, 0), COALESCE(williams_r14, 0),
+	COALESCE(aroon_up, 0), COALESCE(aroon_down, 0), COALESCE(aroon_oscillator, 0),
+	COALESCE(return_entropy, 0), COALESCE(roc10, 0), COALESCE(roc20, 0),
+	COALESCE(bull_power, 0), COALESCE(bear_power, 0), COALESCE(fractal_dim, 0),
+	COALESCE(pivot_position, 0), COALESCE(bid_ask_spread, 0)
+`
+
+// featureArgs returns the upsertFeatureSQL arguments for a feature row
+func featureArgs(f *model.FeatureRow) []interface{} {
+	return []interface{}{
 		f.WindowID, f.TrendSlope, f.RealizedVolatility, f.MaxDrawdown,
 		f.ATR, f.VolZScore, f.VolBucket, f.TrendBucket, f.DataVersion,
-	)
+		f.KeltnerPosition, f.WilliamsR14,
+		f.AroonUp, f.AroonDown, f.AroonOscillator,
+		f.ReturnEntropy, f.ROC10, f.ROC20,
+		f.BullPower, f.BearPower, f.FractalDim,
+		f.PivotPosition, f.BidAskSpread,
+	}
+}
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// featureDest returns scan destinations for selectFeatureColumns, in order
+func featureDest(f *model.FeatureRow) []interface{} {
+	return []interface{}{
+		&f.WindowID, &f.TrendSlope, &f.RealizedVolatility, &f.MaxDrawdown,
+		&f.ATR, &f.VolZScore, &f.VolBucket, &f.TrendBucket, &f.DataVersion,
+		&f.KeltnerPosition, &f.WilliamsR14,
+		&f.AroonUp, &f.AroonDown, &f.AroonOscillator,
+		&f.ReturnEntropy, &f.ROC10, &f.ROC20,
+		&f.BullPower, &f.BearPower, &f.FractalDim,
+		&f.PivotPosition, &f.BidAskSpread,
+	}
+}
+
+// scanFeature scans a row selected with selectFeatureColumns into a FeatureRow
+func scanFeature(s rowScanner) (*model.FeatureRow, error) {
+	var f model.FeatureRow
+	if err := s.Scan(featureDest(&f)...); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// Insert inserts a single feature row
+func (r *FeatureRepo) Insert(ctx context.Context, f *model.FeatureRow) error {
+	return r.client.Exec(upsertFeatureSQL, featureArgs(f)...)
 }
 
 // InsertBatch inserts multiple feature rows in a transaction
@@ -49,33 +142,14 @@ func (r *FeatureRepo) InsertBatch(ctx context.Context, features []*model.Feature
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.Prepare(`
-		INSERT INTO window_features (
-			window_id, trend_slope, realized_volatility, max_drawdown,
-			atr, vol_z_score, vol_bucket, trend_bucket, data_version
-		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-		ON CONFLICT (window_id) DO UPDATE SET
-			trend_slope = EXCLUDED.trend_slope,
-			realized_volatility = EXCLUDED.realized_volatility,
-			max_drawdown = EXCLUDED.max_drawdown,
-			atr = EXCLUDED.atr,
-			vol_z_score = EXCLUDED.vol_z_score,
-			vol_bucket = EXCLUDED.vol_bucket,
-			trend_bucket = EXCLUDED.trend_bucket,
-			data_version = EXCLUDED.data_version
-	`)
+	stmt, err := tx.Prepare(upsertFeatureSQL)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
 	defer stmt.Close()
 
 	for _, f := range features {
-		_, err := stmt.Exec(
-			f.WindowID, f.TrendSlope, f.RealizedVolatility, f.MaxDrawdown,
-			f.ATR, f.VolZScore, f.VolBucket, f.TrendBucket, f.DataVersion,
-		)
-		if err != nil {
+		if _, err := stmt.Exec(featureArgs(f)...); err != nil {
 			return fmt.Errorf("failed to insert feature: %w", err)
 		}
 	}
@@ -83,33 +157,119 @@ func (r *FeatureRepo) InsertBatch(ctx context.Context, features []*model.Feature
 	return tx.Commit()
 }
 
+// UpsertBatch inserts or replaces multiple feature rows with multi-row VALUES statements
+// Unlike InsertBatch it binds upsertBatchRows rows per statement instead of executing a prepared statement per row,
+// and commits each statement on its own, so concurrent workers hold short transactions and only retry the rows
+// that raced. Rows repeating a window ID collapse to the last one, since DuckDB rejects updating the same row
+// twice in one statement. Conflicts follow featureConflictSQL (DuckDB's INSERT OR REPLACE, except that a zero
+// spread keeps the stored one). Upserts are idempotent, so an error may leave earlier statements applied
+func (r *FeatureRepo) UpsertBatch(ctx context.Context, features []*model.FeatureRow) error {
+	rows := dedupeFeatures(features)
+
+	for start := 0; start < len(rows); start += upsertBatchRows {
+		chunk := rows[start:min(start+upsertBatchRows, len(rows))]
+
+		placeholders := make([]string, len(chunk))
+		args := make([]interface{}, 0, len(chunk)*len(featureArgs(chunk[0])))
+		for i, f := range chunk {
+			placeholders[i] = featureRowPlaceholders
+			args = append(args, featureArgs(f)...)
+		}
+
+		query := insertFeatureSQL + strings.Join(placeholders, ", ") + featureConflictSQL
+		if err := r.execWithRetry(ctx, query, args); err != nil {
+			return fmt.Errorf("failed to upsert features: %w", err)
+		}
+	}
+	return nil
+}
+
+// execWithRetry executes query, retrying with jittered backoff while it conflicts with a concurrent transaction
+func (r *FeatureRepo) execWithRetry(ctx context.Context, query string, args []interface{}) error {
+	var err error
+	for attempt := 1; attempt <= upsertBatchAttempts; attempt++ {
+		if _, err = r.client.DB().ExecContext(ctx, query, args...); err == nil || !isWriteConflict(err) {
+			return err
+		}
+
+		delay := time.Duration(attempt) * upsertRetryDelay
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay + rand.N(delay)):
+		}
+	}
+	return err
+}
+
+// isWriteConflict reports whether err is DuckDB rejecting a write that raced a concurrent transaction
+func isWriteConflict(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "constraint violated") || strings.Contains(msg, "Conflict")
+}
+
+// dedupeFeatures keeps the last row of each window ID, in order of first appearance
+func dedupeFeatures(features []*model.FeatureRow) []*model.FeatureRow {
+	index := make(map[string]int, len(features))
+	rows := make([]*model.FeatureRow, 0, len(features))
+	for _, f := range features {
+		if i, ok := index[f.WindowID]; ok {
+			rows[i] = f
+			continue
+		}
+		index[f.WindowID] = len(rows)
+		rows = append(rows, f)
+	}
+	return rows
+}
+
 // GetByID retrieves a feature row by window ID
 func (r *FeatureRepo) GetByID(ctx context.Context, windowID string) (*model.FeatureRow, error) {
-	query := `
-		SELECT window_id, trend_slope, realized_volatility, max_drawdown,
-			   atr, vol_z_score, vol_bucket, trend_bucket, data_version
+	query := `SELECT ` + selectFeatureColumns + `
 		FROM window_features
 		WHERE window_id = ?
 	`
 
-	row := r.client.QueryRow(query, windowID)
-	var f model.FeatureRow
-	err := row.Scan(
-		&f.WindowID, &f.TrendSlope, &f.RealizedVolatility, &f.MaxDrawdown,
-		&f.ATR, &f.VolZScore, &f.VolBucket, &f.TrendBucket, &f.DataVersion,
-	)
+	return scanFeature(r.client.QueryRow(query, windowID))
+}
+
+// GetByIDs retrieves feature rows for the given windows; windows without features are omitted
+func (r *FeatureRepo) GetByIDs(ctx context.Context, windowIDs []string) ([]*model.FeatureRow, error) {
+	if len(windowIDs) == 0 {
+		return nil, nil
+	}
+
+	args := make([]interface{}, len(windowIDs))
+	for i, id := range windowIDs {
+		args[i] = id
+	}
+
+	query := `SELECT ` + selectFeatureColumns + `
+		FROM window_features
+		WHERE window_id IN (` + placeholders(len(windowIDs)) + `)
+	`
+
+	rows, err := r.client.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to query features: %w", err)
+	}
+	defer rows.Close()
+
+	var features []*model.FeatureRow
+	for rows.Next() {
+		f, err := scanFeature(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan feature: %w", err)
+		}
+		features = append(features, f)
 	}
 
-	return &f, nil
+	return features, nil
 }
 
 // GetByBuckets retrieves features matching specific bucket filters
 func (r *FeatureRepo) GetByBuckets(ctx context.Context, volBucket, trendBucket int, limit int) ([]*model.FeatureRow, error) {
-	query := `
-		SELECT window_id, trend_slope, realized_volatility, max_drawdown,
-			   atr, vol_z_score, vol_bucket, trend_bucket, data_version
+	query := `SELECT ` + selectFeatureColumns + `
 		FROM window_features
 		WHERE vol_bucket = ? AND trend_bucket = ?
 		LIMIT ?
@@ -123,16 +283,405 @@ func (r *FeatureRepo) GetByBuckets(ctx context.Context, volBucket, trendBucket i
 
 	var features []*model.FeatureRow
 	for rows.Next() {
-		var f model.FeatureRow
-		err := rows.Scan(
-			&f.WindowID, &f.TrendSlope, &f.RealizedVolatility, &f.MaxDrawdown,
-			&f.ATR, &f.VolZScore, &f.VolBucket, &f.TrendBucket, &f.DataVersion,
-		)
+		f, err := scanFeature(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan feature: %w", err)
 		}
-		features = append(features, &f)
+		features = append(features, f)
 	}
 
 	return features, nil
 }
+
+// featureColumns lists the numeric window_features columns used for analysis queries
+var featureColumns = []string{
+	"trend_slope",
+	"realized_volatility",
+	"max_drawdown",
+	"atr",
+	"vol_z_score",
+	"keltner_position",
+	"williams_r14",
+	"aroon_up",
+	"aroon_down",
+	"aroon_oscillator",
+	"return_entropy",
+	"roc10",
+	"roc20",
+	"bull_power",
+	"bear_power",
+	"fractal_dim",
+	"pivot_position",
+	"bid_ask_spread",
+}
+
+// validateFeatureColumn returns an error unless column is a known numeric feature column
+// Column names are interpolated into SQL, so only whitelisted names may pass
+func validateFeatureColumn(column string) error {
+	for _, c := range featureColumns {
+		if c == column {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown feature column %q", column)
+}
+
+// GetByFeatureRange retrieves features whose column value lies within [min, max]
+func (r *FeatureRepo) GetByFeatureRange(ctx context.Context, column string, min, max float64, limit int) ([]*model.FeatureRow, error) {
+	if err := validateFeatureColumn(column); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + selectFeatureColumns + `
+		FROM window_features
+		WHERE ` + column + ` BETWEEN ? AND ?
+		LIMIT ?
+	`
+
+	rows, err := r.client.Query(query, min, max, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query features: %w", err)
+	}
+	defer rows.Close()
+
+	var features []*model.FeatureRow
+	for rows.Next() {
+		f, err := scanFeature(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan feature: %w", err)
+		}
+		features = append(features, f)
+	}
+
+	return features, nil
+}
+
+// GetTopNByFeature retrieves the limit windows of a symbol/timeframe with the highest (descending) or lowest feature value
+// Windows with a NULL value are skipped; ties are broken by window ID
+func (r *FeatureRepo) GetTopNByFeature(ctx context.Context, symbol, timeframe string, feature string, descending bool, limit int) ([]*model.FeatureRow, error) {
+	if err := validateFeatureColumn(feature); err != nil {
+		return nil, err
+	}
+	order := "ASC"
+	if descending {
+		order = "DESC"
+	}
+
+	query := `SELECT ` + selectFeatureColumns + `
+		FROM window_features wf
+		JOIN windows w USING (window_id)
+		WHERE w.symbol = ? AND w.timeframe = ? AND wf.` + feature + ` IS NOT NULL
+		ORDER BY wf.` + feature + ` ` + order + `, window_id
+		LIMIT ?
+	`
+
+	rows, err := r.client.Query(query, symbol, timeframe, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query features: %w", err)
+	}
+	defer rows.Close()
+
+	var features []*model.FeatureRow
+	for rows.Next() {
+		f, err := scanFeature(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan feature: %w", err)
+		}
+		features = append(features, f)
+	}
+
+	return features, rows.Err()
+}
+
+// GetWindowIDsByPercentileRange returns the IDs of symbol/timeframe windows whose feature value lies between its
+// loPct and hiPct percentiles (0-100, inclusive), oldest first
+// The bounds come from DuckDB's approx_quantile, so windows right at a bound may fall on either side of it
+func (r *FeatureRepo) GetWindowIDsByPercentileRange(ctx context.Context, symbol, timeframe, feature string, loPct, hiPct float64) ([]string, error) {
+	if err := validateFeatureColumn(feature); err != nil {
+		return nil, err
+	}
+	if loPct < 0 || hiPct > 100 || loPct > hiPct {
+		return nil, fmt.Errorf("invalid percentile range [%g, %g]: want 0 <= lo <= hi <= 100", loPct, hiPct)
+	}
+
+	query := `
+		WITH series AS (
+			SELECT w.window_id, w.t_end, wf.` + feature + ` AS value
+			FROM window_features wf
+			JOIN windows w USING (window_id)
+			WHERE w.symbol = ? AND w.timeframe = ?
+		),
+		bounds AS (
+			SELECT approx_quantile(value, CAST(? AS FLOAT)) AS lo, approx_quantile(value, CAST(? AS FLOAT)) AS hi
+			FROM series
+		)
+		SELECT series.window_id
+		FROM series, bounds
+		WHERE series.value BETWEEN bounds.lo AND bounds.hi
+		ORDER BY series.t_end ASC
+	`
+
+	rows, err := r.client.Query(query, symbol, timeframe, loPct/100, hiPct/100)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query window ids: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan window id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// CorrelationMatrix holds pairwise Pearson correlations between feature columns
+type CorrelationMatrix struct {
+	Features []string                      // Column order for display
+	Values   map[string]map[string]float64 // Values[a][b] = CORR(a, b); NaN when undefined
+}
+
+// ComputeFeatureCorrelationMatrix computes pairwise feature correlations for a symbol/timeframe in a single query
+func (r *FeatureRepo) ComputeFeatureCorrelationMatrix(ctx context.Context, symbol, timeframe string) (*CorrelationMatrix, error) {
+	type pair struct{ a, b string }
+
+	var pairs []pair
+	var selects []string
+	for i, a := range featureColumns {
+		for _, b := range featureColumns[i:] {
+			pairs = append(pairs, pair{a, b})
+			selects = append(selects, fmt.Sprintf("CORR(wf.%s, wf.%s)", a, b))
+		}
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM window_features wf
+		JOIN windows w USING (window_id)
+		WHERE w.symbol = ? AND w.timeframe = ?
+	`, strings.Join(selects, ", "))
+
+	values := make([]sql.NullFloat64, len(pairs))
+	dest := make([]interface{}, len(pairs))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	if err := r.client.QueryRow(query, symbol, timeframe).Scan(dest...); err != nil {
+		return nil, fmt.Errorf("failed to compute correlations: %w", err)
+	}
+
+	m := &CorrelationMatrix{
+		Features: append([]string(nil), featureColumns...),
+		Values:   make(map[string]map[string]float64, len(featureColumns)),
+	}
+	for _, f := range featureColumns {
+		m.Values[f] = make(map[string]float64, len(featureColumns))
+	}
+
+	for i, p := range pairs {
+		v := math.NaN()
+		if values[i].Valid {
+			v = values[i].Float64
+		}
+		m.Values[p.a][p.b] = v
+		m.Values[p.b][p.a] = v
+	}
+
+	return m, nil
+}
+
+// BucketCount is a populated (vol_bucket, trend_bucket) combination and its window count
+type BucketCount struct {
+	VolBucket   int
+	TrendBucket int
+	Count       int64
+}
+
+// GetBucketCombinations returns every bucket combination with at least one window for a symbol/timeframe
+func (r *FeatureRepo) GetBucketCombinations(ctx context.Context, symbol, timeframe string) ([]BucketCount, error) {
+	query := `
+		SELECT wf.vol_bucket, wf.trend_bucket, COUNT(*)
+		FROM window_features wf
+		JOIN windows w USING (window_id)
+		WHERE w.symbol = ? AND w.timeframe = ?
+		GROUP BY wf.vol_bucket, wf.trend_bucket
+		ORDER BY wf.vol_bucket, wf.trend_bucket
+	`
+
+	rows, err := r.client.Query(query, symbol, timeframe)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bucket combinations: %w", err)
+	}
+	defer rows.Close()
+
+	var buckets []BucketCount
+	for rows.Next() {
+		var b BucketCount
+		if err := rows.Scan(&b.VolBucket, &b.TrendBucket, &b.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan bucket combination: %w", err)
+		}
+		buckets = append(buckets, b)
+	}
+
+	return buckets, nil
+}
+
+// GetWindowIDsByBuckets returns the IDs of every window of a symbol/timeframe in a bucket combination
+func (r *FeatureRepo) GetWindowIDsByBuckets(ctx context.Context, symbol, timeframe string, volBucket, trendBucket int) ([]string, error) {
+	query := `
+		SELECT w.window_id
+		FROM window_features wf
+		JOIN windows w USING (window_id)
+		WHERE w.symbol = ? AND w.timeframe = ? AND wf.vol_bucket = ? AND wf.trend_bucket = ?
+		ORDER BY w.t_end ASC
+	`
+
+	rows, err := r.client.Query(query, symbol, timeframe, volBucket, trendBucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query window ids: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan window id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// ColumnStats holds the corpus mean and sample standard deviation of a feature column
+type ColumnStats struct {
+	Mean float64
+	Std  float64
+}
+
+// GetColumnStats computes per-column mean and standard deviation over a symbol/timeframe's windows
+// Columns without any non-NULL values report zero statistics
+func (r *FeatureRepo) GetColumnStats(ctx context.Context, symbol, timeframe string, columns []string) (map[string]ColumnStats, error) {
+	if len(columns) == 0 {
+		return map[string]ColumnStats{}, nil
+	}
+
+	selects := make([]string, 0, 2*len(columns))
+	for _, c := range columns {
+		if err := validateFeatureColumn(c); err != nil {
+			return nil, err
+		}
+		selects = append(selects, fmt.Sprintf("AVG(wf.%s), STDDEV_SAMP(wf.%s)", c, c))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM window_features wf
+		JOIN windows w USING (window_id)
+		WHERE w.symbol = ? AND w.timeframe = ?
+	`, strings.Join(selects, ", "))
+
+	values := make([]sql.NullFloat64, 2*len(columns))
+	dest := make([]interface{}, len(values))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	if err := r.client.QueryRow(query, symbol, timeframe).Scan(dest...); err != nil {
+		return nil, fmt.Errorf("failed to compute column stats: %w", err)
+	}
+
+	stats := make(map[string]ColumnStats, len(columns))
+	for i, c := range columns {
+		stats[c] = ColumnStats{Mean: values[2*i].Float64, Std: values[2*i+1].Float64}
+	}
+
+	return stats, nil
+}
+
+// Distribution summarizes the empirical distribution of one feature column
+type Distribution struct {
+	Min    float64
+	P10    float64
+	P25    float64
+	P50    float64
+	P75    float64
+	P90    float64
+	Max    float64
+	Mean   float64
+	StdDev float64
+	Count  int64 // Non-NULL values
+}
+
+// GetFeatureDistribution computes quantiles and moments of a feature column over a symbol/timeframe's windows
+// A column without any non-NULL values reports a zero distribution
+func (r *FeatureRepo) GetFeatureDistribution(ctx context.Context, symbol, timeframe, feature string) (*Distribution, error) {
+	if err := validateFeatureColumn(feature); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			MIN(wf.%[1]s),
+			quantile_cont(wf.%[1]s, 0.10),
+			quantile_cont(wf.%[1]s, 0.25),
+			quantile_cont(wf.%[1]s, 0.50),
+			quantile_cont(wf.%[1]s, 0.75),
+			quantile_cont(wf.%[1]s, 0.90),
+			MAX(wf.%[1]s),
+			AVG(wf.%[1]s),
+			STDDEV_SAMP(wf.%[1]s),
+			COUNT(wf.%[1]s)
+		FROM window_features wf
+		JOIN windows w USING (window_id)
+		WHERE w.symbol = ? AND w.timeframe = ?
+	`, feature)
+
+	values := make([]sql.NullFloat64, 9)
+	dest := make([]interface{}, 0, len(values)+1)
+	for i := range values {
+		dest = append(dest, &values[i])
+	}
+	d := &Distribution{}
+	dest = append(dest, &d.Count)
+
+	if err := r.client.QueryRow(query, symbol, timeframe).Scan(dest...); err != nil {
+		return nil, fmt.Errorf("failed to compute feature distribution: %w", err)
+	}
+
+	d.Min = values[0].Float64
+	d.P10 = values[1].Float64
+	d.P25 = values[2].Float64
+	d.P50 = values[3].Float64
+	d.P75 = values[4].Float64
+	d.P90 = values[5].Float64
+	d.Max = values[6].Float64
+	d.Mean = values[7].Float64
+	d.StdDev = values[8].Float64
+
+	return d, nil
+}
+
+// GetAllFeatureDistributions computes the distribution of every numeric feature column, keyed by column
+func (r *FeatureRepo) GetAllFeatureDistributions(ctx context.Context, symbol, timeframe string) (map[string]*Distribution, error) {
+	distributions := make(map[string]*Distribution, len(featureColumns))
+	for _, c := range featureColumns {
+		d, err := r.GetFeatureDistribution(ctx, symbol, timeframe, c)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute %s distribution: %w", c, err)
+		}
+		distributions[c] = d
+	}
+	return distributions, nil
+}
+
+// FeatureColumns returns the numeric feature column names, in storage order
+func FeatureColumns() []string {
+	return append([]string(nil), featureColumns...)
+}
@@ -0,0 +1,234 @@
+package duckdb
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// seedFeatures stores n daily windows of symbol/timeframe ending from start, each with the feature row of feature(i)
+func seedFeatures(t testing.TB, c *Client, symbol, timeframe string, start time.Time, n int, feature func(i int) model.FeatureRow) []*model.FeatureRow {
+	t.Helper()
+	ctx := context.Background()
+
+	windows := make([]*model.Window, n)
+	rows := make([]*model.FeatureRow, n)
+	for i := range windows {
+		tEnd := start.AddDate(0, 0, i)
+		windows[i] = model.NewWindow(symbol, timeframe, tEnd, 10, 1, nil)
+		f := feature(i)
+		f.WindowID = windows[i].WindowID
+		rows[i] = &f
+	}
+	if err := NewWindowRepo(c).InsertBatch(ctx, windows); err != nil {
+		t.Fatalf("InsertBatch windows: %v", err)
+	}
+	if err := NewFeatureRepo(c).InsertBatch(ctx, rows); err != nil {
+		t.Fatalf("InsertBatch features: %v", err)
+	}
+	return rows
+}
+
+// varyingFeatures returns a feature row whose columns vary with i, not all linearly
+func varyingFeatures(i int) model.FeatureRow {
+	x := float64(i)
+	return model.FeatureRow{
+		TrendSlope:         x,
+		RealizedVolatility: x * x,
+		MaxDrawdown:        -x,
+		ATR:                math.Sin(x),
+		VolZScore:          float64(i % 3),
+	}
+}
+
+func TestComputeFeatureCorrelationMatrixDiagonal(t *testing.T) {
+	c := newTestClient(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	seedFeatures(t, c, "BTCUSDT", "1d", start, 20, varyingFeatures)
+
+	m, err := NewFeatureRepo(c).ComputeFeatureCorrelationMatrix(context.Background(), "BTCUSDT", "1d")
+	if err != nil {
+		t.Fatalf("ComputeFeatureCorrelationMatrix: %v", err)
+	}
+
+	for _, f := range []string{"trend_slope", "realized_volatility", "max_drawdown", "atr", "vol_z_score"} {
+		if got := m.Values[f][f]; math.Abs(got-1) > 1e-9 {
+			t.Errorf("CORR(%s, %s) = %v, want 1", f, f, got)
+		}
+	}
+	if got := m.Values["trend_slope"]["max_drawdown"]; math.Abs(got+1) > 1e-9 {
+		t.Errorf("CORR(trend_slope, max_drawdown) = %v, want -1", got)
+	}
+	for _, a := range m.Features {
+		for _, b := range m.Features {
+			if x, y := m.Values[a][b], m.Values[b][a]; x != y && !(math.IsNaN(x) && math.IsNaN(y)) {
+				t.Errorf("CORR(%s, %s) = %v but CORR(%s, %s) = %v", a, b, x, b, a, y)
+			}
+		}
+	}
+}
+
+func TestComputeFeatureCorrelationMatrixOtherSeries(t *testing.T) {
+	c := newTestClient(t)
+	seedFeatures(t, c, "BTCUSDT", "1d", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 5, varyingFeatures)
+
+	m, err := NewFeatureRepo(c).ComputeFeatureCorrelationMatrix(context.Background(), "ETHUSDT", "1d")
+	if err != nil {
+		t.Fatalf("ComputeFeatureCorrelationMatrix: %v", err)
+	}
+	if got := m.Values["trend_slope"]["trend_slope"]; !math.IsNaN(got) {
+		t.Errorf("CORR over no windows = %v, want NaN", got)
+	}
+}
+
+func TestGetTopNByFeature(t *testing.T) {
+	c := newTestClient(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Volatility runs 0, 7, 4, 1, 8, ... so neither order matches insertion order
+	seedFeatures(t, c, "BTCUSDT", "1d", start, 10, func(i int) model.FeatureRow {
+		return model.FeatureRow{RealizedVolatility: float64(i * 7 % 10)}
+	})
+	seedFeatures(t, c, "ETHUSDT", "1d", start, 3, func(int) model.FeatureRow {
+		return model.FeatureRow{RealizedVolatility: 100}
+	})
+	repo := NewFeatureRepo(c)
+
+	for _, tt := range []struct {
+		descending bool
+		want       []float64
+	}{
+		{true, []float64{9, 8, 7}},
+		{false, []float64{0, 1, 2}},
+	} {
+		rows, err := repo.GetTopNByFeature(context.Background(), "BTCUSDT", "1d", "realized_volatility", tt.descending, 3)
+		if err != nil {
+			t.Fatalf("GetTopNByFeature(descending=%v): %v", tt.descending, err)
+		}
+		got := make([]float64, len(rows))
+		for i, f := range rows {
+			got[i] = f.RealizedVolatility
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GetTopNByFeature(descending=%v) = %v, want %v", tt.descending, got, tt.want)
+		}
+	}
+
+	if _, err := repo.GetTopNByFeature(context.Background(), "BTCUSDT", "1d", "atr; DROP TABLE windows", true, 3); err == nil {
+		t.Error("unknown feature column succeeded")
+	}
+}
+
+func TestGetWindowIDsByPercentileRange(t *testing.T) {
+	c := newTestClient(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := seedFeatures(t, c, "BTCUSDT", "1d", start, 10, varyingFeatures)
+	repo := NewFeatureRepo(c)
+
+	all, err := repo.GetWindowIDsByPercentileRange(context.Background(), "BTCUSDT", "1d", "trend_slope", 0, 100)
+	if err != nil {
+		t.Fatalf("GetWindowIDsByPercentileRange: %v", err)
+	}
+	if !reflect.DeepEqual(all, windowIDs(rows)) {
+		t.Errorf("0-100 percentile = %v, want every window", all)
+	}
+
+	upper, err := repo.GetWindowIDsByPercentileRange(context.Background(), "BTCUSDT", "1d", "trend_slope", 50, 100)
+	if err != nil {
+		t.Fatalf("GetWindowIDsByPercentileRange: %v", err)
+	}
+	if len(upper) < 4 || len(upper) > 6 || upper[len(upper)-1] != rows[9].WindowID {
+		t.Errorf("50-100 percentile = %v, want about the newest half", upper)
+	}
+
+	if _, err := repo.GetWindowIDsByPercentileRange(context.Background(), "BTCUSDT", "1d", "trend_slope", 60, 40); err == nil {
+		t.Error("inverted percentile range succeeded")
+	}
+}
+
+// featureRows returns n feature rows for synthetic window IDs, without windows
+func featureRows(n int) []*model.FeatureRow {
+	rows := make([]*model.FeatureRow, n)
+	for i := range rows {
+		f := varyingFeatures(i)
+		f.WindowID = fmt.Sprintf("w%06d", i)
+		rows[i] = &f
+	}
+	return rows
+}
+
+// countFeatures returns the number of stored feature rows
+func countFeatures(t testing.TB, c *Client) int64 {
+	t.Helper()
+	var n int64
+	if err := c.QueryRow("SELECT COUNT(*) FROM window_features").Scan(&n); err != nil {
+		t.Fatalf("count features: %v", err)
+	}
+	return n
+}
+
+func TestUpsertBatchConcurrent(t *testing.T) {
+	c := newTestClient(t)
+	rows := featureRows(1000)
+	repo := NewFeatureRepo(c)
+
+	// Each worker upserts half of the rows, overlapping both neighbours
+	const workers = 4
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	for g := 0; g < workers; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			chunk := make([]*model.FeatureRow, 0, len(rows)/2)
+			for i := 0; i < len(rows)/2; i++ {
+				chunk = append(chunk, rows[(g*len(rows)/workers+i)%len(rows)])
+			}
+			errs <- repo.UpsertBatch(context.Background(), chunk)
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("UpsertBatch: %v", err)
+		}
+	}
+	if n := countFeatures(t, c); n != int64(len(rows)) {
+		t.Errorf("stored %d feature rows, want %d", n, len(rows))
+	}
+}
+
+func BenchmarkFeatureBatchWrite(b *testing.B) {
+	rows := featureRows(10000)
+
+	for _, bm := range []struct {
+		name  string
+		write func(*FeatureRepo, []*model.FeatureRow) error
+	}{
+		{"InsertBatch", func(r *FeatureRepo, rows []*model.FeatureRow) error { return r.InsertBatch(context.Background(), rows) }},
+		{"UpsertBatch", func(r *FeatureRepo, rows []*model.FeatureRow) error { return r.UpsertBatch(context.Background(), rows) }},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			c := newTestClient(b)
+			repo := NewFeatureRepo(c)
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				if err := c.Exec("DELETE FROM window_features"); err != nil {
+					b.Fatalf("clear features: %v", err)
+				}
+				b.StartTimer()
+
+				if err := bm.write(repo, rows); err != nil {
+					b.Fatalf("%s: %v", bm.name, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package duckdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// FundingRateRepo handles perpetual funding rate persistence
+type FundingRateRepo struct {
+	client *Client
+}
+
+// NewFundingRateRepo creates a new funding rate repository
+func NewFundingRateRepo(client *Client) *FundingRateRepo {
+	return &FundingRateRepo{client: client}
+}
+
+// InsertBatch inserts multiple funding rates in a transaction
+func (r *FundingRateRepo) InsertBatch(ctx context.Context, rates []model.FundingRate) error {
+	tx, err := r.client.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO funding_rates (symbol, funding_time, rate)
+		VALUES (?, ?, ?)
+		ON CONFLICT (symbol, funding_time) DO UPDATE SET
+			rate = EXCLUDED.rate
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, f := range rates {
+		if _, err := stmt.Exec(f.Symbol, f.FundingTime, f.Rate); err != nil {
+			return fmt.Errorf("failed to insert funding rate: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetByTimeRange retrieves funding rates within a time range
+func (r *FundingRateRepo) GetByTimeRange(ctx context.Context, symbol string, start, end time.Time) ([]model.FundingRate, error) {
+	query := `
+		SELECT symbol, funding_time, rate
+		FROM funding_rates
+		WHERE symbol = ? AND funding_time >= ? AND funding_time <= ?
+		ORDER BY funding_time ASC
+	`
+
+	rows, err := r.client.Query(query, symbol, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query funding rates: %w", err)
+	}
+	defer rows.Close()
+
+	var rates []model.FundingRate
+	for rows.Next() {
+		var f model.FundingRate
+		if err := rows.Scan(&f.Symbol, &f.FundingTime, &f.Rate); err != nil {
+			return nil, fmt.Errorf("failed to scan funding rate: %w", err)
+		}
+		rates = append(rates, f)
+	}
+
+	return rates, nil
+}
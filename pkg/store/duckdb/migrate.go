@@ -0,0 +1,41 @@
+package duckdb
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// DataMigration is an idempotent data fix-up applied to existing rows
+type DataMigration struct {
+	Name string
+	SQL  string
+}
+
+// BackfillCandleVWAP sets missing candle VWAP to the typical price
+const BackfillCandleVWAP = `
+UPDATE candles
+SET vwap = (high + low + close) / 3
+WHERE vwap = 0 OR vwap IS NULL
+`
+
+// DataMigrations are applied in order by RunDataMigrations
+var DataMigrations = []DataMigration{
+	{Name: "backfill_candle_vwap", SQL: BackfillCandleVWAP},
+}
+
+// RunDataMigrations applies every data migration, logging the rows each one changed
+func RunDataMigrations(ctx context.Context, c *Client) error {
+	for _, m := range DataMigrations {
+		res, err := c.DB().ExecContext(ctx, m.SQL)
+		if err != nil {
+			return fmt.Errorf("failed to run migration %s: %w", m.Name, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to count rows of migration %s: %w", m.Name, err)
+		}
+		log.Printf("Migration %s updated %d rows", m.Name, n)
+	}
+	return nil
+}
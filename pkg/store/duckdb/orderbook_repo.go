@@ -0,0 +1,103 @@
+package duckdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// OrderBookRepo handles order book snapshot persistence
+type OrderBookRepo struct {
+	client *Client
+}
+
+// NewOrderBookRepo creates a new order book repository
+func NewOrderBookRepo(client *Client) *OrderBookRepo {
+	return &OrderBookRepo{client: client}
+}
+
+// InsertBatch inserts multiple order book snapshots in a transaction
+func (r *OrderBookRepo) InsertBatch(ctx context.Context, snapshots []model.OrderBookSnapshot) error {
+	tx, err := r.client.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO orderbook_snapshots (
+			symbol, ts, bid_price, ask_price, mid_price, spread, bid_depth_20, ask_depth_20
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (symbol, ts) DO UPDATE SET
+			bid_price = EXCLUDED.bid_price,
+			ask_price = EXCLUDED.ask_price,
+			mid_price = EXCLUDED.mid_price,
+			spread = EXCLUDED.spread,
+			bid_depth_20 = EXCLUDED.bid_depth_20,
+			ask_depth_20 = EXCLUDED.ask_depth_20
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, s := range snapshots {
+		if _, err := stmt.Exec(s.Symbol, s.Timestamp, s.BidPrice, s.AskPrice, s.MidPrice, s.Spread, s.BidDepth20, s.AskDepth20); err != nil {
+			return fmt.Errorf("failed to insert order book snapshot: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetLatest retrieves the most recent order book snapshot of symbol
+func (r *OrderBookRepo) GetLatest(ctx context.Context, symbol string) (*model.OrderBookSnapshot, error) {
+	query := `
+		SELECT symbol, ts, bid_price, ask_price, mid_price, spread, bid_depth_20, ask_depth_20
+		FROM orderbook_snapshots
+		WHERE symbol = ?
+		ORDER BY ts DESC
+		LIMIT 1
+	`
+
+	var s model.OrderBookSnapshot
+	err := r.client.QueryRow(query, symbol).Scan(&s.Symbol, &s.Timestamp, &s.BidPrice, &s.AskPrice, &s.MidPrice, &s.Spread, &s.BidDepth20, &s.AskDepth20)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest order book snapshot: %w", err)
+	}
+
+	return &s, nil
+}
+
+// applySpreadSQL sets bid_ask_spread from the last snapshot taken at or before each window's end, within a max age
+const applySpreadSQL = `
+	UPDATE window_features
+	SET bid_ask_spread = s.rel_spread
+	FROM (
+		SELECT w.window_id, o.spread / o.mid_price AS rel_spread
+		FROM windows w
+		ASOF JOIN orderbook_snapshots o ON o.symbol = w.symbol AND o.ts <= w.t_end
+		WHERE w.symbol = ? AND w.timeframe = ?
+		  AND o.ts >= w.t_end - to_microseconds(?)
+		  AND o.mid_price > 0
+	) s
+	WHERE window_features.window_id = s.window_id
+`
+
+// ApplySpreadFeatures joins order book snapshots onto the stored features of symbol/timeframe windows
+// Each window takes the relative spread of the last snapshot at or before its TEnd that is at most maxAge old;
+// windows without such a snapshot are left unchanged. It returns the number of feature rows updated
+func (r *OrderBookRepo) ApplySpreadFeatures(ctx context.Context, symbol, timeframe string, maxAge time.Duration) (int64, error) {
+	res, err := r.client.DB().ExecContext(ctx, applySpreadSQL, symbol, timeframe, maxAge.Microseconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to apply spread features: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count updated features: %w", err)
+	}
+	return n, nil
+}
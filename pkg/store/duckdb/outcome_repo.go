@@ -0,0 +1,182 @@
+package duckdb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// OutcomeRepo handles cached window outcome persistence
+type OutcomeRepo struct {
+	client *Client
+}
+
+// NewOutcomeRepo creates a new outcome repository
+func NewOutcomeRepo(client *Client) *OutcomeRepo {
+	return &OutcomeRepo{client: client}
+}
+
+// InsertBatch upserts multiple outcome rows in a transaction
+func (r *OutcomeRepo) InsertBatch(ctx context.Context, outcomes []*model.Outcome) error {
+	tx, err := r.client.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO window_outcomes (
+			window_id, horizon, fwd_ret_mean, fwd_ret_p10, fwd_ret_p50, fwd_ret_p90,
+			mdd_p95, fwd_ret_end, hit_rate, mfe, mae, benchmark_symbol, excess_ret_end
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (window_id, horizon) DO UPDATE SET
+			fwd_ret_mean = EXCLUDED.fwd_ret_mean,
+			fwd_ret_p10 = EXCLUDED.fwd_ret_p10,
+			fwd_ret_p50 = EXCLUDED.fwd_ret_p50,
+			fwd_ret_p90 = EXCLUDED.fwd_ret_p90,
+			mdd_p95 = EXCLUDED.mdd_p95,
+			fwd_ret_end = EXCLUDED.fwd_ret_end,
+			hit_rate = EXCLUDED.hit_rate,
+			mfe = EXCLUDED.mfe,
+			mae = EXCLUDED.mae,
+			benchmark_symbol = EXCLUDED.benchmark_symbol,
+			excess_ret_end = EXCLUDED.excess_ret_end
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, o := range outcomes {
+		_, err := stmt.Exec(
+			o.WindowID, o.Horizon, o.FwdRetMean, o.FwdRetP10, o.FwdRetP50, o.FwdRetP90,
+			o.MDDP95, o.FwdRetEnd, o.HitRate, o.MFE, o.MAE, o.BenchmarkSymbol, o.ExcessRetEnd,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert outcome: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetByWindowIDs retrieves cached outcomes for the given windows and horizons
+// An empty horizons slice returns outcomes for every cached horizon
+func (r *OutcomeRepo) GetByWindowIDs(ctx context.Context, windowIDs []string, horizons []int) ([]*model.Outcome, error) {
+	if len(windowIDs) == 0 {
+		return nil, nil
+	}
+
+	args := make([]interface{}, 0, len(windowIDs)+len(horizons))
+	for _, id := range windowIDs {
+		args = append(args, id)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT window_id, horizon, fwd_ret_mean, fwd_ret_p10, fwd_ret_p50, fwd_ret_p90,
+			   mdd_p95, fwd_ret_end, hit_rate, mfe, mae, benchmark_symbol, excess_ret_end
+		FROM window_outcomes
+		WHERE window_id IN (%s)
+	`, placeholders(len(windowIDs)))
+
+	if len(horizons) > 0 {
+		query += fmt.Sprintf(" AND horizon IN (%s)", placeholders(len(horizons)))
+		for _, h := range horizons {
+			args = append(args, h)
+		}
+	}
+
+	rows, err := r.client.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outcomes: %w", err)
+	}
+	defer rows.Close()
+
+	var outcomes []*model.Outcome
+	for rows.Next() {
+		var o model.Outcome
+		var fwdRetEnd, hitRate, mfe, mae, benchmarkSymbol, excessRetEnd interface{}
+		err := rows.Scan(
+			&o.WindowID, &o.Horizon, &o.FwdRetMean, &o.FwdRetP10, &o.FwdRetP50, &o.FwdRetP90,
+			&o.MDDP95, &fwdRetEnd, &hitRate, &mfe, &mae, &benchmarkSymbol, &excessRetEnd,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outcome: %w", err)
+		}
+
+		if v, ok := fwdRetEnd.(float64); ok {
+			o.FwdRetEnd = v
+		}
+		if v, ok := hitRate.(float64); ok {
+			o.HitRate = v
+		}
+		if v, ok := mfe.(float64); ok {
+			o.MFE = v
+		}
+		if v, ok := mae.(float64); ok {
+			o.MAE = v
+		}
+		if v, ok := benchmarkSymbol.(string); ok {
+			o.BenchmarkSymbol = v
+		}
+		if v, ok := excessRetEnd.(float64); ok {
+			o.ExcessRetEnd = v
+		}
+
+		outcomes = append(outcomes, &o)
+	}
+
+	return outcomes, nil
+}
+
+// Count returns the total number of cached outcome rows
+func (r *OutcomeRepo) Count(ctx context.Context) (int64, error) {
+	var count int64
+	row := r.client.QueryRow("SELECT COUNT(*) FROM window_outcomes")
+	err := row.Scan(&count)
+	return count, err
+}
+
+// placeholders returns a comma-separated list of n query placeholders
+func placeholders(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// GetPendingWindowIDs returns windows ending at or before cutoff that have no stored outcome for horizon
+// Callers pass cutoff = asOf - horizon×bar so only windows whose horizon has fully elapsed are returned
+func (r *OutcomeRepo) GetPendingWindowIDs(ctx context.Context, symbol, timeframe string, horizon int, cutoff time.Time) ([]string, error) {
+	query := `
+		SELECT w.window_id
+		FROM windows w
+		WHERE w.symbol = ? AND w.timeframe = ? AND w.t_end <= ?
+		  AND NOT EXISTS (
+			SELECT 1 FROM window_outcomes o
+			WHERE o.window_id = w.window_id AND o.horizon = ?
+		  )
+		ORDER BY w.t_end ASC
+	`
+
+	rows, err := r.client.Query(query, symbol, timeframe, cutoff, horizon)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending windows: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan window id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
@@ -5,6 +5,7 @@ import "fmt"
 // Schema contains table creation statements for all required tables
 
 // CreateCandlesTable creates the candles fact table
+// close_time is deliberately not indexed: DuckDB rejects ON CONFLICT updates of indexed columns, which InsertBatch relies on
 const CreateCandlesTable = `
 CREATE TABLE IF NOT EXISTS candles (
     symbol VARCHAR NOT NULL,
@@ -49,10 +50,40 @@ CREATE TABLE IF NOT EXISTS window_features (
     vol_z_score DOUBLE,
     vol_bucket INTEGER,
     trend_bucket INTEGER,
-    data_version INTEGER NOT NULL
+    data_version INTEGER NOT NULL,
+    keltner_position DOUBLE,
+    williams_r14 DOUBLE,
+    aroon_up DOUBLE,
+    aroon_down DOUBLE,
+    aroon_oscillator DOUBLE,
+    return_entropy DOUBLE,
+    roc10 DOUBLE,
+    roc20 DOUBLE,
+    bull_power DOUBLE,
+    bear_power DOUBLE,
+    fractal_dim DOUBLE,
+    pivot_position DOUBLE,
+    bid_ask_spread DOUBLE
 );
 `
 
+// MigrateWindowFeaturesTable adds columns introduced after the initial window_features schema
+const MigrateWindowFeaturesTable = `
+ALTER TABLE window_features ADD COLUMN IF NOT EXISTS keltner_position DOUBLE;
+ALTER TABLE window_features ADD COLUMN IF NOT EXISTS williams_r14 DOUBLE;
+ALTER TABLE window_features ADD COLUMN IF NOT EXISTS aroon_up DOUBLE;
+ALTER TABLE window_features ADD COLUMN IF NOT EXISTS aroon_down DOUBLE;
+ALTER TABLE window_features ADD COLUMN IF NOT EXISTS aroon_oscillator DOUBLE;
+ALTER TABLE window_features ADD COLUMN IF NOT EXISTS return_entropy DOUBLE;
+ALTER TABLE window_features ADD COLUMN IF NOT EXISTS roc10 DOUBLE;
+ALTER TABLE window_features ADD COLUMN IF NOT EXISTS roc20 DOUBLE;
+ALTER TABLE window_features ADD COLUMN IF NOT EXISTS bull_power DOUBLE;
+ALTER TABLE window_features ADD COLUMN IF NOT EXISTS bear_power DOUBLE;
+ALTER TABLE window_features ADD COLUMN IF NOT EXISTS fractal_dim DOUBLE;
+ALTER TABLE window_features ADD COLUMN IF NOT EXISTS pivot_position DOUBLE;
+ALTER TABLE window_features ADD COLUMN IF NOT EXISTS bid_ask_spread DOUBLE;
+`
+
 // CreateWindowOutcomesTable creates the window outcomes cache table
 const CreateWindowOutcomesTable = `
 CREATE TABLE IF NOT EXISTS window_outcomes (
@@ -63,17 +94,125 @@ CREATE TABLE IF NOT EXISTS window_outcomes (
     fwd_ret_p50 DOUBLE,
     fwd_ret_p90 DOUBLE,
     mdd_p95 DOUBLE,
+    fwd_ret_end DOUBLE,
+    hit_rate DOUBLE,
+    mfe DOUBLE,
+    mae DOUBLE,
+    benchmark_symbol VARCHAR,
+    excess_ret_end DOUBLE,
     PRIMARY KEY (window_id, horizon)
 );
 `
 
+// MigrateWindowOutcomesTable adds columns introduced after the initial window_outcomes schema
+const MigrateWindowOutcomesTable = `
+ALTER TABLE window_outcomes ADD COLUMN IF NOT EXISTS fwd_ret_end DOUBLE;
+ALTER TABLE window_outcomes ADD COLUMN IF NOT EXISTS hit_rate DOUBLE;
+ALTER TABLE window_outcomes ADD COLUMN IF NOT EXISTS mfe DOUBLE;
+ALTER TABLE window_outcomes ADD COLUMN IF NOT EXISTS mae DOUBLE;
+ALTER TABLE window_outcomes ADD COLUMN IF NOT EXISTS benchmark_symbol VARCHAR;
+ALTER TABLE window_outcomes ADD COLUMN IF NOT EXISTS excess_ret_end DOUBLE;
+`
+
+// CreateWindowCandleRangesTable links each window to the candle rows it covers
+// t_start and t_end are the open times of the window's first and last candles
+const CreateWindowCandleRangesTable = `
+CREATE TABLE IF NOT EXISTS window_candle_ranges (
+    window_id VARCHAR PRIMARY KEY,
+    symbol VARCHAR NOT NULL,
+    timeframe VARCHAR NOT NULL,
+    t_start TIMESTAMP NOT NULL,
+    t_end TIMESTAMP NOT NULL
+);
+`
+
+// CreateScoreCalibrationsTable stores fitted similarity score calibrations
+// quantiles is a JSON array of pair-score percentiles
+const CreateScoreCalibrationsTable = `
+CREATE TABLE IF NOT EXISTS score_calibrations (
+    calibration_key VARCHAR PRIMARY KEY,
+    quantiles VARCHAR NOT NULL,
+    sample_pairs BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+// CreateFundingRatesTable creates the perpetual funding rates table
+const CreateFundingRatesTable = `
+CREATE TABLE IF NOT EXISTS funding_rates (
+    symbol VARCHAR NOT NULL,
+    funding_time TIMESTAMP NOT NULL,
+    rate DOUBLE,
+    PRIMARY KEY (symbol, funding_time)
+);
+`
+
+// CreateOrderBookTable creates the order book snapshots table
+const CreateOrderBookTable = `
+CREATE TABLE IF NOT EXISTS orderbook_snapshots (
+    symbol VARCHAR NOT NULL,
+    ts TIMESTAMP NOT NULL,
+    bid_price DOUBLE,
+    ask_price DOUBLE,
+    mid_price DOUBLE,
+    spread DOUBLE,
+    bid_depth_20 DOUBLE,
+    ask_depth_20 DOUBLE,
+    PRIMARY KEY (symbol, ts)
+);
+`
+
+// CreateBackfillRunsTable stores backfill checkpoints, keyed by a run ID derived from the backfill config
+// Timestamps are NULL until the phase has made progress
+const CreateBackfillRunsTable = `
+CREATE TABLE IF NOT EXISTS backfill_runs (
+    run_id VARCHAR PRIMARY KEY,
+    symbol VARCHAR NOT NULL,
+    timeframe VARCHAR NOT NULL,
+    phase VARCHAR NOT NULL,
+    last_candle_open_time TIMESTAMP,
+    last_window_t_end TIMESTAMP,
+    milvus_batches BIGINT NOT NULL DEFAULT 0,
+    flushed_batches BIGINT NOT NULL DEFAULT 0,
+    started_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+`
+
+// CreateSignalTable stores trading signals generated from search results
+// symbol and timeframe are indexed, so upserts must not update them
+const CreateSignalTable = `
+CREATE TABLE IF NOT EXISTS signals (
+    signal_id VARCHAR PRIMARY KEY,
+    symbol VARCHAR NOT NULL,
+    timeframe VARCHAR NOT NULL,
+    signal_time TIMESTAMP NOT NULL,
+    window_id VARCHAR,
+    direction VARCHAR NOT NULL,
+    confidence DOUBLE,
+    horizon INTEGER,
+    expected_return DOUBLE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_signals_symbol_tf ON signals(symbol, timeframe);
+`
+
 // InitializeSchema creates all required tables
 func InitializeSchema(c *Client) error {
 	schemas := []string{
 		CreateCandlesTable,
 		CreateWindowsTable,
 		CreateWindowFeaturesTable,
+		MigrateWindowFeaturesTable,
 		CreateWindowOutcomesTable,
+		MigrateWindowOutcomesTable,
+		CreateFundingRatesTable,
+		CreateOrderBookTable,
+		CreateWindowCandleRangesTable,
+		CreateScoreCalibrationsTable,
+		CreateBackfillRunsTable,
+		CreateSignalTable,
 	}
 
 	for _, schema := range schemas {
@@ -87,7 +226,7 @@ func InitializeSchema(c *Client) error {
 
 // DropAllTables drops all tables (use with caution)
 func DropAllTables(c *Client) error {
-	tables := []string{"window_outcomes", "window_features", "windows", "candles"}
+	tables := []string{"signals", "backfill_runs", "score_calibrations", "window_candle_ranges", "orderbook_snapshots", "funding_rates", "window_outcomes", "window_features", "windows", "candles"}
 	for _, table := range tables {
 		if err := c.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", table)); err != nil {
 			return fmt.Errorf("failed to drop table %s: %w", table, err)
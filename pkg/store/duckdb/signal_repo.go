@@ -0,0 +1,124 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// SignalRepo handles trading signal persistence
+type SignalRepo struct {
+	client *Client
+}
+
+// NewSignalRepo creates a new signal repository
+func NewSignalRepo(client *Client) *SignalRepo {
+	return &SignalRepo{client: client}
+}
+
+// Insert upserts signals in a transaction; a signal with an existing ID replaces the stored one
+func (r *SignalRepo) Insert(ctx context.Context, signals []*model.Signal) error {
+	tx, err := r.client.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO signals (
+			signal_id, symbol, timeframe, signal_time, window_id, direction,
+			confidence, horizon, expected_return, created_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (signal_id) DO UPDATE SET
+			signal_time = EXCLUDED.signal_time,
+			window_id = EXCLUDED.window_id,
+			direction = EXCLUDED.direction,
+			confidence = EXCLUDED.confidence,
+			horizon = EXCLUDED.horizon,
+			expected_return = EXCLUDED.expected_return,
+			created_at = EXCLUDED.created_at
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, s := range signals {
+		createdAt := s.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = now
+		}
+		_, err := stmt.Exec(
+			s.SignalID, s.Symbol, s.Timeframe, s.SignalTime, s.WindowID, s.Direction,
+			s.Confidence, s.Horizon, s.ExpectedReturn, createdAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert signal: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetRecent retrieves the latest limit signals of symbol/timeframe, newest first
+func (r *SignalRepo) GetRecent(ctx context.Context, symbol, timeframe string, limit int) ([]*model.Signal, error) {
+	rows, err := r.client.Query(`
+		SELECT signal_id, symbol, timeframe, signal_time, window_id, direction,
+			confidence, horizon, expected_return, created_at
+		FROM signals
+		WHERE symbol = ? AND timeframe = ?
+		ORDER BY signal_time DESC, horizon ASC
+		LIMIT ?
+	`, symbol, timeframe, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query signals: %w", err)
+	}
+	defer rows.Close()
+
+	return scanSignals(rows)
+}
+
+// GetByDirection retrieves every signal with direction, newest first
+func (r *SignalRepo) GetByDirection(ctx context.Context, direction string) ([]*model.Signal, error) {
+	rows, err := r.client.Query(`
+		SELECT signal_id, symbol, timeframe, signal_time, window_id, direction,
+			confidence, horizon, expected_return, created_at
+		FROM signals
+		WHERE direction = ?
+		ORDER BY signal_time DESC, symbol, timeframe, horizon ASC
+	`, direction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query signals: %w", err)
+	}
+	defer rows.Close()
+
+	return scanSignals(rows)
+}
+
+// scanSignals reads the signal rows selected by GetRecent and GetByDirection
+func scanSignals(rows *sql.Rows) ([]*model.Signal, error) {
+	var signals []*model.Signal
+	for rows.Next() {
+		s := &model.Signal{}
+		var windowID sql.NullString
+		var confidence, expectedReturn sql.NullFloat64
+		var horizon sql.NullInt64
+		if err := rows.Scan(
+			&s.SignalID, &s.Symbol, &s.Timeframe, &s.SignalTime, &windowID, &s.Direction,
+			&confidence, &horizon, &expectedReturn, &s.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan signal: %w", err)
+		}
+		s.WindowID = windowID.String
+		s.Confidence = confidence.Float64
+		s.Horizon = int(horizon.Int64)
+		s.ExpectedReturn = expectedReturn.Float64
+		signals = append(signals, s)
+	}
+	return signals, rows.Err()
+}
@@ -0,0 +1,105 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// WindowCandleRangeRepo links windows to the candle rows they cover without duplicating OHLCV data
+type WindowCandleRangeRepo struct {
+	client *Client
+}
+
+// NewWindowCandleRangeRepo creates a new window candle range repository
+func NewWindowCandleRangeRepo(client *Client) *WindowCandleRangeRepo {
+	return &WindowCandleRangeRepo{client: client}
+}
+
+// insertWindowCandleRangeSQL records a window's candle range, keeping any existing record
+const insertWindowCandleRangeSQL = `
+	INSERT INTO window_candle_ranges (window_id, symbol, timeframe, t_start, t_end)
+	VALUES (?, ?, ?, ?, ?)
+	ON CONFLICT (window_id) DO NOTHING
+`
+
+// windowCandleRangeArgs returns the insert arguments for a window, or false if it holds no candles
+func windowCandleRangeArgs(w *model.Window) ([]interface{}, bool) {
+	first, last := w.FirstCandle(), w.LastCandle()
+	if first == nil || last == nil {
+		return nil, false
+	}
+	return []interface{}{w.WindowID, w.Symbol, w.Timeframe, first.OpenTime, last.OpenTime}, true
+}
+
+// Insert records the candle range of a window; windows without candles are skipped
+func (r *WindowCandleRangeRepo) Insert(ctx context.Context, w *model.Window) error {
+	args, ok := windowCandleRangeArgs(w)
+	if !ok {
+		return nil
+	}
+	if err := r.client.Exec(insertWindowCandleRangeSQL, args...); err != nil {
+		return fmt.Errorf("failed to insert window candle range: %w", err)
+	}
+	return nil
+}
+
+// GetCandlesForWindow reconstructs a window's candles by joining its range with the candles table
+// Returns sql.ErrNoRows if no range is recorded for the window
+func (r *WindowCandleRangeRepo) GetCandlesForWindow(ctx context.Context, windowID string) ([]model.Candle, error) {
+	var exists int
+	if err := r.client.QueryRow("SELECT COUNT(*) FROM window_candle_ranges WHERE window_id = ?", windowID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up window candle range: %w", err)
+	}
+	if exists == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	query := `
+		SELECT c.symbol, c.timeframe, c.open_time, c.close_time, c.open, c.high, c.low, c.close, c.volume, c.trades, c.vwap
+		FROM window_candle_ranges r
+		JOIN candles c
+		  ON c.symbol = r.symbol AND c.timeframe = r.timeframe
+		 AND c.open_time BETWEEN r.t_start AND r.t_end
+		WHERE r.window_id = ?
+		ORDER BY c.open_time ASC
+	`
+
+	rows, err := r.client.Query(query, windowID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query window candles: %w", err)
+	}
+	defer rows.Close()
+
+	var candles []model.Candle
+	for rows.Next() {
+		var c model.Candle
+		var closeTime, vwap interface{}
+		var trades interface{}
+
+		err := rows.Scan(
+			&c.Symbol, &c.Timeframe, &c.OpenTime, &closeTime,
+			&c.Open, &c.High, &c.Low, &c.Close, &c.Volume, &trades, &vwap,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan candle: %w", err)
+		}
+
+		if ct, ok := closeTime.(time.Time); ok {
+			c.CloseTime = ct
+		}
+		if t, ok := trades.(int64); ok {
+			c.Trades = t
+		}
+		if v, ok := vwap.(float64); ok {
+			c.VWAP = v
+		}
+
+		candles = append(candles, c)
+	}
+
+	return candles, nil
+}
@@ -3,6 +3,7 @@ package duckdb
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/tunogya/etna/pkg/model"
 )
@@ -29,7 +30,7 @@ func (r *WindowRepo) Insert(ctx context.Context, w *model.Window) error {
 	)
 }
 
-// InsertBatch inserts multiple windows in a transaction
+// InsertBatch inserts multiple windows in a transaction, recording each window's candle range alongside it
 func (r *WindowRepo) InsertBatch(ctx context.Context, windows []*model.Window) error {
 	tx, err := r.client.Begin()
 	if err != nil {
@@ -47,6 +48,12 @@ func (r *WindowRepo) InsertBatch(ctx context.Context, windows []*model.Window) e
 	}
 	defer stmt.Close()
 
+	rangeStmt, err := tx.Prepare(insertWindowCandleRangeSQL)
+	if err != nil {
+		return fmt.Errorf("failed to prepare range statement: %w", err)
+	}
+	defer rangeStmt.Close()
+
 	for _, w := range windows {
 		_, err := stmt.Exec(
 			w.WindowID, w.Symbol, w.Timeframe, w.TEnd, w.W, w.FeatureVersion, w.CreatedAt,
@@ -54,6 +61,12 @@ func (r *WindowRepo) InsertBatch(ctx context.Context, windows []*model.Window) e
 		if err != nil {
 			return fmt.Errorf("failed to insert window: %w", err)
 		}
+
+		if args, ok := windowCandleRangeArgs(w); ok {
+			if _, err := rangeStmt.Exec(args...); err != nil {
+				return fmt.Errorf("failed to insert window candle range: %w", err)
+			}
+		}
 	}
 
 	return tx.Commit()
@@ -67,6 +80,30 @@ func (r *WindowRepo) Exists(ctx context.Context, windowID string) (bool, error)
 	return count > 0, err
 }
 
+// existsBatchSize bounds the window IDs bound per ExistsBatch query
+const existsBatchSize = 1000
+
+// ExistsBatch returns the subset of windowIDs already stored, checked existsBatchSize IDs per query
+func (r *WindowRepo) ExistsBatch(ctx context.Context, windowIDs []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	for start := 0; start < len(windowIDs); start += existsBatchSize {
+		chunk := windowIDs[start:min(start+existsBatchSize, len(windowIDs))]
+		args := make([]interface{}, len(chunk))
+		for i, id := range chunk {
+			args[i] = id
+		}
+
+		ids, err := r.queryWindowIDs(`SELECT window_id FROM windows WHERE window_id IN (`+placeholders(len(chunk))+`)`, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check windows: %w", err)
+		}
+		for _, id := range ids {
+			existing[id] = true
+		}
+	}
+	return existing, nil
+}
+
 // GetByID retrieves a window by ID
 func (r *WindowRepo) GetByID(ctx context.Context, windowID string) (*model.Window, error) {
 	query := `
@@ -85,6 +122,188 @@ func (r *WindowRepo) GetByID(ctx context.Context, windowID string) (*model.Windo
 	return &w, nil
 }
 
+// GetClosestByTEnd retrieves the latest symbol/timeframe window ending at or before t
+// Windows ending after t are never returned, so a search as of t cannot see the future. Returns sql.ErrNoRows if none
+func (r *WindowRepo) GetClosestByTEnd(ctx context.Context, symbol, timeframe string, t time.Time) (*model.Window, error) {
+	query := `
+		SELECT window_id, symbol, timeframe, t_end, w, feature_version, created_at
+		FROM windows
+		WHERE symbol = ? AND timeframe = ? AND t_end <= ?
+		ORDER BY t_end DESC
+		LIMIT 1
+	`
+
+	row := r.client.QueryRow(query, symbol, timeframe, t)
+	var w model.Window
+	err := row.Scan(&w.WindowID, &w.Symbol, &w.Timeframe, &w.TEnd, &w.W, &w.FeatureVersion, &w.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &w, nil
+}
+
+// selectWindowWithFeatures selects window columns followed by selectFeatureColumns from the joined tables
+const selectWindowWithFeatures = `
+	SELECT w.symbol, w.timeframe, w.t_end, w.w, w.feature_version, w.created_at,` + selectFeatureColumns + `
+	FROM windows w
+	JOIN window_features wf USING (window_id)
+`
+
+// scanWindowWithFeatures scans a row selected with selectWindowWithFeatures
+func scanWindowWithFeatures(s rowScanner) (*model.WindowWithFeatures, error) {
+	var wf model.WindowWithFeatures
+	dest := []interface{}{
+		&wf.Symbol, &wf.Timeframe, &wf.TEnd, &wf.W, &wf.FeatureVersion, &wf.CreatedAt,
+	}
+	if err := s.Scan(append(dest, featureDest(&wf.FeatureRow)...)...); err != nil {
+		return nil, err
+	}
+	wf.Window.WindowID = wf.FeatureRow.WindowID
+	return &wf, nil
+}
+
+// GetWindowWithFeatures retrieves a window and its features in a single query
+func (r *WindowRepo) GetWindowWithFeatures(ctx context.Context, windowID string) (*model.WindowWithFeatures, error) {
+	query := selectWindowWithFeatures + `WHERE window_id = ?`
+	return scanWindowWithFeatures(r.client.QueryRow(query, windowID))
+}
+
+// GetWindowsWithFeaturesByBuckets retrieves windows and features matching bucket filters for a symbol/timeframe
+func (r *WindowRepo) GetWindowsWithFeaturesByBuckets(ctx context.Context, symbol, timeframe string, volBucket, trendBucket, limit int) ([]*model.WindowWithFeatures, error) {
+	query := selectWindowWithFeatures + `
+		WHERE w.symbol = ? AND w.timeframe = ? AND wf.vol_bucket = ? AND wf.trend_bucket = ?
+		ORDER BY w.t_end ASC
+		LIMIT ?
+	`
+
+	rows, err := r.client.Query(query, symbol, timeframe, volBucket, trendBucket, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query windows with features: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*model.WindowWithFeatures
+	for rows.Next() {
+		wf, err := scanWindowWithFeatures(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan window with features: %w", err)
+		}
+		results = append(results, wf)
+	}
+
+	return results, nil
+}
+
+// GetRecentWindowIDs returns the IDs of windows of a symbol/timeframe ending after since, oldest first
+// limit <= 0 returns all
+func (r *WindowRepo) GetRecentWindowIDs(ctx context.Context, symbol, timeframe string, since time.Time, limit int) ([]string, error) {
+	query := `
+		SELECT window_id
+		FROM windows
+		WHERE symbol = ? AND timeframe = ? AND t_end > ?
+		ORDER BY t_end ASC
+	`
+	args := []interface{}{symbol, timeframe, since}
+	if limit > 0 {
+		query += "LIMIT ?"
+		args = append(args, limit)
+	}
+
+	return r.queryWindowIDs(query, args...)
+}
+
+// SampleWindowIDs returns up to n window IDs drawn uniformly at random from the windows of symbols and timeframes
+// An empty symbols or timeframes list matches every symbol or timeframe
+func (r *WindowRepo) SampleWindowIDs(ctx context.Context, symbols, timeframes []string, n int) ([]string, error) {
+	query := "SELECT window_id FROM windows WHERE 1 = 1"
+	var args []interface{}
+	for _, clause := range []struct {
+		column string
+		values []string
+	}{{"symbol", symbols}, {"timeframe", timeframes}} {
+		if len(clause.values) == 0 {
+			continue
+		}
+		query += " AND " + clause.column + " IN (" + placeholders(len(clause.values)) + ")"
+		for _, v := range clause.values {
+			args = append(args, v)
+		}
+	}
+	query += " ORDER BY random() LIMIT ?"
+	args = append(args, n)
+
+	return r.queryWindowIDs(query, args...)
+}
+
+// GetWindowIDsWithoutOutcomes returns the IDs of windows of a symbol/timeframe with no stored outcome for horizon, oldest first
+func (r *WindowRepo) GetWindowIDsWithoutOutcomes(ctx context.Context, symbol, timeframe string, horizon int) ([]string, error) {
+	query := `
+		SELECT w.window_id
+		FROM windows w
+		LEFT JOIN window_outcomes o ON o.window_id = w.window_id AND o.horizon = ?
+		WHERE w.symbol = ? AND w.timeframe = ? AND o.window_id IS NULL
+		ORDER BY w.t_end ASC
+	`
+	return r.queryWindowIDs(query, horizon, symbol, timeframe)
+}
+
+// queryWindowIDs runs a query selecting a single window_id column
+func (r *WindowRepo) queryWindowIDs(query string, args ...interface{}) ([]string, error) {
+	rows, err := r.client.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query window ids: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan window id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// GetWindowIDsPage returns one page of symbol/timeframe window IDs ordered by t_end
+func (r *WindowRepo) GetWindowIDsPage(ctx context.Context, symbol, timeframe string, offset, limit int) ([]string, error) {
+	return r.queryWindowIDs(`
+		SELECT window_id FROM windows
+		WHERE symbol = ? AND timeframe = ?
+		ORDER BY t_end, window_id
+		LIMIT ? OFFSET ?
+	`, symbol, timeframe, limit, offset)
+}
+
+// GetWindowsPage returns one page of symbol/timeframe windows ordered by t_end, without candles
+func (r *WindowRepo) GetWindowsPage(ctx context.Context, symbol, timeframe string, offset, limit int) ([]*model.Window, error) {
+	rows, err := r.client.Query(`
+		SELECT window_id, symbol, timeframe, t_end, w, feature_version, created_at
+		FROM windows
+		WHERE symbol = ? AND timeframe = ?
+		ORDER BY t_end, window_id
+		LIMIT ? OFFSET ?
+	`, symbol, timeframe, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query windows: %w", err)
+	}
+	defer rows.Close()
+
+	var windows []*model.Window
+	for rows.Next() {
+		var w model.Window
+		if err := rows.Scan(&w.WindowID, &w.Symbol, &w.Timeframe, &w.TEnd, &w.W, &w.FeatureVersion, &w.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan window: %w", err)
+		}
+		windows = append(windows, &w)
+	}
+
+	return windows, rows.Err()
+}
+
 // Count returns the total number of windows
 func (r *WindowRepo) Count(ctx context.Context, symbol, timeframe string) (int64, error) {
 	var count int64
@@ -95,3 +314,37 @@ func (r *WindowRepo) Count(ctx context.Context, symbol, timeframe string) (int64
 	err := row.Scan(&count)
 	return count, err
 }
+
+// SeriesCount holds the stored window and feature row counts of one symbol/timeframe
+type SeriesCount struct {
+	Symbol    string
+	Timeframe string
+	Windows   int64
+	Features  int64 // Windows that have a feature row
+}
+
+// CountBySeries returns window and feature counts for every stored symbol/timeframe
+func (r *WindowRepo) CountBySeries(ctx context.Context) ([]SeriesCount, error) {
+	rows, err := r.client.Query(`
+		SELECT w.symbol, w.timeframe, COUNT(*), COUNT(f.window_id)
+		FROM windows w
+		LEFT JOIN window_features f ON f.window_id = w.window_id
+		GROUP BY w.symbol, w.timeframe
+		ORDER BY w.symbol, w.timeframe
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count windows: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []SeriesCount
+	for rows.Next() {
+		var c SeriesCount
+		if err := rows.Scan(&c.Symbol, &c.Timeframe, &c.Windows, &c.Features); err != nil {
+			return nil, fmt.Errorf("failed to scan window count: %w", err)
+		}
+		counts = append(counts, c)
+	}
+
+	return counts, rows.Err()
+}
@@ -0,0 +1,141 @@
+package duckdb
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// windowIDs returns the window IDs of rows
+func windowIDs(rows []*model.FeatureRow) []string {
+	ids := make([]string, len(rows))
+	for i, f := range rows {
+		ids[i] = f.WindowID
+	}
+	return ids
+}
+
+func TestGetWindowWithFeatures(t *testing.T) {
+	c := newTestClient(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := seedFeatures(t, c, "BTCUSDT", "1d", start, 3, varyingFeatures)
+
+	got, err := NewWindowRepo(c).GetWindowWithFeatures(context.Background(), rows[2].WindowID)
+	if err != nil {
+		t.Fatalf("GetWindowWithFeatures: %v", err)
+	}
+	if got.Window.WindowID != rows[2].WindowID || got.FeatureRow.WindowID != rows[2].WindowID {
+		t.Errorf("window/feature IDs = %s/%s, want %s", got.Window.WindowID, got.FeatureRow.WindowID, rows[2].WindowID)
+	}
+	if got.Symbol != "BTCUSDT" || got.Timeframe != "1d" || !got.TEnd.Equal(start.AddDate(0, 0, 2)) {
+		t.Errorf("window = %s/%s ending %s, want BTCUSDT/1d ending %s", got.Symbol, got.Timeframe, got.TEnd, start.AddDate(0, 0, 2))
+	}
+	if got.RealizedVolatility != rows[2].RealizedVolatility || got.TrendSlope != rows[2].TrendSlope {
+		t.Errorf("features = %v/%v, want %v/%v", got.RealizedVolatility, got.TrendSlope, rows[2].RealizedVolatility, rows[2].TrendSlope)
+	}
+
+	if _, err := NewWindowRepo(c).GetWindowWithFeatures(context.Background(), "missing"); err == nil {
+		t.Error("missing window succeeded")
+	}
+}
+
+func TestGetWindowsWithFeaturesByBuckets(t *testing.T) {
+	c := newTestClient(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := seedFeatures(t, c, "BTCUSDT", "1d", start, 6, func(i int) model.FeatureRow {
+		return model.FeatureRow{VolBucket: i % 2, TrendBucket: 1}
+	})
+	seedFeatures(t, c, "ETHUSDT", "1d", start, 2, func(int) model.FeatureRow {
+		return model.FeatureRow{VolBucket: 0, TrendBucket: 1}
+	})
+
+	got, err := NewWindowRepo(c).GetWindowsWithFeaturesByBuckets(context.Background(), "BTCUSDT", "1d", 0, 1, 10)
+	if err != nil {
+		t.Fatalf("GetWindowsWithFeaturesByBuckets: %v", err)
+	}
+	var ids []string
+	for _, wf := range got {
+		if wf.VolBucket != 0 || wf.TrendBucket != 1 || wf.Symbol != "BTCUSDT" {
+			t.Errorf("window %s in %s has buckets %d/%d", wf.Window.WindowID, wf.Symbol, wf.VolBucket, wf.TrendBucket)
+		}
+		ids = append(ids, wf.Window.WindowID)
+	}
+	if want := windowIDs([]*model.FeatureRow{rows[0], rows[2], rows[4]}); !reflect.DeepEqual(ids, want) {
+		t.Errorf("window IDs = %v, want %v", ids, want)
+	}
+
+	limited, err := NewWindowRepo(c).GetWindowsWithFeaturesByBuckets(context.Background(), "BTCUSDT", "1d", 0, 1, 2)
+	if err != nil {
+		t.Fatalf("GetWindowsWithFeaturesByBuckets: %v", err)
+	}
+	if len(limited) != 2 {
+		t.Errorf("limit 2 returned %d windows", len(limited))
+	}
+}
+
+func TestGetRecentWindowIDs(t *testing.T) {
+	c := newTestClient(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := seedFeatures(t, c, "BTCUSDT", "1d", start, 5, varyingFeatures)
+	seedFeatures(t, c, "ETHUSDT", "1d", start, 5, varyingFeatures)
+	repo := NewWindowRepo(c)
+
+	tests := []struct {
+		name  string
+		since time.Time
+		limit int
+		want  []string
+	}{
+		{"after second", start.AddDate(0, 0, 1), 0, windowIDs(rows[2:])},
+		{"since is exclusive", start, 0, windowIDs(rows[1:])},
+		{"limited keeps oldest", start.AddDate(0, 0, 1), 2, windowIDs(rows[2:4])},
+		{"none newer", start.AddDate(0, 0, 4), 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.GetRecentWindowIDs(context.Background(), "BTCUSDT", "1d", tt.since, tt.limit)
+			if err != nil {
+				t.Fatalf("GetRecentWindowIDs: %v", err)
+			}
+			if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("GetRecentWindowIDs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetWindowIDsWithoutOutcomes(t *testing.T) {
+	c := newTestClient(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := seedFeatures(t, c, "BTCUSDT", "1d", start, 4, varyingFeatures)
+
+	// Window 0 has both horizons, window 2 only horizon 24
+	outcomes := []*model.Outcome{
+		{WindowID: rows[0].WindowID, Horizon: 12},
+		{WindowID: rows[0].WindowID, Horizon: 24},
+		{WindowID: rows[2].WindowID, Horizon: 24},
+	}
+	if err := NewOutcomeRepo(c).InsertBatch(context.Background(), outcomes); err != nil {
+		t.Fatalf("InsertBatch outcomes: %v", err)
+	}
+
+	for _, tt := range []struct {
+		horizon int
+		want    []string
+	}{
+		{12, windowIDs([]*model.FeatureRow{rows[1], rows[2], rows[3]})},
+		{24, windowIDs([]*model.FeatureRow{rows[1], rows[3]})},
+		{48, windowIDs(rows)},
+	} {
+		got, err := NewWindowRepo(c).GetWindowIDsWithoutOutcomes(context.Background(), "BTCUSDT", "1d", tt.horizon)
+		if err != nil {
+			t.Fatalf("GetWindowIDsWithoutOutcomes(%d): %v", tt.horizon, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GetWindowIDsWithoutOutcomes(%d) = %v, want %v", tt.horizon, got, tt.want)
+		}
+	}
+}
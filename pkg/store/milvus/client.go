@@ -2,7 +2,9 @@ package milvus
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/milvus-io/milvus-sdk-go/v2/client"
 	"github.com/milvus-io/milvus-sdk-go/v2/entity"
@@ -12,6 +14,12 @@ import (
 type Client struct {
 	conn client.Client
 	addr string
+
+	partitionGranularity string // Time partitioning for inserts and searches; empty disables
+
+	mu        sync.Mutex
+	validated map[string]int // Collection name -> embedding dimension whose schema was validated
+	nlists    map[string]int // Collection name -> nlist of its embedding index, which sets the search nprobe
 }
 
 // Config holds Milvus connection configuration
@@ -19,6 +27,10 @@ type Config struct {
 	Address  string // Milvus server address (e.g., "localhost:19530")
 	Username string // Optional username for authentication
 	Password string // Optional password for authentication
+
+	// PartitionGranularity routes inserts to, and prunes searches by, time partitions ("year" or "quarter")
+	// Empty keeps all data in the default partition
+	PartitionGranularity string
 }
 
 // DefaultConfig returns a Config with default values
@@ -33,6 +45,12 @@ func NewClient(ctx context.Context, cfg Config) (*Client, error) {
 	var conn client.Client
 	var err error
 
+	if cfg.PartitionGranularity != "" {
+		if err := validateGranularity(cfg.PartitionGranularity); err != nil {
+			return nil, err
+		}
+	}
+
 	if cfg.Username != "" && cfg.Password != "" {
 		conn, err = client.NewClient(ctx, client.Config{
 			Address:  cfg.Address,
@@ -50,8 +68,11 @@ func NewClient(ctx context.Context, cfg Config) (*Client, error) {
 	}
 
 	return &Client{
-		conn: conn,
-		addr: cfg.Address,
+		conn:                 conn,
+		addr:                 cfg.Address,
+		partitionGranularity: cfg.PartitionGranularity,
+		validated:            make(map[string]int),
+		nlists:               make(map[string]int),
 	}, nil
 }
 
@@ -75,11 +96,12 @@ func (c *Client) HasCollection(ctx context.Context, name string) (bool, error) {
 
 // CreateIndex creates an IVF_FLAT index on the embedding field
 func (c *Client) CreateIndex(ctx context.Context, collectionName, fieldName string) error {
-	idx, err := entity.NewIndexIvfFlat(entity.COSINE, 128)
+	idx, err := entity.NewIndexIvfFlat(entity.COSINE, defaultNlist)
 	if err != nil {
 		return fmt.Errorf("failed to create index: %w", err)
 	}
 
+	c.setNlist(collectionName, 0)
 	return c.conn.CreateIndex(ctx, collectionName, fieldName, idx, false)
 }
 
@@ -97,3 +119,15 @@ func (c *Client) ReleaseCollection(ctx context.Context, collectionName string) e
 func (c *Client) DropCollection(ctx context.Context, collectionName string) error {
 	return c.conn.DropCollection(ctx, collectionName)
 }
+
+// SetAlias points alias at collectionName, moving it from another collection if it already exists
+func (c *Client) SetAlias(ctx context.Context, collectionName, alias string) error {
+	alterErr := c.conn.AlterAlias(ctx, collectionName, alias)
+	if alterErr == nil {
+		return nil
+	}
+	if err := c.conn.CreateAlias(ctx, collectionName, alias); err != nil {
+		return fmt.Errorf("failed to set alias %s: %w", alias, errors.Join(alterErr, err))
+	}
+	return nil
+}
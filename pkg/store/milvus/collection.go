@@ -2,10 +2,16 @@ package milvus
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"strconv"
 	"time"
 
+	"github.com/milvus-io/milvus-sdk-go/v2/client"
 	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+	"golang.org/x/sync/errgroup"
 )
 
 const (
@@ -120,11 +126,57 @@ func (c *Client) Insert(ctx context.Context, collectionName string, data *Window
 }
 
 // InsertBatch inserts multiple window embeddings
+// The collection schema is validated against the embedding dimension before the first insert
+// With time partitioning enabled, each window is routed to the partition for its TEnd
 func (c *Client) InsertBatch(ctx context.Context, collectionName string, dataList []*WindowData) error {
+	return c.writeBatch(ctx, collectionName, dataList, false)
+}
+
+// UpsertBatch inserts multiple window embeddings, replacing any already stored under the same window IDs
+// Partitioning and schema validation follow InsertBatch
+func (c *Client) UpsertBatch(ctx context.Context, collectionName string, dataList []*WindowData) error {
+	return c.writeBatch(ctx, collectionName, dataList, true)
+}
+
+// writeBatch inserts or upserts window embeddings, routed to their time partitions
+func (c *Client) writeBatch(ctx context.Context, collectionName string, dataList []*WindowData, upsert bool) error {
 	if len(dataList) == 0 {
 		return nil
 	}
 
+	if err := c.ensureSchema(ctx, collectionName, len(dataList[0].Embedding)); err != nil {
+		return err
+	}
+
+	if c.partitionGranularity == "" {
+		return c.writePartition(ctx, collectionName, "", dataList, upsert)
+	}
+
+	// Group by partition, preserving input order within each group
+	var order []string
+	groups := make(map[string][]*WindowData)
+	for _, d := range dataList {
+		name := PartitionNameForTime(d.TEnd, c.partitionGranularity)
+		if _, ok := groups[name]; !ok {
+			order = append(order, name)
+		}
+		groups[name] = append(groups[name], d)
+	}
+
+	for _, name := range order {
+		if err := c.ensurePartition(ctx, collectionName, name); err != nil {
+			return err
+		}
+		if err := c.writePartition(ctx, collectionName, name, groups[name], upsert); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writePartition inserts or upserts window embeddings into a single partition ("" for the default)
+func (c *Client) writePartition(ctx context.Context, collectionName, partitionName string, dataList []*WindowData, upsert bool) error {
 	// Prepare column data
 	windowIDs := make([]string, len(dataList))
 	embeddings := make([][]float32, len(dataList))
@@ -158,8 +210,14 @@ func (c *Client) InsertBatch(ctx context.Context, collectionName string, dataLis
 		entity.NewColumnInt32("data_version", dataVersions),
 	}
 
-	_, err := c.conn.Insert(ctx, collectionName, "", columns...)
-	if err != nil {
+	if upsert {
+		if _, err := c.conn.Upsert(ctx, collectionName, partitionName, columns...); err != nil {
+			return fmt.Errorf("failed to upsert: %w", err)
+		}
+		return nil
+	}
+
+	if _, err := c.conn.Insert(ctx, collectionName, partitionName, columns...); err != nil {
 		return fmt.Errorf("failed to insert: %w", err)
 	}
 
@@ -176,29 +234,39 @@ type SearchResult struct {
 	VolBucket   int32
 	TrendBucket int32
 	DataVersion int32
+	Embedding   []float32 // Populated only when the embedding field is requested
 }
 
+// resultOutputFields lists the scalar fields parsed into a SearchResult
+var resultOutputFields = []string{"window_id", "symbol", "timeframe", "t_end", "vol_bucket", "trend_bucket", "data_version"}
+
+// queryPageSize is the batch size used by QueryAll when paging through results
+const queryPageSize = 1000
+
 // Search performs a TopK similarity search
 func (c *Client) Search(ctx context.Context, collectionName string, embedding []float32, filter string, topK int) ([]SearchResult, error) {
 	// Create search vectors
 	vectors := []entity.Vector{entity.FloatVector(embedding)}
 
-	// Search parameters
-	sp, err := entity.NewIndexIvfFlatSearchParam(16) // nprobe
+	// Probe a share of clusters that scales with the index's nlist, which AnnealIndex may have raised
+	sp, err := entity.NewIndexIvfFlatSearchParam(nprobeFor(c.storedNlist(ctx, collectionName)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create search param: %w", err)
 	}
 
-	// Output fields
-	outputFields := []string{"window_id", "symbol", "timeframe", "t_end", "vol_bucket", "trend_bucket", "data_version"}
+	// Restrict to partitions the filter's t_end bounds can match
+	partitions, err := c.searchPartitions(ctx, collectionName, filter)
+	if err != nil {
+		return nil, err
+	}
 
 	// Execute search
 	results, err := c.conn.Search(
 		ctx,
 		collectionName,
-		nil,          // partitions
-		filter,       // expression filter
-		outputFields, // output fields
+		partitions,         // partitions
+		filter,             // expression filter
+		resultOutputFields, // output fields
 		vectors,
 		"embedding",
 		entity.COSINE,
@@ -221,45 +289,7 @@ func (c *Client) Search(ctx context.Context, collectionName string, embedding []
 		}
 
 		// Extract fields from columns
-		for _, field := range results[0].Fields {
-			switch field.Name() {
-			case "window_id":
-				if col, ok := field.(*entity.ColumnVarChar); ok {
-					val, _ := col.ValueByIdx(i)
-					result.WindowID = val
-				}
-			case "symbol":
-				if col, ok := field.(*entity.ColumnVarChar); ok {
-					val, _ := col.ValueByIdx(i)
-					result.Symbol = val
-				}
-			case "timeframe":
-				if col, ok := field.(*entity.ColumnVarChar); ok {
-					val, _ := col.ValueByIdx(i)
-					result.Timeframe = val
-				}
-			case "t_end":
-				if col, ok := field.(*entity.ColumnInt64); ok {
-					val, _ := col.ValueByIdx(i)
-					result.TEnd = time.Unix(val, 0)
-				}
-			case "vol_bucket":
-				if col, ok := field.(*entity.ColumnInt32); ok {
-					val, _ := col.ValueByIdx(i)
-					result.VolBucket = val
-				}
-			case "trend_bucket":
-				if col, ok := field.(*entity.ColumnInt32); ok {
-					val, _ := col.ValueByIdx(i)
-					result.TrendBucket = val
-				}
-			case "data_version":
-				if col, ok := field.(*entity.ColumnInt32); ok {
-					val, _ := col.ValueByIdx(i)
-					result.DataVersion = val
-				}
-			}
-		}
+		parseResultFields(results[0].Fields, i, &result)
 
 		searchResults = append(searchResults, result)
 	}
@@ -267,6 +297,267 @@ func (c *Client) Search(ctx context.Context, collectionName string, embedding []
 	return searchResults, nil
 }
 
+// SearchQuery is one query of a SearchBatch
+type SearchQuery struct {
+	Embedding []float32
+	Filter    string
+}
+
+// searchBatchWorkers caps the searches a SearchBatch runs at once
+const searchBatchWorkers = 8
+
+// SearchBatch runs each query through Search on up to searchBatchWorkers goroutines
+// Results are returned in query order. The first failure, or cancellation of ctx, stops the remaining searches
+func (c *Client) SearchBatch(ctx context.Context, collectionName string, queries []SearchQuery, topK int) ([][]SearchResult, error) {
+	if len(queries) == 0 {
+		return nil, nil
+	}
+
+	results := make([][]SearchResult, len(queries))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(min(len(queries), searchBatchWorkers))
+	for i, q := range queries {
+		g.Go(func() error {
+			if err := gctx.Err(); err != nil {
+				return err
+			}
+			r, err := c.Search(gctx, collectionName, q.Embedding, q.Filter, topK)
+			if err != nil {
+				return fmt.Errorf("query %d: %w", i, err)
+			}
+			results[i] = r
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// QueryByAttributes retrieves windows matching a scalar filter expression without a query vector
+// Results reuse SearchResult with Score set to 1.0 as a sentinel; outputFields defaults to all scalar fields
+func (c *Client) QueryByAttributes(ctx context.Context, collectionName, expr string, outputFields []string, limit int) ([]SearchResult, error) {
+	if len(outputFields) == 0 {
+		outputFields = resultOutputFields
+	}
+
+	var opts []client.SearchQueryOptionFunc
+	if limit > 0 {
+		opts = append(opts, client.WithLimit(int64(limit)))
+	}
+
+	rs, err := c.conn.Query(ctx, collectionName, nil, expr, outputFields, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %w", err)
+	}
+
+	return parseQueryResults(rs), nil
+}
+
+// GetByIDs retrieves windows by primary key, optionally including their embeddings
+func (c *Client) GetByIDs(ctx context.Context, collectionName string, windowIDs []string, withEmbedding bool) ([]SearchResult, error) {
+	if len(windowIDs) == 0 {
+		return nil, nil
+	}
+
+	outputFields := resultOutputFields
+	if withEmbedding {
+		outputFields = append(append([]string{}, resultOutputFields...), "embedding")
+	}
+
+	rs, err := c.conn.Get(ctx, collectionName, entity.NewColumnVarChar("window_id", windowIDs),
+		client.GetWithOutputFields(outputFields...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get windows by id: %w", err)
+	}
+
+	return parseQueryResults(rs), nil
+}
+
+// QueryAll retrieves every window matching expr, paging through results by primary key
+func (c *Client) QueryAll(ctx context.Context, collectionName, expr string) ([]SearchResult, error) {
+	opt := client.NewQueryIteratorOption(collectionName).
+		WithExpr(expr).
+		WithOutputFields(resultOutputFields...).
+		WithBatchSize(queryPageSize)
+
+	itr, err := c.conn.QueryIterator(ctx, opt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query iterator: %w", err)
+	}
+
+	var all []SearchResult
+	for {
+		rs, err := itr.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch query page: %w", err)
+		}
+		all = append(all, parseQueryResults(rs)...)
+	}
+
+	return all, nil
+}
+
+// parseQueryResults converts a query result set into SearchResults with a sentinel score of 1.0
+func parseQueryResults(rs client.ResultSet) []SearchResult {
+	n := rs.Len()
+	results := make([]SearchResult, 0, n)
+	for i := 0; i < n; i++ {
+		result := SearchResult{Score: 1.0}
+		parseResultFields(rs, i, &result)
+		results = append(results, result)
+	}
+	return results
+}
+
+// parseResultFields copies the scalar fields of row i into result
+func parseResultFields(fields []entity.Column, i int, result *SearchResult) {
+	for _, field := range fields {
+		switch field.Name() {
+		case "window_id":
+			if col, ok := field.(*entity.ColumnVarChar); ok {
+				val, _ := col.ValueByIdx(i)
+				result.WindowID = val
+			}
+		case "symbol":
+			if col, ok := field.(*entity.ColumnVarChar); ok {
+				val, _ := col.ValueByIdx(i)
+				result.Symbol = val
+			}
+		case "timeframe":
+			if col, ok := field.(*entity.ColumnVarChar); ok {
+				val, _ := col.ValueByIdx(i)
+				result.Timeframe = val
+			}
+		case "t_end":
+			if col, ok := field.(*entity.ColumnInt64); ok {
+				val, _ := col.ValueByIdx(i)
+				result.TEnd = time.Unix(val, 0)
+			}
+		case "vol_bucket":
+			if col, ok := field.(*entity.ColumnInt32); ok {
+				val, _ := col.ValueByIdx(i)
+				result.VolBucket = val
+			}
+		case "trend_bucket":
+			if col, ok := field.(*entity.ColumnInt32); ok {
+				val, _ := col.ValueByIdx(i)
+				result.TrendBucket = val
+			}
+		case "data_version":
+			if col, ok := field.(*entity.ColumnInt32); ok {
+				val, _ := col.ValueByIdx(i)
+				result.DataVersion = val
+			}
+		case "embedding":
+			if col, ok := field.(*entity.ColumnFloatVector); ok && i < len(col.Data()) {
+				result.Embedding = col.Data()[i]
+			}
+		}
+	}
+}
+
+// Bounds of the nlist chosen by AnnealIndex; Milvus accepts IVF nlist in [1, 65536]
+const (
+	minAnnealNlist = 1
+	maxAnnealNlist = 65536
+)
+
+// AnnealIndex rebuilds the IVF_FLAT index on fieldName with nlist sized to the collection's final row count
+// Meant to run once after a bulk load has been flushed: the index built while rows were still arriving
+// was sized with a fixed nlist. The collection is checked against cfg before its index is dropped,
+// is unavailable for search while the index rebuilds, and is loaded again on success.
+// If the rebuild fails, the previous index is restored and the collection reloaded; when that fails too,
+// the error says how to recover by hand.
+//
+// IVF splits the vectors into nlist clusters; a search compares the query with every centroid, then scans
+// the nprobe nearest clusters of about N/nlist vectors each. The cost nlist + nprobe×N/nlist is smallest
+// near nlist = sqrt(N), which balances the centroid scan against the cluster scans. More clusters make each
+// probed cluster smaller and searches faster, but a probe then covers a smaller share of the data, so Search
+// raises nprobe with the stored nlist (see nprobeFor) to hold recall; building the index also takes longer.
+func (c *Client) AnnealIndex(ctx context.Context, collectionName, fieldName string, cfg CollectionConfig) error {
+	if err := c.ValidateSchema(ctx, collectionName, cfg); err != nil {
+		return err
+	}
+
+	stats, err := c.conn.GetCollectionStatistics(ctx, collectionName)
+	if err != nil {
+		return fmt.Errorf("failed to get collection statistics: %w", err)
+	}
+	rows, err := strconv.ParseInt(stats["row_count"], 10, 64)
+	if err != nil {
+		return fmt.Errorf("failed to parse row count %q: %w", stats["row_count"], err)
+	}
+	nlist := min(max(int(math.Round(math.Sqrt(float64(rows)))), minAnnealNlist), maxAnnealNlist)
+
+	idx, err := entity.NewIndexIvfFlat(entity.COSINE, nlist)
+	if err != nil {
+		return fmt.Errorf("failed to create index: %w", err)
+	}
+
+	// Remember the current index so a failed rebuild can put it back
+	oldNlist := defaultNlist
+	if indexes, err := c.conn.DescribeIndex(ctx, collectionName, fieldName); err == nil && len(indexes) > 0 {
+		if n, ok := indexNlist(indexes[0].Params()); ok {
+			oldNlist = n
+		}
+	}
+
+	if err := c.conn.ReleaseCollection(ctx, collectionName); err != nil {
+		return fmt.Errorf("failed to release collection: %w", err)
+	}
+	if err := c.conn.DropIndex(ctx, collectionName, fieldName); err != nil {
+		return fmt.Errorf("failed to drop index: %w", err)
+	}
+	c.setNlist(collectionName, 0)
+	if err := c.conn.CreateIndex(ctx, collectionName, fieldName, idx, false); err != nil {
+		err = fmt.Errorf("failed to rebuild index with nlist %d: %w", nlist, err)
+		return c.restoreIndex(ctx, collectionName, fieldName, oldNlist, err)
+	}
+	if err := c.conn.LoadCollection(ctx, collectionName, false); err != nil {
+		return fmt.Errorf("failed to load collection: %w", err)
+	}
+	c.setNlist(collectionName, nlist)
+	return nil
+}
+
+// restoreIndex rebuilds the IVF_FLAT index with oldNlist after a failed anneal and reloads the collection
+// It returns annealErr, extended with a manual recovery hint if the restore fails as well
+func (c *Client) restoreIndex(ctx context.Context, collectionName, fieldName string, oldNlist int, annealErr error) error {
+	idx, err := entity.NewIndexIvfFlat(entity.COSINE, oldNlist)
+	if err == nil {
+		err = c.conn.CreateIndex(ctx, collectionName, fieldName, idx, false)
+	}
+	if err == nil {
+		err = c.conn.LoadCollection(ctx, collectionName, false)
+	}
+	if err != nil {
+		return fmt.Errorf("%w; restoring the previous index (nlist %d) also failed: %v; collection %s has no index and is "+
+			"not loaded, so rerun backfill -anneal-index or create an IVF_FLAT index on %s and load the collection",
+			annealErr, oldNlist, err, collectionName, fieldName)
+	}
+	return fmt.Errorf("%w; restored the previous index (nlist %d)", annealErr, oldNlist)
+}
+
+// Count returns the number of entities matching expr; the collection must be loaded
+func (c *Client) Count(ctx context.Context, collectionName, expr string) (int64, error) {
+	rs, err := c.conn.Query(ctx, collectionName, nil, expr, []string{"count(*)"})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count entities: %w", err)
+	}
+
+	col, ok := rs.GetColumn("count(*)").(*entity.ColumnInt64)
+	if !ok || col.Len() == 0 {
+		return 0, fmt.Errorf("failed to count entities: unexpected count result")
+	}
+	return col.ValueByIdx(0)
+}
+
 // Flush flushes the collection to ensure data persistence
 func (c *Client) Flush(ctx context.Context, collectionName string) error {
 	return c.conn.Flush(ctx, collectionName, false)
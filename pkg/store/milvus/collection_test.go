@@ -0,0 +1,88 @@
+package milvus
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/milvus-io/milvus-sdk-go/v2/client"
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+)
+
+// latencyConn answers each search after latency with one hit whose window ID is the search's filter
+type latencyConn struct {
+	client.Client
+	latency time.Duration
+}
+
+func (c *latencyConn) Search(ctx context.Context, collName string, partitions []string, expr string, outputFields []string,
+	vectors []entity.Vector, vectorField string, metricType entity.MetricType, topK int, sp entity.SearchParam, opts ...client.SearchQueryOptionFunc) ([]client.SearchResult, error) {
+	select {
+	case <-time.After(c.latency):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return []client.SearchResult{{
+		ResultCount: 1,
+		Scores:      []float32{0.9},
+		Fields:      client.ResultSet{entity.NewColumnVarChar("window_id", []string{expr})},
+	}}, nil
+}
+
+// newLatencyClient returns a client whose searches of "windows" take latency each
+func newLatencyClient(latency time.Duration) *Client {
+	return &Client{
+		conn:   &latencyConn{latency: latency},
+		nlists: map[string]int{"windows": 128}, // Skips describing the index
+	}
+}
+
+// batchQueries returns n queries whose filters number them
+func batchQueries(n int) []SearchQuery {
+	queries := make([]SearchQuery, n)
+	for i := range queries {
+		queries[i] = SearchQuery{Embedding: []float32{1, 0}, Filter: fmt.Sprintf("q%d", i)}
+	}
+	return queries
+}
+
+func TestSearchBatchKeepsQueryOrder(t *testing.T) {
+	c := newLatencyClient(time.Millisecond)
+	queries := batchQueries(20)
+
+	results, err := c.SearchBatch(context.Background(), "windows", queries, 5)
+	if err != nil {
+		t.Fatalf("SearchBatch: %v", err)
+	}
+	for i, r := range results {
+		if len(r) != 1 || r[0].WindowID != queries[i].Filter {
+			t.Errorf("results[%d] = %+v, want the hit of %s", i, r, queries[i].Filter)
+		}
+	}
+}
+
+// BenchmarkSearchBatch compares searching 32 queries one after another with SearchBatch
+// Each search waits 2ms, standing in for a Milvus round trip
+func BenchmarkSearchBatch(b *testing.B) {
+	c := newLatencyClient(2 * time.Millisecond)
+	queries := batchQueries(32)
+	ctx := context.Background()
+
+	b.Run("sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, q := range queries {
+				if _, err := c.Search(ctx, "windows", q.Embedding, q.Filter, 10); err != nil {
+					b.Fatalf("Search: %v", err)
+				}
+			}
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := c.SearchBatch(ctx, "windows", queries, 10); err != nil {
+				b.Fatalf("SearchBatch: %v", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package milvus
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter builds a search expression from clauses joined with &&
+type Filter struct {
+	clauses []string
+}
+
+// NewFilter creates an empty filter, which matches every row
+func NewFilter() *Filter {
+	return &Filter{}
+}
+
+// In restricts a VARCHAR field to values; no values adds no clause
+func (f *Filter) In(field string, values ...string) *Filter {
+	if len(values) == 0 {
+		return f
+	}
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+	if len(quoted) == 1 {
+		return f.add(fmt.Sprintf("%s == %s", field, quoted[0]))
+	}
+	return f.add(fmt.Sprintf("%s in [%s]", field, strings.Join(quoted, ", ")))
+}
+
+// IntIn restricts an integer field to values; no values adds no clause
+// Contiguous values become a range comparison, which Milvus evaluates without a list scan
+func (f *Filter) IntIn(field string, values ...int) *Filter {
+	if len(values) == 0 {
+		return f
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+
+	lo, hi := sorted[0], sorted[len(sorted)-1]
+	switch {
+	case lo == hi:
+		return f.add(fmt.Sprintf("%s == %d", field, lo))
+	case hi-lo == len(sorted)-1:
+		return f.add(fmt.Sprintf("%s >= %d && %s <= %d", field, lo, field, hi))
+	}
+	items := make([]string, len(sorted))
+	for i, v := range sorted {
+		items[i] = strconv.Itoa(v)
+	}
+	return f.add(fmt.Sprintf("%s in [%s]", field, strings.Join(items, ", ")))
+}
+
+// TEndRange restricts t_end to [from, to); a zero time leaves that side open
+// The bounds are plain comparisons, so time partitions outside them are skipped by Search
+func (f *Filter) TEndRange(from, to time.Time) *Filter {
+	if !from.IsZero() {
+		f.add(fmt.Sprintf("t_end >= %d", from.Unix()))
+	}
+	if !to.IsZero() {
+		f.add(fmt.Sprintf("t_end < %d", to.Unix()))
+	}
+	return f
+}
+
+// String returns the expression, or "" when there are no clauses
+func (f *Filter) String() string {
+	return strings.Join(f.clauses, " && ")
+}
+
+// add appends a clause
+func (f *Filter) add(clause string) *Filter {
+	f.clauses = append(f.clauses, clause)
+	return f
+}
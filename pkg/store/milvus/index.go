@@ -0,0 +1,79 @@
+package milvus
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+)
+
+// IVF parameters of the index built by CreateIndex; searches probe the same share of clusters at any nlist
+const (
+	defaultNlist  = 128
+	defaultNprobe = 16
+)
+
+// nprobeFor returns the nprobe searching an IVF index of nlist clusters
+// It keeps the defaultNprobe/defaultNlist share of clusters probed, never below defaultNprobe or above nlist,
+// so an index annealed to a larger nlist keeps its recall
+func nprobeFor(nlist int) int {
+	if nlist <= 0 {
+		return defaultNprobe
+	}
+	nprobe := (nlist*defaultNprobe + defaultNlist - 1) / defaultNlist
+	return min(max(nprobe, defaultNprobe), nlist)
+}
+
+// indexNlist reads nlist from described index params, which carry it either directly or in a "params" JSON object
+func indexNlist(params map[string]string) (int, bool) {
+	if v, ok := params["nlist"]; ok {
+		n, err := strconv.Atoi(v)
+		return n, err == nil && n > 0
+	}
+
+	var nested map[string]any
+	if err := json.Unmarshal([]byte(params["params"]), &nested); err != nil {
+		return 0, false
+	}
+	switch v := nested["nlist"].(type) {
+	case float64:
+		return int(v), v > 0
+	case string:
+		n, err := strconv.Atoi(v)
+		return n, err == nil && n > 0
+	}
+	return 0, false
+}
+
+// storedNlist returns the nlist of the embedding index of collectionName, describing it on first use
+// Returns 0 when the index cannot be described
+func (c *Client) storedNlist(ctx context.Context, collectionName string) int {
+	c.mu.Lock()
+	nlist, ok := c.nlists[collectionName]
+	c.mu.Unlock()
+	if ok {
+		return nlist
+	}
+
+	indexes, err := c.conn.DescribeIndex(ctx, collectionName, "embedding")
+	if err != nil || len(indexes) == 0 {
+		return 0
+	}
+	nlist, ok = indexNlist(indexes[0].Params())
+	if !ok {
+		return 0
+	}
+
+	c.setNlist(collectionName, nlist)
+	return nlist
+}
+
+// setNlist records the nlist of collectionName's embedding index; 0 forgets it so the next search describes it again
+func (c *Client) setNlist(collectionName string, nlist int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if nlist == 0 {
+		delete(c.nlists, collectionName)
+		return
+	}
+	c.nlists[collectionName] = nlist
+}
@@ -0,0 +1,44 @@
+package milvus
+
+import "testing"
+
+func TestNprobeFor(t *testing.T) {
+	tests := []struct {
+		nlist int
+		want  int
+	}{
+		{0, defaultNprobe}, // Index not described
+		{8, 8},             // Never more than every cluster
+		{128, 16},
+		{1000, 125},
+		{65536, 8192},
+	}
+	for _, tt := range tests {
+		if got := nprobeFor(tt.nlist); got != tt.want {
+			t.Errorf("nprobeFor(%d) = %d, want %d", tt.nlist, got, tt.want)
+		}
+	}
+}
+
+func TestIndexNlist(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]string
+		want   int
+		wantOK bool
+	}{
+		{"flat", map[string]string{"index_type": "IVF_FLAT", "nlist": "1000"}, 1000, true},
+		{"nested number", map[string]string{"params": `{"nlist":1000}`}, 1000, true},
+		{"nested string", map[string]string{"params": `{"nlist":"128"}`}, 128, true},
+		{"missing", map[string]string{"index_type": "HNSW", "params": `{"M":16}`}, 0, false},
+		{"malformed", map[string]string{"nlist": "many"}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := indexNlist(tt.params)
+			if ok != tt.wantOK || (ok && got != tt.want) {
+				t.Errorf("indexNlist = %d, %v; want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
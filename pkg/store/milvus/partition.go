@@ -0,0 +1,190 @@
+package milvus
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Time partition granularities
+const (
+	GranularityYear    = "year"
+	GranularityQuarter = "quarter"
+)
+
+// defaultPartitionName is Milvus's built-in partition, holding data inserted without a partition
+const defaultPartitionName = "_default"
+
+// partitionNamePattern matches names produced by PartitionNameForTime
+var partitionNamePattern = regexp.MustCompile(`^(?:y(\d{4})|q(\d{4})q([1-4]))$`)
+
+// tEndBoundPattern matches simple t_end comparisons in a filter expression
+var tEndBoundPattern = regexp.MustCompile(`t_end\s*(>=|<=|==|>|<)\s*(-?\d+)`)
+
+// PartitionNameForTime returns the partition holding windows ending at t, e.g. "y2023" or "q2023q1"
+// Returns an empty string (the default partition) for an unknown granularity
+func PartitionNameForTime(t time.Time, granularity string) string {
+	t = t.UTC()
+	switch granularity {
+	case GranularityYear:
+		return fmt.Sprintf("y%d", t.Year())
+	case GranularityQuarter:
+		return fmt.Sprintf("q%dq%d", t.Year(), (int(t.Month())-1)/3+1)
+	default:
+		return ""
+	}
+}
+
+// periodStart returns the start of the partition period containing t
+func periodStart(t time.Time, granularity string) time.Time {
+	t = t.UTC()
+	if granularity == GranularityQuarter {
+		month := time.Month((int(t.Month())-1)/3*3 + 1)
+		return time.Date(t.Year(), month, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// nextPeriod returns the start of the partition period following the one starting at start
+func nextPeriod(start time.Time, granularity string) time.Time {
+	if granularity == GranularityQuarter {
+		return start.AddDate(0, 3, 0)
+	}
+	return start.AddDate(1, 0, 0)
+}
+
+// validateGranularity returns an error unless granularity is year or quarter
+func validateGranularity(granularity string) error {
+	switch granularity {
+	case GranularityYear, GranularityQuarter:
+		return nil
+	default:
+		return fmt.Errorf("invalid partition granularity %q: must be %s or %s", granularity, GranularityYear, GranularityQuarter)
+	}
+}
+
+// CreateTimePartitions creates one partition per year or quarter covering [start, end]
+// Existing partitions are left untouched
+func (c *Client) CreateTimePartitions(ctx context.Context, collectionName string, start, end time.Time, granularity string) error {
+	if err := validateGranularity(granularity); err != nil {
+		return err
+	}
+
+	for p := periodStart(start, granularity); !p.After(end); p = nextPeriod(p, granularity) {
+		if err := c.ensurePartition(ctx, collectionName, PartitionNameForTime(p, granularity)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ensurePartition creates a partition if it does not exist yet
+func (c *Client) ensurePartition(ctx context.Context, collectionName, partitionName string) error {
+	has, err := c.conn.HasPartition(ctx, collectionName, partitionName)
+	if err != nil {
+		return fmt.Errorf("failed to check partition %s: %w", partitionName, err)
+	}
+	if has {
+		return nil
+	}
+
+	if err := c.conn.CreatePartition(ctx, collectionName, partitionName); err != nil {
+		return fmt.Errorf("failed to create partition %s: %w", partitionName, err)
+	}
+	return nil
+}
+
+// searchPartitions returns the partitions a filter's t_end bounds can match
+// Returns nil (search all partitions) when partitioning is disabled, the filter has no t_end bounds,
+// or the filter contains a disjunction whose bounds cannot be safely applied
+func (c *Client) searchPartitions(ctx context.Context, collectionName, filter string) ([]string, error) {
+	if c.partitionGranularity == "" {
+		return nil, nil
+	}
+
+	lower := strings.ToLower(filter)
+	if strings.Contains(lower, "||") || strings.Contains(lower, " or ") || strings.Contains(lower, "not") {
+		return nil, nil
+	}
+
+	from, to, ok := parseTEndBounds(filter)
+	if !ok {
+		return nil, nil
+	}
+
+	partitions, err := c.conn.ShowPartitions(ctx, collectionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+
+	var names []string
+	for _, p := range partitions {
+		if p.Name == defaultPartitionName {
+			// Data inserted before partitioning was enabled lives here
+			names = append(names, p.Name)
+			continue
+		}
+
+		start, end, ok := partitionPeriod(p.Name)
+		if !ok {
+			continue
+		}
+		if end.After(from) && !start.After(to) {
+			names = append(names, p.Name)
+		}
+	}
+
+	return names, nil
+}
+
+// parseTEndBounds extracts the inclusive t_end range implied by simple comparisons in filter
+func parseTEndBounds(filter string) (from, to time.Time, ok bool) {
+	from = time.Unix(0, 0).UTC().AddDate(-100, 0, 0)
+	to = time.Unix(0, 0).UTC().AddDate(1000, 0, 0)
+
+	for _, m := range tEndBoundPattern.FindAllStringSubmatch(filter, -1) {
+		v, err := strconv.ParseInt(m[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		t := time.Unix(v, 0).UTC()
+		switch m[1] {
+		case ">=", ">":
+			if t.After(from) {
+				from = t
+			}
+		case "<=", "<":
+			if t.Before(to) {
+				to = t
+			}
+		case "==":
+			from, to = t, t
+		}
+		ok = true
+	}
+
+	return from, to, ok
+}
+
+// partitionPeriod returns the [start, end) period covered by a time partition name
+func partitionPeriod(name string) (start, end time.Time, ok bool) {
+	m := partitionNamePattern.FindStringSubmatch(name)
+	if m == nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	if m[1] != "" {
+		year, _ := strconv.Atoi(m[1])
+		start = time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		return start, nextPeriod(start, GranularityYear), true
+	}
+
+	year, _ := strconv.Atoi(m[2])
+	quarter, _ := strconv.Atoi(m[3])
+	start = time.Date(year, time.Month((quarter-1)*3+1), 1, 0, 0, 0, 0, time.UTC)
+	return start, nextPeriod(start, GranularityQuarter), true
+}
@@ -0,0 +1,113 @@
+package milvus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrSchemaMismatch is returned when a stored collection schema differs from what this code expects
+var ErrSchemaMismatch = errors.New("milvus schema mismatch")
+
+// embeddingField is the vector field of the kline_windows collection
+const embeddingField = "embedding"
+
+// FieldInfo describes one field of a stored collection
+type FieldInfo struct {
+	Name      string
+	DataType  string
+	IsPrimary bool
+	Dim       int // Vector dimension (0 for scalar fields)
+}
+
+// CollectionSchema describes a stored collection
+type CollectionSchema struct {
+	Name   string
+	Fields []FieldInfo
+}
+
+// Field returns the named field, or nil if the schema has none
+func (s *CollectionSchema) Field(name string) *FieldInfo {
+	for i := range s.Fields {
+		if s.Fields[i].Name == name {
+			return &s.Fields[i]
+		}
+	}
+	return nil
+}
+
+// GetCollectionSchema returns the schema of a stored collection
+func (c *Client) GetCollectionSchema(ctx context.Context, collectionName string) (*CollectionSchema, error) {
+	coll, err := c.conn.DescribeCollection(ctx, collectionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe collection: %w", err)
+	}
+
+	schema := &CollectionSchema{Name: coll.Name}
+	if coll.Schema == nil {
+		return schema, nil
+	}
+
+	for _, f := range coll.Schema.Fields {
+		info := FieldInfo{
+			Name:      f.Name,
+			DataType:  f.DataType.Name(),
+			IsPrimary: f.PrimaryKey,
+		}
+		if dim, ok := f.TypeParams["dim"]; ok {
+			info.Dim, _ = strconv.Atoi(dim)
+		}
+		schema.Fields = append(schema.Fields, info)
+	}
+
+	return schema, nil
+}
+
+// ValidateSchema checks that a stored collection has the fields this code reads and writes,
+// with a window_id primary key and an embedding dimension equal to expected.Dimension
+func (c *Client) ValidateSchema(ctx context.Context, collectionName string, expected CollectionConfig) error {
+	schema, err := c.GetCollectionSchema(ctx, collectionName)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	for _, name := range append([]string{embeddingField}, resultOutputFields...) {
+		if schema.Field(name) == nil {
+			problems = append(problems, fmt.Sprintf("missing field %q", name))
+		}
+	}
+	if pk := schema.Field("window_id"); pk != nil && !pk.IsPrimary {
+		problems = append(problems, "window_id is not the primary key")
+	}
+	if emb := schema.Field(embeddingField); emb != nil && expected.Dimension > 0 && emb.Dim != expected.Dimension {
+		problems = append(problems, fmt.Sprintf("embedding dim is %d, expected %d", emb.Dim, expected.Dimension))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: collection %s: %s", ErrSchemaMismatch, collectionName, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// ensureSchema validates a collection's schema against the embedding dimension being written
+// Successful validations are cached per collection and dimension
+func (c *Client) ensureSchema(ctx context.Context, collectionName string, dim int) error {
+	c.mu.Lock()
+	ok := c.validated[collectionName] == dim
+	c.mu.Unlock()
+	if ok {
+		return nil
+	}
+
+	if err := c.ValidateSchema(ctx, collectionName, CollectionConfig{Name: collectionName, Dimension: dim}); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.validated[collectionName] = dim
+	c.mu.Unlock()
+	return nil
+}
@@ -1,6 +1,9 @@
 package window
 
 import (
+	"fmt"
+	"math"
+
 	"github.com/tunogya/etna/pkg/model"
 )
 
@@ -20,12 +23,13 @@ type Builder struct {
 
 // Config holds configuration for window builder
 type Config struct {
-	W              int    // Window length
-	S              int    // Step size
-	Warmup         int    // Warmup period (defaults to W if 0)
-	FeatureVersion int    // Feature version (defaults to 1)
-	Symbol         string // Trading pair
-	Timeframe      string // Timeframe
+	W              int     // Window length
+	S              int     // Step size
+	OverlapRatio   float64 // Fraction of candles shared with the previous window, in [0, 1); overrides S when > 0
+	Warmup         int     // Warmup period (defaults to W if 0)
+	FeatureVersion int     // Feature version (defaults to 1)
+	Symbol         string  // Trading pair
+	Timeframe      string  // Timeframe
 }
 
 // DefaultConfig returns a Config with sensible defaults
@@ -40,8 +44,28 @@ func DefaultConfig(symbol, timeframe string) Config {
 	}
 }
 
+// StepForOverlap returns the step size giving each window of w candles the given overlap ratio with the previous one
+// The step is rounded to the nearest candle, since (1 - 0.8) × 10 is 1.999… in floating point, and is at least 1
+func StepForOverlap(w int, overlapRatio float64) int {
+	s := int(math.Round((1 - overlapRatio) * float64(w)))
+	if s < 1 {
+		return 1
+	}
+	return s
+}
+
 // NewBuilder creates a new window builder with the given configuration
-func NewBuilder(cfg Config) *Builder {
+// Returns an error if OverlapRatio is outside [0, 1)
+func NewBuilder(cfg Config) (*Builder, error) {
+	if cfg.OverlapRatio < 0 || cfg.OverlapRatio >= 1 {
+		return nil, fmt.Errorf("overlap ratio %v out of range [0, 1)", cfg.OverlapRatio)
+	}
+
+	step := cfg.S
+	if cfg.OverlapRatio > 0 {
+		step = StepForOverlap(cfg.W, cfg.OverlapRatio)
+	}
+
 	warmup := cfg.Warmup
 	if warmup <= 0 {
 		warmup = cfg.W
@@ -49,7 +73,7 @@ func NewBuilder(cfg Config) *Builder {
 
 	return &Builder{
 		W:              cfg.W,
-		S:              cfg.S,
+		S:              step,
 		Warmup:         warmup,
 		FeatureVersion: cfg.FeatureVersion,
 		Symbol:         cfg.Symbol,
@@ -57,7 +81,7 @@ func NewBuilder(cfg Config) *Builder {
 		buffer:         NewRingBuffer(cfg.W),
 		stepCount:      0,
 		warmedUp:       false,
-	}
+	}, nil
 }
 
 // Push adds a new candle and potentially produces a window
@@ -110,6 +134,50 @@ func (b *Builder) Reset() {
 	b.warmedUp = false
 }
 
+// BuilderState is a serializable snapshot of a builder's buffered candles and step position
+type BuilderState struct {
+	Symbol         string         `json:"symbol"`
+	Timeframe      string         `json:"timeframe"`
+	W              int            `json:"w"`
+	FeatureVersion int            `json:"feature_version"`
+	Candles        []model.Candle `json:"candles"` // Buffered candles, oldest first
+	StepCount      int            `json:"step_count"`
+	WarmedUp       bool           `json:"warmed_up"`
+}
+
+// Snapshot returns the builder's current state
+func (b *Builder) Snapshot() BuilderState {
+	return BuilderState{
+		Symbol:         b.Symbol,
+		Timeframe:      b.Timeframe,
+		W:              b.W,
+		FeatureVersion: b.FeatureVersion,
+		Candles:        b.buffer.ToSlice(),
+		StepCount:      b.stepCount,
+		WarmedUp:       b.warmedUp,
+	}
+}
+
+// Restore replaces the builder's state with a snapshot taken from a builder with the same configuration
+// Restored candles are pushed through the buffer, so restore before subscribing
+func (b *Builder) Restore(state BuilderState) error {
+	if state.Symbol != b.Symbol || state.Timeframe != b.Timeframe || state.W != b.W || state.FeatureVersion != b.FeatureVersion {
+		return fmt.Errorf("snapshot of %s %s W=%d v%d does not match builder %s %s W=%d v%d",
+			state.Symbol, state.Timeframe, state.W, state.FeatureVersion, b.Symbol, b.Timeframe, b.W, b.FeatureVersion)
+	}
+	if len(state.Candles) > b.W {
+		return fmt.Errorf("snapshot holds %d candles, more than W=%d", len(state.Candles), b.W)
+	}
+
+	b.buffer.Clear()
+	for _, c := range state.Candles {
+		b.buffer.Push(c)
+	}
+	b.stepCount = state.StepCount
+	b.warmedUp = state.WarmedUp
+	return nil
+}
+
 // IsWarmedUp returns true if the warmup period is complete
 func (b *Builder) IsWarmedUp() bool {
 	return b.warmedUp
@@ -120,6 +188,11 @@ func (b *Builder) CurrentSize() int {
 	return b.buffer.Size()
 }
 
+// Subscribe returns a channel receiving every candle pushed into the builder, and a cancel function
+func (b *Builder) Subscribe() (<-chan model.Candle, func()) {
+	return b.buffer.Subscribe()
+}
+
 // ProcessCandles processes a batch of candles and returns all produced windows
 func (b *Builder) ProcessCandles(candles []model.Candle) []*model.Window {
 	var windows []*model.Window
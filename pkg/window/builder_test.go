@@ -0,0 +1,97 @@
+package window
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+// testCandles returns n consecutive 1m candles of BTCUSDT
+func testCandles(n int) []model.Candle {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := make([]model.Candle, n)
+	for i := range candles {
+		open := start.Add(time.Duration(i) * time.Minute)
+		candles[i] = model.Candle{
+			Symbol:    "BTCUSDT",
+			Timeframe: "1m",
+			OpenTime:  open,
+			CloseTime: open.Add(time.Minute - time.Millisecond),
+			Open:      100,
+			High:      101,
+			Low:       99,
+			Close:     100,
+			Volume:    1,
+		}
+	}
+	return candles
+}
+
+func TestStepForOverlap(t *testing.T) {
+	tests := []struct {
+		w       int
+		overlap float64
+		want    int
+	}{
+		{10, 0.5, 5},
+		{60, 0.5, 30},
+		{10, 0.8, 2},
+		{20, 0.8, 4},
+		{60, 0.9, 6},
+		{7, 0.99, 1}, // Never below one candle
+	}
+	for _, tt := range tests {
+		if got := StepForOverlap(tt.w, tt.overlap); got != tt.want {
+			t.Errorf("StepForOverlap(%d, %v) = %d, want %d", tt.w, tt.overlap, got, tt.want)
+		}
+	}
+}
+
+func TestNewBuilderOverlapRatio(t *testing.T) {
+	tests := []struct {
+		name     string
+		overlap  float64
+		wantStep int
+		wantErr  bool
+	}{
+		{"step kept without overlap", 0, 3, false},
+		{"half overlap", 0.5, 5, false},
+		{"negative", -0.1, 0, true},
+		{"full overlap", 1, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := NewBuilder(Config{W: 10, S: 3, OverlapRatio: tt.overlap, FeatureVersion: 1, Symbol: "BTCUSDT", Timeframe: "1m"})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("NewBuilder succeeded, want an out-of-range error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewBuilder: %v", err)
+			}
+			if b.S != tt.wantStep {
+				t.Errorf("S = %d, want %d", b.S, tt.wantStep)
+			}
+		})
+	}
+}
+
+func TestBuilderHalfOverlapWindows(t *testing.T) {
+	b, err := NewBuilder(Config{W: 10, OverlapRatio: 0.5, FeatureVersion: 1, Symbol: "BTCUSDT", Timeframe: "1m"})
+	if err != nil {
+		t.Fatalf("NewBuilder: %v", err)
+	}
+
+	windows := b.ProcessCandles(testCandles(30))
+	if len(windows) != 5 {
+		t.Fatalf("got %d windows, want 5 (at candles 10, 15, 20, 25, 30)", len(windows))
+	}
+	for i := 1; i < len(windows); i++ {
+		if got := model.OverlapWithPrevious(windows[i-1], windows[i]); got < 0.49 || got > 0.51 {
+			t.Errorf("window %d overlaps the previous by %.3f, want 0.5", i, got)
+		}
+	}
+}
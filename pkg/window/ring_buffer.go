@@ -13,6 +13,32 @@ type RingBuffer struct {
 	size     int
 	head     int // points to the next write position
 	mu       sync.RWMutex
+
+	subscribers []*subscriber
+	sendMu      sync.Mutex // Serializes fan-out so subscribers see candles in push order
+}
+
+// subscriber is a fan-out consumer registered via Subscribe
+type subscriber struct {
+	ch   chan model.Candle
+	done chan struct{} // closed on cancel so a blocked Push can give up
+
+	mu     sync.Mutex // Held while sending, so cancel cannot close ch under a send
+	closed bool
+}
+
+// send delivers c unless the subscriber is cancelled first
+func (s *subscriber) send(c model.Candle) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	select {
+	case s.ch <- c:
+	case <-s.done:
+	}
 }
 
 // NewRingBuffer creates a new ring buffer with the specified capacity
@@ -27,15 +53,70 @@ func NewRingBuffer(capacity int) *RingBuffer {
 
 // Push adds a candle to the buffer
 // If the buffer is full, the oldest candle is overwritten
+// Subscribers are sent the candle after the buffer lock is released, so a slow subscriber delays
+// later pushes but not readers of the buffer
 func (rb *RingBuffer) Push(c model.Candle) {
-	rb.mu.Lock()
-	defer rb.mu.Unlock()
+	rb.sendMu.Lock()
+	defer rb.sendMu.Unlock()
 
+	rb.mu.Lock()
 	rb.data[rb.head] = c
 	rb.head = (rb.head + 1) % rb.capacity
 	if rb.size < rb.capacity {
 		rb.size++
 	}
+	subscribers := make([]*subscriber, len(rb.subscribers))
+	copy(subscribers, rb.subscribers)
+	rb.mu.Unlock()
+
+	for _, sub := range subscribers {
+		sub.send(c)
+	}
+}
+
+// Subscribe returns a channel receiving every candle pushed after the call, and a cancel function
+// The channel is buffered to the buffer capacity; when it fills, Push blocks until the consumer
+// reads or cancels. Cancel is idempotent and closes the channel.
+func (rb *RingBuffer) Subscribe() (<-chan model.Candle, func()) {
+	sub := &subscriber{
+		ch:   make(chan model.Candle, rb.capacity),
+		done: make(chan struct{}),
+	}
+
+	rb.mu.Lock()
+	rb.subscribers = append(rb.subscribers, sub)
+	rb.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			// Unblock any in-flight Push before taking the lock
+			close(sub.done)
+
+			rb.mu.Lock()
+			for i, s := range rb.subscribers {
+				if s == sub {
+					rb.subscribers = append(rb.subscribers[:i], rb.subscribers[i+1:]...)
+					break
+				}
+			}
+			rb.mu.Unlock()
+
+			sub.mu.Lock()
+			defer sub.mu.Unlock()
+			sub.closed = true
+			close(sub.ch)
+		})
+	}
+
+	return sub.ch, cancel
+}
+
+// SubscriberCount returns the number of active subscribers
+func (rb *RingBuffer) SubscriberCount() int {
+	rb.mu.RLock()
+	defer rb.mu.RUnlock()
+	return len(rb.subscribers)
 }
 
 // Size returns the current number of elements in the buffer
@@ -81,6 +162,57 @@ func (rb *RingBuffer) ToSlice() []model.Candle {
 	return result
 }
 
+// SliceRange returns a copy of the candles from index start up to but excluding end, oldest first
+// Index 0 is the oldest candle; negative indices count back from the newest, so -1 is the newest.
+// Returns nil if the range falls outside the buffer or end precedes start
+func (rb *RingBuffer) SliceRange(start, end int) []model.Candle {
+	rb.mu.RLock()
+	defer rb.mu.RUnlock()
+
+	if start < 0 {
+		start += rb.size
+	}
+	if end < 0 {
+		end += rb.size
+	}
+	if start < 0 || end > rb.size || start > end {
+		return nil
+	}
+	return rb.copyRange(start, end)
+}
+
+// LastN returns a copy of the newest n candles, oldest first, or every candle if fewer are buffered
+func (rb *RingBuffer) LastN(n int) []model.Candle {
+	rb.mu.RLock()
+	defer rb.mu.RUnlock()
+
+	n = max(0, min(n, rb.size))
+	return rb.copyRange(rb.size-n, rb.size)
+}
+
+// FirstN returns a copy of the oldest n candles, oldest first, or every candle if fewer are buffered
+func (rb *RingBuffer) FirstN(n int) []model.Candle {
+	rb.mu.RLock()
+	defer rb.mu.RUnlock()
+
+	n = max(0, min(n, rb.size))
+	return rb.copyRange(0, n)
+}
+
+// copyRange copies the candles at chronological indices [start, end); the caller holds the lock
+func (rb *RingBuffer) copyRange(start, end int) []model.Candle {
+	oldest := 0
+	if rb.size == rb.capacity {
+		oldest = rb.head
+	}
+
+	result := make([]model.Candle, end-start)
+	for i := range result {
+		result[i] = rb.data[(oldest+start+i)%rb.capacity]
+	}
+	return result
+}
+
 // Last returns the most recent candle
 func (rb *RingBuffer) Last() *model.Candle {
 	rb.mu.RLock()
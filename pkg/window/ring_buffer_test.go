@@ -0,0 +1,156 @@
+package window
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tunogya/etna/pkg/model"
+)
+
+func TestRingBufferSlices(t *testing.T) {
+	rb := NewRingBuffer(5)
+	for _, c := range testCandles(7) { // Wraps: holds candles 2..6
+		rb.Push(c)
+	}
+	if got := openMinutes(rb.ToSlice()); !slices.Equal(got, []int{2, 3, 4, 5, 6}) {
+		t.Fatalf("buffer holds minutes %v, want 2..6", got)
+	}
+
+	tests := []struct {
+		name string
+		got  []model.Candle
+		want []int
+	}{
+		{"SliceRange(1, 3)", rb.SliceRange(1, 3), []int{3, 4}},
+		{"SliceRange(-2, 5)", rb.SliceRange(-2, 5), []int{5, 6}},
+		{"LastN(3)", rb.LastN(3), []int{4, 5, 6}},
+		{"FirstN(2)", rb.FirstN(2), []int{2, 3}},
+		{"LastN(10)", rb.LastN(10), []int{2, 3, 4, 5, 6}},
+	}
+	for _, tt := range tests {
+		if got := openMinutes(tt.got); !slices.Equal(got, tt.want) {
+			t.Errorf("%s = minutes %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	for _, bad := range [][2]int{{3, 2}, {0, 6}, {-6, 1}} {
+		if got := rb.SliceRange(bad[0], bad[1]); got != nil {
+			t.Errorf("SliceRange(%d, %d) = %d candles, want nil", bad[0], bad[1], len(got))
+		}
+	}
+}
+
+func TestRingBufferSlowSubscriberDoesNotBlockReaders(t *testing.T) {
+	rb := NewRingBuffer(2)
+	ch, cancel := rb.Subscribe()
+	candles := testCandles(4)
+
+	// Two pushes fill the subscriber's channel, so the third blocks on it
+	rb.Push(candles[0])
+	rb.Push(candles[1])
+	pushed := make(chan struct{})
+	go func() {
+		rb.Push(candles[2])
+		close(pushed)
+	}()
+
+	// Readers must not wait for the blocked send
+	read := make(chan int)
+	go func() { read <- rb.Size() + len(rb.ToSlice()) }()
+	select {
+	case n := <-read:
+		if n != 4 {
+			t.Errorf("Size + len(ToSlice) = %d, want 4", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("readers blocked behind a full subscriber")
+	}
+
+	// Draining delivers the blocked candle in order
+	for i := 0; i < 3; i++ {
+		if got := <-ch; !got.OpenTime.Equal(candles[i].OpenTime) {
+			t.Errorf("received candle %d out of order", i)
+		}
+	}
+	<-pushed
+
+	// Cancelling releases a blocked Push and closes the channel
+	rb.Push(candles[3])
+	rb.Push(candles[3])
+	go rb.Push(candles[3])
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	cancel()
+	if rb.SubscriberCount() != 0 {
+		t.Errorf("SubscriberCount = %d after cancel", rb.SubscriberCount())
+	}
+	for range ch {
+	}
+}
+
+func TestRingBufferConcurrentSubscribers(t *testing.T) {
+	rb := NewRingBuffer(4)
+	candles := testCandles(50)
+
+	// Three subscribers each receive every candle, in push order, while consuming concurrently
+	const subscribers = 3
+	received := make([][]int, subscribers)
+	var wg sync.WaitGroup
+	cancels := make([]func(), subscribers)
+	for i := 0; i < subscribers; i++ {
+		ch, cancel := rb.Subscribe()
+		cancels[i] = cancel
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for c := range ch {
+				received[i] = append(received[i], c.OpenTime.Minute())
+				if len(received[i]) == len(candles) {
+					return
+				}
+			}
+		}(i)
+	}
+	if n := rb.SubscriberCount(); n != subscribers {
+		t.Fatalf("SubscriberCount = %d, want %d", n, subscribers)
+	}
+
+	for _, c := range candles {
+		rb.Push(c)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscribers did not receive every candle")
+	}
+
+	want := openMinutes(candles)
+	for i, got := range received {
+		if !slices.Equal(got, want) {
+			t.Errorf("subscriber %d received minutes %v, want %v", i, got, want)
+		}
+	}
+	for _, cancel := range cancels {
+		cancel()
+	}
+	if n := rb.SubscriberCount(); n != 0 {
+		t.Errorf("SubscriberCount = %d after cancelling all", n)
+	}
+}
+
+// openMinutes returns the minute of each candle's open time
+func openMinutes(candles []model.Candle) []int {
+	minutes := make([]int, len(candles))
+	for i, c := range candles {
+		minutes[i] = c.OpenTime.Minute()
+	}
+	return minutes
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/csv"
 	"encoding/json"
 	"flag"
@@ -10,6 +11,10 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"time"
+
+	"github.com/tunogya/etna/pkg/data/binance"
+	"github.com/tunogya/etna/pkg/model"
 )
 
 func main() {
@@ -20,12 +25,18 @@ func main() {
 	output := flag.String("output", "", "Output CSV file path")
 	all := flag.Bool("all", false, "Fetch all available history")
 	startTime := flag.Int64("startTime", 1502928000000, "Start time in ms (default: 2017-08-17)")
+	checkpoint := flag.String("checkpoint", "", "Checkpoint JSON file; resumes from it and appends new closed klines to the output")
 	flag.Parse()
 
 	if *output == "" {
 		*output = fmt.Sprintf("data/%s_%s.csv", *symbol, *interval)
 	}
 
+	if *checkpoint != "" {
+		fetchIncremental(*symbol, *interval, *output, *checkpoint, *startTime)
+		return
+	}
+
 	var allKlines [][]interface{}
 	currentStartTime := *startTime
 
@@ -121,3 +132,52 @@ func main() {
 
 	log.Printf("Saved to %s", *output)
 }
+
+// fetchIncremental appends the closed klines after the checkpoint to output, advancing the checkpoint per page
+func fetchIncremental(symbol, interval, output, checkpoint string, startTime int64) {
+	if err := os.MkdirAll("data", 0755); err != nil {
+		log.Fatalf("Failed to create data directory: %v", err)
+	}
+
+	_, statErr := os.Stat(output)
+	file, err := os.OpenFile(output, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Fatalf("Failed to open output file: %v", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if os.IsNotExist(statErr) {
+		writer.Write([]string{"symbol", "timeframe", "open_time", "close_time", "open", "high", "low", "close", "volume", "trades"})
+	}
+
+	fetcher := binance.NewKlineBatchFetcher(binance.NewFileCheckpointStore(checkpoint))
+	total, err := fetcher.Fetch(context.Background(), symbol, interval, time.UnixMilli(startTime), time.Time{}, func(candles []model.Candle) error {
+		for _, c := range candles {
+			writer.Write([]string{
+				c.Symbol,
+				c.Timeframe,
+				strconv.FormatInt(c.OpenTime.UnixMilli(), 10),
+				strconv.FormatInt(c.CloseTime.UnixMilli(), 10),
+				strconv.FormatFloat(c.Open, 'f', -1, 64),
+				strconv.FormatFloat(c.High, 'f', -1, 64),
+				strconv.FormatFloat(c.Low, 'f', -1, 64),
+				strconv.FormatFloat(c.Close, 'f', -1, 64),
+				strconv.FormatFloat(c.Volume, 'f', -1, 64),
+				strconv.FormatInt(c.Trades, 10),
+			})
+		}
+		// Rows must reach the file before the checkpoint moves past them
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return err
+		}
+		log.Printf("Appended %d klines up to %s", len(candles), candles[len(candles)-1].CloseTime.UTC().Format(time.RFC3339))
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Failed to fetch klines: %v", err)
+	}
+
+	log.Printf("Appended %d klines to %s", total, output)
+}